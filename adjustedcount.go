@@ -0,0 +1,102 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// SamplingThresholdKey is the attribute holding the sampling
+	// threshold, encoded as in the tracestate "th" sub-key.
+	SamplingThresholdKey = attribute.Key("sampling.threshold")
+
+	// SamplingAdjustedCountKey is the attribute holding the
+	// adjusted count, the inverse of the sampling probability.
+	SamplingAdjustedCountKey = attribute.Key("sampling.adjusted_count")
+
+	// SamplingProbabilityKey is the attribute holding the
+	// sampling probability.  See WithProbabilityAttribute.
+	SamplingProbabilityKey = attribute.Key("sampling.probability")
+)
+
+// AnnotateAdjustedCount is a sampler that adds the sampling threshold
+// and adjusted count to spans sampled by another sampler, so that
+// backends can re-weight data derived from spans without parsing
+// tracestate.  Nothing is added when the threshold is not reliable.
+// The attributes describe the threshold written to the tracestate,
+// which is rounded down by WithThresholdPrecision.
+//
+// This should be the outermost ComposableSampler, because the
+// attributes are computed from its intent.
+func AnnotateAdjustedCount(sampler ComposableSampler) ComposableSampler {
+	return &adjustedCountSampler{
+		sampler: sampler,
+	}
+}
+
+type adjustedCountSampler struct {
+	sampler ComposableSampler
+}
+
+var _ ComposableSampler = &adjustedCountSampler{}
+var _ SamplerOptimizer = &adjustedCountSampler{}
+var _ CostStatsProvider = &adjustedCountSampler{}
+
+// GetSamplingIntent implements ComposableSampler.
+func (ac *adjustedCountSampler) GetSamplingIntent(params ComposableSamplingParameters) SamplingIntent {
+	intent := ac.sampler.GetSamplingIntent(params)
+	if !intent.ThresholdReliable || intent.Threshold < ALWAYS_SAMPLE_THRESHOLD || intent.Threshold >= NEVER_SAMPLE_THRESHOLD {
+		return intent
+	}
+	threshold := intent.Threshold
+	if params.thresholdDigits != 0 {
+		threshold = threshold.truncate(params.thresholdDigits)
+	}
+	intent.Attributes = CombineAttributes(intent.Attributes, func() []attribute.KeyValue {
+		return []attribute.KeyValue{
+			SamplingThresholdKey.String(threshold.String()),
+			SamplingAdjustedCountKey.Float64(threshold.AdjustedCount()),
+		}
+	})
+	return intent
+}
+
+// Description implements ComposableSampler.
+func (ac *adjustedCountSampler) Description() string {
+	return fmt.Sprintf("AnnotateAdjustedCount(%s)", ac.sampler.Description())
+}
+
+// CostStats implements CostStatsProvider, returning the statistics of
+// the wrapped sampler, if any.
+func (ac *adjustedCountSampler) CostStats() []CostStats {
+	return costStatsOf(ac.sampler)
+}
+
+// Optimize implements SamplerOptimizer.
+func (ac *adjustedCountSampler) Optimize(res *resource.Resource, scope instrumentation.Scope) ComposableSampler {
+	return AnnotateAdjustedCount(Optimize(ac.sampler, res, scope))
+}
+
+// AdjustedCountFromTraceState returns the adjusted count of a span
+// from the "th" sub-key of its OpenTelemetry tracestate, for span
+// processors and exporters that weight sampled spans.  It returns
+// false when the tracestate has no valid threshold, in which case the
+// adjusted count is unknown.
+func AdjustedCountFromTraceState(ts trace.TraceState) (float64, bool) {
+	otts := ts.Get("ot")
+	if otts == "" {
+		return 0, false
+	}
+	threshold, _, has, err := tracestateHasThreshold(otts)
+	if err != nil || !has {
+		return 0, false
+	}
+	return threshold.AdjustedCount(), true
+}
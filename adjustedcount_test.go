@@ -0,0 +1,115 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestAnnotateAdjustedCount(t *testing.T) {
+	sampler := CompositeSampler(AnnotateAdjustedCount(ComposableParentBased(TraceIDRatioBased(0.25))))
+	require.Equal(t, "AnnotateAdjustedCount(RuleBased{rule(root?)=TraceIDRatioBased{0.25},rule(true)=ParentThreshold})", sampler.Description())
+
+	// Root span, sampled at 25%.
+	test := defaultTestFuncs()
+	test.parentid = func(*rand.Rand) trace.TraceID {
+		return trace.TraceID{}
+	}
+	test.traceid = func(*rand.Rand) trace.TraceID {
+		return trace.TraceID{0, 0, 0, 0, 0, 0, 0, 0, 0, 0xf0}
+	}
+	result := sampler.ShouldSample(makeTestContext(test).SamplingParameters)
+	require.Equal(t, RecordAndSample, result.Decision)
+	require.Equal(t, []attribute.KeyValue{
+		SamplingThresholdKey.String("c"),
+		SamplingAdjustedCountKey.Float64(4),
+	}, result.Attributes)
+
+	// Child span with unknown threshold.
+	result = sampler.ShouldSample(makeTestContext(defaultTestFuncs()).SamplingParameters)
+	require.Equal(t, RecordAndSample, result.Decision)
+	require.Empty(t, result.Attributes)
+}
+
+func TestAnnotateAdjustedCountPrecision(t *testing.T) {
+	root := SamplingParameters{
+		ParentContext: context.Background(),
+		TraceID:       trace.TraceID{15: 0xff, 14: 0xff, 13: 0xff, 12: 0xff, 11: 0xff, 10: 0xff, 9: 0xff},
+	}
+	sampler := AnnotateAdjustedCount(TraceIDRatioBased(1.0 / 3))
+
+	// The threshold of the intent.
+	result := CompositeSampler(sampler).ShouldSample(root)
+	require.Equal(t, "th:aaab", result.Tracestate.Get("ot"))
+	require.Equal(t, []attribute.KeyValue{
+		SamplingThresholdKey.String("aaab"),
+		SamplingAdjustedCountKey.Float64(65536.0 / 0x5555),
+	}, result.Attributes)
+
+	// The written threshold, rounded down to two digits.
+	result = CompositeSampler(sampler, WithThresholdPrecision(2)).ShouldSample(root)
+	require.Equal(t, "th:aa", result.Tracestate.Get("ot"))
+	require.Equal(t, []attribute.KeyValue{
+		SamplingThresholdKey.String("aa"),
+		SamplingAdjustedCountKey.Float64(256.0 / 86),
+	}, result.Attributes)
+	count, ok := AdjustedCountFromTraceState(result.Tracestate)
+	require.True(t, ok)
+	require.Equal(t, 256.0/86, count)
+}
+
+func TestAdjustedCountFromTraceState(t *testing.T) {
+	for _, test := range []struct {
+		in    string
+		count float64
+		ok    bool
+	}{
+		{"", 0, false},
+		{"a=b", 0, false},
+		{"ot=rv:c0000000000000", 0, false},
+		{"ot=th:xyz", 0, false},
+		{"ot=th:0", 1, true},
+		{"ot=th:8", 2, true},
+		{"ot=rv:c0000000000000;th:c,a=b", 4, true},
+	} {
+		t.Run(test.in, func(t *testing.T) {
+			ts, err := trace.ParseTraceState(test.in)
+			require.NoError(t, err)
+			count, ok := AdjustedCountFromTraceState(ts)
+			require.Equal(t, test.ok, ok)
+			require.Equal(t, test.count, count)
+		})
+	}
+}
+
+func TestProbabilityAttribute(t *testing.T) {
+	root := SamplingParameters{
+		ParentContext: context.Background(),
+		TraceID:       trace.TraceID{15: 0xff, 14: 0xff, 13: 0xff, 12: 0xff, 11: 0xff, 10: 0xff, 9: 0xff},
+	}
+	quarter := AnnotatingSampler(TraceIDRatioBased(0.25), WithSampledAttributes(func() []attribute.KeyValue {
+		return []attribute.KeyValue{attribute.String("a", "b")}
+	}))
+
+	res := CompositeSampler(quarter).ShouldSample(root)
+	require.Equal(t, []attribute.KeyValue{attribute.String("a", "b")}, res.Attributes)
+
+	res = CompositeSampler(quarter, WithProbabilityAttribute()).ShouldSample(root)
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Equal(t, []attribute.KeyValue{attribute.String("a", "b"), SamplingProbabilityKey.Float64(0.25)}, res.Attributes)
+
+	// The probability of the written threshold.
+	res = CompositeSampler(TraceIDRatioBased(1.0/3), WithProbabilityAttribute(), WithThresholdPrecision(1)).ShouldSample(root)
+	require.Equal(t, []attribute.KeyValue{SamplingProbabilityKey.Float64(0.375)}, res.Attributes)
+
+	// Not for unreliable thresholds.
+	res = CompositeSampler(ComposableAlwaysSample(), WithProbabilityAttribute(), WithShortTraceIDPolicy(UnreliableRandomness)).ShouldSample(root)
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Empty(t, res.Attributes)
+}
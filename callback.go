@@ -0,0 +1,90 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SamplingContext describes a span to a TracesSamplerFunc.
+type SamplingContext struct {
+	// Context is the parent context, from which values such as
+	// baggage can be read.
+	Context context.Context
+	// Name is the span name.
+	Name string
+	// Kind is the span kind.
+	Kind trace.SpanKind
+	// Attributes are the span's starting attributes.
+	Attributes []attribute.KeyValue
+	// HasParent is true when the span has a valid parent.
+	HasParent bool
+	// ParentSampled is true when the parent is sampled.
+	ParentSampled bool
+	// ParentRemote is true when the parent was propagated from
+	// another process.
+	ParentRemote bool
+}
+
+// TracesSamplerFunc returns the probability of sampling a span, in
+// the style of the traces_sampler callbacks of Sentry and similar
+// tracing SDKs.
+type TracesSamplerFunc func(SamplingContext) float64
+
+// CallbackSampler is a sampler that samples each span consistently
+// with the probability returned by a callback, which is converted to
+// a reliable threshold, as by TraceIDRatioBased.  Probabilities of
+// one or more always sample, and probabilities that are zero,
+// negative, NaN, or smaller than the smallest supported probability
+// never sample.
+//
+// The callback is called for every span, including children of
+// sampled parents, whose threshold it replaces.  To apply it to root
+// spans only, so that other spans follow their parent, use
+// ComposableParentBased(CallbackSampler(fn, description)).
+func CallbackSampler(fn TracesSamplerFunc, description string) ComposableSampler {
+	return &callbackSampler{
+		fn:          fn,
+		description: description,
+	}
+}
+
+type callbackSampler struct {
+	fn          TracesSamplerFunc
+	description string
+}
+
+var _ ComposableSampler = &callbackSampler{}
+
+// GetSamplingIntent implements ComposableSampler.
+func (cs *callbackSampler) GetSamplingIntent(params ComposableSamplingParameters) SamplingIntent {
+	psc := params.ParentSpanContext
+	probability := cs.fn(SamplingContext{
+		Context:       params.ParentContext,
+		Name:          params.Name,
+		Kind:          params.Kind,
+		Attributes:    params.Attributes,
+		HasParent:     psc.IsValid(),
+		ParentSampled: psc.IsValid() && psc.IsSampled(),
+		ParentRemote:  psc.IsValid() && psc.IsRemote(),
+	})
+	if !(probability >= minSupportedProbability) {
+		return SamplingIntent{
+			Threshold: NEVER_SAMPLE_THRESHOLD,
+		}
+	}
+	return SamplingIntent{
+		Threshold:         ProbabilityToThreshold(probability),
+		ThresholdReliable: true,
+	}
+}
+
+// Description implements ComposableSampler.
+func (cs *callbackSampler) Description() string {
+	return fmt.Sprintf("Callback{%s}", cs.description)
+}
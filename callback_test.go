@@ -0,0 +1,80 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestCallbackSampler(t *testing.T) {
+	var got SamplingContext
+	probability := 0.0
+	sampler := CallbackSampler(func(sc SamplingContext) float64 {
+		got = sc
+		return probability
+	}, "checkout")
+	require.Equal(t, "Callback{checkout}", sampler.Description())
+
+	for _, test := range []struct {
+		probability float64
+		threshold   Threshold
+		reliable    bool
+	}{
+		{0.5, ProbabilityToThreshold(0.5), true},
+		{0.01, ProbabilityToThreshold(0.01), true},
+		{1, ALWAYS_SAMPLE_THRESHOLD, true},
+		{2, ALWAYS_SAMPLE_THRESHOLD, true},
+		{0, NEVER_SAMPLE_THRESHOLD, false},
+		{-1, NEVER_SAMPLE_THRESHOLD, false},
+		{math.NaN(), NEVER_SAMPLE_THRESHOLD, false},
+	} {
+		probability = test.probability
+		intent := sampler.GetSamplingIntent(ComposableSamplingParameters{})
+		require.Equal(t, test.threshold, intent.Threshold, "%v", test.probability)
+		require.Equal(t, test.reliable, intent.ThresholdReliable, "%v", test.probability)
+	}
+
+	// The callback receives the span and its parent.
+	probability = 0.25
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{9: 0xff},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), parent)
+	attrs := []attribute.KeyValue{attribute.String("http.route", "/cart")}
+	res := CompositeSampler(sampler).ShouldSample(SamplingParameters{
+		ParentContext: ctx,
+		TraceID:       parent.TraceID(),
+		Name:          "GET /cart",
+		Kind:          trace.SpanKindServer,
+		Attributes:    attrs,
+	})
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Equal(t, "th:c", res.Tracestate.Get("ot"))
+	require.Equal(t, SamplingContext{
+		Context:       ctx,
+		Name:          "GET /cart",
+		Kind:          trace.SpanKindServer,
+		Attributes:    attrs,
+		HasParent:     true,
+		ParentSampled: true,
+		ParentRemote:  true,
+	}, got)
+
+	// Applied to roots only, children follow their parent.
+	got = SamplingContext{}
+	res = CompositeSampler(ComposableParentBased(sampler)).ShouldSample(SamplingParameters{
+		ParentContext: ctx,
+		TraceID:       parent.TraceID(),
+	})
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Equal(t, SamplingContext{}, got)
+}
@@ -0,0 +1,147 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package celpredicate implements sampler predicates using Common
+// Expression Language (CEL) expressions, so that policy conditions
+// can be configured as strings.
+//
+// Expressions are evaluated with the following variables:
+//
+//	name        string            the span name
+//	kind        string            the span kind, e.g., "server"
+//	attributes  map(string, dyn)  the span's starting attributes
+//	resource    map(string, dyn)  the tracer's Resource attributes
+//	parent      map(string, dyn)  the parent context, with keys "valid",
+//	                              "sampled", "remote" (bool) and
+//	                              "tracestate" (string)
+//
+// For example:
+//
+//	name.startsWith("/api/") && attributes["http.request.method"] == "POST"
+//
+// The resource variable is empty until the predicate is optimized
+// for a Resource.  Expressions that depend only on the resource
+// become constants when optimized.
+package celpredicate
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/interpreter"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+
+	"github.com/jmacd/sampler"
+)
+
+var spanVariables = []string{"name", "kind", "attributes", "parent"}
+
+// New compiles a CEL expression with a boolean result into a
+// predicate.  Compilation errors are returned here.
+func New(expr string) (sampler.Predicate, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("name", cel.StringType),
+		cel.Variable("kind", cel.StringType),
+		cel.Variable("attributes", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("resource", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("parent", cel.MapType(cel.StringType, cel.DynType)),
+	)
+	if err != nil {
+		return sampler.Predicate{}, err
+	}
+	ast, iss := env.Compile(expr)
+	if iss.Err() != nil {
+		return sampler.Predicate{}, fmt.Errorf("cel: %w", iss.Err())
+	}
+	if ast.OutputType() != cel.BoolType {
+		return sampler.Predicate{}, fmt.Errorf("cel: expression has type %s, not bool", ast.OutputType())
+	}
+	prg, err := env.Program(ast)
+	if err != nil {
+		return sampler.Predicate{}, fmt.Errorf("cel: %w", err)
+	}
+	partial, err := env.Program(ast, cel.EvalOptions(cel.OptPartialEval))
+	if err != nil {
+		return sampler.Predicate{}, fmt.Errorf("cel: %w", err)
+	}
+	cp := &celPredicate{
+		expr:     expr,
+		desc:     fmt.Sprintf("CEL(%s)", expr),
+		program:  prg,
+		partial:  partial,
+		resource: map[string]any{},
+	}
+	return cp.predicate(), nil
+}
+
+type celPredicate struct {
+	expr     string
+	desc     string
+	program  cel.Program
+	partial  cel.Program
+	resource map[string]any
+}
+
+var _ sampler.PredicateOptimizer = &celPredicate{}
+
+// predicate returns the Predicate evaluating this expression, which
+// is described by the Spec named "cel" with the argument "expr".
+func (cp *celPredicate) predicate() sampler.Predicate {
+	return sampler.PredicateFunc(cp.decide).Describe(cp.desc).WithOptimizer(cp).
+		WithSpec(sampler.Spec{Name: "cel", Args: map[string]any{"expr": cp.expr}})
+}
+
+// decide evaluates the expression for one span.  Evaluation errors,
+// such as a missing map key, do not match.
+func (cp *celPredicate) decide(params sampler.ComposableSamplingParameters) bool {
+	psc := params.ParentSpanContext
+	out, _, err := cp.program.Eval(map[string]any{
+		"name":       params.Name,
+		"kind":       params.Kind.String(),
+		"attributes": attributeMap(params.Attributes),
+		"resource":   cp.resource,
+		"parent": map[string]any{
+			"valid":      psc.IsValid(),
+			"sampled":    psc.IsSampled(),
+			"remote":     psc.IsRemote(),
+			"tracestate": psc.TraceState().String(),
+		},
+	})
+	return err == nil && out == types.True
+}
+
+// Optimize implements sampler.PredicateOptimizer.
+func (cp *celPredicate) Optimize(res *resource.Resource, _ instrumentation.Scope) sampler.Predicate {
+	opt := *cp
+	opt.resource = attributeMap(res.Attributes())
+
+	// Evaluate with unknown span variables: a known result means
+	// the expression depends only on the resource.
+	var unknown []*interpreter.AttributePattern
+	for _, name := range spanVariables {
+		unknown = append(unknown, cel.AttributePattern(name))
+	}
+	act, err := cel.PartialVars(map[string]any{"resource": opt.resource}, unknown...)
+	if err == nil {
+		out, _, err := opt.partial.Eval(act)
+		if err == nil && types.IsBool(out) {
+			if out == types.True {
+				return sampler.TruePredicate()
+			}
+			return sampler.FalsePredicate()
+		}
+	}
+	return opt.predicate()
+}
+
+// attributeMap converts attributes to a map of CEL-compatible values.
+func attributeMap(attrs []attribute.KeyValue) map[string]any {
+	m := make(map[string]any, len(attrs))
+	for _, kv := range attrs {
+		m[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	return m
+}
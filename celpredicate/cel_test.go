@@ -0,0 +1,91 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package celpredicate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jmacd/sampler"
+)
+
+func params(name string, kind trace.SpanKind, attrs ...attribute.KeyValue) sampler.ComposableSamplingParameters {
+	return sampler.ComposableSamplingParameters{
+		SamplingParameters: sampler.SamplingParameters{
+			Name:       name,
+			Kind:       kind,
+			Attributes: attrs,
+		},
+	}
+}
+
+func TestCELPredicate(t *testing.T) {
+	pred, err := New(`name.startsWith("/api/") && kind == "server" && attributes["http.request.method"] == "POST"`)
+	require.NoError(t, err)
+	require.Equal(t, `CEL(name.startsWith("/api/") && kind == "server" && attributes["http.request.method"] == "POST")`, pred.Description())
+	spec, ok := pred.Spec()
+	require.True(t, ok)
+	require.Equal(t, sampler.Spec{Name: "cel", Args: map[string]any{
+		"expr": `name.startsWith("/api/") && kind == "server" && attributes["http.request.method"] == "POST"`,
+	}}, spec)
+
+	post := attribute.String("http.request.method", "POST")
+	get := attribute.String("http.request.method", "GET")
+	require.True(t, pred.Decide(params("/api/orders", trace.SpanKindServer, post)))
+	require.False(t, pred.Decide(params("/api/orders", trace.SpanKindServer, get)))
+	require.False(t, pred.Decide(params("/api/orders", trace.SpanKindClient, post)))
+
+	// A missing key is an evaluation error, which does not match.
+	require.False(t, pred.Decide(params("/api/orders", trace.SpanKindServer)))
+
+	numeric, err := New(`attributes["messaging.batch.message_count"] > 100 && !parent.valid`)
+	require.NoError(t, err)
+	require.True(t, numeric.Decide(params("batch", trace.SpanKindConsumer, attribute.Int("messaging.batch.message_count", 1000))))
+	require.False(t, numeric.Decide(params("batch", trace.SpanKindConsumer, attribute.Int("messaging.batch.message_count", 10))))
+}
+
+func TestCELPredicateErrors(t *testing.T) {
+	_, err := New(`name ==`)
+	require.ErrorContains(t, err, "cel:")
+
+	_, err = New(`name`)
+	require.ErrorContains(t, err, "not bool")
+
+	_, err = New(`unknown == 1`)
+	require.ErrorContains(t, err, "undeclared reference")
+}
+
+func TestCELPredicateOptimize(t *testing.T) {
+	staging := resource.NewSchemaless(attribute.String("deployment.environment", "staging"))
+	prod := resource.NewSchemaless(attribute.String("deployment.environment", "production"))
+
+	// Depends only on the resource: folds to a constant.
+	env, err := New(`resource["deployment.environment"] == "staging"`)
+	require.NoError(t, err)
+	require.False(t, env.Decide(params("x", trace.SpanKindServer)))
+	require.Equal(t, sampler.TruePredicate().Description(), env.Optimize(staging, instrumentation.Scope{}).Description())
+	require.Equal(t, sampler.FalsePredicate().Description(), env.Optimize(prod, instrumentation.Scope{}).Description())
+
+	// Depends on both: the resource is bound.
+	both, err := New(`resource["deployment.environment"] == "staging" && name == "/debug"`)
+	require.NoError(t, err)
+	opt := both.Optimize(staging, instrumentation.Scope{})
+	require.Equal(t, both.Description(), opt.Description())
+	require.True(t, opt.Decide(params("/debug", trace.SpanKindServer)))
+	require.False(t, opt.Decide(params("/other", trace.SpanKindServer)))
+
+	rb := sampler.RuleBased(
+		sampler.WithRule(both, sampler.ComposableAlwaysSample()),
+		sampler.WithRule(env, sampler.ComposableNeverSample()),
+		sampler.WithDefaultRule(sampler.TraceIDRatioBased(0.5)),
+	)
+	require.Equal(t, "RuleBased{rule(true)=TraceIDRatioBased{0.5}}",
+		sampler.Optimize(rb, prod, instrumentation.Scope{}).Description())
+	require.Equal(t, `RuleBased{rule(CEL(resource["deployment.environment"] == "staging" && name == "/debug"))=AlwaysOn,rule(true)=AlwaysOff}`,
+		sampler.Optimize(rb, staging, instrumentation.Scope{}).Description())
+}
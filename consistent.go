@@ -0,0 +1,155 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// legacyRandomnessSearchKey is the deprecated geometric randomness
+// field.
+const legacyRandomnessSearchKey fieldSearchKey = ";r:"
+
+// maxLegacyRValue is the largest valid "r" sub-key.
+const maxLegacyRValue = 62
+
+// WithLegacyRandomness reads the deprecated "r" sub-key of the
+// OpenTelemetry tracestate, the geometric randomness written by the
+// earlier consistent-probability samplers, when there is no "rv"
+// sub-key.  The randomness of a span with "r:N" has N leading one
+// bits followed by a zero bit, with the remaining bits taken from the
+// TraceID, so that a threshold of probability 2^-p samples exactly
+// the spans that a consistent-probability sampler with that
+// probability does, and other thresholds sample at least the spans
+// that the next smaller power of two would.  Invalid "r" sub-keys are
+// reported to the error handler and ignored.
+func WithLegacyRandomness() CompositeOption {
+	return func(cfg *compositeConfig) {
+		cfg.legacyRandomness = true
+	}
+}
+
+// WithLegacySubKeys writes the deprecated "p" and "r" sub-keys of the
+// OpenTelemetry tracestate, alongside "th" and "rv", for sampled spans,
+// so that downstream services still using the earlier
+// consistent-probability samplers count them correctly.  The "p"
+// sub-key is written when the threshold is reliable and its
+// probability is a power of two, and removed otherwise.  The "r"
+// sub-key, unless already present, is the number of leading one bits
+// of the randomness, so that those samplers make the same decisions
+// as threshold samplers with power-of-two probabilities.
+func WithLegacySubKeys() CompositeOption {
+	return func(cfg *compositeConfig) {
+		cfg.legacySubKeys = true
+	}
+}
+
+// WithConsistentProbabilityMigration combines WithLegacyProbability,
+// WithLegacyRandomness, and WithLegacySubKeys, for services migrating
+// from the consistent-probability samplers of
+// go.opentelemetry.io/contrib/samplers/probability/consistent while
+// other services in their traces still use them.  During the
+// migration, replace
+//
+//	consistent.ProbabilityBased(fraction)    TraceIDRatioBased(fraction)
+//	consistent.ParentProbabilityBased(root)  ComposableParentBased(root)
+//
+// Power-of-two fractions then make the same decisions as those
+// samplers in every trace.  ProbabilityBased samples other fractions
+// by randomly choosing between the two nearest powers of two for each
+// span, so TraceIDRatioBased decisions agree with its decisions at
+// the smaller power of two, and spans that it samples at the larger
+// power of two are sampled with the intended probability.
+func WithConsistentProbabilityMigration() CompositeOption {
+	return func(cfg *compositeConfig) {
+		cfg.legacyProbability = true
+		cfg.legacyRandomness = true
+		cfg.legacySubKeys = true
+	}
+}
+
+// tracestateHasLegacyRandomness determines whether there is a
+// deprecated "r" sub-key, and converts it to randomness, filling the
+// bits following its leading one bits and zero bit from fill.
+func tracestateHasLegacyRandomness(otts string, fill int64) (int64, bool, error) {
+	val, _, has := tracestateHasOTelField(otts, legacyRandomnessSearchKey)
+	if !has {
+		return 0, false, nil
+	}
+	r, err := strconv.ParseUint(val, 10, 8)
+	if err != nil || r > maxLegacyRValue {
+		return 0, false, fmt.Errorf("could not parse tracestate legacy randomness: %q: %w", otts, strconv.ErrSyntax)
+	}
+	if r >= 56 {
+		return int64(randomnessMask), true, nil
+	}
+	ones := randomnessMask &^ (randomnessMask >> r)
+	return int64(ones | uint64(fill)&(randomnessMask>>(r+1))), true, nil
+}
+
+// legacyPValue returns the "p" sub-key of a threshold whose
+// probability is a power of two.
+func legacyPValue(threshold Threshold) (int, bool) {
+	rejected := maxAdjustedCount - uint64(threshold)
+	if !threshold.IsValid() || rejected == 0 || rejected&(rejected-1) != 0 {
+		return 0, false
+	}
+	return 56 - bits.TrailingZeros64(rejected), true
+}
+
+// legacyRValue returns the "r" sub-key of a randomness value, the
+// number of its leading one bits.
+func legacyRValue(rnd int64) int {
+	return bits.LeadingZeros64(^(uint64(rnd) << 8))
+}
+
+// legacySubKeys updates the "p" and "r" sub-keys of a sampled span's
+// tracestate, see WithLegacySubKeys.
+func legacySubKeys(ts trace.TraceState, threshold Threshold, thresholdReliable, randomnessReliable bool, rnd int64) trace.TraceState {
+	otts := ts.Get("ot")
+	_, _, hasR := tracestateHasOTelField(otts, legacyRandomnessSearchKey)
+	var pvalue string
+	if p, ok := legacyPValue(threshold); ok && thresholdReliable {
+		pvalue = "p:" + strconv.Itoa(p)
+	}
+	var fields []string
+	for _, field := range strings.Split(otts, ";") {
+		switch {
+		case field == "":
+		case strings.HasPrefix(field, "p:"):
+			// Replaced in place.
+			if pvalue != "" {
+				fields = append(fields, pvalue)
+				pvalue = ""
+			}
+		default:
+			fields = append(fields, field)
+		}
+	}
+	if pvalue != "" {
+		fields = append(fields, pvalue)
+	}
+	if !hasR && randomnessReliable {
+		fields = append(fields, "r:"+strconv.Itoa(legacyRValue(rnd)))
+	}
+	value := strings.Join(fields, ";")
+	switch {
+	case value == otts:
+		return ts
+	case value == "":
+		return ts.Delete("ot")
+	}
+	updated, err := ts.Insert("ot", value)
+	if err != nil {
+		otel.Handle(fmt.Errorf("tracestate: %w", err))
+		return ts
+	}
+	return updated
+}
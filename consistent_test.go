@@ -0,0 +1,147 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTraceStateHasLegacyRandomness(t *testing.T) {
+	const fill = 0x123456789abcde
+	for _, test := range []struct {
+		otts string
+		rnd  int64
+		has  bool
+		err  bool
+	}{
+		{"", 0, false, false},
+		{"p:2", 0, false, false},
+		{"r:0", 0x123456789abcde & 0x7fffffffffffff, true, false},
+		{"r:1", 0x80000000000000 | 0x123456789abcde&0x3fffffffffffff, true, false},
+		{"p:1;r:4", 0xf0000000000000 | 0x123456789abcde&0x07ffffffffffff, true, false},
+		{"r:55", 0xfffffffffffffe, true, false},
+		{"r:56", 0xffffffffffffff, true, false},
+		{"r:62", 0xffffffffffffff, true, false},
+		{"r:63", 0, false, true},
+		{"r:x", 0, false, true},
+	} {
+		rnd, has, err := tracestateHasLegacyRandomness(test.otts, fill)
+		require.Equal(t, test.rnd, rnd, test.otts)
+		require.Equal(t, test.has, has, test.otts)
+		require.Equal(t, test.err, err != nil, test.otts)
+	}
+}
+
+func TestLegacyPValue(t *testing.T) {
+	for p := 0; p <= 56; p++ {
+		th := ProbabilityToThreshold(1 / float64(uint64(1)<<p))
+		got, ok := legacyPValue(th)
+		require.True(t, ok, p)
+		require.Equal(t, p, got)
+	}
+	_, ok := legacyPValue(ProbabilityToThreshold(0.3))
+	require.False(t, ok)
+	_, ok = legacyPValue(NEVER_SAMPLE_THRESHOLD)
+	require.False(t, ok)
+}
+
+func TestLegacyRandomness(t *testing.T) {
+	// The TraceID alone would not be sampled at any probability
+	// below one.
+	traceID := trace.TraceID{15: 1}
+	parent := func(otts string) SamplingParameters {
+		ts, err := trace.ParseTraceState("ot=" + otts)
+		require.NoError(t, err)
+		return SamplingParameters{
+			ParentContext: trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID:    traceID,
+				SpanID:     trace.SpanID{1},
+				TraceState: ts,
+			})),
+			TraceID: traceID,
+		}
+	}
+
+	// A consistent-probability sampler with p:3 samples r >= 3.
+	eighth := TraceIDRatioBased(0.125)
+	res := CompositeSampler(eighth).ShouldSample(parent("r:3"))
+	require.Equal(t, Drop, res.Decision)
+	res = CompositeSampler(eighth, WithLegacyRandomness()).ShouldSample(parent("r:3"))
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Equal(t, "th:e;r:3", res.Tracestate.Get("ot"))
+	res = CompositeSampler(eighth, WithLegacyRandomness()).ShouldSample(parent("r:2"))
+	require.Equal(t, Drop, res.Decision)
+
+	// The "rv" sub-key takes precedence.
+	res = CompositeSampler(eighth, WithLegacyRandomness()).ShouldSample(parent("rv:00000000000000;r:3"))
+	require.Equal(t, Drop, res.Decision)
+
+	// Invalid sub-keys are reported.
+	var errs []error
+	res = CompositeSampler(eighth, WithLegacyRandomness(), WithTraceStateErrorHandler(func(err error) {
+		errs = append(errs, err)
+	})).ShouldSample(parent("r:99"))
+	require.Equal(t, Drop, res.Decision)
+	require.Len(t, errs, 1)
+	require.ErrorIs(t, errs[0], strconv.ErrSyntax)
+}
+
+func TestLegacySubKeys(t *testing.T) {
+	root := SamplingParameters{
+		ParentContext: context.Background(),
+		TraceID:       trace.TraceID{9: 0xfe},
+	}
+
+	// Power-of-two probabilities write "p", and "r" counts the
+	// leading one bits of the randomness.
+	res := CompositeSampler(TraceIDRatioBased(0.25), WithLegacySubKeys()).ShouldSample(root)
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Equal(t, "th:c;p:2;r:7", res.Tracestate.Get("ot"))
+
+	res = CompositeSampler(ComposableAlwaysSample(), WithLegacySubKeys()).ShouldSample(root)
+	require.Equal(t, "th:0;p:0;r:7", res.Tracestate.Get("ot"))
+
+	res = CompositeSampler(TraceIDRatioBased(0.3), WithLegacySubKeys()).ShouldSample(root)
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Equal(t, "th:b333;r:7", res.Tracestate.Get("ot"))
+
+	// Unsampled spans are unchanged.
+	res = CompositeSampler(TraceIDRatioBased(0.001), WithLegacySubKeys()).ShouldSample(root)
+	require.Equal(t, Drop, res.Decision)
+	require.Equal(t, "", res.Tracestate.Get("ot"))
+}
+
+func TestConsistentProbabilityMigration(t *testing.T) {
+	// A child of a span sampled by a consistent-probability sampler
+	// keeps its sub-keys, with the threshold of its "p" sub-key.
+	ts, err := trace.ParseTraceState("ot=p:2;r:5")
+	require.NoError(t, err)
+	traceID := trace.TraceID{15: 1}
+	params := SamplingParameters{
+		ParentContext: trace.ContextWithRemoteSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    traceID,
+			SpanID:     trace.SpanID{1},
+			TraceFlags: trace.FlagsSampled,
+			TraceState: ts,
+			Remote:     true,
+		})),
+		TraceID: traceID,
+	}
+	res := CompositeSampler(ComposableParentBased(TraceIDRatioBased(0.5)), WithConsistentProbabilityMigration()).ShouldSample(params)
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Equal(t, "th:c;p:2;r:5", res.Tracestate.Get("ot"))
+
+	// A rule that raises the probability of the trace rewrites "p".
+	res = CompositeSampler(TraceIDRatioBased(0.5), WithConsistentProbabilityMigration()).ShouldSample(params)
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Equal(t, "th:8;p:1;r:5", res.Tracestate.Get("ot"))
+
+	// And a rule that lowers it below r drops the span.
+	res = CompositeSampler(TraceIDRatioBased(1.0/64), WithConsistentProbabilityMigration()).ShouldSample(params)
+	require.Equal(t, Drop, res.Decision)
+}
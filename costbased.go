@@ -0,0 +1,197 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultSpanCost is the estimated size of a span, in bytes,
+	// not counting its attributes.
+	defaultSpanCost = 256
+
+	// defaultAttributeCost is the estimated size of one span
+	// attribute, in bytes.
+	defaultAttributeCost = 32
+
+	// defaultCostInterval is the interval over which the span
+	// arrival rate is measured.
+	defaultCostInterval = time.Second
+)
+
+// CostBasedOption configures a CostBased sampler.
+type CostBasedOption func(*costBasedConfig)
+
+type costBasedConfig struct {
+	nameCosts     map[string]float64
+	defaultCost   float64
+	attributeCost float64
+	interval      time.Duration
+	now           func() time.Time
+}
+
+// WithSpanNameCost sets the estimated size in bytes of spans with the
+// given name, not counting their attributes.
+func WithSpanNameCost(name string, bytes float64) CostBasedOption {
+	return func(cfg *costBasedConfig) {
+		cfg.nameCosts[name] = bytes
+	}
+}
+
+// WithDefaultSpanCost sets the estimated size in bytes of spans that
+// have no WithSpanNameCost estimate, not counting their attributes.
+func WithDefaultSpanCost(bytes float64) CostBasedOption {
+	return func(cfg *costBasedConfig) {
+		cfg.defaultCost = bytes
+	}
+}
+
+// WithAttributeCost sets the estimated size in bytes of each
+// attribute present at the start of the span.
+func WithAttributeCost(bytes float64) CostBasedOption {
+	return func(cfg *costBasedConfig) {
+		cfg.attributeCost = bytes
+	}
+}
+
+// WithCostInterval sets the interval over which the span arrival
+// rate is measured.
+func WithCostInterval(interval time.Duration) CostBasedOption {
+	return func(cfg *costBasedConfig) {
+		cfg.interval = interval
+	}
+}
+
+// CostBased is a sampler that targets a budget of estimated span
+// bytes per second, as opposed to a number of spans per second.  Each
+// span is sampled with probability inversely proportional to its
+// estimated cost, so that every sampled span contributes the same
+// expected number of bytes.
+//
+// The span arrival rate is measured over fixed intervals and the
+// measurement from the previous interval determines the probability
+// used in the next one.  All spans are sampled during the first
+// interval.
+func CostBased(bytesPerSecond float64, options ...CostBasedOption) ComposableSampler {
+	cfg := costBasedConfig{
+		nameCosts:     map[string]float64{},
+		defaultCost:   defaultSpanCost,
+		attributeCost: defaultAttributeCost,
+		interval:      defaultCostInterval,
+		now:           time.Now,
+	}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	return &costBased{
+		budget: bytesPerSecond,
+		config: cfg,
+		start:  cfg.now(),
+	}
+}
+
+type costBased struct {
+	budget float64
+	config costBasedConfig
+
+	lock  sync.Mutex
+	start time.Time // start of the current interval
+	count uint64    // spans in the current interval
+	rate  float64   // spans per second in the previous interval
+}
+
+var _ ComposableSampler = &costBased{}
+var _ CostStatsProvider = &costBased{}
+
+// CostStats are the runtime statistics of one CostBased sampler.
+type CostStats struct {
+	// Description describes the sampler.
+	Description string
+	// Budget is the sampler's budget of estimated bytes per
+	// second, or of spans per second for rate limits configured
+	// with a span cost of one.
+	Budget float64
+	// SpanRate is the span arrival rate measured in the previous
+	// interval, which determines the probabilities of the current
+	// one.
+	SpanRate float64
+}
+
+// CostStatsProvider is implemented by CostBased samplers, and by
+// RuleBased and wrapping samplers, such as AnnotatingSampler, for the
+// samplers they contain.
+type CostStatsProvider interface {
+	// CostStats returns statistics for each CostBased sampler.
+	CostStats() []CostStats
+}
+
+// costStatsOf returns the statistics of a sampler that implements
+// CostStatsProvider, otherwise nil.
+func costStatsOf(s ComposableSampler) []CostStats {
+	if cp, ok := s.(CostStatsProvider); ok {
+		return cp.CostStats()
+	}
+	return nil
+}
+
+// estimate returns the estimated size of the span in bytes.
+func (cb *costBased) estimate(params ComposableSamplingParameters) float64 {
+	cost, ok := cb.config.nameCosts[params.Name]
+	if !ok {
+		cost = cb.config.defaultCost
+	}
+	return cost + cb.config.attributeCost*float64(len(params.Attributes))
+}
+
+// spanRate counts one span and returns the span arrival rate
+// measured in the previous interval.
+func (cb *costBased) spanRate() float64 {
+	now := cb.config.now()
+
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+
+	cb.count++
+	if elapsed := now.Sub(cb.start); elapsed >= cb.config.interval {
+		cb.rate = float64(cb.count) / elapsed.Seconds()
+		cb.count = 0
+		cb.start = now
+	}
+	return cb.rate
+}
+
+// GetSamplingIntent implements ComposableSampler.
+func (cb *costBased) GetSamplingIntent(params ComposableSamplingParameters) SamplingIntent {
+	rate := cb.spanRate()
+	cost := cb.estimate(params)
+
+	threshold := ALWAYS_SAMPLE_THRESHOLD
+	if rate > 0 && cost > 0 {
+		// Each span is allotted an equal share of the budget.
+		threshold = ProbabilityToThreshold(cb.budget / (rate * cost))
+	}
+	return SamplingIntent{
+		Threshold:         threshold,
+		ThresholdReliable: true,
+	}
+}
+
+// CostStats implements CostStatsProvider.
+func (cb *costBased) CostStats() []CostStats {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+	return []CostStats{{
+		Description: cb.Description(),
+		Budget:      cb.budget,
+		SpanRate:    cb.rate,
+	}}
+}
+
+// Description implements ComposableSampler.
+func (cb *costBased) Description() string {
+	return fmt.Sprintf("CostBased{%g}", cb.budget)
+}
@@ -0,0 +1,74 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestCostBased(t *testing.T) {
+	clock := time.Unix(1000, 0)
+	now := func() time.Time { return clock }
+
+	// 100 spans per second of the default cost, half the budget.
+	sampler := CostBased(100*defaultSpanCost/2,
+		WithAttributeCost(0),
+		WithSpanNameCost("large", 4*defaultSpanCost),
+		func(cfg *costBasedConfig) { cfg.now = now },
+	)
+	params := ComposableSamplingParameters{
+		SamplingParameters: SamplingParameters{
+			Name:       "test",
+			Attributes: testAttrs,
+		},
+	}
+	large := params
+	large.Name = "large"
+
+	// The first interval samples everything.
+	for range 100 {
+		intent := sampler.GetSamplingIntent(params)
+		require.Equal(t, ALWAYS_SAMPLE_THRESHOLD, intent.Threshold)
+		require.True(t, intent.ThresholdReliable)
+	}
+
+	clock = clock.Add(time.Second)
+
+	// The 101st span closes the interval at 101 spans/second.
+	require.Equal(t, ProbabilityToThreshold(100.0/101/2), sampler.GetSamplingIntent(params).Threshold)
+	require.Equal(t, ProbabilityToThreshold(100.0/101/8), sampler.GetSamplingIntent(large).Threshold)
+	require.Equal(t, "CostBased{12800}", sampler.Description())
+
+	stats := []CostStats{{Description: "CostBased{12800}", Budget: 12800, SpanRate: 101}}
+	require.Equal(t, stats, sampler.(CostStatsProvider).CostStats())
+	rb := RuleBased(
+		WithRule(SpanNamePredicate("large"), ComposableAlwaysSample()),
+		WithDefaultRule(sampler),
+	)
+	require.Equal(t, stats, rb.(CostStatsProvider).CostStats())
+
+	// Wrapped samplers are found through their wrappers.
+	wrapped := RuleBased(
+		WithRule(SpanNamePredicate("large"), AnnotatingSampler(sampler,
+			WithSampledAttributeValues(attribute.String("policy", "limited")))),
+		WithDefaultRule(ExportOnlySampler(ErrorHintBiased(sampler, 1))),
+	)
+	require.Equal(t, append(stats, stats...), wrapped.(CostStatsProvider).CostStats())
+}
+
+func TestCostBasedAttributes(t *testing.T) {
+	cb := CostBased(1000, WithDefaultSpanCost(100), WithAttributeCost(10)).(*costBased)
+	params := ComposableSamplingParameters{
+		SamplingParameters: SamplingParameters{
+			Attributes: []attribute.KeyValue{
+				attribute.String("a", "1"),
+				attribute.String("b", "2"),
+			},
+		},
+	}
+	require.Equal(t, 120.0, cb.estimate(params))
+}
@@ -0,0 +1,99 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// ReplicaDecorrelated is a sampler that rotates the randomness seen
+// by another sampler by a per-process offset.  This is meant for
+// fleets of identical replicas sampling at the same rate, which would
+// otherwise all select the same traces when randomness is degraded,
+// for example by non-random legacy trace IDs.
+//
+// The offset is derived from the Resource in Optimize, in the range
+// [0, jitter) of the 2^56 randomness values, so the Resource should
+// distinguish replicas (e.g., using service.instance.id).  Rotating
+// the randomness modulo 2^56 keeps the sampling rate of the wrapped
+// sampler, and it gives up consistency: a replica's decisions no
+// longer agree with those of other replicas or with the trace's
+// randomness, so they are made with an unknown threshold, which is not
+// propagated.  Until it is optimized, this sampler has no effect.
+//
+// The jitter must be in the range [0, 1).  Other values are reported
+// via otel.Handle and the sampler is returned unchanged.
+func ReplicaDecorrelated(sampler ComposableSampler, jitter float64) ComposableSampler {
+	if !(jitter >= 0 && jitter < 1) {
+		otel.Handle(fmt.Errorf("invalid replica decorrelation jitter: %g", jitter))
+		return sampler
+	}
+	return &replicaDecorrelated{
+		sampler: sampler,
+		jitter:  jitter,
+	}
+}
+
+type replicaDecorrelated struct {
+	sampler ComposableSampler
+	jitter  float64
+	offset  uint64 // in [0, jitter*2^56), set by Optimize
+}
+
+var _ ComposableSampler = &replicaDecorrelated{}
+var _ SamplerOptimizer = &replicaDecorrelated{}
+var _ CostStatsProvider = &replicaDecorrelated{}
+
+// GetSamplingIntent implements ComposableSampler.
+func (rd *replicaDecorrelated) GetSamplingIntent(params ComposableSamplingParameters) SamplingIntent {
+	if rd.offset == 0 {
+		return rd.sampler.GetSamplingIntent(params)
+	}
+	rotated := params
+	rotated.randomness = int64((uint64(params.randomness) + rd.offset) & randomnessMask)
+	intent := rd.sampler.GetSamplingIntent(rotated)
+	if intent.Threshold > ALWAYS_SAMPLE_THRESHOLD && intent.Threshold < NEVER_SAMPLE_THRESHOLD {
+		// The decision depends on the rotated randomness, so the
+		// threshold no longer describes it.
+		if intent.WouldSample(rotated) {
+			intent.Threshold = INVALID_THRESHOLD
+		} else {
+			intent.Threshold = NEVER_SAMPLE_THRESHOLD
+		}
+		intent.ThresholdReliable = false
+	}
+	return intent
+}
+
+// Description implements ComposableSampler.
+func (rd *replicaDecorrelated) Description() string {
+	return fmt.Sprintf("ReplicaDecorrelated{%s,%g}", rd.sampler.Description(), rd.jitter)
+}
+
+// CostStats implements CostStatsProvider, returning the statistics of
+// the wrapped sampler, if any.
+func (rd *replicaDecorrelated) CostStats() []CostStats {
+	return costStatsOf(rd.sampler)
+}
+
+// Optimize implements SamplerOptimizer.
+func (rd *replicaDecorrelated) Optimize(res *resource.Resource, scope instrumentation.Scope) ComposableSampler {
+	sum := sha256.Sum256([]byte(res.Encoded(attribute.DefaultEncoder())))
+
+	// Map the hash uniformly onto [0, 1).
+	unit := float64(binary.BigEndian.Uint64(sum[:8])>>11) / (1 << 53)
+
+	return &replicaDecorrelated{
+		sampler: Optimize(rd.sampler, res, scope),
+		jitter:  rd.jitter,
+		offset:  uint64(unit * rd.jitter * float64(maxAdjustedCount)),
+	}
+}
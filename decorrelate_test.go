@@ -0,0 +1,87 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+func TestReplicaDecorrelated(t *testing.T) {
+	const jitter = 0.5
+	ratio := TraceIDRatioBased(0.1)
+	sampler := ReplicaDecorrelated(ratio, jitter)
+	require.Equal(t, "ReplicaDecorrelated{TraceIDRatioBased{0.1},0.5}", sampler.Description())
+
+	// Evenly spaced randomness values.
+	const n = 10000
+	params := make([]ComposableSamplingParameters, n)
+	for i := range params {
+		params[i].randomness = int64(uint64(i) * (maxAdjustedCount / n))
+	}
+
+	// Not optimized: no effect.
+	for _, p := range params {
+		require.Equal(t, ratio.GetSamplingIntent(p).Threshold, sampler.GetSamplingIntent(p).Threshold)
+	}
+
+	selections := map[string]bool{}
+	for _, id := range []string{"replica-1", "replica-2", "replica-3", "replica-4"} {
+		res := resource.NewSchemaless(attribute.String("service.instance.id", id))
+		opt := Optimize(sampler, res, instrumentation.Scope{})
+
+		selected := make([]byte, n)
+		count := 0
+		for i, p := range params {
+			intent := opt.GetSamplingIntent(p)
+			require.False(t, intent.ThresholdReliable)
+			if intent.WouldSample(p) {
+				require.Equal(t, INVALID_THRESHOLD, intent.Threshold)
+				selected[i] = 1
+				count++
+			} else {
+				require.Equal(t, NEVER_SAMPLE_THRESHOLD, intent.Threshold)
+			}
+
+			// Optimizing is deterministic.
+			require.Equal(t, intent.Threshold, Optimize(sampler, res, instrumentation.Scope{}).GetSamplingIntent(p).Threshold)
+		}
+		// The rate is unchanged.
+		require.InDelta(t, 0.1*n, count, 1)
+		selections[string(selected)] = true
+
+		// 0% and 100% are unaffected.
+		always := Optimize(ReplicaDecorrelated(ComposableAlwaysSample(), jitter), res, instrumentation.Scope{}).GetSamplingIntent(params[0])
+		require.Equal(t, ALWAYS_SAMPLE_THRESHOLD, always.Threshold)
+		require.True(t, always.ThresholdReliable)
+	}
+	// Replicas select different spans.
+	require.Len(t, selections, 4)
+
+	// Zero jitter has no effect.
+	res := resource.NewSchemaless(attribute.String("service.instance.id", "replica-1"))
+	opt := Optimize(ReplicaDecorrelated(ratio, 0), res, instrumentation.Scope{})
+	require.Equal(t, ratio.GetSamplingIntent(params[0]).Threshold, opt.GetSamplingIntent(params[0]).Threshold)
+}
+
+func TestReplicaDecorrelatedInvalid(t *testing.T) {
+	var errs []error
+	previous := otel.GetErrorHandler()
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		errs = append(errs, err)
+	}))
+	t.Cleanup(func() { otel.SetErrorHandler(previous) })
+
+	ratio := TraceIDRatioBased(0.1)
+	for _, jitter := range []float64{-0.1, 1, 2, math.NaN()} {
+		require.Equal(t, ratio, ReplicaDecorrelated(ratio, jitter))
+	}
+	require.Len(t, errs, 4)
+	require.EqualError(t, errs[1], "invalid replica decorrelation jitter: 1")
+}
@@ -36,15 +36,4 @@ const (
 	// randomnessMask is a mask that selects the least-significant
 	// 56 bits of a uint64.
 	randomnessMask uint64 = maxAdjustedCount - 1
-
-	// NEVER_SAMPLE_THRESHOLD indicates a span that should not be sampled.
-	// This is equivalent to sampling with 0% probability.
-	NEVER_SAMPLE_THRESHOLD int64 = 1 << 56
-
-	// ALWAYS_SAMPLE_THREHSOLD indicates to sample with 100% probability.
-	ALWAYS_SAMPLE_THRESHOLD int64 = 0
-
-	// INVALID_THREHSOLD indicates a span that should be sampled with
-	// unknown probability.
-	INVALID_THRESHOLD int64 = -1
 )
@@ -0,0 +1,112 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// defaultErrorHintKeys are attributes which, when present at span
+// start, suggest that the operation is a retry of a failed attempt.
+var defaultErrorHintKeys = []attribute.Key{
+	"http.request.resend_count",
+	"http.resend_count",
+}
+
+// ErrorHintOption configures an ErrorHintBiased sampler.
+type ErrorHintOption func(*errorHintConfig)
+
+type errorHintConfig struct {
+	keys []attribute.Key
+}
+
+// WithErrorHintKeys adds attribute keys that suggest failure.  A hint
+// is present when the attribute has a true, non-zero, or non-empty
+// value.
+func WithErrorHintKeys(keys ...attribute.Key) ErrorHintOption {
+	return func(cfg *errorHintConfig) {
+		cfg.keys = append(cfg.keys, keys...)
+	}
+}
+
+// ErrorHintBiased is a sampler that samples with at least the boosted
+// probability when the span starts with attributes suggesting failure,
+// such as a retry counter.  Otherwise, the base sampler decides.
+func ErrorHintBiased(base ComposableSampler, boosted float64, options ...ErrorHintOption) ComposableSampler {
+	cfg := errorHintConfig{
+		keys: append([]attribute.Key(nil), defaultErrorHintKeys...),
+	}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	return &errorHintBiased{
+		base:      base,
+		boosted:   boosted,
+		threshold: ProbabilityToThreshold(boosted),
+		keys:      cfg.keys,
+	}
+}
+
+type errorHintBiased struct {
+	base      ComposableSampler
+	boosted   float64
+	threshold Threshold
+	keys      []attribute.Key
+}
+
+var _ ComposableSampler = &errorHintBiased{}
+var _ CostStatsProvider = &errorHintBiased{}
+
+// hasHint returns true when any hint attribute is set.
+func (eh *errorHintBiased) hasHint(attrs []attribute.KeyValue) bool {
+	for _, kv := range attrs {
+		for _, key := range eh.keys {
+			if kv.Key != key {
+				continue
+			}
+			switch kv.Value.Type() {
+			case attribute.BOOL:
+				if kv.Value.AsBool() {
+					return true
+				}
+			case attribute.INT64:
+				if kv.Value.AsInt64() != 0 {
+					return true
+				}
+			case attribute.FLOAT64:
+				if kv.Value.AsFloat64() != 0 {
+					return true
+				}
+			case attribute.STRING:
+				if kv.Value.AsString() != "" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// GetSamplingIntent implements ComposableSampler.
+func (eh *errorHintBiased) GetSamplingIntent(params ComposableSamplingParameters) SamplingIntent {
+	intent := eh.base.GetSamplingIntent(params)
+	if eh.threshold < intent.Threshold && eh.hasHint(params.Attributes) {
+		intent.Threshold = eh.threshold
+		intent.ThresholdReliable = true
+	}
+	return intent
+}
+
+// Description implements ComposableSampler.
+func (eh *errorHintBiased) Description() string {
+	return fmt.Sprintf("ErrorHintBiased{%s,%g}", eh.base.Description(), eh.boosted)
+}
+
+// CostStats implements CostStatsProvider, returning the statistics of
+// the base sampler, if any.
+func (eh *errorHintBiased) CostStats() []CostStats {
+	return costStatsOf(eh.base)
+}
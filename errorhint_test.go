@@ -0,0 +1,48 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestErrorHintBiased(t *testing.T) {
+	sampler := ErrorHintBiased(TraceIDRatioBased(0.01), 0.5, WithErrorHintKeys("app.retry"))
+	require.Equal(t, "ErrorHintBiased{TraceIDRatioBased{0.01},0.5}", sampler.Description())
+
+	base := TraceIDRatioBased(0.01).GetSamplingIntent(ComposableSamplingParameters{}).Threshold
+	boosted := ProbabilityToThreshold(0.5)
+
+	for _, test := range []struct {
+		attrs     []attribute.KeyValue
+		threshold Threshold
+	}{
+		{nil, base},
+		{testAttrs, base},
+		{[]attribute.KeyValue{attribute.Int("http.request.resend_count", 0)}, base},
+		{[]attribute.KeyValue{attribute.Int("http.request.resend_count", 2)}, boosted},
+		{[]attribute.KeyValue{attribute.Int("http.resend_count", 1)}, boosted},
+		{[]attribute.KeyValue{attribute.Bool("app.retry", false)}, base},
+		{[]attribute.KeyValue{attribute.Bool("app.retry", true)}, boosted},
+	} {
+		params := ComposableSamplingParameters{
+			SamplingParameters: SamplingParameters{
+				Attributes: test.attrs,
+			},
+		}
+		intent := sampler.GetSamplingIntent(params)
+		require.Equal(t, test.threshold, intent.Threshold, "%v", test.attrs)
+		require.True(t, intent.ThresholdReliable)
+	}
+
+	// A base sampler that samples more does not lose probability.
+	always := ErrorHintBiased(ComposableAlwaysSample(), 0.5)
+	require.Equal(t, ALWAYS_SAMPLE_THRESHOLD, always.GetSamplingIntent(ComposableSamplingParameters{
+		SamplingParameters: SamplingParameters{
+			Attributes: []attribute.KeyValue{attribute.Int("http.resend_count", 1)},
+		},
+	}).Threshold)
+}
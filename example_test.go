@@ -11,13 +11,13 @@ func makeAF(kvs ...attribute.KeyValue) func() []attribute.KeyValue {
 		return kvs
 	}
 }
-func ExampleThreeWayParentBased() {
+func ExampleRuleBased_threeWayParentBased() {
 	root := ComposableAlwaysSample()
 	local := makeAF(attribute.String("local", "true"))
 	remote := makeAF(attribute.String("remote", "true"))
 	sampler := RuleBased(
 		WithRule(IsRootPredicate(), root),
-		WithRule(IsRemotePredicate(), AnnotatingSampler(ParentThreshold(), WithSampledAttributes(remote))),
+		WithRule(IsRemoteParentPredicate(), AnnotatingSampler(ParentThreshold(), WithSampledAttributes(remote))),
 		WithDefaultRule(AnnotatingSampler(ParentThreshold(), WithSampledAttributes(local))),
 	)
 	fmt.Println(sampler.Description())
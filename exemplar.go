@@ -0,0 +1,30 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ThresholdExemplarFilter is an exemplar filter for the OpenTelemetry-Go
+// metrics SDK, for use with metric.WithExemplarFilter, that admits
+// measurements made in the context of a span sampled with a threshold
+// recorded in its tracestate.  Unlike the SDK's TraceBasedFilter,
+// this excludes spans sampled by samplers that do not record a
+// threshold and spans whose threshold is inconsistent with their
+// randomness (see ThresholdFromSpanContext), so that exemplars refer
+// to traces whose sampling is known, and exemplar volume follows
+// consistent trace sampling.  Export-only spans (see
+// WithExportOnlyRecording) are not sampled in their span context, so
+// they are excluded.
+func ThresholdExemplarFilter(ctx context.Context) bool {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsSampled() {
+		return false
+	}
+	_, ok := ThresholdFromSpanContext(sc)
+	return ok
+}
@@ -0,0 +1,57 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/exemplar"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var _ exemplar.Filter = ThresholdExemplarFilter
+
+func TestThresholdExemplarFilter(t *testing.T) {
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSampler(NewSDKSampler(RuleBased(
+		WithRule(SpanNamePredicate("sampled"), ComposableAlwaysSample()),
+		WithRule(SpanNamePredicate("unreliable"), LegacySampler(FromSDKSampler(sdktrace.AlwaysSample()))),
+		WithDefaultRule(ComposableNeverSample()),
+	))))
+	tracer := tp.Tracer("test")
+
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(reader),
+		sdkmetric.WithExemplarFilter(ThresholdExemplarFilter),
+	)
+	counter, err := mp.Meter("test").Int64Counter("requests")
+	require.NoError(t, err)
+
+	var expect []trace.SpanID
+	for _, name := range []string{"sampled", "unreliable", "dropped"} {
+		ctx, span := tracer.Start(context.Background(), name)
+		counter.Add(ctx, 1)
+		span.End()
+		require.Equal(t, name != "dropped", span.SpanContext().IsSampled())
+		if name == "sampled" {
+			expect = append(expect, span.SpanContext().SpanID())
+		}
+	}
+	counter.Add(context.Background(), 1)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	sum := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[int64])
+	require.Equal(t, int64(4), sum.DataPoints[0].Value)
+
+	var got []trace.SpanID
+	for _, ex := range sum.DataPoints[0].Exemplars {
+		got = append(got, trace.SpanID(ex.SpanID))
+	}
+	require.Equal(t, expect, got)
+}
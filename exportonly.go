@@ -0,0 +1,53 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// ExportOnlySampler is a sampler that exports the spans another
+// sampler would sample without propagating the decision, so that
+// child spans are sampled independently.  The CompositeSampler returns
+// ExportOnly decisions for these spans, where the span's tracestate
+// encodes its threshold and the propagated tracestate is unchanged.
+func ExportOnlySampler(sampler ComposableSampler) ComposableSampler {
+	return &exportOnlySampler{
+		sampler: sampler,
+	}
+}
+
+type exportOnlySampler struct {
+	sampler ComposableSampler
+}
+
+var _ ComposableSampler = &exportOnlySampler{}
+var _ SamplerOptimizer = &exportOnlySampler{}
+var _ CostStatsProvider = &exportOnlySampler{}
+
+// GetSamplingIntent implements ComposableSampler.
+func (eo *exportOnlySampler) GetSamplingIntent(params ComposableSamplingParameters) SamplingIntent {
+	intent := eo.sampler.GetSamplingIntent(params)
+	intent.ExportOnly = true
+	return intent
+}
+
+// Description implements ComposableSampler.
+func (eo *exportOnlySampler) Description() string {
+	return fmt.Sprintf("ExportOnly(%s)", eo.sampler.Description())
+}
+
+// CostStats implements CostStatsProvider, returning the statistics of
+// the wrapped sampler, if any.
+func (eo *exportOnlySampler) CostStats() []CostStats {
+	return costStatsOf(eo.sampler)
+}
+
+// Optimize implements SamplerOptimizer.
+func (eo *exportOnlySampler) Optimize(res *resource.Resource, scope instrumentation.Scope) ComposableSampler {
+	return ExportOnlySampler(Optimize(eo.sampler, res, scope))
+}
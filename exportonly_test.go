@@ -0,0 +1,50 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestExportOnlySampler(t *testing.T) {
+	ts, err := trace.ParseTraceState("vnd=x,ot=rv:c0000000000000")
+	require.NoError(t, err)
+	params := SamplingParameters{
+		ParentContext: trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{1},
+			SpanID:     trace.SpanID{1},
+			TraceState: ts,
+		})),
+		TraceID: trace.TraceID{1},
+	}
+
+	sampler := CompositeSampler(ExportOnlySampler(TraceIDRatioBased(0.5)))
+	require.Equal(t, "ExportOnly(TraceIDRatioBased{0.5})", sampler.Description())
+
+	res := sampler.ShouldSample(params)
+	require.Equal(t, ExportOnly, res.Decision)
+	require.Equal(t, ts, res.Tracestate)
+	require.Equal(t, "ot=rv:c0000000000000;th:8,vnd=x", res.SpanTracestate.String())
+
+	// Not sampled: dropped.
+	res = CompositeSampler(ExportOnlySampler(TraceIDRatioBased(0.125))).ShouldSample(params)
+	require.Equal(t, Drop, res.Decision)
+	require.Equal(t, ts, res.Tracestate)
+	require.Equal(t, trace.TraceState{}, res.SpanTracestate)
+
+	// A sampler that would fully sample at a lower threshold takes
+	// precedence when combined.
+	combined := CompositeSampler(RuleBased(
+		WithRule(TruePredicate(), ExportOnlySampler(TraceIDRatioBased(0.5))),
+		WithRule(TruePredicate(), TraceIDRatioBased(0.75)),
+		WithCombineMatching(),
+	))
+	res = combined.ShouldSample(params)
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Equal(t, "rv:c0000000000000;th:4", res.Tracestate.Get("ot"))
+	require.Equal(t, trace.TraceState{}, res.SpanTracestate)
+}
@@ -0,0 +1,439 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ParseError describes a syntax or semantic error in a predicate
+// expression.
+type ParseError struct {
+	Expr   string // the input expression
+	Offset int    // byte offset of the error
+	Msg    string // description of the error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("predicate expression at offset %d: %s: %q", e.Offset, e.Msg, e.Expr)
+}
+
+// ParsePredicate parses a predicate expression such as
+//
+//	Span.Name =~ "/health.*" && Span.Kind == SERVER
+//
+// into a tree of the predicates in this package.  Expressions combine
+// comparisons with &&, ||, !, and parentheses.  The comparisons are:
+//
+//	Span.Name          == != =~ (regular expression) with a string
+//	Span.Kind          == != with SERVER, CLIENT, PRODUCER, CONSUMER, INTERNAL
+//	Span.Attributes[k] == != with a string, number, or bool; =~ with a
+//	                   string; < <= > >= with a number
+//	Resource[k]        == != with a string, number, or bool
+//	Baggage[k]         == != with a string
+//	TraceState[k]      == != with a string
+//
+// where k is a quoted string.  Baggage[k] and TraceState[k] without a
+// comparison test whether the member is present.  The identifiers
+// true, false, IsRoot, IsRemoteParent, IsLocalParent, IsParentSampled,
+// and HasLinks are the corresponding predicates.
+func ParsePredicate(expr string) (Predicate, error) {
+	p := &exprParser{expr: expr}
+	p.next()
+	pred, err := p.parseOr()
+	if err != nil {
+		return Predicate{}, err
+	}
+	if p.tok.kind != tokEOF {
+		return Predicate{}, p.unexpected(`"&&" or "||"`)
+	}
+	return pred, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+	tokError
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return t.text
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+type exprParser struct {
+	expr string
+	pos  int
+	tok  token
+}
+
+var exprOperators = []string{"&&", "||", "==", "!=", "=~", "<=", ">=", "<", ">", "!", "(", ")", "[", "]"}
+
+// next scans the next token.
+func (p *exprParser) next() {
+	for p.pos < len(p.expr) && unicode.IsSpace(rune(p.expr[p.pos])) {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos == len(p.expr) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return
+	}
+	c := p.expr[p.pos]
+	switch {
+	case c == '"':
+		p.pos++
+		for p.pos < len(p.expr) && p.expr[p.pos] != '"' {
+			if p.expr[p.pos] == '\\' {
+				p.pos++
+			}
+			p.pos++
+		}
+		if p.pos >= len(p.expr) {
+			p.tok = token{kind: tokError, text: "unterminated string", pos: start}
+			return
+		}
+		p.pos++
+		p.tok = token{kind: tokString, text: p.expr[start:p.pos], pos: start}
+		return
+	case c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		p.pos++
+		for p.pos < len(p.expr) && strings.IndexByte("0123456789.eE+-", p.expr[p.pos]) >= 0 {
+			p.pos++
+		}
+		p.tok = token{kind: tokNumber, text: p.expr[start:p.pos], pos: start}
+		return
+	case c == '_' || unicode.IsLetter(rune(c)):
+		for p.pos < len(p.expr) && (p.expr[p.pos] == '_' || p.expr[p.pos] == '.' ||
+			unicode.IsLetter(rune(p.expr[p.pos])) || unicode.IsDigit(rune(p.expr[p.pos]))) {
+			p.pos++
+		}
+		p.tok = token{kind: tokIdent, text: p.expr[start:p.pos], pos: start}
+		return
+	}
+	for _, op := range exprOperators {
+		if strings.HasPrefix(p.expr[p.pos:], op) {
+			p.pos += len(op)
+			p.tok = token{kind: tokOp, text: op, pos: start}
+			return
+		}
+	}
+	p.tok = token{kind: tokError, text: fmt.Sprintf("unexpected character %q", c), pos: start}
+}
+
+func (p *exprParser) errorf(pos int, format string, args ...any) error {
+	return &ParseError{Expr: p.expr, Offset: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// isOp returns true when the current token is the operator.
+func (p *exprParser) isOp(op string) bool {
+	return p.tok.kind == tokOp && p.tok.text == op
+}
+
+// expectOp consumes the operator or returns an error.
+func (p *exprParser) expectOp(op string) error {
+	if !p.isOp(op) {
+		return p.unexpected(fmt.Sprintf("%q", op))
+	}
+	p.next()
+	return nil
+}
+
+// unexpected returns an error for the current token.
+func (p *exprParser) unexpected(expected string) error {
+	if p.tok.kind == tokError {
+		return p.errorf(p.tok.pos, "%s", p.tok.text)
+	}
+	return p.errorf(p.tok.pos, "unexpected %s, expected %s", p.tok, expected)
+}
+
+func (p *exprParser) parseOr() (Predicate, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return Predicate{}, err
+	}
+	preds := []Predicate{first}
+	for p.isOp("||") {
+		p.next()
+		pred, err := p.parseAnd()
+		if err != nil {
+			return Predicate{}, err
+		}
+		preds = append(preds, pred)
+	}
+	if len(preds) == 1 {
+		return first, nil
+	}
+	return OrPredicate(preds...), nil
+}
+
+func (p *exprParser) parseAnd() (Predicate, error) {
+	first, err := p.parseUnary()
+	if err != nil {
+		return Predicate{}, err
+	}
+	preds := []Predicate{first}
+	for p.isOp("&&") {
+		p.next()
+		pred, err := p.parseUnary()
+		if err != nil {
+			return Predicate{}, err
+		}
+		preds = append(preds, pred)
+	}
+	if len(preds) == 1 {
+		return first, nil
+	}
+	return AndPredicate(preds...), nil
+}
+
+func (p *exprParser) parseUnary() (Predicate, error) {
+	switch {
+	case p.isOp("!"):
+		p.next()
+		pred, err := p.parseUnary()
+		if err != nil {
+			return Predicate{}, err
+		}
+		return NotPredicate(pred), nil
+	case p.isOp("("):
+		p.next()
+		pred, err := p.parseOr()
+		if err != nil {
+			return Predicate{}, err
+		}
+		if err := p.expectOp(")"); err != nil {
+			return Predicate{}, err
+		}
+		return pred, nil
+	case p.tok.kind == tokIdent:
+		return p.parseIdent()
+	}
+	return Predicate{}, p.unexpected("a predicate")
+}
+
+// exprBuiltins are the predicates named by identifiers.
+var exprBuiltins = map[string]func() Predicate{
+	"true":            TruePredicate,
+	"false":           FalsePredicate,
+	"IsRoot":          IsRootPredicate,
+	"IsRemoteParent":  IsRemoteParentPredicate,
+	"IsLocalParent":   IsLocalParentPredicate,
+	"IsParentSampled": IsParentSampledPredicate,
+	"HasLinks":        HasLinksPredicate,
+}
+
+func (p *exprParser) parseIdent() (Predicate, error) {
+	ident := p.tok
+	if builtin, ok := exprBuiltins[ident.text]; ok {
+		p.next()
+		return builtin(), nil
+	}
+	p.next()
+
+	var key string
+	switch ident.text {
+	case "Span.Name", "Span.Kind":
+	case "Span.Attributes", "Resource", "Baggage", "TraceState":
+		if err := p.expectOp("["); err != nil {
+			return Predicate{}, err
+		}
+		if p.tok.kind != tokString {
+			return Predicate{}, p.unexpected("a quoted key")
+		}
+		var err error
+		if key, err = strconv.Unquote(p.tok.text); err != nil {
+			return Predicate{}, p.errorf(p.tok.pos, "invalid string: %v", err)
+		}
+		p.next()
+		if err := p.expectOp("]"); err != nil {
+			return Predicate{}, err
+		}
+	default:
+		return Predicate{}, p.errorf(ident.pos, "unknown identifier %s", ident)
+	}
+
+	if p.tok.kind != tokOp || strings.IndexByte("=!<>", p.tok.text[0]) < 0 || p.tok.text == "!" {
+		// Presence tests.
+		switch ident.text {
+		case "Baggage":
+			return HasBaggagePredicate(key), nil
+		case "TraceState":
+			return TraceStateMemberPredicate(key), nil
+		}
+		return Predicate{}, p.unexpected("a comparison")
+	}
+	op := p.tok
+	p.next()
+	value := p.tok
+	if value.kind != tokString && value.kind != tokNumber && value.kind != tokIdent {
+		return Predicate{}, p.unexpected("a value")
+	}
+	p.next()
+
+	pred, err := p.comparison(ident, key, op, value)
+	if err != nil {
+		return Predicate{}, err
+	}
+	if op.text == "!=" {
+		return NotPredicate(pred), nil
+	}
+	return pred, nil
+}
+
+// comparison returns the predicate for one comparison.  For "!=", it
+// returns the equality predicate, which the caller negates.
+func (p *exprParser) comparison(field token, key string, op, value token) (Predicate, error) {
+	badOp := func() error {
+		return p.errorf(op.pos, "operator %s not supported by %s", op, field)
+	}
+	badValue := func(expected string) error {
+		return p.errorf(value.pos, "unexpected %s, expected %s", value, expected)
+	}
+	str := func() (string, error) {
+		if value.kind != tokString {
+			return "", badValue("a quoted string")
+		}
+		s, err := strconv.Unquote(value.text)
+		if err != nil {
+			return "", p.errorf(value.pos, "invalid string: %v", err)
+		}
+		return s, nil
+	}
+	equality := op.text == "==" || op.text == "!="
+
+	switch field.text {
+	case "Span.Name":
+		s, err := str()
+		if err != nil {
+			return Predicate{}, err
+		}
+		switch {
+		case equality:
+			return SpanNamePredicate(s), nil
+		case op.text == "=~":
+			pred, err := SpanNameRegexPredicate(s)
+			if err != nil {
+				return Predicate{}, p.errorf(value.pos, "%v", err)
+			}
+			return pred, nil
+		}
+		return Predicate{}, badOp()
+
+	case "Span.Kind":
+		if !equality {
+			return Predicate{}, badOp()
+		}
+		kinds := map[string]trace.SpanKind{
+			"SERVER":   trace.SpanKindServer,
+			"CLIENT":   trace.SpanKindClient,
+			"PRODUCER": trace.SpanKindProducer,
+			"CONSUMER": trace.SpanKindConsumer,
+			"INTERNAL": trace.SpanKindInternal,
+		}
+		kind, ok := kinds[strings.ToUpper(value.text)]
+		if value.kind != tokIdent || !ok {
+			return Predicate{}, badValue("SERVER, CLIENT, PRODUCER, CONSUMER, or INTERNAL")
+		}
+		return SpanKindPredicate(kind), nil
+
+	case "Span.Attributes", "Resource":
+		akey := attribute.Key(key)
+		if op.text == "=~" && field.text == "Span.Attributes" {
+			s, err := str()
+			if err != nil {
+				return Predicate{}, err
+			}
+			pred, err := AttributeRegexPredicate(akey, s)
+			if err != nil {
+				return Predicate{}, p.errorf(value.pos, "%v", err)
+			}
+			return pred, nil
+		}
+		if value.kind == tokNumber {
+			num, err := strconv.ParseFloat(value.text, 64)
+			if err != nil {
+				return Predicate{}, p.errorf(value.pos, "invalid number %s", value)
+			}
+			if field.text == "Resource" {
+				if !equality {
+					return Predicate{}, badOp()
+				}
+				if i, err := strconv.ParseInt(value.text, 10, 64); err == nil {
+					return ResourceAttributePredicate(akey.Int64(i)), nil
+				}
+				return ResourceAttributePredicate(akey.Float64(num)), nil
+			}
+			switch op.text {
+			case "==", "!=":
+				// Matches both integer and floating-point values.
+				return AttributeRangePredicate(akey, num, num), nil
+			case "<":
+				return AttributeLessPredicate(akey, num), nil
+			case ">":
+				return AttributeGreaterPredicate(akey, num), nil
+			case "<=":
+				return AttributeRangePredicate(akey, math.Inf(-1), num), nil
+			case ">=":
+				return AttributeRangePredicate(akey, num, math.Inf(+1)), nil
+			}
+			return Predicate{}, badOp()
+		}
+		if !equality {
+			return Predicate{}, badOp()
+		}
+		var kv attribute.KeyValue
+		switch {
+		case value.kind == tokIdent && (value.text == "true" || value.text == "false"):
+			kv = akey.Bool(value.text == "true")
+		default:
+			s, err := str()
+			if err != nil {
+				return Predicate{}, err
+			}
+			kv = akey.String(s)
+		}
+		if field.text == "Resource" {
+			return ResourceAttributePredicate(kv), nil
+		}
+		return AttributeEqualsPredicate(kv), nil
+
+	case "Baggage", "TraceState":
+		if !equality {
+			return Predicate{}, badOp()
+		}
+		s, err := str()
+		if err != nil {
+			return Predicate{}, err
+		}
+		if field.text == "Baggage" {
+			return BaggageEqualsPredicate(key, s), nil
+		}
+		return TraceStateMemberEqualsPredicate(key, s), nil
+	}
+	return Predicate{}, p.errorf(field.pos, "unknown identifier %s", field)
+}
@@ -0,0 +1,133 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestParsePredicate(t *testing.T) {
+	server := func(name string, attrs ...attribute.KeyValue) ComposableSamplingParameters {
+		params := testAttributeParams(attrs...)
+		params.Name = name
+		params.Kind = trace.SpanKindServer
+		return params
+	}
+	client := func(name string, attrs ...attribute.KeyValue) ComposableSamplingParameters {
+		params := server(name, attrs...)
+		params.Kind = trace.SpanKindClient
+		return params
+	}
+
+	for _, test := range []struct {
+		expr  string
+		desc  string
+		match []ComposableSamplingParameters
+		miss  []ComposableSamplingParameters
+	}{
+		{
+			`Span.Name =~ "/health.*" && Span.Kind == SERVER`,
+			"and(Span.Name=~/health.*,Span.Kind==server)",
+			[]ComposableSamplingParameters{server("/healthz")},
+			[]ComposableSamplingParameters{client("/healthz"), server("/api")},
+		},
+		{
+			`Span.Name == "/a" || Span.Name == "/b"`,
+			"or(Span.Name==/a,Span.Name==/b)",
+			[]ComposableSamplingParameters{server("/a"), client("/b")},
+			[]ComposableSamplingParameters{server("/c")},
+		},
+		{
+			`!(Span.Kind == client) && Span.Name != "/a"`,
+			"and(not(Span.Kind==client),not(Span.Name==/a))",
+			[]ComposableSamplingParameters{server("/b")},
+			[]ComposableSamplingParameters{client("/b"), server("/a")},
+		},
+		{
+			`Span.Attributes["http.response.status_code"] >= 500`,
+			"Span.Attributes[http.response.status_code] in [500,+Inf]",
+			[]ComposableSamplingParameters{server("x", attribute.Int("http.response.status_code", 503))},
+			[]ComposableSamplingParameters{server("x", attribute.Int("http.response.status_code", 200)), server("x")},
+		},
+		{
+			`Span.Attributes["retry"] == 2 && Span.Attributes["debug"] == true`,
+			"and(Span.Attributes[retry] in [2,2],Span.Attributes[debug]==true)",
+			[]ComposableSamplingParameters{server("x", attribute.Float64("retry", 2), attribute.Bool("debug", true))},
+			[]ComposableSamplingParameters{server("x", attribute.Int("retry", 2), attribute.Bool("debug", false))},
+		},
+		{
+			`Span.Attributes["db.system"] =~ "^(mysql|postgresql)$" || Span.Attributes["peer.service"] == "cache"`,
+			"or(Span.Attributes[db.system]=~^(mysql|postgresql)$,Span.Attributes[peer.service]==cache)",
+			[]ComposableSamplingParameters{server("x", attribute.String("db.system", "mysql")), server("x", attribute.String("peer.service", "cache"))},
+			[]ComposableSamplingParameters{server("x", attribute.String("db.system", "redis"))},
+		},
+		{
+			`IsRoot && true`,
+			"and(root?,true)",
+			[]ComposableSamplingParameters{server("x")},
+			nil,
+		},
+		{
+			`Baggage["tenant"] || TraceState["vendor"] == "x"`,
+			"or(Baggage[tenant]?,TraceState[vendor]==x)",
+			nil,
+			[]ComposableSamplingParameters{server("x")},
+		},
+		{
+			`Resource["service.name"] == "checkout"`,
+			"Resource[service.name]==checkout",
+			nil,
+			[]ComposableSamplingParameters{server("x")},
+		},
+	} {
+		t.Run(test.expr, func(t *testing.T) {
+			pred, err := ParsePredicate(test.expr)
+			require.NoError(t, err)
+			require.Equal(t, test.desc, pred.Description())
+			for _, params := range test.match {
+				require.True(t, pred.Decide(params))
+			}
+			for _, params := range test.miss {
+				require.False(t, pred.Decide(params))
+			}
+		})
+	}
+}
+
+func TestParsePredicateErrors(t *testing.T) {
+	for _, test := range []struct {
+		expr   string
+		offset int
+		msg    string
+	}{
+		{``, 0, "unexpected end of expression, expected a predicate"},
+		{`Span.Name == "/a" &&`, 20, "unexpected end of expression, expected a predicate"},
+		{`Span.Name == "/a" Span.Kind`, 18, `unexpected "Span.Kind"`},
+		{`(Span.Name == "/a"`, 18, `unexpected end of expression, expected ")"`},
+		{`Span.Nme == "/a"`, 0, `unknown identifier "Span.Nme"`},
+		{`Span.Kind == SERVR`, 13, "expected SERVER, CLIENT, PRODUCER, CONSUMER, or INTERNAL"},
+		{`Span.Kind =~ "s.*"`, 10, `operator "=~" not supported by "Span.Kind"`},
+		{`Span.Name == 1`, 13, "expected a quoted string"},
+		{`Span.Name =~ "("`, 13, "span name:"},
+		{`Span.Name == "/a`, 13, "unterminated string"},
+		{`Span.Attributes[key] == 1`, 16, "expected a quoted key"},
+		{`Span.Attributes["k"]`, 20, "expected a comparison"},
+		{`Resource["k"] > 1`, 14, `operator ">" not supported by "Resource"`},
+		{`Span.Name == "/a" & x`, 18, `unexpected character '&'`},
+	} {
+		t.Run(test.expr, func(t *testing.T) {
+			_, err := ParsePredicate(test.expr)
+			require.Error(t, err)
+			var perr *ParseError
+			require.ErrorAs(t, err, &perr)
+			require.Equal(t, test.offset, perr.Offset)
+			require.Contains(t, perr.Msg, test.msg)
+			require.Contains(t, err.Error(), strconv.Quote(test.expr))
+		})
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"context"
+	"encoding/binary"
+	"math/rand/v2"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RandomIDGenerator returns an ID generator for the OpenTelemetry-Go
+// SDK, for use with sdktrace.WithIDGenerator, whose TraceIDs are
+// uniformly random in all 128 bits, including the 56 bits that
+// consistent samplers use as randomness.  The IDs come from the
+// ChaCha8-based generator of math/rand/v2, which is seeded randomly
+// and safe for concurrent use.
+//
+// The SDK does not allow ID generators to set trace flags, so spans
+// with these TraceIDs do not have the W3C random trace flag, except
+// in a TracerProvider (see NewTracerProvider), which uses this
+// generator and sets the flag on root spans.
+func RandomIDGenerator() sdktrace.IDGenerator {
+	return randomIDGenerator{}
+}
+
+type randomIDGenerator struct{}
+
+var _ sdktrace.IDGenerator = randomIDGenerator{}
+
+// NewIDs implements sdktrace.IDGenerator.
+func (gen randomIDGenerator) NewIDs(ctx context.Context) (trace.TraceID, trace.SpanID) {
+	var tid trace.TraceID
+	for !tid.IsValid() {
+		binary.BigEndian.PutUint64(tid[:8], rand.Uint64())
+		binary.BigEndian.PutUint64(tid[8:], rand.Uint64())
+	}
+	return tid, gen.NewSpanID(ctx, tid)
+}
+
+// NewSpanID implements sdktrace.IDGenerator.
+func (randomIDGenerator) NewSpanID(context.Context, trace.TraceID) trace.SpanID {
+	var sid trace.SpanID
+	for !sid.IsValid() {
+		binary.BigEndian.PutUint64(sid[:], rand.Uint64())
+	}
+	return sid
+}
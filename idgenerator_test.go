@@ -0,0 +1,39 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRandomIDGenerator(t *testing.T) {
+	gen := RandomIDGenerator()
+	ctx := context.Background()
+
+	// The randomness of TraceIDs is uniform: about half are sampled
+	// at 50%, and a quarter at 25%.
+	const n = 10000
+	var half, quarter int
+	seen := map[[16]byte]bool{}
+	for range n {
+		tid, sid := gen.NewIDs(ctx)
+		require.True(t, tid.IsValid())
+		require.True(t, sid.IsValid())
+		require.True(t, gen.NewSpanID(ctx, tid).IsValid())
+		require.False(t, seen[tid])
+		seen[tid] = true
+
+		rnd := traceIDRandomness(tid)
+		if ProbabilityToThreshold(0.5).ShouldSample(rnd) {
+			half++
+		}
+		if ProbabilityToThreshold(0.25).ShouldSample(rnd) {
+			quarter++
+		}
+	}
+	require.InDelta(t, n/2, half, 300)
+	require.InDelta(t, n/4, quarter, 300)
+}
@@ -0,0 +1,75 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InconsistencyKind describes an impossible combination of the parent
+// sampled flag, threshold, and randomness.
+type InconsistencyKind int
+
+const (
+	// SampledAboveThreshold indicates a sampled parent whose
+	// randomness is less than its threshold, meaning the parent
+	// sampler did not respect the threshold it propagated.  The
+	// threshold is treated as unknown.
+	SampledAboveThreshold InconsistencyKind = iota
+
+	// UnsampledBelowThreshold indicates an unsampled parent whose
+	// randomness is at least its threshold.  The parent is treated
+	// as sampled.
+	UnsampledBelowThreshold
+)
+
+// String returns a short name for the kind of inconsistency.
+func (k InconsistencyKind) String() string {
+	switch k {
+	case SampledAboveThreshold:
+		return "sampled above threshold"
+	case UnsampledBelowThreshold:
+		return "unsampled below threshold"
+	}
+	return fmt.Sprintf("InconsistencyKind(%d)", int(k))
+}
+
+// Inconsistency describes a parent context whose sampled flag
+// disagrees with its threshold and randomness, which indicates a
+// broken sampler upstream.
+type Inconsistency struct {
+	Kind InconsistencyKind
+
+	// ParentSpanContext is the parent span context, as received.
+	ParentSpanContext trace.SpanContext
+
+	// Threshold is the parent threshold.
+	Threshold Threshold
+
+	// Randomness is the randomness value the threshold was
+	// compared with.
+	Randomness int64
+
+	// ExplicitRandomness is true when Randomness came from the
+	// "rv" sub-key, false when it came from the TraceID.
+	ExplicitRandomness bool
+}
+
+// Error implements error, so that inconsistencies can be passed to
+// error handlers.
+func (i Inconsistency) Error() string {
+	return fmt.Sprintf("inconsistent parent sampling: %s: th:%s rv:%014x", i.Kind, i.Threshold, i.Randomness)
+}
+
+// WithInconsistencyHandler calls handler when the sampled flag of a
+// parent context disagrees with its threshold and randomness, so that
+// broken samplers elsewhere can be counted or alerted on.  The
+// handler is called synchronously in the sampling decision.
+func WithInconsistencyHandler(handler func(Inconsistency)) CompositeOption {
+	return func(cfg *compositeConfig) {
+		cfg.inconsistencyHandler = handler
+	}
+}
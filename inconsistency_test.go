@@ -0,0 +1,57 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestInconsistencyHandler(t *testing.T) {
+	params := func(tracestate string, flags trace.TraceFlags) SamplingParameters {
+		ts, err := trace.ParseTraceState(tracestate)
+		require.NoError(t, err)
+		return SamplingParameters{
+			ParentContext: trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID:    trace.TraceID{1},
+				SpanID:     trace.SpanID{1},
+				TraceFlags: flags,
+				TraceState: ts,
+			})),
+			TraceID: trace.TraceID{1},
+		}
+	}
+
+	var found []Inconsistency
+	sampler := CompositeSampler(ComposableParentBased(ComposableNeverSample()),
+		WithInconsistencyHandler(func(i Inconsistency) {
+			found = append(found, i)
+		}),
+	)
+
+	// Consistent.
+	sampler.ShouldSample(params("ot=th:8;rv:c0000000000000", trace.FlagsSampled))
+	sampler.ShouldSample(params("ot=th:8;rv:40000000000000", 0))
+	require.Empty(t, found)
+
+	res := sampler.ShouldSample(params("ot=th:8;rv:40000000000000", trace.FlagsSampled))
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Len(t, found, 1)
+	require.Equal(t, SampledAboveThreshold, found[0].Kind)
+	require.Equal(t, Threshold(0x80000000000000), found[0].Threshold)
+	require.Equal(t, int64(0x40000000000000), found[0].Randomness)
+	require.True(t, found[0].ExplicitRandomness)
+	require.True(t, found[0].ParentSpanContext.IsSampled())
+	require.EqualError(t, found[0], "inconsistent parent sampling: sampled above threshold: th:8 rv:40000000000000")
+
+	found = nil
+	res = sampler.ShouldSample(params("ot=th:8;rv:c0000000000000", 0))
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Len(t, found, 1)
+	require.Equal(t, UnsampledBelowThreshold, found[0].Kind)
+	require.False(t, found[0].ParentSpanContext.IsSampled())
+}
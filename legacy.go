@@ -0,0 +1,133 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LegacyOption configures a LegacySampler.
+type LegacyOption func(*legacyConfig)
+
+type legacyConfig struct {
+	threshold Threshold
+	reliable  bool
+}
+
+// WithLegacyThreshold sets the threshold of the intent when the legacy
+// sampler samples, for a legacy sampler known to sample with about the
+// threshold's probability.  The threshold is reliable, so that it is
+// recorded in the tracestate and determines adjusted counts.  Spans
+// that the legacy sampler samples are only sampled when their
+// randomness also meets the threshold.  By default, the threshold is
+// ALWAYS_SAMPLE_THRESHOLD and unreliable, so that every span the
+// legacy sampler samples is sampled, without a recorded probability.
+func WithLegacyThreshold(threshold Threshold) LegacyOption {
+	return func(cfg *legacyConfig) {
+		cfg.threshold = threshold
+		cfg.reliable = true
+	}
+}
+
+// LegacySampler wraps a Sampler of the original, non-compositional
+// API as a ComposableSampler, so that existing custom samplers can be
+// used in compositions such as RuleBased.  The legacy sampler is
+// called by GetSamplingIntent, and its decision becomes the intent's
+// threshold:
+//
+//	RecordAndSample  ALWAYS_SAMPLE_THRESHOLD, see WithLegacyThreshold
+//	ExportOnly       the same, with ExportOnly set
+//	RecordOnly       NEVER_SAMPLE_THRESHOLD, with Record set
+//	Drop             NEVER_SAMPLE_THRESHOLD
+//
+// The attributes of the legacy result are added when the composed
+// decision samples or records, and the tracestate members that it
+// adds or changes, except "ot", are applied when it samples.
+func LegacySampler(sampler Sampler, options ...LegacyOption) ComposableSampler {
+	cfg := legacyConfig{
+		threshold: ALWAYS_SAMPLE_THRESHOLD,
+	}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	return &legacySampler{
+		sampler: sampler,
+		config:  cfg,
+	}
+}
+
+type legacySampler struct {
+	sampler Sampler
+	config  legacyConfig
+}
+
+var _ ComposableSampler = &legacySampler{}
+
+// GetSamplingIntent implements ComposableSampler.
+func (ls *legacySampler) GetSamplingIntent(params ComposableSamplingParameters) SamplingIntent {
+	result := ls.sampler.ShouldSample(params.SamplingParameters)
+	var attrs AttributesFunc
+	if len(result.Attributes) != 0 {
+		attrs = func() []attribute.KeyValue {
+			return result.Attributes
+		}
+	}
+	switch result.Decision {
+	case RecordAndSample, ExportOnly:
+		return SamplingIntent{
+			Threshold:         ls.config.threshold,
+			ThresholdReliable: ls.config.reliable,
+			Attributes:        attrs,
+			TraceState:        legacyTraceState(params.ParentSpanContext.TraceState(), result.Tracestate),
+			ExportOnly:        result.Decision == ExportOnly,
+		}
+	case RecordOnly:
+		return SamplingIntent{
+			Record:               true,
+			Threshold:            NEVER_SAMPLE_THRESHOLD,
+			NonSampledAttributes: attrs,
+		}
+	default:
+		return SamplingIntent{
+			Threshold: NEVER_SAMPLE_THRESHOLD,
+		}
+	}
+}
+
+// Description implements ComposableSampler.
+func (ls *legacySampler) Description() string {
+	if ls.config.reliable {
+		return fmt.Sprintf("Legacy{%s,%s}", ls.sampler.Description(), ls.config.threshold)
+	}
+	return fmt.Sprintf("Legacy{%s}", ls.sampler.Description())
+}
+
+// legacyTraceState returns a function applying the members of the
+// legacy result that differ from the parent's, other than "ot", which
+// belongs to the composite sampler.
+func legacyTraceState(parent, result trace.TraceState) TraceStateFunc {
+	if result.Len() == 0 {
+		return nil
+	}
+	return func(ts trace.TraceState) trace.TraceState {
+		type member struct{ key, value string }
+		var changed []member
+		result.Walk(func(key, value string) bool {
+			if key != "ot" && parent.Get(key) != value {
+				changed = append(changed, member{key, value})
+			}
+			return true
+		})
+		// Inserted in reverse, since Insert adds to the front.
+		for i := len(changed) - 1; i >= 0; i-- {
+			if updated, err := ts.Insert(changed[i].key, changed[i].value); err == nil {
+				ts = updated
+			}
+		}
+		return ts
+	}
+}
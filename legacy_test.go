@@ -0,0 +1,97 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// legacyFunc is a Sampler of the original API.
+type legacyFunc func(SamplingParameters) SamplingResult
+
+func (f legacyFunc) ShouldSample(params SamplingParameters) SamplingResult {
+	return f(params)
+}
+
+func (f legacyFunc) Description() string {
+	return "legacyFunc"
+}
+
+func TestLegacySampler(t *testing.T) {
+	parentTS, err := trace.ParseTraceState("vnd=a,other=b")
+	require.NoError(t, err)
+	legacy := legacyFunc(func(params SamplingParameters) SamplingResult {
+		ts := trace.SpanContextFromContext(params.ParentContext).TraceState()
+		ts, _ = ts.Insert("vnd", "x")
+		ts, _ = ts.Insert("mine", "y")
+		decision := Drop
+		switch params.Name {
+		case "sample":
+			decision = RecordAndSample
+		case "record":
+			decision = RecordOnly
+		case "export":
+			decision = ExportOnly
+		}
+		return SamplingResult{
+			Decision:   decision,
+			Attributes: []attribute.KeyValue{attribute.String("legacy", params.Name)},
+			Tracestate: ts,
+		}
+	})
+
+	s := CompositeSampler(RuleBased(
+		WithRule(SpanNamePredicate("other"), ComposableAlwaysSample()),
+		WithDefaultRule(LegacySampler(legacy)),
+	))
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+		TraceState: parentTS,
+	}))
+	sample := func(name string) SamplingResult {
+		return s.ShouldSample(SamplingParameters{ParentContext: ctx, TraceID: trace.TraceID{1}, Name: name})
+	}
+
+	result := sample("sample")
+	require.Equal(t, RecordAndSample, result.Decision)
+	require.Equal(t, []attribute.KeyValue{attribute.String("legacy", "sample")}, result.Attributes)
+	require.Equal(t, "mine=y,vnd=x,other=b", result.Tracestate.String())
+
+	result = sample("record")
+	require.Equal(t, RecordOnly, result.Decision)
+	require.Equal(t, []attribute.KeyValue{attribute.String("legacy", "record")}, result.Attributes)
+
+	result = sample("export")
+	require.Equal(t, ExportOnly, result.Decision)
+	require.Equal(t, "vnd=a,other=b", result.Tracestate.String())
+
+	result = sample("drop")
+	require.Equal(t, Drop, result.Decision)
+	require.Nil(t, result.Attributes)
+
+	// With a threshold, the decision is also subject to it.
+	cs := LegacySampler(legacy, WithLegacyThreshold(ProbabilityToThreshold(0.5)))
+	require.Equal(t, "Legacy{legacyFunc,8}", cs.Description())
+	intent := cs.GetSamplingIntent(ComposableSamplingParameters{SamplingParameters: SamplingParameters{Name: "sample"}})
+	require.Equal(t, ProbabilityToThreshold(0.5), intent.Threshold)
+	require.True(t, intent.ThresholdReliable)
+}
+
+func TestFromSDKSampler(t *testing.T) {
+	cs := LegacySampler(FromSDKSampler(sdktrace.AlwaysSample()))
+	require.Equal(t, "Legacy{AlwaysOnSampler}", cs.Description())
+	intent := cs.GetSamplingIntent(ComposableSamplingParameters{})
+	require.Equal(t, ALWAYS_SAMPLE_THRESHOLD, intent.Threshold)
+	require.False(t, intent.ThresholdReliable)
+
+	intent = LegacySampler(FromSDKSampler(sdktrace.NeverSample())).GetSamplingIntent(ComposableSamplingParameters{})
+	require.Equal(t, NEVER_SAMPLE_THRESHOLD, intent.Threshold)
+}
@@ -0,0 +1,162 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package logsampler samples log records consistently with the traces
+// they are correlated with, using the thresholds and randomness of
+// the sampler package, so that the logs and spans of a request are
+// kept or dropped together.
+package logsampler
+
+import (
+	"context"
+	"math/rand/v2"
+
+	"github.com/jmacd/sampler"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// randomnessMask selects the 56 bits of randomness used for
+// consistent sampling decisions.
+const randomnessMask = 1<<56 - 1
+
+// Option configures a Processor.
+type Option func(*config)
+
+type config struct {
+	threshold    sampler.Threshold
+	hasThreshold bool
+	uncorrelated sampler.ComposableSampler
+}
+
+// WithThreshold samples log records correlated with a trace using a
+// threshold and the trace's randomness, in place of the trace's
+// sampled flag.  A record is kept when its trace would be sampled by
+// a consistent sampler with the threshold, so records are kept for
+// every trace sampled with the same or a lower probability, whether
+// or not the span that emitted the record was sampled.
+func WithThreshold(threshold sampler.Threshold) Option {
+	return func(cfg *config) {
+		cfg.threshold = threshold
+		cfg.hasThreshold = true
+	}
+}
+
+// WithUncorrelatedSampler samples log records that are not correlated
+// with a trace, which are kept by default, using the threshold of a
+// sampler's intent and a random value for each record.  The sampler
+// is called without span parameters, so for example a rate of N
+// records per second is
+//
+//	sampler.CostBased(N, sampler.WithDefaultSpanCost(1), sampler.WithAttributeCost(0))
+func WithUncorrelatedSampler(s sampler.ComposableSampler) Option {
+	return func(cfg *config) {
+		cfg.uncorrelated = s
+	}
+}
+
+// Processor returns a log Processor that passes the records it keeps
+// to next.  By default, a record correlated with a trace is kept when
+// its trace flags are sampled, i.e., when the span that emitted it
+// was sampled, and records that are not correlated with a trace are
+// kept.
+//
+// The returned Processor also implements the log SDK's experimental
+// FilterProcessor, for records whose context has a span.
+func Processor(next sdklog.Processor, options ...Option) sdklog.Processor {
+	var cfg config
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	return &processor{
+		next:   next,
+		config: cfg,
+	}
+}
+
+type processor struct {
+	next   sdklog.Processor
+	config config
+}
+
+var _ sdklog.Processor = &processor{}
+
+// enabler is the log SDK's experimental FilterProcessor.
+type enabler interface {
+	Enabled(ctx context.Context, param log.EnabledParameters) bool
+}
+
+var _ enabler = &processor{}
+
+// OnEmit implements sdklog.Processor.
+func (p *processor) OnEmit(ctx context.Context, record *sdklog.Record) error {
+	if !p.keep(ctx, record) {
+		return nil
+	}
+	return p.next.OnEmit(ctx, record)
+}
+
+// Enabled returns false for records emitted in the context of a span
+// whose records would be dropped, otherwise the result of next when
+// it is a FilterProcessor.
+func (p *processor) Enabled(ctx context.Context, param log.EnabledParameters) bool {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() && !p.keepCorrelated(sc) {
+		return false
+	}
+	if e, ok := p.next.(enabler); ok {
+		return e.Enabled(ctx, param)
+	}
+	return true
+}
+
+// Shutdown implements sdklog.Processor.
+func (p *processor) Shutdown(ctx context.Context) error {
+	return p.next.Shutdown(ctx)
+}
+
+// ForceFlush implements sdklog.Processor.
+func (p *processor) ForceFlush(ctx context.Context) error {
+	return p.next.ForceFlush(ctx)
+}
+
+// keep returns true for records that are passed to the next processor.
+func (p *processor) keep(ctx context.Context, record *sdklog.Record) bool {
+	if !record.TraceID().IsValid() {
+		return p.keepUncorrelated()
+	}
+	sc := trace.SpanContextFromContext(ctx)
+	if sc.TraceID() != record.TraceID() {
+		// The record's own IDs, without a tracestate.  The span
+		// ID is not used for sampling, but must be valid.
+		spanID := record.SpanID()
+		if !spanID.IsValid() {
+			spanID = trace.SpanID{1}
+		}
+		sc = trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    record.TraceID(),
+			SpanID:     spanID,
+			TraceFlags: record.TraceFlags(),
+		})
+	}
+	return p.keepCorrelated(sc)
+}
+
+// keepCorrelated returns true for records of the given span context.
+func (p *processor) keepCorrelated(sc trace.SpanContext) bool {
+	if !p.config.hasThreshold {
+		return sc.IsSampled()
+	}
+	randomness, _ := sampler.RandomnessFromSpanContext(sc)
+	return p.config.threshold.ShouldSample(randomness)
+}
+
+// keepUncorrelated returns true for uncorrelated records that are
+// sampled.
+func (p *processor) keepUncorrelated() bool {
+	if p.config.uncorrelated == nil {
+		return true
+	}
+	intent := p.config.uncorrelated.GetSamplingIntent(sampler.ComposableSamplingParameters{})
+	return intent.Threshold.ShouldSample(int64(rand.Uint64() & randomnessMask))
+}
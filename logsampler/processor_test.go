@@ -0,0 +1,99 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package logsampler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmacd/sampler"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recorder is a Processor that records the bodies of emitted records.
+type recorder struct {
+	bodies []string
+}
+
+func (r *recorder) OnEmit(_ context.Context, record *sdklog.Record) error {
+	r.bodies = append(r.bodies, record.Body().AsString())
+	return nil
+}
+
+func (r *recorder) Shutdown(context.Context) error   { return nil }
+func (r *recorder) ForceFlush(context.Context) error { return nil }
+
+func spanContext(t *testing.T, flags trace.TraceFlags, tracestate string) context.Context {
+	ts, err := trace.ParseTraceState(tracestate)
+	require.NoError(t, err)
+	return trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		// The randomness is 0x80000000000000.
+		TraceID:    trace.TraceID{15: 0, 9: 0x80},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: flags,
+		TraceState: ts,
+	}))
+}
+
+func emit(ctx context.Context, logger log.Logger, body string) {
+	var record log.Record
+	record.SetBody(log.StringValue(body))
+	logger.Emit(ctx, record)
+}
+
+func TestProcessor(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		options []Option
+		expect  []string
+	}{
+		{
+			name:   "default",
+			expect: []string{"sampled", "uncorrelated"},
+		},
+		{
+			// The TraceID randomness meets a threshold of 50% but not
+			// 25%, while the explicit randomness meets both.
+			name:    "threshold",
+			options: []Option{WithThreshold(sampler.ProbabilityToThreshold(0.5))},
+			expect:  []string{"sampled", "unsampled", "uncorrelated", "explicit"},
+		},
+		{
+			name:    "low threshold",
+			options: []Option{WithThreshold(sampler.ProbabilityToThreshold(0.25))},
+			expect:  []string{"uncorrelated", "explicit"},
+		},
+		{
+			name:    "no uncorrelated",
+			options: []Option{WithThreshold(sampler.ProbabilityToThreshold(0.25)), WithUncorrelatedSampler(sampler.ComposableNeverSample())},
+			expect:  []string{"explicit"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			rec := &recorder{}
+			lp := sdklog.NewLoggerProvider(sdklog.WithProcessor(Processor(rec, test.options...)))
+			logger := lp.Logger("test")
+
+			emit(spanContext(t, trace.FlagsSampled, ""), logger, "sampled")
+			emit(spanContext(t, 0, ""), logger, "unsampled")
+			emit(context.Background(), logger, "uncorrelated")
+			emit(spanContext(t, 0, "ot=rv:ffffffffffffff"), logger, "explicit")
+			require.Equal(t, test.expect, rec.bodies)
+		})
+	}
+}
+
+func TestProcessorEnabled(t *testing.T) {
+	p := Processor(&recorder{}).(enabler)
+	require.True(t, p.Enabled(spanContext(t, trace.FlagsSampled, ""), log.EnabledParameters{}))
+	require.False(t, p.Enabled(spanContext(t, 0, ""), log.EnabledParameters{}))
+	require.True(t, p.Enabled(context.Background(), log.EnabledParameters{}))
+
+	// The next processor is consulted.
+	nested := Processor(Processor(&recorder{}, WithThreshold(sampler.NEVER_SAMPLE_THRESHOLD))).(enabler)
+	require.False(t, nested.Enabled(spanContext(t, trace.FlagsSampled, ""), log.EnabledParameters{}))
+}
@@ -0,0 +1,95 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"fmt"
+	"sync"
+
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// defaultMemoizeSize is the default number of results cached by a
+// memoized predicate.
+const defaultMemoizeSize = 1024
+
+// MemoizeOption configures MemoizePredicate.
+type MemoizeOption func(*memoizeConfig)
+
+type memoizeConfig struct {
+	key  func(ComposableSamplingParameters) string
+	size int
+}
+
+// WithMemoizeKey sets the function computing the cache key.  The
+// predicate's result must depend only on the key.  The default key is
+// the span name.
+func WithMemoizeKey(key func(ComposableSamplingParameters) string) MemoizeOption {
+	return func(cfg *memoizeConfig) {
+		cfg.key = key
+	}
+}
+
+// WithMemoizeSize sets the maximum number of cached results.  When
+// the cache is full, it is cleared.
+func WithMemoizeSize(size int) MemoizeOption {
+	return func(cfg *memoizeConfig) {
+		cfg.size = size
+	}
+}
+
+// MemoizePredicate caches the results of an expensive predicate, such
+// as a regular expression or CEL expression, in a bounded map keyed
+// by span name.  This is effective when the predicate is evaluated
+// for a small set of distinct names.
+func MemoizePredicate(pred Predicate, options ...MemoizeOption) Predicate {
+	cfg := memoizeConfig{
+		key: func(params ComposableSamplingParameters) string {
+			return params.Name
+		},
+		size: defaultMemoizeSize,
+	}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	return memoize(pred, cfg)
+}
+
+func memoize(pred Predicate, cfg memoizeConfig) Predicate {
+	if _, ok := pred.isConstant(); ok {
+		return pred
+	}
+	var (
+		lock  sync.RWMutex
+		cache = map[string]bool{}
+	)
+	memo := NewPredicate(func(params ComposableSamplingParameters) bool {
+		key := cfg.key(params)
+
+		lock.RLock()
+		result, ok := cache[key]
+		lock.RUnlock()
+		if ok {
+			return result
+		}
+
+		result = pred.Decide(params)
+
+		lock.Lock()
+		defer lock.Unlock()
+		if len(cache) >= cfg.size {
+			clear(cache)
+		}
+		cache[key] = result
+		return result
+	}, fmt.Sprintf("memo(%s)", pred.Description()))
+	memo.spec = pred.spec
+	if pred.optimize != nil {
+		memo.optimize = func(res *resource.Resource, scope instrumentation.Scope) Predicate {
+			return memoize(pred.Optimize(res, scope), cfg)
+		}
+	}
+	return memo
+}
@@ -0,0 +1,82 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+func TestMemoizePredicate(t *testing.T) {
+	calls := 0
+	health, err := SpanNameRegexPredicate("^/health")
+	require.NoError(t, err)
+	counting := PredicateFunc(func(params ComposableSamplingParameters) bool {
+		calls++
+		return health.Decide(params)
+	}).Describe(health.Description())
+
+	pred := MemoizePredicate(counting, WithMemoizeSize(2))
+	require.Equal(t, "memo(Span.Name=~^/health)", pred.Description())
+
+	named := func(name string) ComposableSamplingParameters {
+		return ComposableSamplingParameters{
+			SamplingParameters: SamplingParameters{
+				Name: name,
+			},
+		}
+	}
+	for _, test := range []struct {
+		name  string
+		match bool
+		calls int
+	}{
+		{"/healthz", true, 1},
+		{"/healthz", true, 1},
+		{"/api", false, 2},
+		{"/api", false, 2},
+		{"/healthz", true, 2},
+		// The cache is full and is cleared.
+		{"/debug", false, 3},
+		{"/healthz", true, 4},
+	} {
+		require.Equal(t, test.match, pred.Decide(named(test.name)), test.name)
+		require.Equal(t, test.calls, calls, test.name)
+	}
+}
+
+func TestMemoizePredicateKey(t *testing.T) {
+	calls := 0
+	route := AttributeEqualsPredicate(attribute.String("http.route", "/orders"))
+	counting := PredicateFunc(func(params ComposableSamplingParameters) bool {
+		calls++
+		return route.Decide(params)
+	}).Describe(route.Description())
+
+	pred := MemoizePredicate(counting, WithMemoizeKey(func(params ComposableSamplingParameters) string {
+		value, _ := findAttribute(params.Attributes, "http.route")
+		return value.Emit()
+	}))
+	require.True(t, pred.Decide(testAttributeParams(attribute.String("http.route", "/orders"))))
+	require.True(t, pred.Decide(testAttributeParams(attribute.String("http.route", "/orders"))))
+	require.False(t, pred.Decide(testAttributeParams(attribute.String("http.route", "/users"))))
+	require.Equal(t, 2, calls)
+}
+
+func TestMemoizePredicateOptimize(t *testing.T) {
+	require.Equal(t, "true", MemoizePredicate(TruePredicate()).Description())
+
+	staging := ResourceAttributePredicate(attribute.String("deployment.environment", "staging"))
+	pred := MemoizePredicate(OrPredicate(staging, SpanNamePredicate("/debug")))
+	require.Equal(t, "memo(or(Resource[deployment.environment]==staging,Span.Name==/debug))", pred.Description())
+
+	res := resource.NewSchemaless(attribute.String("deployment.environment", "staging"))
+	opt := pred.Optimize(res, instrumentation.Scope{})
+	v, ok := opt.isConstant()
+	require.True(t, ok)
+	require.True(t, v)
+}
@@ -0,0 +1,74 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package middleware
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/jmacd/sampler"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+)
+
+// grpcResendHeader is the metadata that gRPC sets on retried RPCs.
+const grpcResendHeader = "grpc-previous-rpc-attempts"
+
+// StatsHandler returns a gRPC stats handler that adds the attributes
+// of each RPC to the span started by h, which is typically the stats
+// handler of the gRPC instrumentation, since it starts spans before
+// interceptors run.  The attributes are "rpc.system", "rpc.service",
+// "rpc.method", and those configured by WithHeaderAttribute and
+// WithResendCountHeader, which are read from the incoming metadata of
+// servers and the outgoing metadata of clients.
+func StatsHandler(h stats.Handler, options ...Option) stats.Handler {
+	cfg := newConfig(options)
+	if cfg.resendHeader == "" {
+		cfg.resendHeader = grpcResendHeader
+	}
+	return &statsHandler{
+		Handler: h,
+		config:  cfg,
+	}
+}
+
+type statsHandler struct {
+	stats.Handler
+	config config
+}
+
+var _ stats.Handler = &statsHandler{}
+
+// TagRPC implements stats.Handler.
+func (sh *statsHandler) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	attrs := []attribute.KeyValue{attribute.String("rpc.system", "grpc")}
+	service, method, ok := strings.Cut(strings.TrimPrefix(info.FullMethodName, "/"), "/")
+	if ok {
+		attrs = append(attrs,
+			attribute.String("rpc.service", service),
+			attribute.String("rpc.method", method),
+		)
+	}
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		md, _ = metadata.FromOutgoingContext(ctx)
+	}
+	get := func(header string) string {
+		if values := md.Get(header); len(values) != 0 {
+			return values[0]
+		}
+		return ""
+	}
+	for _, ha := range sh.config.headers {
+		if value := get(ha.header); value != "" {
+			attrs = append(attrs, ha.key.String(value))
+		}
+	}
+	if count, err := strconv.Atoi(get(sh.config.resendHeader)); err == nil {
+		attrs = append(attrs, ResendCountKey.Int(count))
+	}
+	return sh.Handler.TagRPC(sampler.ContextWithStartAttributes(ctx, attrs...), info)
+}
@@ -0,0 +1,42 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/jmacd/sampler"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Handler returns an HTTP handler that adds the attributes of each
+// request to the span started by h, namely "http.request.method",
+// "url.path", "http.route" (see WithRouteFunc), and those configured
+// by WithHeaderAttribute and WithResendCountHeader.
+func Handler(h http.Handler, options ...Option) http.Handler {
+	cfg := newConfig(options)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attrs := []attribute.KeyValue{
+			attribute.String("http.request.method", r.Method),
+			attribute.String("url.path", r.URL.Path),
+		}
+		if cfg.route != nil {
+			if route := cfg.route(r); route != "" {
+				attrs = append(attrs, attribute.String("http.route", route))
+			}
+		}
+		for _, ha := range cfg.headers {
+			if value := r.Header.Get(ha.header); value != "" {
+				attrs = append(attrs, ha.key.String(value))
+			}
+		}
+		if cfg.resendHeader != "" {
+			if count, err := strconv.Atoi(r.Header.Get(cfg.resendHeader)); err == nil {
+				attrs = append(attrs, ResendCountKey.Int(count))
+			}
+		}
+		h.ServeHTTP(w, r.WithContext(sampler.ContextWithStartAttributes(r.Context(), attrs...)))
+	})
+}
@@ -0,0 +1,104 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package middleware adds request attributes to the spans started by
+// HTTP and gRPC instrumentation, before they are sampled, so that
+// predicates such as HTTPRoutePredicate and samplers such as
+// ErrorHintBiased have the routing information they need.  The
+// attributes are carried in the request context, see
+// sampler.ContextWithStartAttributes, so the middleware must run
+// before the instrumentation that starts the span:
+//
+//	handler := middleware.Handler(otelhttp.NewHandler(mux, "server"), middleware.WithServeMux(mux))
+//	server := grpc.NewServer(grpc.StatsHandler(middleware.StatsHandler(otelgrpc.NewServerHandler())))
+//
+// The attributes are recorded with the span when it is sampled.
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ResendCountKey is the attribute holding the number of previous
+// attempts of a request, which ErrorHintBiased recognizes.
+const ResendCountKey = attribute.Key("http.request.resend_count")
+
+// Option configures a Handler or StatsHandler.
+type Option func(*config)
+
+type config struct {
+	headers      []headerAttribute
+	resendHeader string
+	route        func(*http.Request) string
+}
+
+// WithHeaderAttribute adds the value of a request header, or of gRPC
+// metadata with the header's name, as a string attribute, for example
+// a tenant:
+//
+//	middleware.WithHeaderAttribute("X-Tenant-ID", "tenant.id")
+func WithHeaderAttribute(header string, key attribute.Key) Option {
+	return func(cfg *config) {
+		cfg.headers = append(cfg.headers, headerAttribute{header: header, key: key})
+	}
+}
+
+// WithResendCountHeader sets the request header holding the number of
+// previous attempts of a retried request, which is added as the
+// ResendCountKey attribute.  For gRPC, the default is the
+// "grpc-previous-rpc-attempts" metadata, which gRPC sets on retries.
+// For HTTP, there is no default.
+func WithResendCountHeader(header string) Option {
+	return func(cfg *config) {
+		cfg.resendHeader = header
+	}
+}
+
+// WithRouteFunc sets a function that returns the route template of an
+// HTTP request, such as "/users/{id}", which is added as the
+// "http.route" attribute when it is not empty.
+func WithRouteFunc(route func(*http.Request) string) Option {
+	return func(cfg *config) {
+		cfg.route = route
+	}
+}
+
+// WithServeMux takes the route template of an HTTP request from the
+// pattern of the mux that will handle it, without the pattern's
+// method and host, so that "GET example.com/users/{id}" is the route
+// "/users/{id}".
+func WithServeMux(mux *http.ServeMux) Option {
+	return WithRouteFunc(func(r *http.Request) string {
+		_, pattern := mux.Handler(r)
+		return patternRoute(pattern)
+	})
+}
+
+// patternRoute returns the path of a ServeMux pattern, which has the
+// form "[METHOD ][HOST]/[PATH]".
+func patternRoute(pattern string) string {
+	if i := strings.IndexAny(pattern, " \t"); i >= 0 {
+		pattern = strings.TrimLeft(pattern[i:], " \t")
+	}
+	if i := strings.IndexByte(pattern, '/'); i > 0 {
+		pattern = pattern[i:]
+	}
+	return pattern
+}
+
+// headerAttribute is a header and the attribute holding its value.
+type headerAttribute struct {
+	header string
+	key    attribute.Key
+}
+
+func newConfig(options []Option) config {
+	var cfg config
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	return cfg
+}
@@ -0,0 +1,130 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jmacd/sampler"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+)
+
+func newTracerProvider(s sampler.ComposableSampler) (*sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sampler.NewSDKSampler(s)),
+		sdktrace.WithSpanProcessor(recorder),
+	), recorder
+}
+
+func TestHandler(t *testing.T) {
+	tp, recorder := newTracerProvider(sampler.RuleBased(
+		sampler.WithRule(sampler.HTTPRoutePredicate("/users/{id}", "POST"), sampler.ComposableAlwaysSample()),
+		sampler.WithDefaultRule(sampler.ComposableNeverSample()),
+	))
+	tracer := tp.Tracer("test")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/{id}", func(http.ResponseWriter, *http.Request) {})
+
+	// An instrumented handler, which extracts the remote parent
+	// and starts a span.
+	instrumented := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := propagation.TraceContext{}.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		_, span := tracer.Start(ctx, "server")
+		defer span.End()
+		mux.ServeHTTP(w, r)
+	})
+	handler := Handler(instrumented,
+		WithServeMux(mux),
+		WithHeaderAttribute("X-Tenant", "tenant.id"),
+		WithResendCountHeader("X-Retry"),
+	)
+
+	req := httptest.NewRequest("POST", "/users/42", nil)
+	req.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	req.Header.Set("X-Tenant", "acme")
+	req.Header.Set("X-Retry", "2")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42", nil))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, []attribute.KeyValue{
+		attribute.String("http.request.method", "POST"),
+		attribute.String("url.path", "/users/42"),
+		attribute.String("http.route", "/users/{id}"),
+		attribute.String("tenant.id", "acme"),
+		ResendCountKey.Int(2),
+	}, spans[0].Attributes())
+}
+
+func TestServeMuxRoute(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(http.ResponseWriter, *http.Request) {})
+	mux.HandleFunc("example.com/x/", func(http.ResponseWriter, *http.Request) {})
+	mux.HandleFunc("POST\texample.com/items/{id...}", func(http.ResponseWriter, *http.Request) {})
+	mux.HandleFunc("/", func(http.ResponseWriter, *http.Request) {})
+	route := newConfig([]Option{WithServeMux(mux)}).route
+
+	for _, test := range []struct {
+		method, target, route string
+	}{
+		{"GET", "/users/42", "/users/{id}"},
+		{"GET", "http://example.com/x/y", "/x/"},
+		{"POST", "http://example.com/items/a/b", "/items/{id...}"},
+		{"GET", "/other", "/"},
+	} {
+		require.Equal(t, test.route, route(httptest.NewRequest(test.method, test.target, nil)), test.target)
+	}
+}
+
+// spanStarter is a stats.Handler that starts a span in TagRPC, as the
+// gRPC instrumentation does.
+type spanStarter struct {
+	stats.Handler
+	tracer trace.Tracer
+	span   trace.Span
+}
+
+func (s *spanStarter) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	ctx, s.span = s.tracer.Start(ctx, info.FullMethodName)
+	return ctx
+}
+
+func TestStatsHandler(t *testing.T) {
+	// Retries are sampled.
+	tp, recorder := newTracerProvider(sampler.ErrorHintBiased(sampler.ComposableNeverSample(), 1))
+	starter := &spanStarter{tracer: tp.Tracer("test")}
+	handler := StatsHandler(starter, WithHeaderAttribute("x-tenant", "tenant.id"))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"x-tenant", "acme",
+		"grpc-previous-rpc-attempts", "1",
+	))
+	handler.TagRPC(ctx, &stats.RPCTagInfo{FullMethodName: "/pkg.Users/Get"})
+	starter.span.End()
+	handler.TagRPC(context.Background(), &stats.RPCTagInfo{FullMethodName: "/pkg.Users/Get"})
+	starter.span.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, []attribute.KeyValue{
+		attribute.String("rpc.system", "grpc"),
+		attribute.String("rpc.service", "pkg.Users"),
+		attribute.String("rpc.method", "Get"),
+		attribute.String("tenant.id", "acme"),
+		ResendCountKey.Int(1),
+	}, spans[0].Attributes())
+}
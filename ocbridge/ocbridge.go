@@ -0,0 +1,139 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package ocbridge converts OpenCensus samplers to composable
+// samplers, for programs that still configure OpenCensus sampling
+// while their instrumentation migrates through the OpenCensus bridge.
+package ocbridge
+
+import (
+	"encoding/binary"
+
+	"github.com/jmacd/sampler"
+	octrace "go.opencensus.io/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// probabilityRange is the number of values that OpenCensus compares
+// with a ProbabilitySampler's bound, the first 63 bits of the TraceID.
+const probabilityRange = 1 << 63
+
+// Sampler returns a ComposableSampler equivalent to an OpenCensus
+// sampler.  OpenCensus samplers are functions, so the sampler is
+// identified by probing it with synthetic parameters:
+//
+//	AlwaysSample            ComposableAlwaysSample
+//	NeverSample             ComposableNeverSample
+//	ProbabilitySampler(p)   ParentThreshold for sampled parents, otherwise
+//	                        TraceIDRatioBased(p)
+//
+// A ProbabilitySampler becomes consistent: it samples with the same
+// probability, using the randomness of the TraceID's last 56 bits
+// instead of its first 63 bits, so that its threshold is recorded in
+// the tracestate and determines adjusted counts.  As in OpenCensus,
+// children of sampled parents are sampled.
+//
+// Other samplers are called for each span, as by Legacy, and wrapped
+// by sampler.LegacySampler, so that spans they sample have no known
+// probability.  Probing cannot distinguish samplers that depend on the
+// span name or on the parent's IDs from the samplers above; wrap them
+// with Legacy explicitly.
+func Sampler(s octrace.Sampler) sampler.ComposableSampler {
+	bound, ok := probabilityBound(s)
+	sampledParent := s(octrace.SamplingParameters{
+		ParentContext: octrace.SpanContext{TraceOptions: 1},
+		TraceID:       octrace.TraceID{0: 0xff, 15: 0xff},
+	}).Sample
+	switch {
+	case !ok:
+	case bound == probabilityRange && sampledParent:
+		return sampler.ComposableAlwaysSample()
+	case bound == 0 && !sampledParent:
+		return sampler.ComposableNeverSample()
+	case sampledParent:
+		return sampler.RuleBased(
+			sampler.WithRule(sampler.IsParentSampledPredicate(), sampler.ParentThreshold()),
+			sampler.WithDefaultRule(sampler.TraceIDRatioBased(float64(bound)/probabilityRange)),
+		)
+	}
+	return sampler.LegacySampler(Legacy(s))
+}
+
+// probabilityBound returns the bound of a sampler that samples spans
+// with unsampled parents when the first 63 bits of their TraceID are
+// less than the bound, as ProbabilitySampler does, and false for other
+// samplers.
+func probabilityBound(s octrace.Sampler) (uint64, bool) {
+	samples := func(x uint64, fill byte) bool {
+		var tid octrace.TraceID
+		binary.BigEndian.PutUint64(tid[:8], x<<1|uint64(fill&1))
+		for i := 8; i < len(tid); i++ {
+			tid[i] = fill
+		}
+		return s(octrace.SamplingParameters{
+			TraceID: tid,
+			SpanID:  octrace.SpanID{0: fill, 7: 1},
+			Name:    "probe",
+		}).Sample
+	}
+	// The smallest x that is not sampled, or probabilityRange.
+	lo, hi := uint64(0), uint64(probabilityRange)
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if samples(mid, 0) {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	bound := lo
+	probes := []uint64{0, bound / 2, bound - 1, bound, bound + (probabilityRange-bound)/2, probabilityRange - 1}
+	for _, x := range probes {
+		if x >= probabilityRange {
+			continue
+		}
+		for _, fill := range []byte{0, 0xff} {
+			if samples(x, fill) != (x < bound) {
+				return 0, false
+			}
+		}
+	}
+	return bound, true
+}
+
+// Legacy returns a Sampler of the original API that calls an
+// OpenCensus sampler for each span, for use with sampler.LegacySampler.
+func Legacy(s octrace.Sampler) sampler.Sampler {
+	return legacySampler{sampler: s}
+}
+
+// legacySampler calls an OpenCensus sampler for each span.
+type legacySampler struct {
+	sampler octrace.Sampler
+}
+
+var _ sampler.Sampler = legacySampler{}
+
+// ShouldSample implements sampler.Sampler.
+func (ls legacySampler) ShouldSample(params sampler.SamplingParameters) sampler.SamplingResult {
+	psc := trace.SpanContextFromContext(params.ParentContext)
+	decision := ls.sampler(octrace.SamplingParameters{
+		ParentContext: octrace.SpanContext{
+			TraceID:      octrace.TraceID(psc.TraceID()),
+			SpanID:       octrace.SpanID(psc.SpanID()),
+			TraceOptions: octrace.TraceOptions(psc.TraceFlags() & trace.FlagsSampled),
+		},
+		TraceID:         octrace.TraceID(params.TraceID),
+		Name:            params.Name,
+		HasRemoteParent: psc.IsRemote(),
+	})
+	if decision.Sample {
+		return sampler.SamplingResult{Decision: sampler.RecordAndSample}
+	}
+	return sampler.SamplingResult{Decision: sampler.Drop}
+}
+
+// Description implements sampler.Sampler.
+func (legacySampler) Description() string {
+	return "OpenCensus"
+}
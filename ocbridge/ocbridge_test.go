@@ -0,0 +1,94 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package ocbridge
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	octrace "go.opencensus.io/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jmacd/sampler"
+)
+
+func TestSampler(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		oc   octrace.Sampler
+		desc string
+	}{
+		{"always", octrace.AlwaysSample(), sampler.ComposableAlwaysSample().Description()},
+		{"never", octrace.NeverSample(), sampler.ComposableNeverSample().Description()},
+		{"one", octrace.ProbabilitySampler(1), sampler.ComposableAlwaysSample().Description()},
+		{"quarter", octrace.ProbabilitySampler(0.25), sampler.RuleBased(
+			sampler.WithRule(sampler.IsParentSampledPredicate(), sampler.ParentThreshold()),
+			sampler.WithDefaultRule(sampler.TraceIDRatioBased(0.25)),
+		).Description()},
+		{"custom", func(p octrace.SamplingParameters) octrace.SamplingDecision {
+			return octrace.SamplingDecision{Sample: p.TraceID[15] == 0xff}
+		}, "Legacy{OpenCensus}"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.desc, Sampler(test.oc).Description())
+		})
+	}
+}
+
+func TestProbabilitySampler(t *testing.T) {
+	cs := sampler.CompositeSampler(Sampler(octrace.ProbabilitySampler(0.25)))
+	ctx := context.Background()
+
+	// Roots are sampled with a threshold by their randomness.
+	res := cs.ShouldSample(sampler.SamplingParameters{
+		ParentContext: ctx,
+		TraceID:       trace.TraceID{9: 0xff},
+	})
+	require.Equal(t, sampler.RecordAndSample, res.Decision)
+	require.Equal(t, "th:c", res.Tracestate.Get("ot"))
+
+	res = cs.ShouldSample(sampler.SamplingParameters{
+		ParentContext: ctx,
+		TraceID:       trace.TraceID{15: 0x01},
+	})
+	require.Equal(t, sampler.Drop, res.Decision)
+
+	// Children of sampled parents are sampled.
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{15: 0x01},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+	})
+	res = cs.ShouldSample(sampler.SamplingParameters{
+		ParentContext: trace.ContextWithSpanContext(ctx, parent),
+		TraceID:       parent.TraceID(),
+	})
+	require.Equal(t, sampler.RecordAndSample, res.Decision)
+}
+
+func TestLegacy(t *testing.T) {
+	cs := sampler.CompositeSampler(sampler.LegacySampler(Legacy(func(p octrace.SamplingParameters) octrace.SamplingDecision {
+		return octrace.SamplingDecision{Sample: p.Name == "important" || p.ParentContext.IsSampled()}
+	})))
+	ctx := context.Background()
+
+	res := cs.ShouldSample(sampler.SamplingParameters{ParentContext: ctx, TraceID: trace.TraceID{1}, Name: "important"})
+	require.Equal(t, sampler.RecordAndSample, res.Decision)
+	require.Equal(t, "", res.Tracestate.Get("ot"))
+	res = cs.ShouldSample(sampler.SamplingParameters{ParentContext: ctx, TraceID: trace.TraceID{1}, Name: "other"})
+	require.Equal(t, sampler.Drop, res.Decision)
+
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	res = cs.ShouldSample(sampler.SamplingParameters{
+		ParentContext: trace.ContextWithRemoteSpanContext(ctx, parent),
+		TraceID:       parent.TraceID(),
+		Name:          "other",
+	})
+	require.Equal(t, sampler.RecordAndSample, res.Decision)
+}
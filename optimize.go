@@ -0,0 +1,125 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// SamplerOptimizer is an optional interface for ComposableSamplers
+// that can specialize themselves using the static properties of a
+// Tracer, which are its Resource and instrumentation Scope.  This
+// addresses the standing requests to make these available to
+// samplers without passing them through every ShouldSample() call.
+type SamplerOptimizer interface {
+	// Optimize returns a sampler equivalent to this one for spans
+	// having the given Resource and Scope.
+	Optimize(*resource.Resource, instrumentation.Scope) ComposableSampler
+}
+
+// WithResource sets the Resource of ComposableSamplingParameters, for
+// samplers that read it for each span instead of implementing
+// SamplerOptimizer.  The SDK does not pass its Resource to samplers,
+// so it must be the Resource of the TracerProvider using the sampler.
+// NewTracerProvider sets it.
+func WithResource(res *resource.Resource) CompositeOption {
+	return func(cfg *compositeConfig) {
+		cfg.resource = res
+	}
+}
+
+// WithScope sets the instrumentation Scope of
+// ComposableSamplingParameters, as WithResource does for the
+// Resource.  A CompositeSampler has a single Scope, so this applies
+// to samplers used by one Tracer.  NewTracerProvider sets it for each
+// Tracer.
+func WithScope(scope instrumentation.Scope) CompositeOption {
+	return func(cfg *compositeConfig) {
+		cfg.scope = scope
+	}
+}
+
+// Optimize returns the sampler specialized for a Resource and Scope
+// when it implements SamplerOptimizer, otherwise the sampler itself.
+func Optimize(sampler ComposableSampler, res *resource.Resource, scope instrumentation.Scope) ComposableSampler {
+	if opt, ok := sampler.(SamplerOptimizer); ok {
+		return opt.Optimize(res, scope)
+	}
+	return sampler
+}
+
+// PredicateOptimizer is an optional interface for predicates that can
+// specialize themselves using the static properties of a Tracer.  A
+// predicate that depends only on these should return TruePredicate()
+// or FalsePredicate(), so that RuleBased can fold its rules.
+type PredicateOptimizer interface {
+	// Optimize returns a predicate equivalent to this one for
+	// spans having the given Resource and Scope.
+	Optimize(*resource.Resource, instrumentation.Scope) Predicate
+}
+
+var _ PredicateOptimizer = Predicate{}
+
+// Optimize implements PredicateOptimizer.  Predicates that depend only
+// on the Resource and Scope become constants.
+func (p Predicate) Optimize(res *resource.Resource, scope instrumentation.Scope) Predicate {
+	if p.optimize == nil {
+		return p
+	}
+	return p.optimize(res, scope)
+}
+
+// WithOptimizer returns a copy of the predicate that is optimized by
+// opt, for use with custom predicates.
+func (p Predicate) WithOptimizer(opt PredicateOptimizer) Predicate {
+	p.optimize = opt.Optimize
+	return p
+}
+
+var _ SamplerOptimizer = ruleBased{}
+
+// Optimize implements SamplerOptimizer.  Rules with constant-false
+// predicates are removed.  Unless all matching rules are combined, a
+// rule with a constant-true predicate is the last one that can match,
+// so the rules following it are removed.
+func (rb ruleBased) Optimize(res *resource.Resource, scope instrumentation.Scope) ComposableSampler {
+	opt := ruleBased{
+		combine: rb.combine,
+	}
+	for _, rule := range rb.rules {
+		pred := rule.Predicate.Optimize(res, scope)
+		value, constant := pred.isConstant()
+		if constant && !value {
+			continue
+		}
+		opt.rules = append(opt.rules, ruleAndPredicate{
+			Predicate:         pred,
+			ComposableSampler: Optimize(rule.ComposableSampler, res, scope),
+			priority:          rule.priority,
+			stats:             rule.stats,
+		})
+		if constant && !rb.combine {
+			break
+		}
+	}
+	return opt
+}
+
+var _ SamplerOptimizer = &annotatingSampler{}
+
+// Optimize implements SamplerOptimizer.
+func (as annotatingSampler) Optimize(res *resource.Resource, scope instrumentation.Scope) ComposableSampler {
+	as.sampler = Optimize(as.sampler, res, scope)
+	return &as
+}
+
+var _ SamplerOptimizer = &errorHintBiased{}
+
+// Optimize implements SamplerOptimizer.
+func (eh *errorHintBiased) Optimize(res *resource.Resource, scope instrumentation.Scope) ComposableSampler {
+	opt := *eh
+	opt.base = Optimize(eh.base, res, scope)
+	return &opt
+}
@@ -0,0 +1,72 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// hasSchemaURL is a custom PredicateOptimizer.
+type hasSchemaURL struct{}
+
+func (hasSchemaURL) Optimize(_ *resource.Resource, scope instrumentation.Scope) Predicate {
+	if scope.SchemaURL != "" {
+		return TruePredicate()
+	}
+	return FalsePredicate()
+}
+
+func TestRuleBasedOptimize(t *testing.T) {
+	staging := ResourceAttributePredicate(attribute.String("deployment.environment", "staging"))
+	noisy := ScopePredicate(instrumentation.Scope{Name: "noisy"})
+	schema := NewPredicate(nil, "schema?").WithOptimizer(hasSchemaURL{})
+
+	options := []RuleBasedOption{
+		WithRule(staging, ComposableAlwaysSample()),
+		WithRule(noisy, ComposableNeverSample()),
+		WithRule(schema, TraceIDRatioBased(0.5)),
+		WithRule(SpanNamePredicate("/checkout"), TraceIDRatioBased(0.25)),
+		WithDefaultRule(TraceIDRatioBased(0.1)),
+	}
+	sampler := RuleBased(options...)
+	combined := RuleBased(append(options, WithCombineMatching())...)
+
+	staged := resource.NewSchemaless(attribute.String("deployment.environment", "staging"))
+	prod := resource.NewSchemaless(attribute.String("deployment.environment", "production"))
+
+	for _, test := range []struct {
+		res      *resource.Resource
+		scope    instrumentation.Scope
+		first    string
+		combined string
+	}{
+		{
+			staged, instrumentation.Scope{Name: "noisy"},
+			"RuleBased{rule(Resource[deployment.environment]==staging)=AlwaysOn}",
+			"RuleBasedAll{rule(Resource[deployment.environment]==staging)=AlwaysOn,rule(Scope{Name==noisy})=AlwaysOff,rule(Span.Name==/checkout)=TraceIDRatioBased{0.25},rule(true)=TraceIDRatioBased{0.1}}",
+		},
+		{
+			prod, instrumentation.Scope{Name: "noisy"},
+			"RuleBased{rule(Scope{Name==noisy})=AlwaysOff}",
+			"RuleBasedAll{rule(Scope{Name==noisy})=AlwaysOff,rule(Span.Name==/checkout)=TraceIDRatioBased{0.25},rule(true)=TraceIDRatioBased{0.1}}",
+		},
+		{
+			prod, instrumentation.Scope{Name: "quiet", SchemaURL: "https://opentelemetry.io/schemas/1.26.0"},
+			"RuleBased{rule(true)=TraceIDRatioBased{0.5}}",
+			"RuleBasedAll{rule(true)=TraceIDRatioBased{0.5},rule(Span.Name==/checkout)=TraceIDRatioBased{0.25},rule(true)=TraceIDRatioBased{0.1}}",
+		},
+		{
+			prod, instrumentation.Scope{Name: "quiet"},
+			"RuleBased{rule(Span.Name==/checkout)=TraceIDRatioBased{0.25},rule(true)=TraceIDRatioBased{0.1}}",
+			"RuleBasedAll{rule(Span.Name==/checkout)=TraceIDRatioBased{0.25},rule(true)=TraceIDRatioBased{0.1}}",
+		},
+	} {
+		require.Equal(t, test.first, Optimize(sampler, test.res, test.scope).Description())
+		require.Equal(t, test.combined, Optimize(combined, test.res, test.scope).Description())
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ParentSpan returns the parent span when it is local and recorded by
+// the SDK, so that samplers can read its attributes, name, and other
+// recorded state.  Remote parents, parents that are not recording,
+// and parents of other implementations are not available.  The span
+// may still be recording, so its attributes are those set before the
+// child starts.
+func (p ComposableSamplingParameters) ParentSpan() (sdktrace.ReadOnlySpan, bool) {
+	psc := p.ParentSpanContext
+	if p.ParentContext == nil || !psc.IsValid() || psc.IsRemote() {
+		return nil, false
+	}
+	span, ok := trace.SpanFromContext(p.ParentContext).(sdktrace.ReadOnlySpan)
+	if !ok {
+		return nil, false
+	}
+	sc := span.SpanContext()
+	if sc.TraceID() != psc.TraceID() || sc.SpanID() != psc.SpanID() {
+		return nil, false
+	}
+	return span, true
+}
+
+// parentAttribute returns an attribute of the local parent span.
+func parentAttribute(params ComposableSamplingParameters, key attribute.Key) (attribute.Value, bool) {
+	span, ok := params.ParentSpan()
+	if !ok {
+		return attribute.Value{}, false
+	}
+	return findAttribute(span.Attributes(), key)
+}
+
+// HasParentAttributePredicate matches spans whose local parent span,
+// see ComposableSamplingParameters.ParentSpan, has an attribute with
+// the given key.
+func HasParentAttributePredicate(key attribute.Key) Predicate {
+	return NewPredicate(func(params ComposableSamplingParameters) bool {
+		_, ok := parentAttribute(params, key)
+		return ok
+	}, fmt.Sprintf("Parent.Attributes[%s]?", key)).withSpec("HasParentAttributePredicate", map[string]any{"key": key})
+}
+
+// ParentAttributeEqualsPredicate matches spans whose local parent span,
+// see ComposableSamplingParameters.ParentSpan, has an attribute equal
+// to the given key and value, for example to apply a tenant's policy
+// to the internal spans of its requests.
+func ParentAttributeEqualsPredicate(kv attribute.KeyValue) Predicate {
+	return NewPredicate(func(params ComposableSamplingParameters) bool {
+		value, ok := parentAttribute(params, kv.Key)
+		return ok && value == kv.Value
+	}, fmt.Sprintf("Parent.Attributes[%s]==%s", kv.Key, kv.Value.Emit())).withSpec("ParentAttributeEqualsPredicate", keyValueArgs(kv))
+}
+
+// InheritParentAttributes is a sampler that adds the attributes of the
+// local parent span with the given keys, see
+// ComposableSamplingParameters.ParentSpan, to the spans that another
+// sampler samples, such as a tenant attribute set by the span of an
+// incoming request.  Attributes that the span starts with are not
+// replaced.
+func InheritParentAttributes(sampler ComposableSampler, keys ...attribute.Key) ComposableSampler {
+	return &inheritParentAttributes{
+		sampler: sampler,
+		keys:    keys,
+	}
+}
+
+type inheritParentAttributes struct {
+	sampler ComposableSampler
+	keys    []attribute.Key
+}
+
+var _ ComposableSampler = &inheritParentAttributes{}
+var _ SamplerOptimizer = &inheritParentAttributes{}
+var _ CostStatsProvider = &inheritParentAttributes{}
+
+// GetSamplingIntent implements ComposableSampler.
+func (ip *inheritParentAttributes) GetSamplingIntent(params ComposableSamplingParameters) SamplingIntent {
+	intent := ip.sampler.GetSamplingIntent(params)
+	span, ok := params.ParentSpan()
+	if !ok {
+		return intent
+	}
+	intent.Attributes = CombineAttributes(intent.Attributes, func() []attribute.KeyValue {
+		parent := span.Attributes()
+		var attrs []attribute.KeyValue
+		for _, key := range ip.keys {
+			if _, has := findAttribute(params.Attributes, key); has {
+				continue
+			}
+			if value, has := findAttribute(parent, key); has {
+				attrs = append(attrs, attribute.KeyValue{Key: key, Value: value})
+			}
+		}
+		return attrs
+	})
+	return intent
+}
+
+// Description implements ComposableSampler.
+func (ip *inheritParentAttributes) Description() string {
+	keys := make([]string, len(ip.keys))
+	for i, key := range ip.keys {
+		keys[i] = string(key)
+	}
+	return fmt.Sprintf("InheritParentAttributes(%s, %s)", ip.sampler.Description(), strings.Join(keys, ","))
+}
+
+// CostStats implements CostStatsProvider, returning the statistics of
+// the wrapped sampler, if any.
+func (ip *inheritParentAttributes) CostStats() []CostStats {
+	return costStatsOf(ip.sampler)
+}
+
+// Optimize implements SamplerOptimizer.
+func (ip *inheritParentAttributes) Optimize(res *resource.Resource, scope instrumentation.Scope) ComposableSampler {
+	return InheritParentAttributes(Optimize(ip.sampler, res, scope), ip.keys...)
+}
@@ -0,0 +1,85 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestParentAttributes(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tenant := attribute.String("tenant", "a")
+	cs := InheritParentAttributes(RuleBased(
+		WithRule(IsRootPredicate(), ComposableAlwaysSample()),
+		WithRule(ParentAttributeEqualsPredicate(tenant), ComposableAlwaysSample()),
+		WithDefaultRule(ComposableNeverSample()),
+	), "tenant")
+	require.Equal(t, "InheritParentAttributes(RuleBased{rule(root?)=AlwaysOn,"+
+		"rule(Parent.Attributes[tenant]==a)=AlwaysOn,rule(true)=AlwaysOff}, tenant)", cs.Description())
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(NewSDKSampler(cs)),
+		sdktrace.WithSpanProcessor(recorder),
+	)
+	tracer := tp.Tracer("test")
+
+	// Children of the tenant's spans are sampled and inherit the
+	// attribute, unless they start with it.
+	ctx, root := tracer.Start(context.Background(), "a", trace.WithAttributes(tenant))
+	_, child := tracer.Start(ctx, "child")
+	require.True(t, child.SpanContext().IsSampled())
+	child.End()
+	_, other := tracer.Start(ctx, "other", trace.WithAttributes(attribute.String("tenant", "b")))
+	require.True(t, other.SpanContext().IsSampled())
+	other.End()
+	root.End()
+
+	// Children of other tenants' spans are not.
+	ctx, root = tracer.Start(context.Background(), "b", trace.WithAttributes(attribute.String("tenant", "b")))
+	_, child = tracer.Start(ctx, "child")
+	require.False(t, child.SpanContext().IsSampled())
+	child.End()
+	root.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 4)
+	require.Equal(t, []attribute.KeyValue{tenant}, spans[0].Attributes())
+	require.Equal(t, []attribute.KeyValue{attribute.String("tenant", "b")}, spans[1].Attributes())
+
+	// Remote parents are not available.
+	remote := trace.ContextWithRemoteSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+	}))
+	params := ComposableSamplingParameters{
+		SamplingParameters: SamplingParameters{ParentContext: remote},
+		ParentSpanContext:  trace.SpanContextFromContext(remote),
+	}
+	_, ok := params.ParentSpan()
+	require.False(t, ok)
+
+	// Nor are spans of other contexts.
+	ctx, root = tracer.Start(context.Background(), "a", trace.WithAttributes(tenant))
+	defer root.End()
+	params = ComposableSamplingParameters{
+		SamplingParameters: SamplingParameters{ParentContext: ctx},
+		ParentSpanContext:  trace.SpanContextFromContext(ctx),
+	}
+	span, ok := params.ParentSpan()
+	require.True(t, ok)
+	require.Equal(t, "a", span.Name())
+	params.ParentSpanContext = trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1},
+		SpanID:  trace.SpanID{1},
+	})
+	_, ok = params.ParentSpan()
+	require.False(t, ok)
+}
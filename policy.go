@@ -0,0 +1,157 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// Policy is a builder for RuleBased samplers.  Unlike the variadic
+// RuleBasedOptions, a Policy checks its rules when it is built and
+// reports every problem it finds:
+//
+//	s, err := NewPolicy().
+//		Rule(SpanNamePredicate("/health"), ComposableNeverSample()).
+//		Default(TraceIDRatioBased(0.1)).
+//		Build()
+//
+// Methods of a Policy modify and return it, so that calls may be
+// chained.  Each call to Build returns a new sampler, which is not
+// affected by later changes to the Policy.
+type Policy struct {
+	rules      []policyRule
+	defRule    ComposableSampler
+	hasDefault int
+	combine    bool
+	optimize   bool
+	resource   *resource.Resource
+	scope      instrumentation.Scope
+}
+
+// policyRule is a rule and the order in which it was added, for
+// reporting errors after the rules are sorted by priority.
+type policyRule struct {
+	ruleAndPredicate
+	index int
+}
+
+// NewPolicy returns an empty Policy.
+func NewPolicy() *Policy {
+	return &Policy{}
+}
+
+// Rule adds a rule with priority zero, as WithRule does.
+func (p *Policy) Rule(predicate Predicate, sampler ComposableSampler) *Policy {
+	return p.PriorityRule(0, predicate, sampler)
+}
+
+// PriorityRule adds a rule with an explicit priority, as
+// WithPriorityRule does.
+func (p *Policy) PriorityRule(priority int, predicate Predicate, sampler ComposableSampler) *Policy {
+	p.rules = append(p.rules, policyRule{
+		ruleAndPredicate: ruleAndPredicate{
+			Predicate:         predicate,
+			ComposableSampler: sampler,
+			priority:          priority,
+		},
+		index: len(p.rules),
+	})
+	return p
+}
+
+// Default sets the sampler used when no rule matches, as
+// WithDefaultRule does.  It is an error to set the default twice.
+func (p *Policy) Default(sampler ComposableSampler) *Policy {
+	p.defRule = sampler
+	p.hasDefault++
+	return p
+}
+
+// CombineMatching combines the intents of all matching rules, as
+// WithCombineMatching does.
+func (p *Policy) CombineMatching() *Policy {
+	p.combine = true
+	return p
+}
+
+// OptimizeFor specializes the built sampler for a Resource and Scope,
+// as Optimize does.
+func (p *Policy) OptimizeFor(res *resource.Resource, scope instrumentation.Scope) *Policy {
+	p.optimize = true
+	p.resource = res
+	p.scope = scope
+	return p
+}
+
+// Build checks the policy and returns its sampler.  The errors
+// returned, joined by errors.Join, are: rules or a default without a
+// sampler, rules without a predicate, rules that never match, rules
+// and defaults that are unreachable because an earlier rule always
+// matches, a default set more than once, and a policy without any
+// rules or default.
+func (p *Policy) Build() (ComposableSampler, error) {
+	rules := append([]policyRule{}, p.rules...)
+	// Higher priorities first, as RuleBased orders them.
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].priority > rules[j].priority
+	})
+
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("policy: "+format, args...))
+	}
+	always := -1
+	for _, rule := range rules {
+		if rule.ComposableSampler == nil {
+			fail("rules[%d]: missing sampler", rule.index)
+		}
+		if rule.function == nil {
+			fail("rules[%d]: missing predicate", rule.index)
+			continue
+		}
+		value, constant := rule.isConstant()
+		switch {
+		case always >= 0:
+			fail("rules[%d]: rule is unreachable, rules[%d] always matches", rule.index, always)
+		case constant && !value:
+			fail("rules[%d]: rule never matches", rule.index)
+		case constant && !p.combine:
+			always = rule.index
+		}
+	}
+	switch {
+	case p.hasDefault > 1:
+		fail("default is set %d times", p.hasDefault)
+	case p.hasDefault == 1 && p.defRule == nil:
+		fail("default: missing sampler")
+	case p.hasDefault == 1 && always >= 0:
+		fail("default is unreachable, rules[%d] always matches", always)
+	case p.hasDefault == 0 && len(rules) == 0:
+		fail("no rules or default")
+	}
+	if len(errs) != 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	options := make([]RuleBasedOption, 0, len(rules)+2)
+	for _, rule := range rules {
+		options = append(options, WithPriorityRule(rule.priority, rule.Predicate, rule.ComposableSampler))
+	}
+	if p.defRule != nil {
+		options = append(options, WithDefaultRule(p.defRule))
+	}
+	if p.combine {
+		options = append(options, WithCombineMatching())
+	}
+	sampler := RuleBased(options...)
+	if p.optimize {
+		sampler = Optimize(sampler, p.resource, p.scope)
+	}
+	return sampler, nil
+}
@@ -0,0 +1,69 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+func TestPolicy(t *testing.T) {
+	health := SpanNamePredicate("/health")
+	policy := NewPolicy().
+		Rule(health, ComposableNeverSample()).
+		PriorityRule(1, IsRootPredicate(), TraceIDRatioBased(0.5)).
+		Default(ParentThreshold())
+	s, err := policy.Build()
+	require.NoError(t, err)
+	require.Equal(t, RuleBased(
+		WithPriorityRule(1, IsRootPredicate(), TraceIDRatioBased(0.5)),
+		WithRule(health, ComposableNeverSample()),
+		WithDefaultRule(ParentThreshold()),
+	).Description(), s.Description())
+
+	// Later changes do not modify the built sampler.
+	policy.Rule(IsRemoteParentPredicate(), ComposableAlwaysSample())
+	require.Equal(t, "RuleBased{rule(root?)=TraceIDRatioBased{0.5},rule(Span.Name==/health)=AlwaysOff,rule(true)=ParentThreshold}", s.Description())
+
+	// Optimizing removes rules that cannot match.
+	s, err = NewPolicy().
+		Rule(ResourceAttributePredicate(attribute.String("service.name", "other")), ComposableNeverSample()).
+		Default(ComposableAlwaysSample()).
+		OptimizeFor(resource.NewSchemaless(attribute.String("service.name", "mine")), instrumentation.Scope{}).
+		Build()
+	require.NoError(t, err)
+	require.Equal(t, "RuleBased{rule(true)=AlwaysOn}", s.Description())
+}
+
+func TestPolicyErrors(t *testing.T) {
+	_, err := NewPolicy().
+		Rule(FalsePredicate(), ComposableAlwaysSample()).
+		Rule(Predicate{}, ComposableAlwaysSample()).
+		Rule(TruePredicate(), nil).
+		Rule(IsRootPredicate(), ComposableAlwaysSample()).
+		Default(ComposableNeverSample()).
+		Build()
+	require.EqualError(t, err, "policy: rules[0]: rule never matches\n"+
+		"policy: rules[1]: missing predicate\n"+
+		"policy: rules[2]: missing sampler\n"+
+		"policy: rules[3]: rule is unreachable, rules[2] always matches\n"+
+		"policy: default is unreachable, rules[2] always matches")
+
+	_, err = NewPolicy().
+		CombineMatching().
+		Rule(TruePredicate(), ComposableAlwaysSample()).
+		Rule(IsRootPredicate(), ComposableAlwaysSample()).
+		Build()
+	require.NoError(t, err)
+
+	_, err = NewPolicy().Default(ComposableAlwaysSample()).Default(nil).Build()
+	require.EqualError(t, err, "policy: default is set 2 times")
+	_, err = NewPolicy().Default(nil).Build()
+	require.EqualError(t, err, "policy: default: missing sampler")
+	_, err = NewPolicy().Build()
+	require.EqualError(t, err, "policy: no rules or default")
+}
@@ -5,7 +5,14 @@ package sampler
 
 import (
 	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"unicode/utf8"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -20,18 +27,76 @@ func WithRule(predicate Predicate, sampler ComposableSampler) RuleBasedOption {
 	}
 }
 
+// WithPriorityRule adds a rule with an explicit priority.  Rules are
+// evaluated from highest to lowest priority, and rules with equal
+// priority are evaluated in the order they were added.  Rules added
+// by WithRule have priority zero, and the default rule is always last.
+func WithPriorityRule(priority int, predicate Predicate, sampler ComposableSampler) RuleBasedOption {
+	return func(rb *ruleBasedConfig) {
+		rb.rules = append(rb.rules, ruleAndPredicate{
+			Predicate:         predicate,
+			ComposableSampler: sampler,
+			priority:          priority,
+		})
+	}
+}
+
 func WithDefaultRule(sampler ComposableSampler) RuleBasedOption {
 	return func(rb *ruleBasedConfig) {
 		rb.defRule = sampler
 	}
 }
 
+// WithCombineMatching evaluates every rule and combines the intents
+// of those that match, instead of using the first matching rule.  The
+// combined intent has the least threshold among matching rules and
+// the attributes of all of them.
+func WithCombineMatching() RuleBasedOption {
+	return func(rb *ruleBasedConfig) {
+		rb.combine = true
+	}
+}
+
 type Predicate struct {
-	function    func(ComposableSamplingParameters) bool
+	function    PredicateFunc
 	description string
+
+	// constant is set for predicates known not to depend on the
+	// span, for example after optimization.
+	constant predicateConstant
+
+	// optimize, when set, specializes the predicate for a Resource
+	// and Scope.  See Optimize.
+	optimize func(*resource.Resource, instrumentation.Scope) Predicate
+
+	// spec, when set, describes how the predicate was constructed.
+	// See Spec.
+	spec Spec
+}
+
+type predicateConstant int8
+
+const (
+	notConstant predicateConstant = iota
+	constantTrue
+	constantFalse
+)
+
+// PredicateFunc is an adapter allowing ordinary functions to be used
+// as predicates, in the manner of http.HandlerFunc.
+type PredicateFunc func(ComposableSamplingParameters) bool
+
+// Decide calls f(params).
+func (f PredicateFunc) Decide(params ComposableSamplingParameters) bool {
+	return f(params)
+}
+
+// Describe returns a Predicate that calls f, with a description.
+func (f PredicateFunc) Describe(description string) Predicate {
+	return NewPredicate(f, description)
 }
 
-func NewPredicate(function func(ComposableSamplingParameters) bool, description string) Predicate {
+func NewPredicate(function PredicateFunc, description string) Predicate {
 	return Predicate{
 		function:    function,
 		description: description,
@@ -49,44 +114,728 @@ func (p Predicate) Description() string {
 	return p.description
 }
 
+// isConstant returns the value of a constant predicate.
+func (p Predicate) isConstant() (value, ok bool) {
+	return p.constant == constantTrue, p.constant != notConstant
+}
+
+// constantPredicate returns a predicate that always has one value.
+func constantPredicate(value bool, description string) Predicate {
+	pred := NewPredicate(func(ComposableSamplingParameters) bool {
+		return value
+	}, description)
+	pred.constant = constantFalse
+	if value {
+		pred.constant = constantTrue
+	}
+	return pred
+}
+
 func TruePredicate() Predicate {
-	return NewPredicate(func(params ComposableSamplingParameters) bool {
-		return true
-	}, "true")
+	return constantPredicate(true, "true")
+}
+
+// FalsePredicate never matches.
+func FalsePredicate() Predicate {
+	return constantPredicate(false, "false")
 }
 
 func NegatePredicate(original Predicate) Predicate {
-	return NewPredicate(func(params ComposableSamplingParameters) bool {
-		return !original.function(params)
-	}, fmt.Sprintf("not(%s)", original.description))
+	if value, ok := original.isConstant(); ok {
+		return constantPredicate(!value, fmt.Sprintf("not(%s)", original.description))
+	}
+	pred := NewPredicate(func(params ComposableSamplingParameters) bool {
+		return !original.Decide(params)
+	}, fmt.Sprintf("not(%s)", original.description)).withSpec("NotPredicate", map[string]any{"original": original})
+	if original.optimize != nil {
+		pred.optimize = func(res *resource.Resource, scope instrumentation.Scope) Predicate {
+			return NegatePredicate(original.Optimize(res, scope))
+		}
+	}
+	return pred
+}
+
+// NotPredicate matches when the original predicate does not.  This is
+// the same as NegatePredicate, named for use with AndPredicate and
+// OrPredicate.
+func NotPredicate(original Predicate) Predicate {
+	return NegatePredicate(original)
+}
+
+// describePredicates joins the descriptions of several predicates.
+func describePredicates(name string, preds []Predicate) string {
+	desc := make([]string, len(preds))
+	for i, pred := range preds {
+		desc[i] = pred.Description()
+	}
+	return fmt.Sprintf("%s(%s)", name, strings.Join(desc, ","))
+}
+
+// AndPredicate matches when every predicate matches, evaluating them
+// in order until one does not.  With no predicates, it matches.
+func AndPredicate(preds ...Predicate) Predicate {
+	return combinePredicates("and", false, preds)
+}
+
+// OrPredicate matches when any predicate matches, evaluating them in
+// order until one does.  With no predicates, it does not match.
+func OrPredicate(preds ...Predicate) Predicate {
+	return combinePredicates("or", true, preds)
+}
+
+// combinePredicates implements AndPredicate (shortCircuit is false)
+// and OrPredicate (shortCircuit is true).  Constant predicates are
+// folded: a predicate equal to shortCircuit determines the result,
+// and the others are removed.
+func combinePredicates(name string, shortCircuit bool, preds []Predicate) Predicate {
+	desc := describePredicates(name, preds)
+
+	var remain []Predicate
+	optimizable := false
+	for _, pred := range preds {
+		if value, ok := pred.isConstant(); ok {
+			if value == shortCircuit {
+				return constantPredicate(shortCircuit, desc)
+			}
+			continue
+		}
+		remain = append(remain, pred)
+		optimizable = optimizable || pred.optimize != nil
+	}
+	if len(remain) == 0 {
+		return constantPredicate(!shortCircuit, desc)
+	}
+
+	specName := "AndPredicate"
+	if shortCircuit {
+		specName = "OrPredicate"
+	}
+	pred := NewPredicate(func(params ComposableSamplingParameters) bool {
+		for _, pred := range remain {
+			if pred.Decide(params) == shortCircuit {
+				return shortCircuit
+			}
+		}
+		return !shortCircuit
+	}, desc).withSpec(specName, map[string]any{"preds": remain})
+	if optimizable {
+		pred.optimize = func(res *resource.Resource, scope instrumentation.Scope) Predicate {
+			opt := make([]Predicate, len(remain))
+			for i, pred := range remain {
+				opt[i] = pred.Optimize(res, scope)
+			}
+			return combinePredicates(name, shortCircuit, opt)
+		}
+	}
+	return pred
 }
 
 func SpanNamePredicate(name string) Predicate {
 	return NewPredicate(func(params ComposableSamplingParameters) bool {
 		return name == params.Name
-	}, fmt.Sprintf("Span.Name==%s", name))
+	}, fmt.Sprintf("Span.Name==%s", name)).withSpec("SpanNamePredicate", map[string]any{"name": name})
+}
+
+// SpanNameInSetPredicate matches spans with any of the given names,
+// using a hash set so that large sets of names match in constant time.
+func SpanNameInSetPredicate(names ...string) Predicate {
+	set := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		set[name] = struct{}{}
+	}
+	return NewPredicate(func(params ComposableSamplingParameters) bool {
+		_, ok := set[params.Name]
+		return ok
+	}, fmt.Sprintf("Span.Name in {%s}", strings.Join(names, ","))).withSpec("SpanNameInSetPredicate", map[string]any{"names": append([]string(nil), names...)})
+}
+
+// findAttribute returns the value of the first attribute with key.
+func findAttribute(attrs []attribute.KeyValue, key attribute.Key) (attribute.Value, bool) {
+	for _, attr := range attrs {
+		if attr.Key == key {
+			return attr.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+// AttributeEqualsPredicate matches spans that start with an attribute
+// equal to the given key and value.
+func AttributeEqualsPredicate(kv attribute.KeyValue) Predicate {
+	return NewPredicate(func(params ComposableSamplingParameters) bool {
+		value, ok := findAttribute(params.Attributes, kv.Key)
+		return ok && value == kv.Value
+	}, fmt.Sprintf("Span.Attributes[%s]==%s", kv.Key, kv.Value.Emit())).withSpec("AttributeEqualsPredicate", keyValueArgs(kv))
+}
+
+// AttributeValueInSetPredicate matches spans that start with an
+// attribute having any of the given values, compared with the value's
+// string form, as written by attribute.Value.Emit, so that "500"
+// matches both the string "500" and the integer 500.
+func AttributeValueInSetPredicate(key attribute.Key, values ...string) Predicate {
+	set := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		set[value] = struct{}{}
+	}
+	return NewPredicate(func(params ComposableSamplingParameters) bool {
+		value, ok := findAttribute(params.Attributes, key)
+		if !ok {
+			return false
+		}
+		_, ok = set[value.Emit()]
+		return ok
+	}, fmt.Sprintf("Span.Attributes[%s] in {%s}", key, strings.Join(values, ","))).withSpec("AttributeValueInSetPredicate", map[string]any{
+		"key":    key,
+		"values": append([]string(nil), values...),
+	})
+}
+
+// AttributeRegexPredicate matches spans that start with a string
+// attribute matching a regular expression.  The expression is
+// compiled here, and an invalid expression returns an error.
+func AttributeRegexPredicate(key attribute.Key, expr string) (Predicate, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return Predicate{}, fmt.Errorf("attribute %s: %w", key, err)
+	}
+	return NewPredicate(func(params ComposableSamplingParameters) bool {
+		value, ok := findAttribute(params.Attributes, key)
+		return ok && value.Type() == attribute.STRING && re.MatchString(value.AsString())
+	}, fmt.Sprintf("Span.Attributes[%s]=~%s", key, expr)).withSpec("AttributeRegexPredicate", map[string]any{
+		"key":  key,
+		"expr": expr,
+	}), nil
+}
+
+// AttributeGlobPredicate matches spans that start with a string
+// attribute matching a pattern, as in SpanNameGlobPredicate.
+func AttributeGlobPredicate(key attribute.Key, pattern string) Predicate {
+	match := globMatcher(pattern)
+	return NewPredicate(func(params ComposableSamplingParameters) bool {
+		value, ok := findAttribute(params.Attributes, key)
+		return ok && value.Type() == attribute.STRING && match(value.AsString())
+	}, fmt.Sprintf("Span.Attributes[%s] glob %s", key, pattern)).withSpec("AttributeGlobPredicate", map[string]any{
+		"key":     key,
+		"pattern": pattern,
+	})
+}
+
+// numericAttribute returns the value of the first attribute with key,
+// when it is an int64 or float64 attribute.
+func numericAttribute(attrs []attribute.KeyValue, key attribute.Key) (float64, bool) {
+	value, ok := findAttribute(attrs, key)
+	if !ok {
+		return 0, false
+	}
+	switch value.Type() {
+	case attribute.INT64:
+		return float64(value.AsInt64()), true
+	case attribute.FLOAT64:
+		return value.AsFloat64(), true
+	}
+	return 0, false
+}
+
+// AttributeGreaterPredicate matches spans that start with a numeric
+// attribute greater than the limit.  Integer attributes are compared
+// as float64 values.
+func AttributeGreaterPredicate(key attribute.Key, limit float64) Predicate {
+	return NewPredicate(func(params ComposableSamplingParameters) bool {
+		value, ok := numericAttribute(params.Attributes, key)
+		return ok && value > limit
+	}, fmt.Sprintf("Span.Attributes[%s]>%g", key, limit)).withSpec("AttributeGreaterPredicate", map[string]any{
+		"key":   key,
+		"limit": limit,
+	})
+}
+
+// AttributeLessPredicate matches spans that start with a numeric
+// attribute less than the limit.  Integer attributes are compared as
+// float64 values.
+func AttributeLessPredicate(key attribute.Key, limit float64) Predicate {
+	return NewPredicate(func(params ComposableSamplingParameters) bool {
+		value, ok := numericAttribute(params.Attributes, key)
+		return ok && value < limit
+	}, fmt.Sprintf("Span.Attributes[%s]<%g", key, limit)).withSpec("AttributeLessPredicate", map[string]any{
+		"key":   key,
+		"limit": limit,
+	})
+}
+
+// AttributeRangePredicate matches spans that start with a numeric
+// attribute in the inclusive range [low, high].  Integer attributes
+// are compared as float64 values.
+func AttributeRangePredicate(key attribute.Key, low, high float64) Predicate {
+	return NewPredicate(func(params ComposableSamplingParameters) bool {
+		value, ok := numericAttribute(params.Attributes, key)
+		return ok && low <= value && value <= high
+	}, fmt.Sprintf("Span.Attributes[%s] in [%g,%g]", key, low, high)).withSpec("AttributeRangePredicate", map[string]any{
+		"key":  key,
+		"low":  low,
+		"high": high,
+	})
+}
+
+// SpanNameRegexPredicate matches span names against a regular
+// expression.  The expression is compiled here, and an invalid
+// expression returns an error.  Anchored literal expressions such as
+// "^/health$" are matched by string comparison.
+func SpanNameRegexPredicate(expr string) (Predicate, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return Predicate{}, fmt.Errorf("span name: %w", err)
+	}
+	desc := fmt.Sprintf("Span.Name=~%s", expr)
+	match := re.MatchString
+	if literal, prefix, ok := anchoredLiteral(expr); ok {
+		if prefix {
+			match = func(name string) bool {
+				return strings.HasPrefix(name, literal)
+			}
+		} else {
+			match = func(name string) bool {
+				return literal == name
+			}
+		}
+	}
+	return NewPredicate(func(params ComposableSamplingParameters) bool {
+		return match(params.Name)
+	}, desc).withSpec("SpanNameRegexPredicate", map[string]any{"expr": expr}), nil
+}
+
+// anchoredLiteral recognizes expressions that are a literal string
+// anchored at the start of text and, unless prefix is true, at the
+// end of text.
+func anchoredLiteral(expr string) (literal string, prefix bool, ok bool) {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil || re.Op != syntax.OpConcat {
+		return "", false, false
+	}
+	subs := re.Sub
+	if len(subs) < 2 || subs[0].Op != syntax.OpBeginText {
+		return "", false, false
+	}
+	subs = subs[1:]
+	if last := subs[len(subs)-1]; last.Op == syntax.OpEndText {
+		subs = subs[:len(subs)-1]
+	} else {
+		prefix = true
+	}
+	if len(subs) != 1 || subs[0].Op != syntax.OpLiteral || subs[0].Flags&syntax.FoldCase != 0 {
+		return "", false, false
+	}
+	return string(subs[0].Rune), prefix, true
 }
 
-func SpanKindPredicate(kind trace.SpanKind) Predicate {
+// SpanNameGlobPredicate matches span names against a pattern where
+// '*' matches any sequence of characters, including '/', and '?'
+// matches any single character.  Patterns without wildcards, and
+// patterns whose only wildcard is a trailing '*', are matched by
+// string comparison.
+func SpanNameGlobPredicate(pattern string) Predicate {
+	match := globMatcher(pattern)
 	return NewPredicate(func(params ComposableSamplingParameters) bool {
-		return kind == params.Kind
-	}, fmt.Sprintf("Span.Kind==%s", kind))
+		return match(params.Name)
+	}, fmt.Sprintf("Span.Name glob %s", pattern)).withSpec("SpanNameGlobPredicate", map[string]any{"pattern": pattern})
+}
+
+// globMatcher returns a function matching the pattern, using string
+// comparison when there are no wildcards except a trailing '*'.
+func globMatcher(pattern string) func(string) bool {
+	wild := strings.IndexAny(pattern, "*?")
+	switch {
+	case wild < 0:
+		return func(s string) bool {
+			return pattern == s
+		}
+	case wild == len(pattern)-1 && pattern[wild] == '*':
+		prefix := pattern[:wild]
+		return func(s string) bool {
+			return strings.HasPrefix(s, prefix)
+		}
+	}
+	return func(s string) bool {
+		return globMatch(pattern, s)
+	}
+}
+
+// globMatch matches '*' and '?' wildcards without recursion, by
+// backtracking to the most recent '*' on a mismatch.  This runs in
+// linear time for patterns with one '*'.
+func globMatch(pattern, name string) bool {
+	var p, n int
+	star, next := -1, 0
+	for n < len(name) {
+		if p < len(pattern) {
+			switch pattern[p] {
+			case '*':
+				// Match the empty sequence first.
+				star, next = p, n
+				p++
+				continue
+			case '?':
+				_, size := utf8.DecodeRuneInString(name[n:])
+				p++
+				n += size
+				continue
+			default:
+				if pattern[p] == name[n] {
+					p++
+					n++
+					continue
+				}
+			}
+		}
+		if star < 0 {
+			return false
+		}
+		// Extend the last '*' by one character.
+		_, size := utf8.DecodeRuneInString(name[next:])
+		next += size
+		p, n = star+1, next
+	}
+	// Trailing '*' may match the empty sequence.
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// HTTPRoutePredicate matches HTTP spans by path and method.  The
+// pattern is matched as in SpanNameGlobPredicate against the
+// "http.route" attribute, or "url.path" when there is no route.  With
+// methods, the "http.request.method" attribute must equal one of them.
+func HTTPRoutePredicate(pattern string, methods ...string) Predicate {
+	const (
+		routeKey  = attribute.Key("http.route")
+		pathKey   = attribute.Key("url.path")
+		methodKey = attribute.Key("http.request.method")
+	)
+	match := globMatcher(pattern)
+	methods = append([]string(nil), methods...)
+	desc := fmt.Sprintf("HTTP{%s}", pattern)
+	if len(methods) != 0 {
+		desc = fmt.Sprintf("HTTP{%s %s}", strings.Join(methods, ","), pattern)
+	}
+	return NewPredicate(func(params ComposableSamplingParameters) bool {
+		// Scan the attributes once.
+		var route, path, method string
+		var hasRoute, hasPath bool
+		for _, attr := range params.Attributes {
+			switch attr.Key {
+			case routeKey:
+				route, hasRoute = attr.Value.AsString(), true
+			case pathKey:
+				path, hasPath = attr.Value.AsString(), true
+			case methodKey:
+				method = attr.Value.AsString()
+			}
+		}
+		if len(methods) != 0 {
+			found := false
+			for _, m := range methods {
+				if strings.EqualFold(m, method) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		switch {
+		case hasRoute:
+			return match(route)
+		case hasPath:
+			return match(path)
+		}
+		return false
+	}, desc).withSpec("HTTPRoutePredicate", map[string]any{
+		"pattern": pattern,
+		"methods": methods,
+	})
+}
+
+// SpanKindPredicate matches spans having any of the given kinds.
+func SpanKindPredicate(kinds ...trace.SpanKind) Predicate {
+	var mask uint64
+	names := make([]string, len(kinds))
+	for i, kind := range kinds {
+		mask |= 1 << uint(kind)
+		names[i] = kind.String()
+	}
+	desc := fmt.Sprintf("Span.Kind in {%s}", strings.Join(names, ","))
+	if len(kinds) == 1 {
+		desc = fmt.Sprintf("Span.Kind==%s", kinds[0])
+	}
+	return NewPredicate(func(params ComposableSamplingParameters) bool {
+		return params.Kind < 64 && mask&(1<<uint(params.Kind)) != 0
+	}, desc).withSpec("SpanKindPredicate", map[string]any{"kinds": append([]trace.SpanKind(nil), kinds...)})
+}
+
+// ResourceAttributePredicate matches spans of tracers whose Resource
+// has an attribute equal to the given key and value.  When it is
+// optimized for a Resource, it becomes a constant.  Otherwise, it
+// tests the Resource of ComposableSamplingParameters, see
+// WithResource, and does not match when the Resource is unknown.
+func ResourceAttributePredicate(kv attribute.KeyValue) Predicate {
+	desc := fmt.Sprintf("Resource[%s]==%s", kv.Key, kv.Value.Emit())
+	matches := func(res *resource.Resource) bool {
+		value, ok := res.Set().Value(kv.Key)
+		return ok && value == kv.Value
+	}
+	pred := NewPredicate(func(params ComposableSamplingParameters) bool {
+		return matches(params.Resource)
+	}, desc).withSpec("ResourceAttributePredicate", keyValueArgs(kv))
+	pred.optimize = func(res *resource.Resource, _ instrumentation.Scope) Predicate {
+		return constantPredicate(matches(res), desc)
+	}
+	return pred
+}
+
+// ScopePredicate matches spans of tracers whose instrumentation scope
+// has the given name, version, and schema URL, where empty fields
+// match any value.  When it is optimized for a Scope, it becomes a
+// constant.  Otherwise, it tests the Scope of
+// ComposableSamplingParameters, see WithScope.
+func ScopePredicate(match instrumentation.Scope) Predicate {
+	var parts []string
+	if match.Name != "" {
+		parts = append(parts, "Name=="+match.Name)
+	}
+	if match.Version != "" {
+		parts = append(parts, "Version=="+match.Version)
+	}
+	if match.SchemaURL != "" {
+		parts = append(parts, "SchemaURL=="+match.SchemaURL)
+	}
+	desc := fmt.Sprintf("Scope{%s}", strings.Join(parts, ","))
+	matches := func(scope instrumentation.Scope) bool {
+		return (match.Name == "" || match.Name == scope.Name) &&
+			(match.Version == "" || match.Version == scope.Version) &&
+			(match.SchemaURL == "" || match.SchemaURL == scope.SchemaURL)
+	}
+	pred := NewPredicate(func(params ComposableSamplingParameters) bool {
+		return matches(params.Scope)
+	}, desc).withSpec("ScopePredicate", map[string]any{"match": match})
+	pred.optimize = func(_ *resource.Resource, scope instrumentation.Scope) Predicate {
+		return constantPredicate(matches(scope), desc)
+	}
+	return pred
 }
 
 func IsRootPredicate() Predicate {
 	return NewPredicate(func(params ComposableSamplingParameters) bool {
 		return !params.ParentSpanContext.IsValid()
-	}, "root?")
+	}, "root?").withSpec("IsRootPredicate", nil)
 }
 
-func IsRemotePredicate() Predicate {
+// IsRemoteParentPredicate matches spans with a remote parent, such as
+// ingress spans continuing a trace from another process.
+func IsRemoteParentPredicate() Predicate {
 	return NewPredicate(func(params ComposableSamplingParameters) bool {
 		return params.ParentSpanContext.IsValid() && params.ParentSpanContext.IsRemote()
-	}, "remote?")
+	}, "remote?").withSpec("IsRemoteParentPredicate", nil)
 }
 
-func IsLocalPredicate() Predicate {
+// IsLocalParentPredicate matches spans with a local parent, such as
+// internal spans continuing a trace in the same process.
+func IsLocalParentPredicate() Predicate {
 	return NewPredicate(func(params ComposableSamplingParameters) bool {
 		return params.ParentSpanContext.IsValid() && !params.ParentSpanContext.IsRemote()
-	}, "local?")
+	}, "local?").withSpec("IsLocalParentPredicate", nil)
+}
+
+// IsParentSampledPredicate matches spans whose parent has the sampled
+// flag.
+func IsParentSampledPredicate() Predicate {
+	return NewPredicate(func(params ComposableSamplingParameters) bool {
+		return params.ParentSpanContext.IsValid() && params.ParentSpanContext.IsSampled()
+	}, "parent sampled?").withSpec("IsParentSampledPredicate", nil)
+}
+
+// IsParentNotSampledPredicate matches spans whose parent does not have
+// the sampled flag.  Root spans do not match.
+func IsParentNotSampledPredicate() Predicate {
+	return NewPredicate(func(params ComposableSamplingParameters) bool {
+		return params.ParentSpanContext.IsValid() && !params.ParentSpanContext.IsSampled()
+	}, "parent not sampled?").withSpec("IsParentNotSampledPredicate", nil)
+}
+
+// HasParentThresholdPredicate matches spans whose parent context has a
+// reliable sampling threshold, which is what ParentThreshold will use.
+func HasParentThresholdPredicate() Predicate {
+	return NewPredicate(func(params ComposableSamplingParameters) bool {
+		return params.parentThresholdReliable
+	}, "parent threshold?").withSpec("HasParentThresholdPredicate", nil)
+}
+
+// ParentProbabilityAtLeastPredicate matches spans whose parent context
+// has a reliable sampling threshold corresponding to a probability of
+// at least fraction, for example to apply a different policy when the
+// caller sampled at better than 1%.  Negate it to match parents that
+// sampled more aggressively.  Spans without a reliable parent
+// threshold do not match.
+func ParentProbabilityAtLeastPredicate(fraction float64) Predicate {
+	limit := ProbabilityToThreshold(fraction)
+	return NewPredicate(func(params ComposableSamplingParameters) bool {
+		return params.parentThresholdReliable && params.parentThreshold <= limit
+	}, fmt.Sprintf("parent probability>=%g", fraction)).withSpec("ParentProbabilityAtLeastPredicate", map[string]any{"fraction": fraction})
+}
+
+// TraceFractionPredicate matches a consistent fraction of traces,
+// selected using the trace randomness, so that a rule can apply to a
+// slice of traffic, for example to use an experimental sampler for 5%
+// of traces.  Every span of a trace has the same result.
+//
+// The slice is selected using a hash of the randomness, while sampling
+// thresholds compare the randomness itself, so that the slice is
+// independent of the sampling decisions made within it.  Every bit of
+// the randomness contributes to the hash, so the fraction holds when
+// fewer than 56 bits are random (see WithTraceIDRandomnessBits).  The
+// randomness is set by CompositeSampler; parameters constructed
+// otherwise have zero randomness, which every positive fraction
+// matches.
+func TraceFractionPredicate(fraction float64) Predicate {
+	var limit uint64
+	switch {
+	case fraction >= 1:
+		limit = maxAdjustedCount
+	case fraction > 0:
+		limit = uint64(fraction * float64(maxAdjustedCount))
+	}
+	return NewPredicate(func(params ComposableSamplingParameters) bool {
+		return mixRandomness(params.randomness) < limit
+	}, fmt.Sprintf("trace fraction %g", fraction)).withSpec("TraceFractionPredicate", map[string]any{"fraction": fraction})
+}
+
+// mixRandomness returns a 56-bit hash of a randomness value, using
+// the SplitMix64 finalizer.
+func mixRandomness(rnd int64) uint64 {
+	x := uint64(rnd)
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	x ^= x >> 31
+	return x >> 8
+}
+
+// HasLinksPredicate matches spans that start with links.
+func HasLinksPredicate() Predicate {
+	return NewPredicate(func(params ComposableSamplingParameters) bool {
+		return len(params.Links) != 0
+	}, "links?").withSpec("HasLinksPredicate", nil)
+}
+
+// AnyLinkSampledPredicate matches spans that start with at least one
+// link to a sampled span context, such as a messaging consumer
+// linked to a sampled producer.
+func AnyLinkSampledPredicate() Predicate {
+	return NewPredicate(func(params ComposableSamplingParameters) bool {
+		for _, link := range params.Links {
+			if link.SpanContext.IsSampled() {
+				return true
+			}
+		}
+		return false
+	}, "any link sampled?").withSpec("AnyLinkSampledPredicate", nil)
+}
+
+// HasLinkAttributePredicate matches spans that start with at least
+// one link carrying an attribute with the given key, such as
+// "messaging.kafka.partition".
+func HasLinkAttributePredicate(key attribute.Key) Predicate {
+	return NewPredicate(func(params ComposableSamplingParameters) bool {
+		for _, link := range params.Links {
+			if _, ok := findAttribute(link.Attributes, key); ok {
+				return true
+			}
+		}
+		return false
+	}, fmt.Sprintf("Link.Attributes[%s]?", key)).withSpec("HasLinkAttributePredicate", map[string]any{"key": key})
+}
+
+// LinkAttributeEqualsPredicate matches spans that start with at least
+// one link carrying an attribute equal to the given key and value.
+func LinkAttributeEqualsPredicate(kv attribute.KeyValue) Predicate {
+	return NewPredicate(func(params ComposableSamplingParameters) bool {
+		for _, link := range params.Links {
+			if value, ok := findAttribute(link.Attributes, kv.Key); ok && value == kv.Value {
+				return true
+			}
+		}
+		return false
+	}, fmt.Sprintf("Link.Attributes[%s]==%s", kv.Key, kv.Value.Emit())).withSpec("LinkAttributeEqualsPredicate", keyValueArgs(kv))
+}
+
+// HasBaggagePredicate matches spans whose parent context has a W3C
+// Baggage member with the given key.
+func HasBaggagePredicate(key string) Predicate {
+	return NewPredicate(func(params ComposableSamplingParameters) bool {
+		return params.Baggage.Member(key).Key() != ""
+	}, fmt.Sprintf("Baggage[%s]?", key)).withSpec("HasBaggagePredicate", map[string]any{"key": key})
+}
+
+// BaggageEqualsPredicate matches spans whose parent context has a W3C
+// Baggage member with the given key and value.
+func BaggageEqualsPredicate(key, value string) Predicate {
+	return NewPredicate(func(params ComposableSamplingParameters) bool {
+		member := params.Baggage.Member(key)
+		return member.Key() != "" && member.Value() == value
+	}, fmt.Sprintf("Baggage[%s]==%s", key, value)).withSpec("BaggageEqualsPredicate", map[string]any{
+		"key":   key,
+		"value": value,
+	})
+}
+
+// TraceStateMemberPredicate matches spans whose parent tracestate has
+// a member with the given vendor key.
+func TraceStateMemberPredicate(key string) Predicate {
+	return NewPredicate(func(params ComposableSamplingParameters) bool {
+		return params.ParentSpanContext.TraceState().Get(key) != ""
+	}, fmt.Sprintf("TraceState[%s]?", key)).withSpec("TraceStateMemberPredicate", map[string]any{"key": key})
+}
+
+// TraceStateMemberEqualsPredicate matches spans whose parent
+// tracestate has a member with the given vendor key and value.
+func TraceStateMemberEqualsPredicate(key, value string) Predicate {
+	return NewPredicate(func(params ComposableSamplingParameters) bool {
+		return params.ParentSpanContext.TraceState().Get(key) == value
+	}, fmt.Sprintf("TraceState[%s]==%s", key, value)).withSpec("TraceStateMemberEqualsPredicate", map[string]any{
+		"key":   key,
+		"value": value,
+	})
+}
+
+// OTelTraceStateFieldPredicate matches spans whose parent tracestate
+// has an OpenTelemetry "ot" sub-key with the given name, e.g., "rv".
+func OTelTraceStateFieldPredicate(field string) Predicate {
+	search := fieldSearchKey(";" + field + ":")
+	return NewPredicate(func(params ComposableSamplingParameters) bool {
+		_, has := params.parentOTelTraceState().field(search)
+		return has
+	}, fmt.Sprintf("TraceState[ot.%s]?", field)).withSpec("OTelTraceStateFieldPredicate", map[string]any{"field": field})
+}
+
+// OTelTraceStateFieldEqualsPredicate matches spans whose parent
+// tracestate has an OpenTelemetry "ot" sub-key with the given name
+// and value.
+func OTelTraceStateFieldEqualsPredicate(field, value string) Predicate {
+	search := fieldSearchKey(";" + field + ":")
+	return NewPredicate(func(params ComposableSamplingParameters) bool {
+		val, has := params.parentOTelTraceState().field(search)
+		return has && val == value
+	}, fmt.Sprintf("TraceState[ot.%s]==%s", field, value)).withSpec("OTelTraceStateFieldEqualsPredicate", map[string]any{
+		"field": field,
+		"value": value,
+	})
+}
+
+// IsRemotePredicate is equivalent to IsRemoteParentPredicate.
+func IsRemotePredicate() Predicate {
+	return IsRemoteParentPredicate()
+}
+
+// IsLocalPredicate is equivalent to IsLocalParentPredicate.
+func IsLocalPredicate() Predicate {
+	return IsLocalParentPredicate()
 }
@@ -0,0 +1,705 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func testAttributeParams(attrs ...attribute.KeyValue) ComposableSamplingParameters {
+	return ComposableSamplingParameters{
+		SamplingParameters: SamplingParameters{
+			Attributes: attrs,
+		},
+	}
+}
+
+func TestAttributeEqualsPredicate(t *testing.T) {
+	for _, test := range []struct {
+		pred   Predicate
+		desc   string
+		attrs  []attribute.KeyValue
+		decide bool
+	}{
+		{AttributeEqualsPredicate(attribute.String("K", "V")), "Span.Attributes[K]==V", testAttrs, true},
+		{AttributeEqualsPredicate(attribute.String("K", "W")), "Span.Attributes[K]==W", testAttrs, false},
+		{AttributeEqualsPredicate(attribute.String("L", "V")), "Span.Attributes[L]==V", testAttrs, false},
+		{AttributeEqualsPredicate(attribute.Int("n", 3)), "Span.Attributes[n]==3", []attribute.KeyValue{attribute.Int("n", 3)}, true},
+		{AttributeEqualsPredicate(attribute.Int("n", 3)), "Span.Attributes[n]==3", []attribute.KeyValue{attribute.Float64("n", 3)}, false},
+		{AttributeEqualsPredicate(attribute.Bool("b", true)), "Span.Attributes[b]==true", []attribute.KeyValue{attribute.Bool("b", true)}, true},
+		{AttributeEqualsPredicate(attribute.Float64("f", 0.5)), "Span.Attributes[f]==0.5", []attribute.KeyValue{attribute.Float64("f", 0.5)}, true},
+	} {
+		require.Equal(t, test.desc, test.pred.Description())
+		require.Equal(t, test.decide, test.pred.Decide(testAttributeParams(test.attrs...)), test.desc)
+	}
+}
+
+func TestAttributeValueInSetPredicate(t *testing.T) {
+	pred := AttributeValueInSetPredicate("http.response.status_code", "500", "503")
+	require.Equal(t, "Span.Attributes[http.response.status_code] in {500,503}", pred.Description())
+	spec, ok := pred.Spec()
+	require.True(t, ok)
+	require.Equal(t, Spec{Name: "AttributeValueInSetPredicate", Args: map[string]any{
+		"key":    attribute.Key("http.response.status_code"),
+		"values": []string{"500", "503"},
+	}}, spec)
+
+	require.True(t, pred.Decide(testAttributeParams(attribute.Int("http.response.status_code", 500))))
+	require.True(t, pred.Decide(testAttributeParams(attribute.String("http.response.status_code", "503"))))
+	require.False(t, pred.Decide(testAttributeParams(attribute.Int("http.response.status_code", 200))))
+	require.False(t, pred.Decide(testAttributeParams(attribute.Int("rpc.grpc.status_code", 500))))
+}
+
+func TestAttributeRegexPredicate(t *testing.T) {
+	pred, err := AttributeRegexPredicate("url.path", "^/api/v[0-9]+/")
+	require.NoError(t, err)
+	require.Equal(t, "Span.Attributes[url.path]=~^/api/v[0-9]+/", pred.Description())
+
+	require.True(t, pred.Decide(testAttributeParams(attribute.String("url.path", "/api/v2/orders"))))
+	require.False(t, pred.Decide(testAttributeParams(attribute.String("url.path", "/health"))))
+	require.False(t, pred.Decide(testAttributeParams(attribute.String("url.full", "/api/v2/orders"))))
+	require.False(t, pred.Decide(testAttributeParams(attribute.Int("url.path", 2))))
+
+	_, err = AttributeRegexPredicate("url.path", "(")
+	require.ErrorContains(t, err, "attribute url.path: error parsing regexp")
+}
+
+func TestAttributeGlobPredicate(t *testing.T) {
+	pred := AttributeGlobPredicate("url.path", "/api/*/orders")
+	require.Equal(t, "Span.Attributes[url.path] glob /api/*/orders", pred.Description())
+
+	require.True(t, pred.Decide(testAttributeParams(attribute.String("url.path", "/api/v2/orders"))))
+	require.False(t, pred.Decide(testAttributeParams(attribute.String("url.path", "/api/v2/users"))))
+	require.False(t, pred.Decide(testAttributeParams(attribute.String("url.full", "/api/v2/orders"))))
+	require.False(t, pred.Decide(testAttributeParams(attribute.Int("url.path", 2))))
+}
+
+func TestAttributeNumericPredicates(t *testing.T) {
+	const key = attribute.Key("messaging.batch.message_count")
+	greater := AttributeGreaterPredicate(key, 100)
+	less := AttributeLessPredicate(key, 10)
+	between := AttributeRangePredicate(key, 10, 100)
+	require.Equal(t, "Span.Attributes[messaging.batch.message_count]>100", greater.Description())
+	require.Equal(t, "Span.Attributes[messaging.batch.message_count]<10", less.Description())
+	require.Equal(t, "Span.Attributes[messaging.batch.message_count] in [10,100]", between.Description())
+
+	for _, test := range []struct {
+		attrs   []attribute.KeyValue
+		greater bool
+		less    bool
+		between bool
+	}{
+		{nil, false, false, false},
+		{[]attribute.KeyValue{key.String("1000")}, false, false, false},
+		{[]attribute.KeyValue{key.Int(1000)}, true, false, false},
+		{[]attribute.KeyValue{key.Int(100)}, false, false, true},
+		{[]attribute.KeyValue{key.Float64(10)}, false, false, true},
+		{[]attribute.KeyValue{key.Float64(9.5)}, false, true, false},
+	} {
+		params := testAttributeParams(test.attrs...)
+		require.Equal(t, test.greater, greater.Decide(params), "%v", test.attrs)
+		require.Equal(t, test.less, less.Decide(params), "%v", test.attrs)
+		require.Equal(t, test.between, between.Decide(params), "%v", test.attrs)
+	}
+}
+
+func TestSpanNameInSetPredicate(t *testing.T) {
+	pred := SpanNameInSetPredicate("/health", "/ready", "/metrics")
+	require.Equal(t, "Span.Name in {/health,/ready,/metrics}", pred.Description())
+
+	for _, test := range []struct {
+		name  string
+		match bool
+	}{
+		{"/health", true},
+		{"/metrics", true},
+		{"/healthz", false},
+		{"", false},
+	} {
+		params := ComposableSamplingParameters{
+			SamplingParameters: SamplingParameters{
+				Name: test.name,
+			},
+		}
+		require.Equal(t, test.match, pred.Decide(params), test.name)
+	}
+	require.False(t, SpanNameInSetPredicate().Decide(ComposableSamplingParameters{}))
+}
+
+func TestSpanNameGlobPredicate(t *testing.T) {
+	for _, test := range []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"/api/*/orders", "/api/v1/orders", true},
+		{"/api/*/orders", "/api/v1/x/orders", true},
+		{"/api/*/orders", "/api/orders", false},
+		{"/api/*/orders", "/api/v1/orders/1", false},
+		{"/api/v?/orders", "/api/v2/orders", true},
+		{"/api/v?/orders", "/api/v10/orders", false},
+		{"/api/v?/orders", "/api/vé/orders", true},
+		{"/health*", "/healthcheck", true},
+		{"/health*", "/health", true},
+		{"/health*", "/heal", false},
+		{"/health", "/health", true},
+		{"/health", "/healthz", false},
+		{"*", "", true},
+		{"**a*", "xxa", true},
+		{"a*b*c", "abxbxc", true},
+		{"a*b*c", "abxbx", false},
+		{"?", "", false},
+	} {
+		pred := SpanNameGlobPredicate(test.pattern)
+		require.Equal(t, "Span.Name glob "+test.pattern, pred.Description())
+		params := ComposableSamplingParameters{
+			SamplingParameters: SamplingParameters{
+				Name: test.name,
+			},
+		}
+		require.Equal(t, test.match, pred.Decide(params), "%s %s", test.pattern, test.name)
+	}
+}
+
+func TestSpanNameRegexPredicate(t *testing.T) {
+	for _, test := range []struct {
+		expr    string
+		literal string
+		prefix  bool
+		ok      bool
+	}{
+		{"^/health$", "/health", false, true},
+		{"^/health", "/health", true, true},
+		{"/health$", "", false, false},
+		{"/health", "", false, false},
+		{"^/health.*$", "", false, false},
+		{"(?i)^/health$", "", false, false},
+	} {
+		literal, prefix, ok := anchoredLiteral(test.expr)
+		require.Equal(t, test.ok, ok, test.expr)
+		require.Equal(t, test.literal, literal, test.expr)
+		require.Equal(t, test.prefix, prefix, test.expr)
+	}
+
+	for _, test := range []struct {
+		expr  string
+		name  string
+		match bool
+	}{
+		{"^/health$", "/health", true},
+		{"^/health$", "/healthz", false},
+		{"^/health", "/healthz", true},
+		{"/health$", "/api/health", true},
+		{"^/api/v[0-9]+/", "/api/v2/orders", true},
+		{"(?i)^/HEALTH$", "/health", true},
+	} {
+		pred, err := SpanNameRegexPredicate(test.expr)
+		require.NoError(t, err)
+		require.Equal(t, "Span.Name=~"+test.expr, pred.Description())
+		params := ComposableSamplingParameters{
+			SamplingParameters: SamplingParameters{
+				Name: test.name,
+			},
+		}
+		require.Equal(t, test.match, pred.Decide(params), "%s %s", test.expr, test.name)
+	}
+
+	_, err := SpanNameRegexPredicate("[")
+	require.ErrorContains(t, err, "span name: error parsing regexp")
+}
+
+func TestSpanKindPredicate(t *testing.T) {
+	server := SpanKindPredicate(trace.SpanKindServer)
+	ingress := SpanKindPredicate(trace.SpanKindServer, trace.SpanKindConsumer)
+	require.Equal(t, "Span.Kind==server", server.Description())
+	require.Equal(t, "Span.Kind in {server,consumer}", ingress.Description())
+
+	for _, test := range []struct {
+		kind    trace.SpanKind
+		server  bool
+		ingress bool
+	}{
+		{trace.SpanKindServer, true, true},
+		{trace.SpanKindConsumer, false, true},
+		{trace.SpanKindInternal, false, false},
+		{trace.SpanKindClient, false, false},
+		{trace.SpanKindUnspecified, false, false},
+	} {
+		params := ComposableSamplingParameters{
+			SamplingParameters: SamplingParameters{
+				Kind: test.kind,
+			},
+		}
+		require.Equal(t, test.server, server.Decide(params), "%v", test.kind)
+		require.Equal(t, test.ingress, ingress.Decide(params), "%v", test.kind)
+	}
+}
+
+func TestPredicateCombinators(t *testing.T) {
+	server := SpanKindPredicate(trace.SpanKindServer)
+	health := SpanNamePredicate("/health")
+	pred := OrPredicate(AndPredicate(server, NotPredicate(health)), SpanNamePredicate("/debug"))
+	require.Equal(t, "or(and(Span.Kind==server,not(Span.Name==/health)),Span.Name==/debug)", pred.Description())
+
+	for _, test := range []struct {
+		name   string
+		kind   trace.SpanKind
+		decide bool
+	}{
+		{"/orders", trace.SpanKindServer, true},
+		{"/health", trace.SpanKindServer, false},
+		{"/orders", trace.SpanKindClient, false},
+		{"/debug", trace.SpanKindClient, true},
+	} {
+		params := ComposableSamplingParameters{
+			SamplingParameters: SamplingParameters{
+				Name: test.name,
+				Kind: test.kind,
+			},
+		}
+		require.Equal(t, test.decide, pred.Decide(params), "%v", test)
+	}
+
+	var params ComposableSamplingParameters
+	require.True(t, AndPredicate().Decide(params))
+	require.False(t, OrPredicate().Decide(params))
+	require.Equal(t, "and()", AndPredicate().Description())
+}
+
+func TestPredicateFunc(t *testing.T) {
+	tenant := PredicateFunc(func(params ComposableSamplingParameters) bool {
+		value, ok := findAttribute(params.Attributes, "tenant")
+		return ok && value.AsString() == "internal"
+	})
+	sampler := RuleBased(
+		WithRule(tenant.Describe("internal tenant"), ComposableNeverSample()),
+		WithDefaultRule(ComposableAlwaysSample()),
+	)
+	require.Equal(t, "RuleBased{rule(internal tenant)=AlwaysOff,rule(true)=AlwaysOn}", sampler.Description())
+
+	params := testAttributeParams(attribute.String("tenant", "internal"))
+	require.True(t, tenant.Decide(params))
+	require.Equal(t, NEVER_SAMPLE_THRESHOLD, sampler.GetSamplingIntent(params).Threshold)
+	require.Equal(t, ALWAYS_SAMPLE_THRESHOLD, sampler.GetSamplingIntent(testAttributeParams()).Threshold)
+}
+
+func TestResourceAttributePredicate(t *testing.T) {
+	staging := ResourceAttributePredicate(attribute.String("deployment.environment", "staging"))
+	require.Equal(t, "Resource[deployment.environment]==staging", staging.Description())
+
+	sampler := RuleBased(
+		WithRule(AndPredicate(staging, SpanNamePredicate("/debug")), ComposableAlwaysSample()),
+		WithRule(staging, TraceIDRatioBased(0.5)),
+		WithDefaultRule(ComposableNeverSample()),
+	)
+	params := func(name string) ComposableSamplingParameters {
+		return ComposableSamplingParameters{
+			SamplingParameters: SamplingParameters{
+				Name: name,
+			},
+		}
+	}
+
+	// Not optimized, with an unknown Resource: does not match.
+	require.False(t, staging.Decide(params("/debug")))
+	require.Equal(t, NEVER_SAMPLE_THRESHOLD, sampler.GetSamplingIntent(params("/debug")).Threshold)
+
+	// Not optimized: the Resource of the CompositeSampler is used.
+	for env, decision := range map[string]SamplingDecision{"staging": RecordAndSample, "production": Drop} {
+		res := resource.NewSchemaless(attribute.String("deployment.environment", env))
+		result := CompositeSampler(sampler, WithResource(res)).ShouldSample(SamplingParameters{
+			ParentContext: context.Background(),
+			TraceID:       trace.TraceID{1},
+			Name:          "/debug",
+		})
+		require.Equal(t, decision, result.Decision, env)
+	}
+
+	for _, test := range []struct {
+		env    string
+		debug  Threshold
+		orders Threshold
+	}{
+		{"staging", ALWAYS_SAMPLE_THRESHOLD, ProbabilityToThreshold(0.5)},
+		{"production", NEVER_SAMPLE_THRESHOLD, NEVER_SAMPLE_THRESHOLD},
+	} {
+		res := resource.NewSchemaless(attribute.String("deployment.environment", test.env))
+
+		value, ok := staging.Optimize(res, instrumentation.Scope{}).isConstant()
+		require.True(t, ok)
+		require.Equal(t, test.env == "staging", value)
+
+		opt := Optimize(sampler, res, instrumentation.Scope{})
+		require.Equal(t, test.debug, opt.GetSamplingIntent(params("/debug")).Threshold)
+		require.Equal(t, test.orders, opt.GetSamplingIntent(params("/orders")).Threshold)
+	}
+
+	// Constants fold through combinators.
+	_, ok := OrPredicate(NotPredicate(FalsePredicate()), SpanNamePredicate("x")).isConstant()
+	require.True(t, ok)
+	_, ok = AndPredicate(TruePredicate(), SpanNamePredicate("x")).isConstant()
+	require.False(t, ok)
+}
+
+func TestScopePredicate(t *testing.T) {
+	noisy := ScopePredicate(instrumentation.Scope{Name: "noisy/lib"})
+	pinned := ScopePredicate(instrumentation.Scope{Name: "noisy/lib", Version: "v1.2.3"})
+	require.Equal(t, "Scope{Name==noisy/lib}", noisy.Description())
+	require.Equal(t, "Scope{Name==noisy/lib,Version==v1.2.3}", pinned.Description())
+	require.False(t, noisy.Decide(ComposableSamplingParameters{}))
+
+	// Not optimized: the Scope of the CompositeSampler is used.
+	s := RuleBased(WithRule(noisy, ComposableNeverSample()), WithDefaultRule(ComposableAlwaysSample()))
+	for name, decision := range map[string]SamplingDecision{"noisy/lib": Drop, "quiet/lib": RecordAndSample} {
+		result := CompositeSampler(s, WithScope(instrumentation.Scope{Name: name})).ShouldSample(SamplingParameters{
+			ParentContext: context.Background(),
+			TraceID:       trace.TraceID{1},
+		})
+		require.Equal(t, decision, result.Decision, name)
+	}
+
+	for _, test := range []struct {
+		scope  instrumentation.Scope
+		noisy  bool
+		pinned bool
+	}{
+		{instrumentation.Scope{Name: "noisy/lib", Version: "v1.2.3"}, true, true},
+		{instrumentation.Scope{Name: "noisy/lib", Version: "v1.3.0"}, true, false},
+		{instrumentation.Scope{Name: "quiet/lib", Version: "v1.2.3"}, false, false},
+	} {
+		value, ok := noisy.Optimize(nil, test.scope).isConstant()
+		require.True(t, ok)
+		require.Equal(t, test.noisy, value)
+
+		value, ok = pinned.Optimize(nil, test.scope).isConstant()
+		require.True(t, ok)
+		require.Equal(t, test.pinned, value)
+	}
+}
+
+func TestParentLocationPredicates(t *testing.T) {
+	remote := IsRemoteParentPredicate()
+	local := IsLocalParentPredicate()
+	root := IsRootPredicate()
+
+	for _, test := range []struct {
+		sc     trace.SpanContext
+		remote bool
+		local  bool
+		root   bool
+	}{
+		{trace.SpanContext{}, false, false, true},
+		{trace.NewSpanContext(trace.SpanContextConfig{TraceID: trace.TraceID{1}, SpanID: trace.SpanID{1}, Remote: true}), true, false, false},
+		{trace.NewSpanContext(trace.SpanContextConfig{TraceID: trace.TraceID{1}, SpanID: trace.SpanID{1}}), false, true, false},
+	} {
+		params := ComposableSamplingParameters{
+			ParentSpanContext: test.sc,
+		}
+		require.Equal(t, test.remote, remote.Decide(params))
+		require.Equal(t, test.local, local.Decide(params))
+		require.Equal(t, test.root, root.Decide(params))
+	}
+	require.Equal(t, IsRemotePredicate().Description(), remote.Description())
+	require.Equal(t, IsLocalPredicate().Description(), local.Description())
+}
+
+func TestParentSampledPredicates(t *testing.T) {
+	sampled := IsParentSampledPredicate()
+	notSampled := IsParentNotSampledPredicate()
+	threshold := HasParentThresholdPredicate()
+	require.Equal(t, "parent sampled?", sampled.Description())
+	require.Equal(t, "parent not sampled?", notSampled.Description())
+	require.Equal(t, "parent threshold?", threshold.Description())
+
+	// If the remote parent did not sample, still sample at 1%.
+	rb := RuleBased(
+		WithRule(AndPredicate(IsRemoteParentPredicate(), notSampled), TraceIDRatioBased(0.01)),
+		WithDefaultRule(ComposableParentBased(ComposableAlwaysSample())),
+	)
+
+	for _, test := range []struct {
+		sampled    bool
+		tracestate string
+		isSampled  bool
+		hasTh      bool
+		threshold  Threshold
+	}{
+		{true, "", true, false, INVALID_THRESHOLD},
+		{true, "ot=th:0", true, true, ALWAYS_SAMPLE_THRESHOLD},
+		{false, "", false, false, ProbabilityToThreshold(0.01)},
+	} {
+		ts, err := trace.ParseTraceState(test.tracestate)
+		require.NoError(t, err)
+
+		var captured ComposableSamplingParameters
+		probe := PredicateFunc(func(params ComposableSamplingParameters) bool {
+			captured = params
+			return false
+		}).Describe("probe")
+
+		tf := defaultTestFuncs()
+		tf.sampled = func() bool { return test.sampled }
+		tf.tracestate = func() trace.TraceState { return ts }
+		CompositeSampler(RuleBased(WithRule(probe, ComposableNeverSample()))).ShouldSample(makeTestContext(tf).SamplingParameters)
+
+		require.Equal(t, test.isSampled, sampled.Decide(captured))
+		require.Equal(t, !test.isSampled, notSampled.Decide(captured))
+		require.Equal(t, test.hasTh, threshold.Decide(captured))
+
+		intent := rb.GetSamplingIntent(captured)
+		require.Equal(t, test.threshold, intent.Threshold)
+	}
+}
+
+func TestParentProbabilityAtLeastPredicate(t *testing.T) {
+	pred := ParentProbabilityAtLeastPredicate(0.01)
+	require.Equal(t, "parent probability>=0.01", pred.Description())
+
+	for _, test := range []struct {
+		threshold Threshold
+		reliable  bool
+		match     bool
+	}{
+		{ALWAYS_SAMPLE_THRESHOLD, true, true},
+		{ProbabilityToThreshold(0.1), true, true},
+		{ProbabilityToThreshold(0.01), true, true},
+		{ProbabilityToThreshold(0.001), true, false},
+		{ALWAYS_SAMPLE_THRESHOLD, false, false},
+		{INVALID_THRESHOLD, false, false},
+	} {
+		params := ComposableSamplingParameters{
+			parentThreshold:         test.threshold,
+			parentThresholdReliable: test.reliable,
+		}
+		require.Equal(t, test.match, pred.Decide(params))
+	}
+}
+
+func TestTraceFractionPredicate(t *testing.T) {
+	pred := TraceFractionPredicate(0.25)
+	require.Equal(t, "trace fraction 0.25", pred.Description())
+
+	rnd := rand.New(rand.NewSource(101333))
+	const trials = 100000
+	var inSlice, sampled, both int
+	half := ProbabilityToThreshold(0.5)
+	for i := 0; i < trials; i++ {
+		params := ComposableSamplingParameters{
+			randomness: int64(rnd.Uint64() & randomnessMask),
+		}
+		gate := pred.Decide(params)
+		// The result depends only on the randomness.
+		require.Equal(t, gate, pred.Decide(params))
+
+		wouldSample := SamplingIntent{Threshold: half}.WouldSample(params)
+		if gate {
+			inSlice++
+		}
+		if wouldSample {
+			sampled++
+		}
+		if gate && wouldSample {
+			both++
+		}
+	}
+	require.InEpsilon(t, 0.25, float64(inSlice)/trials, 0.05)
+	require.InEpsilon(t, 0.5, float64(sampled)/trials, 0.05)
+
+	// Sampling within the slice is independent of the slice.
+	require.InEpsilon(t, 0.5, float64(both)/float64(inSlice), 0.05)
+
+	require.False(t, TraceFractionPredicate(0).Decide(ComposableSamplingParameters{}))
+	require.True(t, TraceFractionPredicate(1).Decide(ComposableSamplingParameters{randomness: int64(randomnessMask)}))
+
+	// Without a CompositeSampler, the randomness is zero.
+	require.True(t, pred.Decide(ComposableSamplingParameters{}))
+
+	// With fewer random bits, whose least-significant bits are zero.
+	sampler := CompositeSampler(RuleBased(
+		WithRule(pred, ComposableAlwaysSample()),
+		WithDefaultRule(ComposableNeverSample()),
+	), WithTraceIDRandomnessBits(0, 24))
+	inSlice = 0
+	for i := 0; i < trials; i++ {
+		var id trace.TraceID
+		rnd.Read(id[:])
+		if sampler.ShouldSample(SamplingParameters{
+			ParentContext: context.Background(),
+			TraceID:       id,
+		}).Decision == RecordAndSample {
+			inSlice++
+		}
+	}
+	require.InEpsilon(t, 0.25, float64(inSlice)/trials, 0.05)
+}
+
+func TestLinkPredicates(t *testing.T) {
+	hasLinks := HasLinksPredicate()
+	anySampled := AnyLinkSampledPredicate()
+	require.Equal(t, "links?", hasLinks.Description())
+	require.Equal(t, "any link sampled?", anySampled.Description())
+
+	link := func(flags trace.TraceFlags) trace.Link {
+		return trace.Link{
+			SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID:    trace.TraceID{1},
+				SpanID:     trace.SpanID{1},
+				TraceFlags: flags,
+			}),
+		}
+	}
+	for _, test := range []struct {
+		links      []trace.Link
+		hasLinks   bool
+		anySampled bool
+	}{
+		{nil, false, false},
+		{[]trace.Link{link(0)}, true, false},
+		{[]trace.Link{link(0), link(trace.FlagsSampled)}, true, true},
+	} {
+		params := ComposableSamplingParameters{
+			SamplingParameters: SamplingParameters{
+				Links: test.links,
+			},
+		}
+		require.Equal(t, test.hasLinks, hasLinks.Decide(params))
+		require.Equal(t, test.anySampled, anySampled.Decide(params))
+	}
+}
+
+func TestLinkAttributePredicates(t *testing.T) {
+	hasPartition := HasLinkAttributePredicate("messaging.kafka.partition")
+	partition3 := LinkAttributeEqualsPredicate(attribute.Int("messaging.kafka.partition", 3))
+	require.Equal(t, "Link.Attributes[messaging.kafka.partition]?", hasPartition.Description())
+	require.Equal(t, "Link.Attributes[messaging.kafka.partition]==3", partition3.Description())
+
+	link := func(attrs ...attribute.KeyValue) trace.Link {
+		return trace.Link{Attributes: attrs}
+	}
+	for _, test := range []struct {
+		links        []trace.Link
+		hasPartition bool
+		partition3   bool
+	}{
+		{nil, false, false},
+		{[]trace.Link{link(attribute.String("other", "x"))}, false, false},
+		{[]trace.Link{link(attribute.Int("messaging.kafka.partition", 1))}, true, false},
+		{[]trace.Link{link(), link(attribute.Int("messaging.kafka.partition", 3))}, true, true},
+	} {
+		params := ComposableSamplingParameters{
+			SamplingParameters: SamplingParameters{
+				Links: test.links,
+			},
+		}
+		require.Equal(t, test.hasPartition, hasPartition.Decide(params))
+		require.Equal(t, test.partition3, partition3.Decide(params))
+	}
+}
+
+func TestBaggagePredicates(t *testing.T) {
+	has := HasBaggagePredicate("tenant")
+	equals := BaggageEqualsPredicate("tenant", "internal")
+	require.Equal(t, "Baggage[tenant]?", has.Description())
+	require.Equal(t, "Baggage[tenant]==internal", equals.Description())
+
+	for _, test := range []struct {
+		baggage string
+		has     bool
+		equals  bool
+	}{
+		{"", false, false},
+		{"other=internal", false, false},
+		{"tenant=external", true, false},
+		{"tenant=internal,other=x", true, true},
+	} {
+		bag, err := baggage.Parse(test.baggage)
+		require.NoError(t, err)
+
+		var captured ComposableSamplingParameters
+		probe := PredicateFunc(func(params ComposableSamplingParameters) bool {
+			captured = params
+			return false
+		}).Describe("probe")
+		CompositeSampler(RuleBased(WithRule(probe, ComposableNeverSample()))).ShouldSample(SamplingParameters{
+			ParentContext: baggage.ContextWithBaggage(context.Background(), bag),
+		})
+
+		require.Equal(t, test.has, has.Decide(captured), test.baggage)
+		require.Equal(t, test.equals, equals.Decide(captured), test.baggage)
+	}
+}
+
+func TestTraceStatePredicates(t *testing.T) {
+	member := TraceStateMemberPredicate("vnd")
+	memberEq := TraceStateMemberEqualsPredicate("vnd", "x")
+	field := OTelTraceStateFieldPredicate("xx")
+	fieldEq := OTelTraceStateFieldEqualsPredicate("xx", "abc")
+	require.Equal(t, "TraceState[vnd]?", member.Description())
+	require.Equal(t, "TraceState[vnd]==x", memberEq.Description())
+	require.Equal(t, "TraceState[ot.xx]?", field.Description())
+	require.Equal(t, "TraceState[ot.xx]==abc", fieldEq.Description())
+
+	for _, test := range []struct {
+		tracestate string
+		decide     []bool
+	}{
+		{"", []bool{false, false, false, false}},
+		{"vnd=y", []bool{true, false, false, false}},
+		{"vnd=x,ot=yy:abc", []bool{true, true, false, false}},
+		{"ot=th:0;xx:abd", []bool{false, false, true, false}},
+		{"ot=xx:abc;th:0", []bool{false, false, true, true}},
+	} {
+		ts, err := trace.ParseTraceState(test.tracestate)
+		require.NoError(t, err)
+		params := ComposableSamplingParameters{
+			ParentSpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID:    trace.TraceID{1},
+				SpanID:     trace.SpanID{1},
+				TraceState: ts,
+			}),
+		}
+		require.Equal(t, test.decide, []bool{
+			member.Decide(params),
+			memberEq.Decide(params),
+			field.Decide(params),
+			fieldEq.Decide(params),
+		}, test.tracestate)
+	}
+}
+
+func TestHTTPRoutePredicate(t *testing.T) {
+	orders := HTTPRoutePredicate("/api/*/orders", "GET", "POST")
+	health := HTTPRoutePredicate("/health*")
+	require.Equal(t, "HTTP{GET,POST /api/*/orders}", orders.Description())
+	require.Equal(t, "HTTP{/health*}", health.Description())
+
+	route := attribute.Key("http.route")
+	path := attribute.Key("url.path")
+	method := attribute.Key("http.request.method")
+	for _, test := range []struct {
+		attrs  []attribute.KeyValue
+		orders bool
+		health bool
+	}{
+		{nil, false, false},
+		{[]attribute.KeyValue{route.String("/api/{version}/orders"), method.String("GET")}, true, false},
+		{[]attribute.KeyValue{route.String("/api/{version}/orders"), method.String("DELETE")}, false, false},
+		{[]attribute.KeyValue{path.String("/api/v1/orders"), method.String("post")}, true, false},
+		{[]attribute.KeyValue{route.String("/api/{id}"), path.String("/api/v1/orders"), method.String("GET")}, false, false},
+		{[]attribute.KeyValue{path.String("/healthz")}, false, true},
+		{[]attribute.KeyValue{route.String("/api/v1/orders")}, false, false},
+	} {
+		params := testAttributeParams(test.attrs...)
+		require.Equal(t, test.orders, orders.Decide(params), "%v", test.attrs)
+		require.Equal(t, test.health, health.Decide(params), "%v", test.attrs)
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SamplingExportOnlyKey is the attribute holding the tracestate of an
+// export-only span recorded by a sampler for the SDK.  See
+// WithExportOnlyRecording.
+const SamplingExportOnlyKey = attribute.Key("sampling.export_only.tracestate")
+
+// AdjustedCountProcessorOption configures an AdjustedCountProcessor.
+type AdjustedCountProcessorOption func(*adjustedCountProcessorConfig)
+
+type adjustedCountProcessorConfig struct {
+	thresholdKey     attribute.Key
+	adjustedCountKey attribute.Key
+}
+
+// WithThresholdAttributeKey sets the attribute holding the threshold,
+// by default SamplingThresholdKey.  An empty key omits the attribute.
+func WithThresholdAttributeKey(key attribute.Key) AdjustedCountProcessorOption {
+	return func(cfg *adjustedCountProcessorConfig) {
+		cfg.thresholdKey = key
+	}
+}
+
+// WithAdjustedCountAttributeKey sets the attribute holding the
+// adjusted count, by default SamplingAdjustedCountKey.  An empty key
+// omits the attribute.
+func WithAdjustedCountAttributeKey(key attribute.Key) AdjustedCountProcessorOption {
+	return func(cfg *adjustedCountProcessorConfig) {
+		cfg.adjustedCountKey = key
+	}
+}
+
+// AdjustedCountProcessor returns a SpanProcessor that passes ended
+// spans to next with attributes holding the sampling threshold and
+// adjusted count of their tracestate, so that backends without
+// tracestate support can re-weight data derived from spans.  Unlike
+// AnnotateAdjustedCount, this applies to spans sampled by any sampler
+// that records a threshold, including their parent's threshold for
+// spans sampled by ParentThreshold.  Spans without a threshold are
+// passed unchanged, and attributes of the span with the same keys are
+// replaced.
+//
+// The SDK does not permit modifying ended spans, so the attributes
+// are visible only to next, which is typically the processor that
+// exports spans:
+//
+//	sdktrace.WithSpanProcessor(sampler.AdjustedCountProcessor(sdktrace.NewBatchSpanProcessor(exporter)))
+func AdjustedCountProcessor(next sdktrace.SpanProcessor, options ...AdjustedCountProcessorOption) sdktrace.SpanProcessor {
+	cfg := adjustedCountProcessorConfig{
+		thresholdKey:     SamplingThresholdKey,
+		adjustedCountKey: SamplingAdjustedCountKey,
+	}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	return &adjustedCountProcessor{
+		next:   next,
+		config: cfg,
+	}
+}
+
+type adjustedCountProcessor struct {
+	next   sdktrace.SpanProcessor
+	config adjustedCountProcessorConfig
+}
+
+var _ sdktrace.SpanProcessor = &adjustedCountProcessor{}
+
+// OnStart implements sdktrace.SpanProcessor.
+func (p *adjustedCountProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	p.next.OnStart(parent, s)
+}
+
+// OnEnd implements sdktrace.SpanProcessor.
+func (p *adjustedCountProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	otts := s.SpanContext().TraceState().Get("ot")
+	if otts == "" {
+		p.next.OnEnd(s)
+		return
+	}
+	threshold, _, has, err := tracestateHasThreshold(otts)
+	if err != nil || !has {
+		p.next.OnEnd(s)
+		return
+	}
+	var extra []attribute.KeyValue
+	if p.config.thresholdKey != "" {
+		extra = append(extra, p.config.thresholdKey.String(threshold.String()))
+	}
+	if p.config.adjustedCountKey != "" {
+		extra = append(extra, p.config.adjustedCountKey.Float64(threshold.AdjustedCount()))
+	}
+	if len(extra) == 0 {
+		p.next.OnEnd(s)
+		return
+	}
+	attrs := make([]attribute.KeyValue, 0, len(s.Attributes())+len(extra))
+	for _, kv := range s.Attributes() {
+		if kv.Key != p.config.thresholdKey && kv.Key != p.config.adjustedCountKey {
+			attrs = append(attrs, kv)
+		}
+	}
+	p.next.OnEnd(annotatedSpan{
+		ReadOnlySpan: s,
+		spanContext:  s.SpanContext(),
+		attributes:   append(attrs, extra...),
+	})
+}
+
+// Shutdown implements sdktrace.SpanProcessor.
+func (p *adjustedCountProcessor) Shutdown(ctx context.Context) error {
+	return p.next.Shutdown(ctx)
+}
+
+// ForceFlush implements sdktrace.SpanProcessor.
+func (p *adjustedCountProcessor) ForceFlush(ctx context.Context) error {
+	return p.next.ForceFlush(ctx)
+}
+
+// ExportOnlyProcessor returns a SpanProcessor that passes the spans
+// recorded for ExportOnly decisions, see WithExportOnlyRecording, to
+// next as sampled spans with the tracestate of their decision, which
+// records their threshold.  The SamplingExportOnlyKey attribute is
+// removed.  Other spans are passed unchanged.
+//
+// Export-only spans are not sampled in their SpanContext, so they are
+// not propagated as sampled and are not counted by processors other
+// than next.  Typically, next is the processor that exports spans,
+// possibly through an AdjustedCountProcessor, which then sees the
+// export-only span's threshold:
+//
+//	sampler.ExportOnlyProcessor(sampler.AdjustedCountProcessor(sdktrace.NewBatchSpanProcessor(exporter)))
+func ExportOnlyProcessor(next sdktrace.SpanProcessor) sdktrace.SpanProcessor {
+	return &exportOnlyProcessor{
+		next: next,
+	}
+}
+
+type exportOnlyProcessor struct {
+	next sdktrace.SpanProcessor
+}
+
+var _ sdktrace.SpanProcessor = &exportOnlyProcessor{}
+
+// OnStart implements sdktrace.SpanProcessor.
+func (p *exportOnlyProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	p.next.OnStart(parent, s)
+}
+
+// OnEnd implements sdktrace.SpanProcessor.
+func (p *exportOnlyProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	sc := s.SpanContext()
+	if sc.IsSampled() {
+		p.next.OnEnd(s)
+		return
+	}
+	var (
+		otts  string
+		found bool
+	)
+	attrs := make([]attribute.KeyValue, 0, len(s.Attributes()))
+	for _, kv := range s.Attributes() {
+		if kv.Key == SamplingExportOnlyKey {
+			otts, found = kv.Value.AsString(), true
+			continue
+		}
+		attrs = append(attrs, kv)
+	}
+	if !found {
+		p.next.OnEnd(s)
+		return
+	}
+	if ts, err := trace.ParseTraceState(otts); err == nil {
+		sc = sc.WithTraceState(ts)
+	}
+	p.next.OnEnd(annotatedSpan{
+		ReadOnlySpan: s,
+		spanContext:  sc.WithTraceFlags(sc.TraceFlags() | trace.FlagsSampled),
+		attributes:   attrs,
+	})
+}
+
+// Shutdown implements sdktrace.SpanProcessor.
+func (p *exportOnlyProcessor) Shutdown(ctx context.Context) error {
+	return p.next.Shutdown(ctx)
+}
+
+// ForceFlush implements sdktrace.SpanProcessor.
+func (p *exportOnlyProcessor) ForceFlush(ctx context.Context) error {
+	return p.next.ForceFlush(ctx)
+}
+
+// annotatedSpan is an ended span with a modified SpanContext and
+// attributes.
+type annotatedSpan struct {
+	sdktrace.ReadOnlySpan
+	spanContext trace.SpanContext
+	attributes  []attribute.KeyValue
+}
+
+// SpanContext implements sdktrace.ReadOnlySpan.
+func (s annotatedSpan) SpanContext() trace.SpanContext {
+	return s.spanContext
+}
+
+// Attributes implements sdktrace.ReadOnlySpan.
+func (s annotatedSpan) Attributes() []attribute.KeyValue {
+	return s.attributes
+}
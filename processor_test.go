@@ -0,0 +1,128 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestAdjustedCountProcessor(t *testing.T) {
+	ts, err := trace.ParseTraceState("ot=th:8")
+	require.NoError(t, err)
+	parent := trace.ContextWithRemoteSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+		TraceState: ts,
+	}))
+
+	for _, test := range []struct {
+		name    string
+		options []AdjustedCountProcessorOption
+		expect  []attribute.KeyValue
+	}{
+		{
+			name: "default",
+			expect: []attribute.KeyValue{
+				attribute.String("other", "kept"),
+				SamplingThresholdKey.String("8"),
+				SamplingAdjustedCountKey.Float64(2),
+			},
+		},
+		{
+			name:    "renamed",
+			options: []AdjustedCountProcessorOption{WithThresholdAttributeKey(""), WithAdjustedCountAttributeKey("weight")},
+			expect: []attribute.KeyValue{
+				SamplingAdjustedCountKey.Float64(10),
+				attribute.String("other", "kept"),
+				attribute.Float64("weight", 2),
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(
+				sdktrace.WithSampler(sdktrace.AlwaysSample()),
+				sdktrace.WithSpanProcessor(AdjustedCountProcessor(recorder, test.options...)),
+			)
+			tracer := tp.Tracer("test")
+
+			_, child := tracer.Start(parent, "child", trace.WithAttributes(
+				SamplingAdjustedCountKey.Float64(10),
+				attribute.String("other", "kept"),
+			))
+			child.End()
+			_, root := tracer.Start(context.Background(), "root")
+			root.End()
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 2)
+			require.Equal(t, test.expect, spans[0].Attributes())
+			require.Empty(t, spans[1].Attributes())
+		})
+	}
+}
+
+func TestExportOnlyProcessor(t *testing.T) {
+	exported := tracetest.NewSpanRecorder()
+	counted := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(NewSDKSampler(RuleBased(
+			WithRule(SpanNamePredicate("export"), ExportOnlySampler(ComposableAlwaysSample())),
+			WithRule(SpanNamePredicate("record"), LegacySampler(legacyFunc(func(SamplingParameters) SamplingResult {
+				return SamplingResult{
+					Decision:   RecordOnly,
+					Attributes: []attribute.KeyValue{attribute.String("recorded", "yes")},
+				}
+			}))),
+			WithDefaultRule(ComposableNeverSample()),
+		), WithExportOnlyRecording())),
+		sdktrace.WithSpanProcessor(counted),
+		sdktrace.WithSpanProcessor(ExportOnlyProcessor(AdjustedCountProcessor(exported))),
+	)
+	tracer := tp.Tracer("test")
+
+	ctx, span := tracer.Start(context.Background(), "export", trace.WithAttributes(attribute.String("k", "v")))
+	require.True(t, span.IsRecording())
+	require.False(t, span.SpanContext().IsSampled())
+	require.Equal(t, "", span.SpanContext().TraceState().Get("ot"))
+
+	// The child is not sampled because of its parent.
+	_, child := tracer.Start(ctx, "child")
+	require.False(t, child.IsRecording())
+	child.End()
+	span.End()
+
+	spans := counted.Ended()
+	require.Len(t, spans, 1)
+	require.False(t, spans[0].SpanContext().IsSampled())
+	require.Equal(t, []attribute.KeyValue{
+		SamplingExportOnlyKey.String("ot=th:0"),
+		attribute.String("k", "v"),
+	}, spans[0].Attributes())
+
+	spans = exported.Ended()
+	require.Len(t, spans, 1)
+	require.True(t, spans[0].SpanContext().IsSampled())
+	require.Equal(t, "th:0", spans[0].SpanContext().TraceState().Get("ot"))
+	require.Equal(t, []attribute.KeyValue{
+		attribute.String("k", "v"),
+		SamplingThresholdKey.String("0"),
+		SamplingAdjustedCountKey.Float64(1),
+	}, spans[0].Attributes())
+
+	// Spans recorded for other reasons are passed unchanged.
+	_, record := tracer.Start(context.Background(), "record")
+	record.End()
+	spans = exported.Ended()
+	require.Len(t, spans, 2)
+	require.False(t, spans[1].SpanContext().IsSampled())
+	require.Equal(t, []attribute.KeyValue{attribute.String("recorded", "yes")}, spans[1].Attributes())
+}
@@ -0,0 +1,133 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PropagatorOption configures a RepairingPropagator.
+type PropagatorOption func(*propagatorConfig)
+
+type propagatorConfig struct {
+	allowed           []string
+	generate          bool
+	untrustedTraceIDs bool
+}
+
+// WithAllowedSubKeys allows OpenTelemetry tracestate sub-keys other
+// than "th", "rv", and registered sub-keys, as WithTraceStateSanitizer
+// does.
+func WithAllowedSubKeys(allowed ...string) PropagatorOption {
+	return func(cfg *propagatorConfig) {
+		cfg.allowed = append(cfg.allowed, allowed...)
+	}
+}
+
+// WithGeneratedRandomness writes a random "rv" sub-key to extracted
+// contexts that have neither an "rv" sub-key nor the W3C random trace
+// flag, so that downstream consistent samplers do not depend on the
+// randomness of a TraceID that is not known to be random.  Note that
+// propagation.TraceContext does not preserve the random flag.
+func WithGeneratedRandomness() PropagatorOption {
+	return func(cfg *propagatorConfig) {
+		cfg.generate = true
+	}
+}
+
+// WithUntrustedTraceIDs writes a random "rv" sub-key to extracted
+// contexts without one, whether or not they have the W3C random trace
+// flag, for services that receive TraceIDs from untrusted clients.
+func WithUntrustedTraceIDs() PropagatorOption {
+	return func(cfg *propagatorConfig) {
+		cfg.generate = true
+		cfg.untrustedTraceIDs = true
+	}
+}
+
+// RepairingPropagator wraps a propagator, typically
+// propagation.TraceContext, so that the OpenTelemetry tracestate of
+// extracted contexts is repaired before it reaches samplers: whitespace
+// and empty sub-keys are removed from the "ot" member, malformed and
+// unknown sub-keys and oversized vendor members are removed, as by
+// WithTraceStateSanitizer, and randomness is generated as configured
+// by WithGeneratedRandomness or WithUntrustedTraceIDs.  When
+// randomness is generated, a "th" sub-key is removed, since it was
+// computed using other randomness.
+// Repairs are reported via otel.Handle.
+//
+// This is meant for the edges of a system, where contexts are received
+// from services that may not follow the OpenTelemetry sampling
+// specification.  Since generated randomness differs from one service
+// to another, it should be generated by only one service in each call
+// path, which then propagates it.  Inject is not modified.
+func RepairingPropagator(p propagation.TextMapPropagator, options ...PropagatorOption) propagation.TextMapPropagator {
+	var cfg propagatorConfig
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	keys := map[string]bool{"th": true, "rv": true}
+	for _, key := range cfg.allowed {
+		keys[key] = true
+	}
+	return &repairingPropagator{
+		TextMapPropagator: p,
+		sanitizer:         traceStateSanitizer{allowed: keys},
+		config:            cfg,
+	}
+}
+
+type repairingPropagator struct {
+	propagation.TextMapPropagator
+	sanitizer traceStateSanitizer
+	config    propagatorConfig
+}
+
+var _ propagation.TextMapPropagator = &repairingPropagator{}
+
+// Extract implements propagation.TextMapPropagator.
+func (rp *repairingPropagator) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	ctx = rp.TextMapPropagator.Extract(ctx, carrier)
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() || !sc.IsRemote() {
+		return ctx
+	}
+	ts := rp.repair(sc)
+	if ts.String() == sc.TraceState().String() {
+		return ctx
+	}
+	return trace.ContextWithRemoteSpanContext(ctx, sc.WithTraceState(ts))
+}
+
+// repair returns the repaired tracestate of an extracted context.
+func (rp *repairingPropagator) repair(sc trace.SpanContext) trace.TraceState {
+	ts, err := rp.sanitizer.sanitize(canonicalizeTraceState(sc.TraceState()))
+	if err != nil {
+		otel.Handle(err)
+	}
+	if !rp.config.generate || (!rp.config.untrustedTraceIDs && sc.TraceFlags()&flagsRandom != 0) {
+		return ts
+	}
+	otts := ts.Get("ot")
+	if _, has, _ := tracestateHasRandomness(otts); has {
+		return ts
+	}
+	var kept []string
+	for _, field := range strings.Split(otts, ";") {
+		switch {
+		case strings.HasPrefix(field, "th:"):
+			otel.Handle(errors.New("tracestate: removed ot.th with generated randomness"))
+		case field != "":
+			kept = append(kept, field)
+		}
+	}
+	ts, _ = insertRandomness(ts, strings.Join(kept, ";"))
+	return ts
+}
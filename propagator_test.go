@@ -0,0 +1,110 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// randomFlagPropagator extracts trace context with the W3C random
+// flag, which propagation.TraceContext does not preserve.
+type randomFlagPropagator struct {
+	propagation.TraceContext
+}
+
+func (randomFlagPropagator) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	sc := trace.SpanContextFromContext(propagation.TraceContext{}.Extract(ctx, carrier))
+	return trace.ContextWithRemoteSpanContext(ctx, sc.WithTraceFlags(sc.TraceFlags()|flagsRandom))
+}
+
+func TestRepairingPropagator(t *testing.T) {
+	var errs []string
+	previous := otel.GetErrorHandler()
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		errs = append(errs, err.Error())
+	}))
+	t.Cleanup(func() { otel.SetErrorHandler(previous) })
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	for _, test := range []struct {
+		name       string
+		options    []PropagatorOption
+		random     bool
+		tracestate string
+		expect     string // generated randomness is "rv:*"
+		errs       []string
+	}{
+		{
+			name:       "unchanged",
+			tracestate: "ot=th:8,vnd=x",
+			expect:     "ot=th:8,vnd=x",
+		},
+		{
+			name:       "sanitized",
+			tracestate: "ot=th:8; zz:1;bad:?,vnd=x",
+			expect:     "ot=th:8,vnd=x",
+			errs:       []string{"tracestate: removed ot.zz,ot.bad"},
+		},
+		{
+			name:       "allowed",
+			options:    []PropagatorOption{WithAllowedSubKeys("zz")},
+			tracestate: "ot=zz:1;th:8",
+			expect:     "ot=zz:1;th:8",
+		},
+		{
+			name:       "generated",
+			options:    []PropagatorOption{WithGeneratedRandomness()},
+			tracestate: "ot=th:8,vnd=x",
+			expect:     "ot=rv:*,vnd=x",
+			errs:       []string{"tracestate: removed ot.th with generated randomness"},
+		},
+		{
+			name:       "random flag",
+			options:    []PropagatorOption{WithGeneratedRandomness()},
+			random:     true,
+			tracestate: "ot=th:8",
+			expect:     "ot=th:8",
+		},
+		{
+			name:       "untrusted",
+			options:    []PropagatorOption{WithUntrustedTraceIDs(), WithAllowedSubKeys("zz")},
+			random:     true,
+			tracestate: "ot=zz:1",
+			expect:     "ot=rv:*;zz:1",
+		},
+		{
+			name:       "existing randomness",
+			options:    []PropagatorOption{WithUntrustedTraceIDs()},
+			tracestate: "ot=rv:ffffffffffffff;th:8",
+			expect:     "ot=rv:ffffffffffffff;th:8",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			errs = nil
+			var prop propagation.TextMapPropagator = propagation.TraceContext{}
+			if test.random {
+				prop = randomFlagPropagator{}
+			}
+			ctx := RepairingPropagator(prop, test.options...).Extract(context.Background(), propagation.MapCarrier{
+				"traceparent": traceparent,
+				"tracestate":  test.tracestate,
+			})
+			sc := trace.SpanContextFromContext(ctx)
+			require.True(t, sc.IsRemote())
+
+			ts := sc.TraceState()
+			if otts := ts.Get("ot"); strings.HasPrefix(test.expect, "ot=rv:*") && len(otts) >= 17 {
+				ts, _ = ts.Insert("ot", "rv:*"+otts[17:])
+			}
+			require.Equal(t, test.expect, ts.String())
+			require.Equal(t, test.errs, errs)
+		})
+	}
+}
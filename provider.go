@@ -0,0 +1,194 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+)
+
+// TracerProviderOption configures a TracerProvider.
+type TracerProviderOption func(*tracerProviderConfig)
+
+type tracerProviderConfig struct {
+	composite []CompositeOption
+	sdk       []sdktrace.TracerProviderOption
+}
+
+// WithCompositeOptions sets the options of the CompositeSampler that
+// makes the provider's sampling decisions.
+func WithCompositeOptions(options ...CompositeOption) TracerProviderOption {
+	return func(cfg *tracerProviderConfig) {
+		cfg.composite = append(cfg.composite, options...)
+	}
+}
+
+// WithSDKOptions sets options of the underlying SDK TracerProvider,
+// such as its span processors.  The provider's Resource, sampler, and
+// ID generator are set by NewTracerProvider and cannot be overridden.
+func WithSDKOptions(options ...sdktrace.TracerProviderOption) TracerProviderOption {
+	return func(cfg *tracerProviderConfig) {
+		cfg.sdk = append(cfg.sdk, options...)
+	}
+}
+
+// TracerProvider is an SDK TracerProvider whose tracers each sample
+// using a sampler optimized for the provider's Resource and the
+// tracer's instrumentation Scope, see SamplerOptimizer, and that sets
+// the Resource and Scope of ComposableSamplingParameters for samplers
+// that do not implement it.  The SDK does not pass the Resource or
+// Scope to samplers, so without this, predicates on them must be
+// evaluated for every span or resolved by optimizing for a single
+// Scope.
+//
+// TraceIDs are generated by RandomIDGenerator, and root spans started
+// by the provider's tracers have the W3C random trace flag, which
+// their local children inherit, so that samplers configured with
+// WithRandomFlagRequired trust their TraceIDs.  This does not apply to
+// root spans started with trace.WithNewRoot, for which the SDK ignores
+// the context.  Note that propagation.TraceContext does not propagate
+// the random flag.
+//
+// Methods of the SDK TracerProvider other than Tracer, such as
+// Shutdown and ForceFlush, are available through the embedded field.
+type TracerProvider struct {
+	*sdktrace.TracerProvider
+
+	spanID    bool
+	resource  *resource.Resource
+	sampler   ComposableSampler
+	composite []CompositeOption
+	fallback  Sampler
+
+	lock    sync.Mutex
+	tracers map[instrumentation.Scope]*optimizedTracer
+}
+
+var _ trace.TracerProvider = &TracerProvider{}
+
+// NewTracerProvider returns a TracerProvider for a Resource that
+// samples using s.  The sampler is optimized once for each Scope,
+// when its first Tracer is created.  A nil Resource is
+// resource.Default(), as in the SDK.
+func NewTracerProvider(s ComposableSampler, res *resource.Resource, options ...TracerProviderOption) *TracerProvider {
+	var cfg tracerProviderConfig
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	if res == nil {
+		res = resource.Default()
+	}
+	var composite compositeConfig
+	for _, opt := range cfg.composite {
+		opt(&composite)
+	}
+	var ids sdktrace.IDGenerator = RandomIDGenerator()
+	if composite.spanID {
+		ids = spanIDRecorder{IDGenerator: ids}
+	}
+	tp := &TracerProvider{
+		spanID:    composite.spanID,
+		resource:  res,
+		sampler:   s,
+		composite: cfg.composite,
+		// Used for spans not started by the provider's tracers,
+		// for which the Scope is unknown.
+		fallback: CompositeSampler(Optimize(s, res, instrumentation.Scope{}), append(cfg.composite[:len(cfg.composite):len(cfg.composite)], WithResource(res))...),
+		tracers:  map[instrumentation.Scope]*optimizedTracer{},
+	}
+	sdkOptions := append(cfg.sdk,
+		sdktrace.WithResource(res),
+		sdktrace.WithIDGenerator(ids),
+		sdktrace.WithSampler(providerSampler{provider: tp}),
+	)
+	tp.TracerProvider = sdktrace.NewTracerProvider(sdkOptions...)
+	return tp
+}
+
+// Tracer implements trace.TracerProvider.
+func (tp *TracerProvider) Tracer(name string, options ...trace.TracerOption) trace.Tracer {
+	tracer := tp.TracerProvider.Tracer(name, options...)
+	cfg := trace.NewTracerConfig(options...)
+	scope := instrumentation.Scope{
+		Name:       name,
+		Version:    cfg.InstrumentationVersion(),
+		SchemaURL:  cfg.SchemaURL(),
+		Attributes: cfg.InstrumentationAttributes(),
+	}
+
+	tp.lock.Lock()
+	defer tp.lock.Unlock()
+	if ot, ok := tp.tracers[scope]; ok {
+		return ot
+	}
+	composite := append(tp.composite[:len(tp.composite):len(tp.composite)], WithResource(tp.resource), WithScope(scope))
+	ot := &optimizedTracer{
+		tracer:  tracer,
+		sampler: CompositeSampler(Optimize(tp.sampler, tp.resource, scope), composite...),
+		spanID:  tp.spanID,
+	}
+	tp.tracers[scope] = ot
+	return ot
+}
+
+// optimizedTracer passes its optimized sampler to the provider's
+// sampler through the context of Start.
+type optimizedTracer struct {
+	embedded.Tracer
+
+	tracer  trace.Tracer
+	sampler Sampler
+	spanID  bool
+}
+
+var _ trace.Tracer = &optimizedTracer{}
+
+type optimizedSamplerKey struct{}
+
+// randomRoot is the parent of root spans, which inherit its flags.
+var randomRoot = trace.SpanContext{}.WithTraceFlags(flagsRandom)
+
+// Start implements trace.Tracer.
+func (ot *optimizedTracer) Start(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	sctx := context.WithValue(ctx, optimizedSamplerKey{}, ot.sampler)
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		sctx = trace.ContextWithSpanContext(sctx, randomRoot)
+	}
+	if ot.spanID {
+		// Filled in by the spanIDRecorder.
+		sctx = ContextWithSpanID(sctx, trace.SpanID{})
+	}
+	_, span := ot.tracer.Start(sctx, name, options...)
+	// The returned context does not carry the sampler, which would
+	// otherwise apply to spans started by other tracers.
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+// providerSampler is the SDK sampler of a TracerProvider, which uses
+// the sampler of the tracer that starts each span.
+type providerSampler struct {
+	provider *TracerProvider
+}
+
+var _ sdktrace.Sampler = providerSampler{}
+
+// ShouldSample implements sdktrace.Sampler.
+func (ps providerSampler) ShouldSample(params sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	s, ok := params.ParentContext.Value(optimizedSamplerKey{}).(Sampler)
+	if !ok {
+		s = ps.provider.fallback
+	}
+	return newSDKSampler(s).ShouldSample(params)
+}
+
+// Description implements sdktrace.Sampler.
+func (ps providerSampler) Description() string {
+	return ps.provider.fallback.Description()
+}
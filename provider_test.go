@@ -0,0 +1,100 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracerProvider(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	res := resource.NewSchemaless(attribute.String("service.name", "checkout"))
+	tp := NewTracerProvider(RuleBased(
+		WithRule(ScopePredicate(instrumentation.Scope{Name: "noisy"}), ComposableNeverSample()),
+		WithRule(ResourceAttributePredicate(attribute.String("service.name", "search")), ComposableNeverSample()),
+		WithDefaultRule(ComposableAlwaysSample()),
+	), res, WithSDKOptions(sdktrace.WithSpanProcessor(recorder)))
+	defer func() { require.NoError(t, tp.Shutdown(context.Background())) }()
+
+	noisy := tp.Tracer("noisy")
+	quiet := tp.Tracer("quiet")
+	require.Same(t, noisy, tp.Tracer("noisy"))
+	require.Equal(t, "RuleBased{rule(Scope{Name==noisy})=AlwaysOff}", noisy.(*optimizedTracer).sampler.Description())
+	require.Equal(t, "RuleBased{rule(true)=AlwaysOn}", quiet.(*optimizedTracer).sampler.Description())
+
+	ctx, root := quiet.Start(context.Background(), "root")
+	require.True(t, root.SpanContext().IsSampled())
+	require.Nil(t, ctx.Value(optimizedSamplerKey{}))
+
+	_, dropped := noisy.Start(ctx, "dropped")
+	require.False(t, dropped.SpanContext().IsSampled())
+
+	// The SDK's own tracers use the sampler optimized for the
+	// Resource only.
+	_, other := tp.TracerProvider.Tracer("noisy").Start(ctx, "other")
+	require.True(t, other.SpanContext().IsSampled())
+
+	other.End()
+	dropped.End()
+	root.End()
+	require.Len(t, recorder.Ended(), 2)
+}
+
+func TestTracerProviderParameters(t *testing.T) {
+	res := resource.NewSchemaless(attribute.String("service.name", "checkout"))
+	var got []ComposableSamplingParameters
+	// A custom predicate that does not implement PredicateOptimizer.
+	record := NewPredicate(func(params ComposableSamplingParameters) bool {
+		got = append(got, params)
+		return true
+	}, "record")
+	tp := NewTracerProvider(RuleBased(WithRule(record, ComposableAlwaysSample())), res)
+	defer func() { require.NoError(t, tp.Shutdown(context.Background())) }()
+
+	ctx, span := tp.Tracer("lib", trace.WithInstrumentationVersion("1.0")).Start(context.Background(), "span")
+	span.End()
+	_, span = tp.TracerProvider.Tracer("sdk").Start(ctx, "span")
+	span.End()
+
+	require.Len(t, got, 2)
+	require.Same(t, res, got[0].Resource)
+	require.Equal(t, instrumentation.Scope{Name: "lib", Version: "1.0"}, got[0].Scope)
+	// The Scope of the SDK's own tracers is unknown.
+	require.Same(t, res, got[1].Resource)
+	require.Equal(t, instrumentation.Scope{}, got[1].Scope)
+
+	// Without the options, they are unset.
+	got = nil
+	CompositeSampler(RuleBased(WithRule(record, ComposableAlwaysSample()))).ShouldSample(SamplingParameters{
+		ParentContext: context.Background(),
+	})
+	require.Len(t, got, 1)
+	require.Nil(t, got[0].Resource)
+}
+
+func TestTracerProviderRandomFlag(t *testing.T) {
+	tp := NewTracerProvider(ComposableAlwaysSample(), nil,
+		WithCompositeOptions(WithRandomFlagRequired(UnreliableRandomness)))
+	tracer := tp.Tracer("test")
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := tracer.Start(ctx, "child")
+	require.NotZero(t, root.SpanContext().TraceFlags()&flagsRandom)
+	require.NotZero(t, child.SpanContext().TraceFlags()&flagsRandom)
+
+	// The child's TraceID is trusted because of the flag.
+	require.Equal(t, "th:0", child.SpanContext().TraceState().Get("ot"))
+
+	// The SDK ignores the context of new roots.
+	_, newRoot := tracer.Start(ctx, "new", trace.WithNewRoot())
+	require.Zero(t, newRoot.SpanContext().TraceFlags()&flagsRandom)
+}
@@ -5,13 +5,17 @@ package sampler
 
 import (
 	"context"
-	"encoding/binary"
 	"fmt"
-	"math"
+	"math/bits"
+	"sort"
 	"strings"
+	"sync/atomic"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -22,6 +26,11 @@ import (
 // - SpanID: controversial because the spec says it's created after ShouldSample()
 // - Scope: controversial because it's a static property
 // - Resource: controversial because it's a static property
+//
+// ComposableSamplingParameters carries the Scope and Resource, which
+// CompositeSampler fills in when configured with WithScope and
+// WithResource, as NewTracerProvider does, and the SpanID, when
+// configured with WithSpanIDParameter.
 type SamplingParameters struct {
 	ParentContext context.Context
 	TraceID       trace.TraceID
@@ -59,10 +68,19 @@ const (
 // In this prototype, we aim to lower the cost of composite sampler
 // decisions by deferring the construction of attributes and tracestate
 // where the decision is combined from multiple samplers.
+//
+// For ExportOnly decisions, the tracestate recorded with the exported
+// span differs from the tracestate propagated to its children: the
+// span's tracestate encodes its threshold, while the propagated
+// tracestate is unchanged so that it does not affect child sampling.
 type SamplingResult struct {
 	Decision   SamplingDecision
 	Attributes []attribute.KeyValue
-	Tracestate trace.TraceState
+	Tracestate trace.TraceState // propagated to children
+
+	// SpanTracestate is the tracestate recorded with the span,
+	// when it differs from Tracestate.  Set for ExportOnly.
+	SpanTracestate trace.TraceState
 }
 
 // ComposableSamplingParameters extend SamplingParameters.
@@ -82,17 +100,77 @@ type ComposableSamplingParameters struct {
 	// multiple predicates will use it.
 	ParentSpanContext trace.SpanContext
 
-	// parentThreshold is only for use by the ParentThreshold
-	// sampler, thus not exported.  When there is no incoming
+	// Baggage equals baggage.FromContext(p.ParentContext), computed
+	// once in case multiple predicates will use it.
+	Baggage baggage.Baggage
+
+	// Resource is the Resource of the tracer starting the span,
+	// when known, see WithResource.  Samplers that implement
+	// SamplerOptimizer should use the Resource passed to Optimize
+	// instead, which is evaluated once.
+	Resource *resource.Resource
+
+	// Scope is the instrumentation Scope of the tracer starting
+	// the span, when known, see WithScope.
+	Scope instrumentation.Scope
+
+	// SpanID is the ID of the span, when it is generated before
+	// the sampling decision, see WithSpanIDParameter.  It is
+	// invalid otherwise.
+	SpanID trace.SpanID
+
+	// parentThreshold is read-only, thus not exported; see the
+	// ParentThreshold method.  When there is no incoming
 	// threshold and sampled, initialize to INVALID_THRESHOLD,
 	// otherwise initialize to NEVER_SAMPLE_THRESHOLD when not
 	// sampled.
-	parentThreshold int64
+	parentThreshold Threshold
 
 	// parentThresholdReliable indicates whether the thresohld
 	// was defined (reliable) or not, because a context had the
 	// sampled flag and no threshold.
 	parentThresholdReliable bool
+
+	// randomness is the 56-bit randomness value, from the
+	// tracestate "rv" sub-key or else the TraceID.  This is not
+	// exported because it cannot be modified by samplers; see
+	// the Randomness method and SamplingIntent.WouldSample.
+	randomness int64
+
+	// otelTraceState is the parsed "ot" tracestate of the parent,
+	// including randomness written by CompositeSampler, if any.
+	otelTraceState otelTraceState
+
+	// thresholdDigits is the number of hex digits of thresholds
+	// written to the tracestate, or zero when they are not limited;
+	// see WithThresholdPrecision.
+	thresholdDigits int
+}
+
+// parentOTelTraceState returns the parsed "ot" tracestate of the
+// parent, parsing it when the parameters were not constructed by
+// CompositeSampler.
+func (p ComposableSamplingParameters) parentOTelTraceState() otelTraceState {
+	if p.otelTraceState.parsed {
+		return p.otelTraceState
+	}
+	return parseOTelTraceState(p.ParentSpanContext.TraceState().Get("ot"))
+}
+
+// ParentThreshold returns the threshold of the parent and whether it
+// is reliable, for samplers outside this package that build
+// consistent logic.  The threshold of a sampled parent without a
+// reliable threshold is INVALID_THRESHOLD, and the threshold of an
+// unsampled parent is unreliable.
+func (p ComposableSamplingParameters) ParentThreshold() (Threshold, bool) {
+	return p.parentThreshold, p.parentThresholdReliable
+}
+
+// Randomness returns the 56-bit randomness value that thresholds are
+// compared with, from the "rv" sub-key of the parent tracestate or
+// else the TraceID.
+func (p ComposableSamplingParameters) Randomness() int64 {
+	return p.randomness
 }
 
 // ComposableSampler is a sampler which separates its intentions from
@@ -115,11 +193,42 @@ type TraceStateFunc func(trace.TraceState) trace.TraceState
 
 // SamplingIntent returns this sampler's intention.
 type SamplingIntent struct {
-	Record            bool           // whether to record
-	Threshold         int64          // i.e., sampling probability, implies record & export when...
-	ThresholdReliable bool           // whether the threshold is reliable
-	Attributes        AttributesFunc // add attributes the span
-	TraceState        TraceStateFunc // update the tracestate
+	Record               bool           // whether to record
+	Threshold            Threshold      // i.e., sampling probability, implies record & export when...
+	ThresholdReliable    bool           // whether the threshold is reliable
+	Attributes           AttributesFunc // add attributes the span, when sampled
+	NonSampledAttributes AttributesFunc // add attributes the span, when recorded and not sampled
+	TraceState           TraceStateFunc // update the tracestate
+	ExportOnly           bool           // when sampled, export without propagating the decision
+}
+
+// WouldSample returns true when this intent, considered on its own,
+// implies sampling the span.  This lets a sampler that is part of a
+// composition determine whether it is responsible for a decision.
+func (i SamplingIntent) WouldSample(params ComposableSamplingParameters) bool {
+	switch {
+	case i.Threshold >= NEVER_SAMPLE_THRESHOLD:
+		return false
+	case i.Threshold <= ALWAYS_SAMPLE_THRESHOLD:
+		return true
+	default:
+		return int64(i.Threshold) <= params.randomness
+	}
+}
+
+// TraceIDRatioOption configures a TraceIDRatioBased sampler.
+type TraceIDRatioOption func(*traceIDRatioConfig)
+
+type traceIDRatioConfig struct {
+	rounding Rounding
+}
+
+// WithRounding sets how the probability is rounded to a threshold.
+// The default is RoundNearest.
+func WithRounding(rounding Rounding) TraceIDRatioOption {
+	return func(cfg *traceIDRatioConfig) {
+		cfg.rounding = rounding
+	}
 }
 
 // TraceIDRatioBased is the OTel-specified probabilistic sampler. This was
@@ -127,12 +236,11 @@ type SamplingIntent struct {
 //
 // Note: Add support for variable precision? This has been done in e.g.,
 // https://github.com/open-telemetry/opentelemetry-collector-contrib/blob/9b515fb83b3f010c4c37f3135caf535e391fb3a3/pkg/sampling/probability.go#L33
-func TraceIDRatioBased(fraction float64) ComposableSampler {
-	const (
-		maxp  = 14                       // maximum precision is 56 bits
-		defp  = defaultSamplingPrecision // default precision
-		hbits = 4                        // bits per hex digit
-	)
+func TraceIDRatioBased(fraction float64, options ...TraceIDRatioOption) ComposableSampler {
+	var cfg traceIDRatioConfig
+	for _, opt := range options {
+		opt(&cfg)
+	}
 
 	if fraction > maxSupportedProbability {
 		return ComposableAlwaysSample()
@@ -142,40 +250,108 @@ func TraceIDRatioBased(fraction float64) ComposableSampler {
 		return ComposableNeverSample()
 	}
 
-	// Calculate the amount of precision needed to encode the
-	// threshold with reasonable precision.
-	//
-	// 13 hex digits is the maximum reasonable precision, since
-	// that equals 52 bits, the number of bits in the float64
-	// significand.
-	//
-	// Frexp() normalizes both the fraction and one-minus the
-	// fraction, because more digits of precision are needed in
-	// both cases -- in these cases the threshold has all leading
-	// '0' or 'f' characters.
-	//
-	// We know that `exp <= 0`.  If `exp <= -4`, there will be a
-	// leading hex `0` or `f`.  For every multiple of -4, another
-	// leading `0` or `f` appears, so this raises precision
-	// accordingly.
-	_, expF := math.Frexp(fraction)
-	precision := min(maxp, defp+expF/-hbits)
+	desc := fmt.Sprintf("TraceIDRatioBased{%g}", fraction)
+	if cfg.rounding != RoundNearest {
+		desc = fmt.Sprintf("TraceIDRatioBased{%g,%s}", fraction, cfg.rounding)
+	}
+	return &traceIDRatio{
+		threshold:   ProbabilityToThresholdRounded(fraction, cfg.rounding),
+		description: desc,
+	}
+}
 
-	// Compute the threshold
-	scaled := uint64(math.Round(fraction * float64(maxAdjustedCount)))
-	threshold := maxAdjustedCount - scaled
+// TraceIDRatioBasedN samples one in n spans, like TraceIDRatioBased
+// with fraction 1/n, except that the threshold is computed exactly
+// using integer arithmetic, which avoids rounding artifacts when n is
+// not a power of two.  n equal to zero or greater than 2^56 never
+// samples.
+func TraceIDRatioBasedN(n uint64, options ...TraceIDRatioOption) ComposableSampler {
+	var cfg traceIDRatioConfig
+	for _, opt := range options {
+		opt(&cfg)
+	}
 
-	// Round to the specified precision, if less than the maximum.
-	if shift := hbits * (maxp - precision); shift != 0 {
-		half := uint64(1) << (shift - 1)
-		threshold += half
-		threshold >>= shift
-		threshold <<= shift
+	switch {
+	case n == 1:
+		return ComposableAlwaysSample()
+	case n == 0 || n > maxAdjustedCount:
+		return ComposableNeverSample()
 	}
 
+	desc := fmt.Sprintf("TraceIDRatioBasedN{%d}", n)
+	if cfg.rounding != RoundNearest {
+		desc = fmt.Sprintf("TraceIDRatioBasedN{%d,%s}", n, cfg.rounding)
+	}
+	return &traceIDRatio{
+		threshold:   oneInNThreshold(n, cfg.rounding),
+		description: desc,
+	}
+}
+
+// TraceIDRatioBasedRational samples with probability num/den, like
+// TraceIDRatioBased, except that the threshold is computed exactly
+// from the fraction using integer arithmetic, so that a configured
+// probability such as 1/1000 is not subject to floating-point
+// rounding.  Probabilities less than 2^-56, including those with
+// zero den, never sample.
+func TraceIDRatioBasedRational(num, den uint64, options ...TraceIDRatioOption) ComposableSampler {
+	var cfg traceIDRatioConfig
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	switch hi, lo := bits.Mul64(num, maxAdjustedCount); {
+	case den == 0 || (hi == 0 && lo < den):
+		return ComposableNeverSample()
+	case num >= den:
+		return ComposableAlwaysSample()
+	}
+
+	desc := fmt.Sprintf("TraceIDRatioBased{%d/%d}", num, den)
+	if cfg.rounding != RoundNearest {
+		desc = fmt.Sprintf("TraceIDRatioBased{%d/%d,%s}", num, den, cfg.rounding)
+	}
+	return &traceIDRatio{
+		threshold:   rationalThreshold(num, den, cfg.rounding),
+		description: desc,
+	}
+}
+
+// TraceIDRatioFromAdjustedCount samples with probability 1/n, for a
+// power of two n, such that sampled spans have an adjusted count of
+// exactly n and aggregates weighted by adjusted count are integers.
+// Other values of n are reported via otel.Handle and rounded down to
+// a power of two, at most 2^56.  Zero n never samples.
+func TraceIDRatioFromAdjustedCount(n uint64) ComposableSampler {
+	if n == 0 {
+		return ComposableNeverSample()
+	}
+	if n&(n-1) != 0 || n > maxAdjustedCount {
+		otel.Handle(fmt.Errorf("adjusted count is not a power of two: %d", n))
+		n = min(uint64(1)<<(bits.Len64(n)-1), maxAdjustedCount)
+	}
+	if n == 1 {
+		return ComposableAlwaysSample()
+	}
+	return &traceIDRatio{
+		threshold:   Threshold(maxAdjustedCount - maxAdjustedCount/n),
+		description: fmt.Sprintf("TraceIDRatioFromAdjustedCount{%d}", n),
+	}
+}
+
+// TraceIDRatioFromThreshold samples using an exact threshold, for
+// example one parsed by ParseThreshold.  The never-sample and invalid
+// thresholds never sample.
+func TraceIDRatioFromThreshold(threshold Threshold) ComposableSampler {
+	switch {
+	case !threshold.IsValid() || threshold == NEVER_SAMPLE_THRESHOLD:
+		return ComposableNeverSample()
+	case threshold == ALWAYS_SAMPLE_THRESHOLD:
+		return ComposableAlwaysSample()
+	}
 	return &traceIDRatio{
 		threshold:   threshold,
-		description: fmt.Sprintf("TraceIDRatioBased{%g}", fraction),
+		description: fmt.Sprintf("TraceIDRatioFromThreshold{%s}", threshold),
 	}
 }
 
@@ -184,7 +360,7 @@ type traceIDRatio struct {
 	// Select when (T <= R)
 	// Drop when (T > R)
 	// Range is [0, 1<<56).
-	threshold   uint64
+	threshold   Threshold
 	description string
 }
 
@@ -198,7 +374,7 @@ func (ts *traceIDRatio) Description() string {
 // GetSamplingIntent implements ComposableSampler.
 func (ts *traceIDRatio) GetSamplingIntent(p ComposableSamplingParameters) SamplingIntent {
 	return SamplingIntent{
-		Threshold:         int64(ts.threshold),
+		Threshold:         ts.threshold,
 		ThresholdReliable: true,
 	}
 }
@@ -278,55 +454,145 @@ func (alwaysOff) Description() string {
 }
 
 // RuleBased is a composite sampler that selects a delegate sampler based on a set of rules.
+//
+// By default, the first matching rule decides.  With the
+// WithCombineMatching option, the intents of all matching rules are
+// combined.
 func RuleBased(options ...RuleBasedOption) ComposableSampler {
 	rbc := &ruleBasedConfig{}
 	for _, opt := range options {
 		opt(rbc)
 	}
+	// Higher priorities first, otherwise in construction order.
+	sort.SliceStable(rbc.rules, func(i, j int) bool {
+		return rbc.rules[i].priority > rbc.rules[j].priority
+	})
 	if rbc.defRule != nil {
 		rbc.rules = append(rbc.rules, ruleAndPredicate{
 			Predicate:         TruePredicate(),
 			ComposableSampler: rbc.defRule,
 		})
 	}
-	return ruleBased(rbc.rules)
+	for i := range rbc.rules {
+		rbc.rules[i].stats = &ruleStats{}
+	}
+	return ruleBased{
+		rules:   rbc.rules,
+		combine: rbc.combine,
+	}
 }
 
 type ruleAndPredicate struct {
 	Predicate
 	ComposableSampler
+	priority int
+	stats    *ruleStats
+}
+
+// ruleStats are shared by optimized copies of a rule.
+type ruleStats struct {
+	matched atomic.Uint64
+	sampled atomic.Uint64
+}
+
+// RuleStats are the runtime statistics of one RuleBased rule.
+type RuleStats struct {
+	// Description describes the rule's predicate and sampler.
+	Description string
+	// Priority is the rule's priority.
+	Priority int
+	// Matched counts spans for which the rule's predicate was true.
+	Matched uint64
+	// Sampled counts matched spans that the rule's sampler would sample.
+	Sampled uint64
+}
+
+// RuleStatsProvider is implemented by RuleBased samplers.
+type RuleStatsProvider interface {
+	// Stats returns statistics for each rule in evaluation order.
+	Stats() []RuleStats
 }
 
 type ruleBasedConfig struct {
 	rules   []ruleAndPredicate
 	defRule ComposableSampler
+	combine bool
 }
 
-type ruleBased []ruleAndPredicate
+type ruleBased struct {
+	rules   []ruleAndPredicate
+	combine bool
+}
 
 var _ ComposableSampler = &ruleBased{}
+var _ RuleStatsProvider = &ruleBased{}
+var _ CostStatsProvider = &ruleBased{}
+
+// describe returns the description of one rule.
+func (rule ruleAndPredicate) describe() string {
+	return fmt.Sprintf("rule(%s)=%s",
+		rule.Predicate.Description(),
+		rule.ComposableSampler.Description(),
+	)
+}
+
+// intent returns the rule's intent and updates its statistics.
+func (rule ruleAndPredicate) intent(params ComposableSamplingParameters) SamplingIntent {
+	intent := rule.ComposableSampler.GetSamplingIntent(params)
+	rule.stats.matched.Add(1)
+	if intent.WouldSample(params) {
+		rule.stats.sampled.Add(1)
+	}
+	return intent
+}
+
+// Stats implements RuleStatsProvider.
+func (rb ruleBased) Stats() []RuleStats {
+	stats := make([]RuleStats, len(rb.rules))
+	for i, rule := range rb.rules {
+		stats[i] = RuleStats{
+			Description: rule.describe(),
+			Priority:    rule.priority,
+			Matched:     rule.stats.matched.Load(),
+			Sampled:     rule.stats.sampled.Load(),
+		}
+	}
+	return stats
+}
+
+// CostStats implements CostStatsProvider, returning the statistics of
+// the rules' samplers that implement it, in evaluation order.
+func (rb ruleBased) CostStats() []CostStats {
+	var stats []CostStats
+	for _, rule := range rb.rules {
+		stats = append(stats, costStatsOf(rule.ComposableSampler)...)
+	}
+	return stats
+}
 
 // Description implements ComposableSampler.
 func (rb ruleBased) Description() string {
-	return fmt.Sprintf("RuleBased{%s}",
+	name := "RuleBased"
+	if rb.combine {
+		name = "RuleBasedAll"
+	}
+	return fmt.Sprintf("%s{%s}", name,
 		strings.Join(func(rules []ruleAndPredicate) (desc []string) {
 			for _, rule := range rules {
-				desc = append(desc,
-					fmt.Sprintf("rule(%s)=%s",
-						rule.Predicate.Description(),
-						rule.ComposableSampler.Description(),
-					),
-				)
+				desc = append(desc, rule.describe())
 			}
 			return
-		}(rb), ","))
+		}(rb.rules), ","))
 }
 
 // GetSamplingIntent implements ComposableSampler.
 func (rb ruleBased) GetSamplingIntent(params ComposableSamplingParameters) SamplingIntent {
-	for _, rule := range rb {
+	if rb.combine {
+		return rb.combineMatching(params)
+	}
+	for _, rule := range rb.rules {
 		if rule.Decide(params) {
-			return rule.ComposableSampler.GetSamplingIntent(params)
+			return rule.intent(params)
 		}
 	}
 
@@ -337,6 +603,19 @@ func (rb ruleBased) GetSamplingIntent(params ComposableSamplingParameters) Sampl
 	}
 }
 
+// combineMatching combines the intents of every matching rule.
+func (rb ruleBased) combineMatching(params ComposableSamplingParameters) SamplingIntent {
+	combined := SamplingIntent{
+		Threshold: NEVER_SAMPLE_THRESHOLD,
+	}
+	for _, rule := range rb.rules {
+		if rule.Decide(params) {
+			combined = UnionIntents(combined, rule.intent(params))
+		}
+	}
+	return combined
+}
+
 // ComposableParentBased combines a root sampler and a ParentThreshold.
 func ComposableParentBased(root ComposableSampler) ComposableSampler {
 	return RuleBased(
@@ -345,6 +624,51 @@ func ComposableParentBased(root ComposableSampler) ComposableSampler {
 	)
 }
 
+// ParentRatioBased samples a fraction of the spans that the parent
+// sampled, with the probability of the parent threshold multiplied by
+// fraction (see ComposeThresholds), for downstream re-sampling.
+// Because the threshold only increases, the sampled spans are a
+// subset of those the parent sampled, and adjusted counts remain
+// exact.  When the parent was sampled without a threshold, the
+// fraction is applied and the threshold is unreliable.  Root spans
+// are not sampled; see ComposableParentBased.
+func ParentRatioBased(fraction float64) ComposableSampler {
+	return &parentRatio{
+		fraction:  fraction,
+		threshold: ProbabilityToThreshold(fraction),
+	}
+}
+
+type parentRatio struct {
+	fraction  float64
+	threshold Threshold
+}
+
+var _ ComposableSampler = &parentRatio{}
+
+// GetSamplingIntent implements ComposableSampler.
+func (pr *parentRatio) GetSamplingIntent(params ComposableSamplingParameters) SamplingIntent {
+	if !params.parentThresholdReliable {
+		if params.parentThreshold == INVALID_THRESHOLD {
+			return SamplingIntent{
+				Threshold: pr.threshold,
+			}
+		}
+		return SamplingIntent{
+			Threshold: NEVER_SAMPLE_THRESHOLD,
+		}
+	}
+	return SamplingIntent{
+		Threshold:         ComposeThresholds(params.parentThreshold, pr.threshold),
+		ThresholdReliable: true,
+	}
+}
+
+// Description implements ComposableSampler.
+func (pr *parentRatio) Description() string {
+	return fmt.Sprintf("ParentRatioBased{%g}", pr.fraction)
+}
+
 // ParentThreshold may be composed to form consistent parent-based sampling.
 func ParentThreshold() ComposableSampler {
 	return parentThreshold{}
@@ -372,15 +696,39 @@ func (parentThreshold) Description() string {
 type AnnotatingOption func(*annotatingConfig)
 
 type annotatingConfig struct {
-	attributes AttributesFunc
+	attributes    AttributesFunc
+	nonSampled    AttributesFunc
+	traceState    TraceStateFunc
+	traceKeys     []string
+	ifWouldSample bool
+
+	// sampledValues and nonSampledValues are the attributes set
+	// by WithSampledAttributeValues and
+	// WithNonSampledAttributeValues, and sampledFunctions and
+	// nonSampledFunctions are set when attributes are also set by
+	// functions.  See Spec.
+	sampledValues       []attribute.KeyValue
+	nonSampledValues    []attribute.KeyValue
+	sampledFunctions    bool
+	nonSampledFunctions bool
 }
 
 type annotatingSampler struct {
-	sampler    ComposableSampler
-	attributes AttributesFunc
+	sampler       ComposableSampler
+	attributes    AttributesFunc
+	nonSampled    AttributesFunc
+	traceState    TraceStateFunc
+	traceKeys     []string
+	ifWouldSample bool
+
+	sampledValues       []attribute.KeyValue
+	nonSampledValues    []attribute.KeyValue
+	sampledFunctions    bool
+	nonSampledFunctions bool
 }
 
 var _ ComposableSampler = &annotatingSampler{}
+var _ CostStatsProvider = &annotatingSampler{}
 
 func AnnotatingSampler(sampler ComposableSampler, options ...AnnotatingOption) ComposableSampler {
 	var config annotatingConfig
@@ -388,12 +736,26 @@ func AnnotatingSampler(sampler ComposableSampler, options ...AnnotatingOption) C
 		opt(&config)
 	}
 	return &annotatingSampler{
-		sampler:    sampler,
-		attributes: config.attributes,
+		sampler:       sampler,
+		attributes:    config.attributes,
+		nonSampled:    config.nonSampled,
+		traceState:    config.traceState,
+		traceKeys:     config.traceKeys,
+		ifWouldSample: config.ifWouldSample,
+
+		sampledValues:       config.sampledValues,
+		nonSampledValues:    config.nonSampledValues,
+		sampledFunctions:    config.sampledFunctions,
+		nonSampledFunctions: config.nonSampledFunctions,
 	}
 }
 
-func combineAttributesFunc(one, two AttributesFunc) AttributesFunc {
+// CombineAttributes returns a function that returns the attributes of
+// both functions, either of which may be nil.  Neither function is
+// called until the result is called.  When both are set, their
+// attributes are copied to a new slice, since the slices they return
+// may be shared.
+func CombineAttributes(one, two AttributesFunc) AttributesFunc {
 	return func() []attribute.KeyValue {
 		if one == nil && two == nil {
 			return nil
@@ -404,38 +766,492 @@ func combineAttributesFunc(one, two AttributesFunc) AttributesFunc {
 		if two == nil {
 			return one()
 		}
-		return append(one(), two()...)
+		first, second := one(), two()
+		attrs := make([]attribute.KeyValue, 0, len(first)+len(second))
+		attrs = append(attrs, first...)
+		return append(attrs, second...)
 	}
 }
 
+// CombineTraceState returns a function that applies both
+// functions in order, either of which may be nil.
+func CombineTraceState(one, two TraceStateFunc) TraceStateFunc {
+	if one == nil {
+		return two
+	}
+	if two == nil {
+		return one
+	}
+	return func(ts trace.TraceState) trace.TraceState {
+		return two(one(ts))
+	}
+}
+
+// UnionIntents returns the intent to sample with the lesser of two
+// thresholds, i.e., with the greater probability, having the
+// side-effects of both.  The result samples when either intent
+// samples.  This is how RuleBased combines the intents of rules that
+// match.
+func UnionIntents(one, two SamplingIntent) SamplingIntent {
+	combined := SamplingIntent{
+		Record:     one.Record || two.Record,
+		Attributes: CombineAttributes(one.Attributes, two.Attributes),
+		TraceState: CombineTraceState(one.TraceState, two.TraceState),
+
+		NonSampledAttributes: CombineAttributes(one.NonSampledAttributes, two.NonSampledAttributes),
+	}
+	switch {
+	case one.Threshold < two.Threshold:
+		combined.Threshold = one.Threshold
+		combined.ThresholdReliable = one.ThresholdReliable
+		combined.ExportOnly = one.ExportOnly
+	case two.Threshold < one.Threshold:
+		combined.Threshold = two.Threshold
+		combined.ThresholdReliable = two.ThresholdReliable
+		combined.ExportOnly = two.ExportOnly
+	default:
+		combined.Threshold = one.Threshold
+		combined.ThresholdReliable = one.ThresholdReliable || two.ThresholdReliable
+		combined.ExportOnly = one.ExportOnly && two.ExportOnly
+	}
+	return combined
+}
+
+// IntersectIntents returns the intent to sample with the greater of
+// two thresholds, i.e., with the lesser probability, having the
+// side-effects of both.  The result samples only when both intents
+// sample, and records only when both intents record.  On equal
+// thresholds, the result is ExportOnly when either intent is.
+func IntersectIntents(one, two SamplingIntent) SamplingIntent {
+	combined := SamplingIntent{
+		Record:     one.Record && two.Record,
+		Attributes: CombineAttributes(one.Attributes, two.Attributes),
+		TraceState: CombineTraceState(one.TraceState, two.TraceState),
+
+		NonSampledAttributes: CombineAttributes(one.NonSampledAttributes, two.NonSampledAttributes),
+	}
+	switch {
+	case one.Threshold > two.Threshold:
+		combined.Threshold = one.Threshold
+		combined.ThresholdReliable = one.ThresholdReliable
+		combined.ExportOnly = one.ExportOnly
+	case two.Threshold > one.Threshold:
+		combined.Threshold = two.Threshold
+		combined.ThresholdReliable = two.ThresholdReliable
+		combined.ExportOnly = two.ExportOnly
+	default:
+		combined.Threshold = one.Threshold
+		combined.ThresholdReliable = one.ThresholdReliable || two.ThresholdReliable
+		combined.ExportOnly = one.ExportOnly || two.ExportOnly
+	}
+	return combined
+}
+
 func WithSampledAttributes(af AttributesFunc) AnnotatingOption {
 	return func(cfg *annotatingConfig) {
-		cfg.attributes = combineAttributesFunc(cfg.attributes, af)
+		cfg.attributes = CombineAttributes(cfg.attributes, af)
+		cfg.sampledFunctions = true
+	}
+}
+
+// WithNonSampledAttributes adds attributes to spans that are recorded
+// but not sampled.
+func WithNonSampledAttributes(af AttributesFunc) AnnotatingOption {
+	return func(cfg *annotatingConfig) {
+		cfg.nonSampled = CombineAttributes(cfg.nonSampled, af)
+		cfg.nonSampledFunctions = true
+	}
+}
+
+// WithSampledAttributeValues adds fixed attributes to sampled spans.
+// Unlike those of WithSampledAttributes, the attributes are part of
+// the sampler's Spec.
+func WithSampledAttributeValues(attrs ...attribute.KeyValue) AnnotatingOption {
+	attrs = append([]attribute.KeyValue(nil), attrs...)
+	return func(cfg *annotatingConfig) {
+		cfg.attributes = CombineAttributes(cfg.attributes, func() []attribute.KeyValue {
+			return attrs
+		})
+		cfg.sampledValues = append(cfg.sampledValues, attrs...)
+	}
+}
+
+// WithNonSampledAttributeValues adds fixed attributes to spans that
+// are recorded but not sampled, as WithSampledAttributeValues does for
+// sampled spans.
+func WithNonSampledAttributeValues(attrs ...attribute.KeyValue) AnnotatingOption {
+	attrs = append([]attribute.KeyValue(nil), attrs...)
+	return func(cfg *annotatingConfig) {
+		cfg.nonSampled = CombineAttributes(cfg.nonSampled, func() []attribute.KeyValue {
+			return attrs
+		})
+		cfg.nonSampledValues = append(cfg.nonSampledValues, attrs...)
+	}
+}
+
+// WithTraceStateEntry sets a vendor tracestate entry on sampled
+// spans, for example to propagate a policy identifier.  The "ot" key
+// is reserved for the sampling threshold and cannot be used; the
+// entry is reported to otel.Handle once and dropped.
+func WithTraceStateEntry(key string, value func() string) AnnotatingOption {
+	if key == "ot" {
+		otel.Handle(fmt.Errorf("tracestate: %q key is reserved", key))
+		return func(*annotatingConfig) {}
+	}
+	return func(cfg *annotatingConfig) {
+		cfg.traceKeys = append(cfg.traceKeys, key)
+		cfg.traceState = CombineTraceState(cfg.traceState, func(ts trace.TraceState) trace.TraceState {
+			mod, err := ts.Insert(key, value())
+			if err != nil {
+				otel.Handle(fmt.Errorf("tracestate: %w", err))
+				return ts
+			}
+			return mod
+		})
+	}
+}
+
+// WithAttributesIfWouldSample adds the sampled attributes and
+// tracestate entries only when the annotated sampler itself would
+// sample the span.  In a
+// composition such as RuleBased with WithCombineMatching, this marks
+// spans with the policies that caused them to be sampled, as opposed
+// to every policy that matched.
+func WithAttributesIfWouldSample() AnnotatingOption {
+	return func(cfg *annotatingConfig) {
+		cfg.ifWouldSample = true
 	}
 }
 
 // GetSamplingIntent implements ComposableSampler.
 func (as annotatingSampler) GetSamplingIntent(params ComposableSamplingParameters) SamplingIntent {
 	intent := as.sampler.GetSamplingIntent(params)
-	intent.Attributes = combineAttributesFunc(intent.Attributes, as.attributes)
+	if !as.ifWouldSample || intent.WouldSample(params) {
+		intent.Attributes = CombineAttributes(intent.Attributes, as.attributes)
+		intent.TraceState = CombineTraceState(intent.TraceState, as.traceState)
+	}
+	if as.nonSampled != nil {
+		intent.NonSampledAttributes = CombineAttributes(intent.NonSampledAttributes, as.nonSampled)
+	}
 	return intent
 }
 
 // Description implements ComposableSampler.
 func (as annotatingSampler) Description() string {
-	set := attribute.NewSet(as.attributes()...)
-	return fmt.Sprintf("Annotate(%s, %s)", as.sampler.Description(), attribute.DefaultEncoder().Encode(set.Iter()))
+	encode := func(af AttributesFunc) string {
+		if af == nil {
+			return ""
+		}
+		set := attribute.NewSet(af()...)
+		return attribute.DefaultEncoder().Encode(set.Iter())
+	}
+	var extra string
+	if as.nonSampled != nil {
+		extra += fmt.Sprintf(", unsampled(%s)", encode(as.nonSampled))
+	}
+	if len(as.traceKeys) != 0 {
+		extra += fmt.Sprintf(", tracestate(%s)", strings.Join(as.traceKeys, ","))
+	}
+	if as.ifWouldSample {
+		extra += ", ifWouldSample"
+	}
+	return fmt.Sprintf("Annotate(%s, %s%s)", as.sampler.Description(), encode(as.attributes), extra)
+}
+
+// CostStats implements CostStatsProvider, returning the statistics of
+// the wrapped sampler, if any.
+func (as annotatingSampler) CostStats() []CostStats {
+	return costStatsOf(as.sampler)
+}
+
+// CompositeOption configures a CompositeSampler.
+type CompositeOption func(*compositeConfig)
+
+type compositeConfig struct {
+	explicitRandomness     bool
+	requireRandomFlag      bool
+	missingRandomness      MissingRandomnessPolicy
+	traceIDRandomness      func(trace.TraceID) int64
+	eviction               TraceStateEvictionPolicy
+	errorHandler           func(error)
+	errorAttribute         bool
+	legacyProbability      bool
+	legacyRandomness       bool
+	legacySubKeys          bool
+	thresholdDigits        int
+	sanitizer              *traceStateSanitizer
+	inconsistencyHandler   func(Inconsistency)
+	extractors             []ThresholdExtractor
+	strictTraceState       bool
+	shortTraceIDs          bool
+	shortTraceIDPolicy     MissingRandomnessPolicy
+	rootThreshold          bool
+	unknownParentThreshold ComposableSampler
+	resource               *resource.Resource
+	scope                  instrumentation.Scope
+	spanID                 bool
+	probabilityAttribute   bool
+	exportOnlyRecording    bool
+}
+
+// flagsRandom is the W3C Trace Context Level 2 random trace flag,
+// indicating that the TraceID has 56 bits of randomness.
+const flagsRandom = trace.TraceFlags(0x02)
+
+// MissingRandomnessPolicy determines what a CompositeSampler does when
+// a span has neither explicit randomness nor a TraceID known to be
+// random.  See WithRandomFlagRequired.
+type MissingRandomnessPolicy int
+
+const (
+	// UseTraceIDRandomness uses the TraceID as if it were random.
+	UseTraceIDRandomness MissingRandomnessPolicy = iota
+
+	// GenerateRandomness generates a random value and writes it as
+	// the "rv" sub-key of the OpenTelemetry tracestate, as
+	// WithExplicitRandomness does for root spans.
+	GenerateRandomness
+
+	// UnreliableRandomness uses the TraceID to make a decision,
+	// but the resulting threshold is unreliable and is not written
+	// to the tracestate.
+	UnreliableRandomness
+)
+
+// WithExplicitRandomness generates a random value for each root span
+// and writes it as the "rv" sub-key of the OpenTelemetry tracestate,
+// for use when TraceIDs cannot be trusted to be random.  The
+// generated value is used in place of the TraceID randomness by this
+// and all downstream consistent samplers.
+func WithExplicitRandomness() CompositeOption {
+	return func(cfg *compositeConfig) {
+		cfg.explicitRandomness = true
+	}
+}
+
+// WithRandomFlagRequired trusts the TraceID of a span with a parent
+// to be random only when the parent has the W3C random trace flag, and
+// otherwise requires the parent tracestate to have an "rv" sub-key.
+// The policy determines what to do when neither is available.  The
+// TraceIDs of root spans are trusted unless WithExplicitRandomness is
+// set.
+func WithRandomFlagRequired(policy MissingRandomnessPolicy) CompositeOption {
+	return func(cfg *compositeConfig) {
+		cfg.requireRandomFlag = true
+		cfg.missingRandomness = policy
+	}
+}
+
+// WithShortTraceIDPolicy sets the policy for TraceIDs whose
+// most-significant 64 bits are zero, as created by bridges from
+// systems with 64-bit trace IDs, when there is no "rv" sub-key.  With
+// UseTraceIDRandomness, the least-significant 56 bits are used as
+// randomness, regardless of WithTraceIDRandomnessBits and
+// WithHashedTraceIDRandomness.  Other policies apply as described for
+// WithRandomFlagRequired.
+func WithShortTraceIDPolicy(policy MissingRandomnessPolicy) CompositeOption {
+	return func(cfg *compositeConfig) {
+		cfg.shortTraceIDs = true
+		cfg.shortTraceIDPolicy = policy
+	}
+}
+
+// WithTraceIDRandomnessBits configures which bits of the TraceID are
+// used as randomness, for ID generators that do not place 56 random
+// bits in the least-significant 7 bytes, as W3C Trace Context Level 2
+// specifies.  The offset counts bits from the most-significant bit of
+// the TraceID, and width is at most 56.  Fewer than 56 bits are
+// scaled to the full range of randomness values, which limits the
+// precision of sampling probabilities.  The default is offset 72 and
+// width 56.  Invalid arguments are reported via otel.Handle and
+// ignored.
+func WithTraceIDRandomnessBits(offset, width int) CompositeOption {
+	return func(cfg *compositeConfig) {
+		if width < 1 || width > 56 || offset < 0 || offset+width > 128 {
+			otel.Handle(fmt.Errorf("invalid TraceID randomness bits: offset %d width %d", offset, width))
+			return
+		}
+		cfg.traceIDRandomness = func(id trace.TraceID) int64 {
+			return traceIDBits(id, offset, width)
+		}
+	}
+}
+
+// WithHashedTraceIDRandomness derives randomness from a SipHash-2-4
+// of the whole TraceID with the given key, for ID generators whose
+// TraceIDs are not uniformly random, which restores unbiased sampling
+// without propagating "rv".  Every service in a trace must use the
+// same key to make consistent decisions.  This replaces
+// WithTraceIDRandomnessBits.
+func WithHashedTraceIDRandomness(key [16]byte) CompositeOption {
+	return func(cfg *compositeConfig) {
+		cfg.traceIDRandomness = hashedTraceIDRandomness(key)
+	}
+}
+
+// WithTraceStateEviction sets the policy for removing tracestate
+// members when the tracestate exceeds the W3C limits of 512
+// characters and 32 members.  The default is EvictLargeThenOldest.
+func WithTraceStateEviction(policy TraceStateEvictionPolicy) CompositeOption {
+	return func(cfg *compositeConfig) {
+		cfg.eviction = policy
+	}
+}
+
+// SamplingTraceStateErrorKey is the attribute holding the error
+// parsing a span's parent OpenTelemetry tracestate.  See
+// WithTraceStateErrorAttribute.
+const SamplingTraceStateErrorKey = attribute.Key("sampling.tracestate.error")
+
+// WithTraceStateErrorHandler sends errors parsing the OpenTelemetry
+// tracestate of parent contexts to handler, instead of otel.Handle, so
+// that malformed tracestate can be counted or alerted on.  The handler
+// is called synchronously in the sampling decision.
+func WithTraceStateErrorHandler(handler func(error)) CompositeOption {
+	return func(cfg *compositeConfig) {
+		cfg.errorHandler = handler
+	}
+}
+
+// WithTraceStateErrorAttribute adds the SamplingTraceStateErrorKey
+// attribute to recorded spans whose parent OpenTelemetry tracestate
+// could not be parsed.
+func WithTraceStateErrorAttribute() CompositeOption {
+	return func(cfg *compositeConfig) {
+		cfg.errorAttribute = true
+	}
+}
+
+// WithProbabilityAttribute adds the SamplingProbabilityKey attribute
+// to sampled spans with a reliable threshold, holding the sampling
+// probability of the threshold written to the tracestate, for
+// backends that read attributes but not tracestate.
+func WithProbabilityAttribute() CompositeOption {
+	return func(cfg *compositeConfig) {
+		cfg.probabilityAttribute = true
+	}
+}
+
+// WithLegacyProbability reads the deprecated "p" sub-key of the
+// OpenTelemetry tracestate, a power-of-two sampling probability
+// written by the earlier consistent-probability samplers, as the
+// parent threshold when there is no "th" sub-key.  This supports
+// fleets that are migrating from those samplers.  The "p" sub-key is
+// not modified, and a "th" sub-key is written as usual.
+//
+// Since those samplers decided against the geometric randomness of
+// the "r" sub-key, not the TraceID, this option also enables
+// WithLegacyRandomness, so that the parent threshold is consistent
+// with the randomness it was decided against.
+func WithLegacyProbability() CompositeOption {
+	return func(cfg *compositeConfig) {
+		cfg.legacyProbability = true
+		cfg.legacyRandomness = true
+	}
+}
+
+// WithThresholdPrecision limits the number of hex digits written to
+// the "th" sub-key of the OpenTelemetry tracestate, from 1 to 14, to
+// reduce the size of the tracestate.  Decisions use the full
+// threshold; the written threshold is rounded down, which raises the
+// probability it represents, so that it remains consistent with the
+// decision.  Invalid arguments are reported via otel.Handle and
+// ignored.
+func WithThresholdPrecision(digits int) CompositeOption {
+	return func(cfg *compositeConfig) {
+		if digits < 1 || digits > 14 {
+			otel.Handle(fmt.Errorf("invalid threshold precision: %d", digits))
+			return
+		}
+		cfg.thresholdDigits = digits
+	}
+}
+
+// WithUnknownParentThreshold determines the parent threshold of spans
+// whose parent is sampled without a threshold, which otherwise is
+// unknown, so that ParentThreshold yields an unreliable threshold.
+// The sampler's intent is used as the parent threshold, for example
+// ComposableAlwaysSample() to assume the parent was always sampled,
+// or TraceIDRatioBased() to assume a default probability.  The
+// intent is ignored when its threshold is unreliable or would not
+// sample the span, since the parent was sampled.
+func WithUnknownParentThreshold(sampler ComposableSampler) CompositeOption {
+	return func(cfg *compositeConfig) {
+		cfg.unknownParentThreshold = sampler
+	}
+}
+
+// WithRootThreshold writes the threshold of every sampled root span to
+// the "th" sub-key of the OpenTelemetry tracestate, including
+// thresholds that are not reliable, for example because the
+// randomness is not reliable, so that every sampled span in a trace
+// carries a threshold.  An unreliable threshold may not equal the
+// actual sampling probability of the span.
+func WithRootThreshold() CompositeOption {
+	return func(cfg *compositeConfig) {
+		cfg.rootThreshold = true
+	}
+}
+
+// WithStrictTraceState disables the default tolerance for whitespace
+// around the sub-keys of the OpenTelemetry tracestate, and preserves
+// the order of sub-keys instead of writing "rv" first and "th"
+// second.  Sub-keys with surrounding whitespace are then not found,
+// as though absent, and no error is reported.
+func WithStrictTraceState() CompositeOption {
+	return func(cfg *compositeConfig) {
+		cfg.strictTraceState = true
+	}
+}
+
+// ThresholdExtractor derives the parent threshold from a parent span
+// context with no "th" sub-key, typically from the tracestate member
+// of another vendor.  It returns false when it does not apply.
+type ThresholdExtractor func(parent trace.SpanContext) (Threshold, bool)
+
+// WithThresholdExtractors derives the parent threshold using the
+// extractors, in order, when the parent OpenTelemetry tracestate has
+// no threshold, so that ParentThreshold can be used in environments
+// where other systems make the upstream sampling decisions.  The
+// derived threshold is validated against the sampled flag and written
+// to the "th" sub-key as usual.
+func WithThresholdExtractors(extractors ...ThresholdExtractor) CompositeOption {
+	return func(cfg *compositeConfig) {
+		cfg.extractors = append(cfg.extractors, extractors...)
+	}
 }
 
 // CompositeSampler construct a Sampler from a ComposableSampler.
-func CompositeSampler(s ComposableSampler) Sampler {
+func CompositeSampler(s ComposableSampler, options ...CompositeOption) Sampler {
+	cfg := compositeConfig{
+		traceIDRandomness: traceIDRandomness,
+		errorHandler:      otel.Handle,
+	}
+	for _, opt := range options {
+		opt(&cfg)
+	}
 	return &compositeSampler{
-		sampler: s,
+		sampler:         s,
+		compositeConfig: cfg,
 	}
 }
 
 type compositeSampler struct {
 	sampler ComposableSampler
+	compositeConfig
+}
+
+func (c *compositeSampler) reportInconsistency(kind InconsistencyKind, psc trace.SpanContext, threshold Threshold, rnd int64, explicit bool) {
+	if c.inconsistencyHandler == nil {
+		return
+	}
+	c.inconsistencyHandler(Inconsistency{
+		Kind:               kind,
+		ParentSpanContext:  psc,
+		Threshold:          threshold,
+		Randomness:         rnd,
+		ExplicitRandomness: explicit,
+	})
 }
 
 var _ Sampler = &compositeSampler{}
@@ -448,33 +1264,80 @@ func (c *compositeSampler) ShouldSample(params SamplingParameters) SamplingResul
 	// because the additional allocations counteract the savings:
 	// - trace.SpanContextFromContext
 	// - TraceState().Get("ot")
-	// - tracestateHasThreshold()
-	// - tracestateHasRandomness()
+	// - parseOTelTraceState()
 	// In benchmarking, it's substantially faster to just run
 	// through these calls w/o allocations.
 
 	psc := trace.SpanContextFromContext(params.ParentContext)
+	startAttrs := extraStartAttributes(params.ParentContext, params.Attributes)
+	if len(startAttrs) != 0 {
+		// Copy, since the attributes belong to the caller.
+		params.Attributes = append(params.Attributes[:len(params.Attributes):len(params.Attributes)], startAttrs...)
+	}
 	returnTracestate := psc.TraceState()
-	otts := returnTracestate.Get("ot")
+	if !c.strictTraceState {
+		returnTracestate = canonicalizeTraceState(returnTracestate)
+	}
+	if c.sanitizer != nil {
+		var err error
+		if returnTracestate, err = c.sanitizer.sanitize(returnTracestate); err != nil {
+			c.errorHandler(err)
+		}
+	}
 
-	parsedThreshold, saveThresholdPos, hasThreshold := tracestateHasThreshold(otts)
-	threshold := parsedThreshold
+	// When the OTel trace state field exists, we will inspect for
+	// a "rv" and "th", otherwise assume that the TraceID is random.
+	ots := parseOTelTraceState(returnTracestate.Get("ot"))
+	parseErr := ots.err
+	if parseErr != nil {
+		c.errorHandler(parseErr)
+	}
 
-	var hasRandom bool
-	var rnd int64
-	if otts != "" {
-		// When the OTel trace state field exists, we will
-		// inspect for a "rv" and "th", otherwise assume that the
-		// TraceID is random.
-		rnd, hasRandom = tracestateHasRandomness(otts)
+	rnd := ots.randomness
+	randomnessReliable := true
+	hasRandomness := ots.hasRandomness
+	if !hasRandomness && c.legacyRandomness && ots.value != "" {
+		var err error
+		rnd, hasRandomness, err = tracestateHasLegacyRandomness(ots.value, c.traceIDRandomness(params.TraceID))
+		if err != nil {
+			c.errorHandler(err)
+		}
+	}
+	if !hasRandomness {
+		policy := c.missingRandomnessPolicy(psc)
+		extract := c.traceIDRandomness
+		if c.shortTraceIDs && policy == UseTraceIDRandomness && isShortTraceID(params.TraceID) {
+			policy = c.shortTraceIDPolicy
+			extract = traceIDRandomness
+		}
+		switch policy {
+		case GenerateRandomness:
+			returnTracestate = c.eviction.reserveOTelTraceState(returnTracestate)
+			returnTracestate, rnd = insertRandomness(returnTracestate, ots.value)
+			ots = parseOTelTraceState(returnTracestate.Get("ot"))
+		case UnreliableRandomness:
+			randomnessReliable = false
+			rnd = extract(params.TraceID)
+		default:
+			rnd = extract(params.TraceID)
+		}
+	}
+
+	threshold := ots.threshold
+	hasThreshold := ots.hasThreshold
+	if !hasThreshold && c.legacyProbability && ots.value != "" {
+		legacy, has, err := tracestateHasLegacyProbability(ots.value)
+		if err != nil {
+			c.errorHandler(err)
+		}
+		threshold, hasThreshold = legacy, has
 	}
-	if !hasRandom {
-		// Interpret the least-significant 8-bytes as an
-		// unsigned number, then zero the top 8 bits using
-		// randomnessMask, yielding the least-significant 56
-		// bits of randomness, as specified in W3C Trace
-		// Context Level 2.
-		rnd = int64(binary.BigEndian.Uint64(params.TraceID[8:16]) & randomnessMask)
+	for _, extract := range c.extractors {
+		if hasThreshold {
+			break
+		}
+		threshold, hasThreshold = extract(psc)
+		hasThreshold = hasThreshold && threshold.IsValid()
 	}
 
 	// thresholdReliable indicates whether the threshold is reliable
@@ -483,7 +1346,7 @@ func (c *compositeSampler) ShouldSample(params SamplingParameters) SamplingResul
 	switch {
 	case hasThreshold:
 		// Validate the threshold.
-		tsampled := threshold <= rnd
+		tsampled := threshold.ShouldSample(rnd)
 		fsampled := psc.IsSampled()
 
 		switch {
@@ -492,10 +1355,12 @@ func (c *compositeSampler) ShouldSample(params SamplingParameters) SamplingResul
 			thresholdReliable = true
 		case tsampled:
 			// Threshold says sampled, flag says not.
+			c.reportInconsistency(UnsampledBelowThreshold, psc, threshold, rnd, ots.hasRandomness)
 			psc = psc.WithTraceFlags(psc.TraceFlags() | trace.FlagsSampled)
 			thresholdReliable = true
 		case fsampled:
 			// Flag says sampled, threshold says not. This erases the invalid threshold.
+			c.reportInconsistency(SampledAboveThreshold, psc, threshold, rnd, ots.hasRandomness)
 			threshold = INVALID_THRESHOLD
 		default:
 			// Good. The two agree.
@@ -507,25 +1372,36 @@ func (c *compositeSampler) ShouldSample(params SamplingParameters) SamplingResul
 		threshold = NEVER_SAMPLE_THRESHOLD
 	}
 
-	intent := c.sampler.GetSamplingIntent(ComposableSamplingParameters{
+	cparams := ComposableSamplingParameters{
 		SamplingParameters:      params,
 		ParentSpanContext:       psc,
+		Baggage:                 baggage.FromContext(params.ParentContext),
+		Resource:                c.resource,
+		Scope:                   c.scope,
 		parentThreshold:         threshold,
 		parentThresholdReliable: thresholdReliable,
-	})
-
-	var sampled bool
-	switch {
-	case intent.Threshold >= NEVER_SAMPLE_THRESHOLD:
-		sampled = false
-	case intent.Threshold <= ALWAYS_SAMPLE_THRESHOLD:
-		sampled = true
-	default:
-		sampled = intent.Threshold <= rnd
+		randomness:              rnd,
+		otelTraceState:          ots,
+		thresholdDigits:         c.thresholdDigits,
+	}
+	if c.spanID {
+		cparams.SpanID = spanIDFromContext(params.ParentContext)
+	}
+	if c.unknownParentThreshold != nil && !hasThreshold && psc.IsSampled() {
+		cparams.parentThreshold, cparams.parentThresholdReliable = c.assumedParentThreshold(cparams)
+	}
+	intent := c.sampler.GetSamplingIntent(cparams)
+	sampled := intent.WouldSample(cparams)
+	if !randomnessReliable {
+		intent.ThresholdReliable = false
+	}
+	if c.rootThreshold && !psc.IsValid() && intent.Threshold.IsValid() {
+		intent.ThresholdReliable = true
 	}
 
 	var decision SamplingDecision
 	var attrs []attribute.KeyValue
+	var spanTracestate trace.TraceState
 	var err error
 	switch {
 	case sampled:
@@ -533,22 +1409,87 @@ func (c *compositeSampler) ShouldSample(params SamplingParameters) SamplingResul
 		if intent.Attributes != nil {
 			attrs = intent.Attributes()
 		}
-		returnTracestate, err = combineTracestate(returnTracestate, intent.Threshold, intent.ThresholdReliable, parsedThreshold, saveThresholdPos, hasThreshold)
+		sampledTracestate := returnTracestate
+		if intent.ThresholdReliable {
+			sampledTracestate = c.eviction.reserveOTelTraceState(sampledTracestate)
+		}
+		update := intent.Threshold
+		if c.thresholdDigits != 0 {
+			update = update.truncate(c.thresholdDigits)
+		}
+		sampledTracestate, err = combineTracestate(sampledTracestate, update, intent.ThresholdReliable, ots, !c.strictTraceState)
+		if c.probabilityAttribute && intent.ThresholdReliable {
+			// Copy, since the attributes may be shared.
+			attrs = append(attrs[:len(attrs):len(attrs)], SamplingProbabilityKey.Float64(update.Probability()))
+		}
+		if c.legacySubKeys {
+			sampledTracestate = legacySubKeys(sampledTracestate, update, intent.ThresholdReliable, randomnessReliable, rnd)
+		}
+		if intent.TraceState != nil {
+			// Applied after the threshold is combined, since the
+			// saved threshold position refers to the original.
+			sampledTracestate = intent.TraceState(sampledTracestate)
+		}
+		if intent.ExportOnly {
+			// The propagated tracestate is unchanged.
+			decision = ExportOnly
+			spanTracestate = c.eviction.limitTraceState(sampledTracestate, maxTraceStateMembers)
+		} else {
+			returnTracestate = sampledTracestate
+		}
 	case intent.Record:
 		decision = RecordOnly
-		attrs = intent.Attributes()
+		if intent.NonSampledAttributes != nil {
+			attrs = intent.NonSampledAttributes()
+		}
 	default:
 		decision = Drop
 	}
+	if len(startAttrs) != 0 && decision != Drop {
+		// Copy, since the attributes may be shared.
+		attrs = append(attrs[:len(attrs):len(attrs)], startAttrs...)
+	}
+	if parseErr != nil && c.errorAttribute && decision != Drop {
+		// Copy, since the attributes may be shared.
+		attrs = append(attrs[:len(attrs):len(attrs)], SamplingTraceStateErrorKey.String(parseErr.Error()))
+	}
 	if err != nil {
 		otel.Handle(fmt.Errorf("tracestate: %w", err))
 	}
+	returnTracestate = c.eviction.limitTraceState(returnTracestate, maxTraceStateMembers)
 
 	return SamplingResult{
-		Attributes: attrs,
-		Tracestate: returnTracestate,
-		Decision:   decision,
+		Attributes:     attrs,
+		Tracestate:     returnTracestate,
+		SpanTracestate: spanTracestate,
+		Decision:       decision,
+	}
+}
+
+// assumedParentThreshold returns the parent threshold determined by
+// the unknown-parent-threshold sampler, when it is reliable and
+// consistent with the sampled flag.
+func (c *compositeSampler) assumedParentThreshold(params ComposableSamplingParameters) (Threshold, bool) {
+	intent := c.unknownParentThreshold.GetSamplingIntent(params)
+	if !intent.ThresholdReliable || !intent.Threshold.ShouldSample(params.randomness) {
+		return INVALID_THRESHOLD, false
+	}
+	return intent.Threshold, true
+}
+
+// missingRandomnessPolicy returns the policy for a span whose parent
+// tracestate has no "rv" sub-key.
+func (c *compositeSampler) missingRandomnessPolicy(psc trace.SpanContext) MissingRandomnessPolicy {
+	switch {
+	case !psc.IsValid():
+		if c.explicitRandomness {
+			return GenerateRandomness
+		}
+		return UseTraceIDRandomness
+	case c.requireRandomFlag && psc.TraceFlags()&flagsRandom == 0:
+		return c.missingRandomness
 	}
+	return UseTraceIDRandomness
 }
 
 // Description implements ComposableSampler.
@@ -6,9 +6,12 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"strconv"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -260,6 +263,11 @@ func TestAnnotatingSampler(t *testing.T) {
 					return tatts
 				})),
 		),
+		CompositeSampler(
+			AnnotatingSampler(
+				ComposableAlwaysSample(),
+				WithSampledAttributeValues(tatts...)),
+		),
 	} {
 		t.Run(fmt.Sprintf("%T:%v", sampler, sampler.Description()), func(t *testing.T) {
 			ts100 := testTsWith("th:0")
@@ -277,6 +285,161 @@ func TestAnnotatingSampler(t *testing.T) {
 	}
 }
 
+func TestRuleBasedCombineMatching(t *testing.T) {
+	boost := []attribute.KeyValue{attribute.String("boost", "checkout")}
+	base := []attribute.KeyValue{attribute.String("base", "true")}
+	sampler := RuleBased(
+		WithCombineMatching(),
+		WithRule(SpanNamePredicate("checkout"), AnnotatingSampler(TraceIDRatioBased(0.5), WithSampledAttributes(makeAF(boost...)))),
+		WithRule(SpanNamePredicate("never"), ComposableNeverSample()),
+		WithDefaultRule(AnnotatingSampler(TraceIDRatioBased(0.1), WithSampledAttributes(makeAF(base...)))),
+	)
+	require.Equal(t, "RuleBasedAll{rule(Span.Name==checkout)=Annotate(TraceIDRatioBased{0.5}, boost=checkout),rule(Span.Name==never)=AlwaysOff,rule(true)=Annotate(TraceIDRatioBased{0.1}, base=true)}", sampler.Description())
+
+	params := func(name string) ComposableSamplingParameters {
+		return ComposableSamplingParameters{
+			SamplingParameters: SamplingParameters{
+				Name: name,
+			},
+		}
+	}
+
+	// Boosted: least threshold, both annotations.
+	intent := sampler.GetSamplingIntent(params("checkout"))
+	require.Equal(t, ProbabilityToThreshold(0.5), intent.Threshold)
+	require.True(t, intent.ThresholdReliable)
+	require.Equal(t, append(append([]attribute.KeyValue(nil), boost...), base...), intent.Attributes())
+
+	// A matching never-sampler does not lower the probability.
+	intent = sampler.GetSamplingIntent(params("never"))
+	require.Equal(t, ProbabilityToThreshold(0.1), intent.Threshold)
+	require.Equal(t, base, intent.Attributes())
+
+	// No matches other than the default.
+	intent = sampler.GetSamplingIntent(params("other"))
+	require.Equal(t, ProbabilityToThreshold(0.1), intent.Threshold)
+	require.Equal(t, base, intent.Attributes())
+}
+
+func TestRuleBasedPriorityStats(t *testing.T) {
+	rb := RuleBased(
+		WithRule(SpanNamePredicate("a"), ComposableNeverSample()),
+		WithPriorityRule(10, SpanNamePredicate("a"), ComposableAlwaysSample()),
+		WithPriorityRule(-1, SpanNamePredicate("c"), ComposableAlwaysSample()),
+		WithDefaultRule(TraceIDRatioBased(0.5)),
+	)
+	require.Equal(t, "RuleBased{rule(Span.Name==a)=AlwaysOn,rule(Span.Name==a)=AlwaysOff,rule(Span.Name==c)=AlwaysOn,rule(true)=TraceIDRatioBased{0.5}}", rb.Description())
+	sampler := CompositeSampler(rb)
+
+	yes := trace.TraceID{0, 0, 0, 0, 0, 0, 0, 0, 0, 0x80}
+	no := trace.TraceID{}
+	for _, span := range []struct {
+		name string
+		tid  trace.TraceID
+	}{
+		{"a", no},
+		{"a", no},
+		{"b", yes},
+		{"b", no},
+		{"b", no},
+	} {
+		test := defaultTestFuncs()
+		test.name = func() string { return span.name }
+		test.parentid = func(*rand.Rand) trace.TraceID { return trace.TraceID{} }
+		test.traceid = func(*rand.Rand) trace.TraceID { return span.tid }
+		sampler.ShouldSample(makeTestContext(test).SamplingParameters)
+	}
+
+	require.Equal(t, []RuleStats{
+		{Description: "rule(Span.Name==a)=AlwaysOn", Priority: 10, Matched: 2, Sampled: 2},
+		{Description: "rule(Span.Name==a)=AlwaysOff", Priority: 0, Matched: 0, Sampled: 0},
+		{Description: "rule(Span.Name==c)=AlwaysOn", Priority: -1, Matched: 0, Sampled: 0},
+		{Description: "rule(true)=TraceIDRatioBased{0.5}", Priority: 0, Matched: 3, Sampled: 1},
+	}, rb.(RuleStatsProvider).Stats())
+}
+
+// TestAnnotatingSamplerConditional tests attributes that depend on
+// whether the annotated sampler would sample.
+func TestAnnotatingSamplerConditional(t *testing.T) {
+	half := []attribute.KeyValue{attribute.String("policy", "half")}
+	unsampled := []attribute.KeyValue{attribute.String("unsampled", "true")}
+	sampler := RuleBased(
+		WithCombineMatching(),
+		WithRule(TruePredicate(), AnnotatingSampler(
+			TraceIDRatioBased(0.5),
+			WithSampledAttributes(makeAF(half...)),
+			WithNonSampledAttributes(makeAF(unsampled...)),
+			WithAttributesIfWouldSample(),
+		)),
+		WithDefaultRule(TraceIDRatioBased(0.25)),
+	)
+	require.Equal(t, "RuleBasedAll{rule(true)=Annotate(TraceIDRatioBased{0.5}, policy=half, unsampled(unsampled=true), ifWouldSample),rule(true)=TraceIDRatioBased{0.25}}", sampler.Description())
+
+	// Randomness selected by the 50% sampler.
+	intent := sampler.GetSamplingIntent(ComposableSamplingParameters{randomness: 0xc0000000000000})
+	require.Equal(t, half, intent.Attributes())
+	require.Equal(t, unsampled, intent.NonSampledAttributes())
+
+	// Randomness not selected by the 50% sampler.
+	intent = sampler.GetSamplingIntent(ComposableSamplingParameters{randomness: 0x40000000000000})
+	require.Empty(t, intent.Attributes())
+	require.False(t, intent.WouldSample(ComposableSamplingParameters{randomness: 0x40000000000000}))
+}
+
+// TestAnnotatingSamplerTraceState tests that tracestate entries are
+// merged with the threshold.
+func TestAnnotatingSamplerTraceState(t *testing.T) {
+	policy := func() string { return "policy1" }
+	for _, sand := range []samplerAnd[bool]{
+		{CompositeSampler(AnnotatingSampler(TraceIDRatioBased(0.5), WithTraceStateEntry("vnd", policy))), true},
+		{CompositeSampler(AnnotatingSampler(TraceIDRatioBased(0.5), WithTraceStateEntry("ot", policy))), false},
+	} {
+		t.Run(sand.name(), func(t *testing.T) {
+			test := defaultTestFuncs()
+			test.tracestate = func() trace.TraceState {
+				return testTs
+			}
+			test.parentid = func(*rand.Rand) trace.TraceID {
+				return trace.TraceID{}
+			}
+			test.traceid = func(*rand.Rand) trace.TraceID {
+				return trace.TraceID{0, 0, 0, 0, 0, 0, 0, 0, 0, 0x80}
+			}
+			result := sand.sampler.ShouldSample(makeTestContext(test).SamplingParameters)
+			require.Equal(t, RecordAndSample, result.Decision)
+
+			expect := testTsWith("th:8")
+			if sand.data {
+				var err error
+				expect, err = expect.Insert("vnd", "policy1")
+				require.NoError(t, err)
+			}
+			require.Equal(t, expect, result.Tracestate)
+		})
+	}
+	require.Equal(t, "Annotate(AlwaysOn, , tracestate(vnd))",
+		AnnotatingSampler(ComposableAlwaysSample(), WithTraceStateEntry("vnd", policy)).Description())
+}
+
+// TestTraceStateEntryReserved tests that the reserved key is reported
+// once, when the option is constructed.
+func TestTraceStateEntryReserved(t *testing.T) {
+	var errs []error
+	previous := otel.GetErrorHandler()
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		errs = append(errs, err)
+	}))
+	t.Cleanup(func() { otel.SetErrorHandler(previous) })
+
+	cs := CompositeSampler(AnnotatingSampler(ComposableAlwaysSample(), WithTraceStateEntry("ot", func() string { return "x" })))
+	require.Len(t, errs, 1)
+	for range 3 {
+		result := cs.ShouldSample(SamplingParameters{ParentContext: context.Background()})
+		require.Equal(t, RecordAndSample, result.Decision)
+	}
+	require.Len(t, errs, 1)
+}
+
 func TestTraceIdRatioBased(t *testing.T) {
 	yes := trace.TraceID{0, 0, 0, 0, 0, 0, 0, 0, 0, 0x80, 0, 0, 0, 0, 0, 0}
 	no := trace.TraceID{0x80, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
@@ -550,3 +713,497 @@ func BenchmarkParentBasedWithOTelTraceStateIncludingRandomness(b *testing.B) {
 		_ = sampler.ShouldSample(ctxs[i%maxContexts].SamplingParameters)
 	}
 }
+
+func TestExplicitRandomness(t *testing.T) {
+	// The TraceID has no randomness, so without explicit
+	// randomness, a 50% sampler never samples.
+	params := SamplingParameters{
+		ParentContext: context.Background(),
+		TraceID:       trace.TraceID{1},
+		Name:          "root",
+	}
+	half := TraceIDRatioBased(0.5)
+	res := CompositeSampler(half).ShouldSample(params)
+	require.Equal(t, Drop, res.Decision)
+	require.Equal(t, "", res.Tracestate.Get("ot"))
+
+	sampler := CompositeSampler(half, WithExplicitRandomness())
+	const trials = 1000
+	sampled := 0
+	for range trials {
+		res := sampler.ShouldSample(params)
+		otts := res.Tracestate.Get("ot")
+		ots := parseOTelTraceState(otts)
+		rnd, ok := ots.randomness, ots.hasRandomness
+		require.True(t, ok, otts)
+
+		wouldSample := ProbabilityToThreshold(0.5).ShouldSample(rnd)
+		if wouldSample {
+			sampled++
+			require.Equal(t, RecordAndSample, res.Decision)
+			require.Equal(t, fmt.Sprintf("rv:%014x;th:8", rnd), otts)
+		} else {
+			require.Equal(t, Drop, res.Decision)
+			require.Equal(t, fmt.Sprintf("rv:%014x", rnd), otts)
+		}
+	}
+	require.InEpsilon(t, trials/2, sampled, 0.2)
+
+	// Child spans use the parent's randomness.
+	ts, err := trace.ParseTraceState("ot=rv:10000000000000")
+	require.NoError(t, err)
+	params.ParentContext = trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceState: ts,
+	}))
+	res = CompositeSampler(ComposableAlwaysSample(), WithExplicitRandomness()).ShouldSample(params)
+	require.Equal(t, "rv:10000000000000;th:0", res.Tracestate.Get("ot"))
+}
+
+func TestRandomFlagRequired(t *testing.T) {
+	traceID := trace.TraceID{0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xf0, 0, 0, 0, 0, 0, 0}
+	parent := func(flags trace.TraceFlags, tracestate string) SamplingParameters {
+		ts, err := trace.ParseTraceState(tracestate)
+		require.NoError(t, err)
+		return SamplingParameters{
+			ParentContext: trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID:    traceID,
+				SpanID:     trace.SpanID{1},
+				TraceFlags: flags,
+				TraceState: ts,
+				Remote:     true,
+			})),
+			TraceID: traceID,
+			Name:    "child",
+		}
+	}
+	half := TraceIDRatioBased(0.5)
+
+	for _, policy := range []MissingRandomnessPolicy{UseTraceIDRandomness, GenerateRandomness, UnreliableRandomness} {
+		sampler := CompositeSampler(half, WithRandomFlagRequired(policy))
+
+		// The random flag is set, or there is explicit randomness.
+		res := sampler.ShouldSample(parent(trace.FlagsSampled|flagsRandom, ""))
+		require.Equal(t, RecordAndSample, res.Decision)
+		require.Equal(t, "th:8", res.Tracestate.Get("ot"))
+
+		res = sampler.ShouldSample(parent(trace.FlagsSampled, "ot=rv:10000000000000"))
+		require.Equal(t, Drop, res.Decision)
+		require.Equal(t, "rv:10000000000000", res.Tracestate.Get("ot"))
+
+		// Neither is available.
+		res = sampler.ShouldSample(parent(trace.FlagsSampled, "ot=th:0"))
+		otts := res.Tracestate.Get("ot")
+		switch policy {
+		case UseTraceIDRandomness:
+			require.Equal(t, RecordAndSample, res.Decision)
+			require.Equal(t, "th:8", otts)
+		case GenerateRandomness:
+			ots := parseOTelTraceState(otts)
+			rnd, ok := ots.randomness, ots.hasRandomness
+			require.True(t, ok)
+			if ProbabilityToThreshold(0.5).ShouldSample(rnd) {
+				require.Equal(t, RecordAndSample, res.Decision)
+				require.Equal(t, fmt.Sprintf("rv:%014x;th:8", rnd), otts)
+			} else {
+				require.Equal(t, Drop, res.Decision)
+				require.Equal(t, fmt.Sprintf("rv:%014x;th:0", rnd), otts)
+			}
+		case UnreliableRandomness:
+			require.Equal(t, RecordAndSample, res.Decision)
+			require.Equal(t, "", otts)
+		}
+	}
+}
+
+func TestTraceStateErrors(t *testing.T) {
+	params := func(tracestate string) SamplingParameters {
+		ts, err := trace.ParseTraceState(tracestate)
+		require.NoError(t, err)
+		return SamplingParameters{
+			ParentContext: trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID:    trace.TraceID{1},
+				SpanID:     trace.SpanID{1},
+				TraceFlags: trace.FlagsSampled,
+				TraceState: ts,
+			})),
+			TraceID: trace.TraceID{1},
+		}
+	}
+
+	var errs []error
+	sampler := CompositeSampler(ComposableParentBased(ComposableNeverSample()),
+		WithTraceStateErrorHandler(func(err error) {
+			errs = append(errs, err)
+		}),
+		WithTraceStateErrorAttribute(),
+	)
+
+	res := sampler.ShouldSample(params("ot=th:8"))
+	require.Empty(t, errs)
+	require.Empty(t, res.Attributes)
+
+	res = sampler.ShouldSample(params("ot=th:xyz;rv:123"))
+	require.Len(t, errs, 1)
+	require.ErrorContains(t, errs[0], `could not parse tracestate threshold: "th:xyz;rv:123"`)
+	require.ErrorContains(t, errs[0], `could not parse tracestate randomness: "th:xyz;rv:123"`)
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Equal(t, []attribute.KeyValue{SamplingTraceStateErrorKey.String(errs[0].Error())}, res.Attributes)
+
+	// Not recorded, no attribute.
+	errs = nil
+	res = CompositeSampler(ComposableNeverSample(), WithTraceStateErrorAttribute(),
+		WithTraceStateErrorHandler(func(err error) {
+			errs = append(errs, err)
+		}),
+	).ShouldSample(params("ot=th:xyz"))
+	require.Len(t, errs, 1)
+	require.Equal(t, Drop, res.Decision)
+	require.Empty(t, res.Attributes)
+}
+
+func TestThresholdPrecision(t *testing.T) {
+	traceID := trace.TraceID{0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	params := SamplingParameters{
+		ParentContext: context.Background(),
+		TraceID:       traceID,
+	}
+	// 1/3 is encoded with 4 digits, "aaab".
+	third := TraceIDRatioBased(1.0 / 3)
+	for _, test := range []struct {
+		options []CompositeOption
+		th      string
+	}{
+		{nil, "th:aaab"},
+		{[]CompositeOption{WithThresholdPrecision(14)}, "th:aaab"},
+		{[]CompositeOption{WithThresholdPrecision(2)}, "th:aa"},
+		{[]CompositeOption{WithThresholdPrecision(1)}, "th:a"},
+		// Invalid, ignored.
+		{[]CompositeOption{WithThresholdPrecision(0)}, "th:aaab"},
+	} {
+		res := CompositeSampler(third, test.options...).ShouldSample(params)
+		require.Equal(t, RecordAndSample, res.Decision)
+		require.Equal(t, test.th, res.Tracestate.Get("ot"))
+	}
+
+	require.Equal(t, Threshold(0xa0000000000000), Threshold(0xaaab0000000000).truncate(1))
+	require.Equal(t, NEVER_SAMPLE_THRESHOLD, NEVER_SAMPLE_THRESHOLD.truncate(1))
+	require.Equal(t, ALWAYS_SAMPLE_THRESHOLD, ALWAYS_SAMPLE_THRESHOLD.truncate(1))
+}
+
+func TestThresholdExtractors(t *testing.T) {
+	params := func(tracestate string) SamplingParameters {
+		ts, err := trace.ParseTraceState(tracestate)
+		require.NoError(t, err)
+		return SamplingParameters{
+			ParentContext: trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID:    trace.TraceID{1},
+				SpanID:     trace.SpanID{1},
+				TraceFlags: trace.FlagsSampled,
+				TraceState: ts,
+			})),
+			TraceID: trace.TraceID{1},
+		}
+	}
+	// A vendor member holding a sampling probability.
+	vendor := func(psc trace.SpanContext) (Threshold, bool) {
+		value := psc.TraceState().Get("vendor")
+		if value == "" {
+			return 0, false
+		}
+		prob, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, false
+		}
+		return ProbabilityToThreshold(prob), true
+	}
+	invalid := func(trace.SpanContext) (Threshold, bool) {
+		return INVALID_THRESHOLD, true
+	}
+	sampler := CompositeSampler(ComposableParentBased(ComposableNeverSample()),
+		WithThresholdExtractors(invalid, vendor),
+		WithExplicitRandomness(),
+	)
+
+	res := sampler.ShouldSample(params("ot=rv:c0000000000000,vendor=0.5"))
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Equal(t, "ot=rv:c0000000000000;th:8,vendor=0.5", res.Tracestate.String())
+
+	// The "th" sub-key has precedence.
+	res = sampler.ShouldSample(params("ot=rv:c0000000000000;th:4,vendor=0.5"))
+	require.Equal(t, "ot=rv:c0000000000000;th:4,vendor=0.5", res.Tracestate.String())
+
+	// Inconsistent with the sampled flag.
+	res = sampler.ShouldSample(params("ot=rv:40000000000000,vendor=0.5"))
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Equal(t, "ot=rv:40000000000000,vendor=0.5", res.Tracestate.String())
+
+	// No vendor member.
+	res = sampler.ShouldSample(params("ot=rv:c0000000000000"))
+	require.Equal(t, "ot=rv:c0000000000000", res.Tracestate.String())
+}
+
+func TestCombineIntents(t *testing.T) {
+	attrs := func(kvs ...attribute.KeyValue) AttributesFunc {
+		return func() []attribute.KeyValue { return kvs }
+	}
+	one := SamplingIntent{
+		Record:            true,
+		Threshold:         0x40000000000000,
+		ThresholdReliable: true,
+		Attributes:        attrs(attribute.String("a", "1")),
+	}
+	two := SamplingIntent{
+		Threshold:  0x80000000000000,
+		Attributes: attrs(attribute.String("b", "2")),
+		ExportOnly: true,
+		TraceState: func(ts trace.TraceState) trace.TraceState {
+			ts, _ = ts.Insert("b", "2")
+			return ts
+		},
+	}
+	both := []attribute.KeyValue{attribute.String("a", "1"), attribute.String("b", "2")}
+
+	union := UnionIntents(one, two)
+	require.True(t, union.Record)
+	require.Equal(t, one.Threshold, union.Threshold)
+	require.True(t, union.ThresholdReliable)
+	require.False(t, union.ExportOnly)
+	require.Equal(t, both, union.Attributes())
+	require.Nil(t, union.NonSampledAttributes())
+	require.Equal(t, "b=2", union.TraceState(trace.TraceState{}).String())
+
+	intersect := IntersectIntents(one, two)
+	require.False(t, intersect.Record)
+	require.Equal(t, two.Threshold, intersect.Threshold)
+	require.False(t, intersect.ThresholdReliable)
+	require.True(t, intersect.ExportOnly)
+	require.Equal(t, both, intersect.Attributes())
+
+	// Equal thresholds.
+	two.Threshold = one.Threshold
+	require.False(t, UnionIntents(one, two).ExportOnly)
+	require.True(t, IntersectIntents(one, two).ExportOnly)
+	require.True(t, IntersectIntents(one, two).ThresholdReliable)
+
+	require.Nil(t, CombineTraceState(nil, nil))
+	require.Equal(t, both[1:], CombineAttributes(nil, two.Attributes)())
+}
+
+// TestCombineAttributesAliasing tests that combined attributes do not
+// write to the slices returned by the combined functions, which may
+// have spare capacity and be shared by concurrent calls.
+func TestCombineAttributesAliasing(t *testing.T) {
+	shared := make([]attribute.KeyValue, 1, 4)
+	shared[0] = attribute.String("a", "1")
+	one := func() []attribute.KeyValue { return shared }
+	b := CombineAttributes(one, makeAF(attribute.String("b", "2")))
+	c := CombineAttributes(one, makeAF(attribute.String("c", "3")))
+
+	first := b()
+	require.Equal(t, []attribute.KeyValue{attribute.String("a", "1"), attribute.String("c", "3")}, c())
+	require.Equal(t, []attribute.KeyValue{attribute.String("a", "1"), attribute.String("b", "2")}, first)
+	require.Equal(t, make([]attribute.KeyValue, 3), shared[1:4])
+
+	// Concurrent samplers do not race.
+	sampler := AnnotatingSampler(ComposableAlwaysSample(),
+		WithSampledAttributes(one),
+		WithSampledAttributeValues(attribute.String("b", "2")),
+	)
+	results := make([][]attribute.KeyValue, 400)
+	var wg sync.WaitGroup
+	for g := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := g * 100; i < (g+1)*100; i++ {
+				results[i] = sampler.GetSamplingIntent(ComposableSamplingParameters{}).Attributes()
+			}
+		}()
+	}
+	wg.Wait()
+	for _, attrs := range results {
+		require.Equal(t, []attribute.KeyValue{attribute.String("a", "1"), attribute.String("b", "2")}, attrs)
+	}
+}
+
+func BenchmarkCompositeUpdateThreshold(b *testing.B) {
+	for _, tracestate := range []string{
+		"co=whateverr,ed=nowaysir,ot=rv:abcdefabcdefab;th:0;xx:abc",
+		"co=whateverr,ed=nowaysir,ot=xx:abc;yy:def;th:0;rv:abcdefabcdefab",
+	} {
+		b.Run(tracestate, func(b *testing.B) {
+			ts, err := trace.ParseTraceState(tracestate)
+			require.NoError(b, err)
+			bfs := defaultTestFuncs()
+			bfs.tracestate = func() trace.TraceState {
+				return ts
+			}
+			ctxs := makeBenchContexts(b.N, bfs)
+			sampler := CompositeSampler(RuleBased(WithDefaultRule(TraceIDRatioBased(0.5))))
+			b.ResetTimer()
+			for i := range b.N {
+				_ = sampler.ShouldSample(ctxs[i%maxContexts].SamplingParameters)
+			}
+		})
+	}
+}
+
+func TestParentRatioBased(t *testing.T) {
+	params := func(flags trace.TraceFlags, tracestate string) SamplingParameters {
+		ts, err := trace.ParseTraceState(tracestate)
+		require.NoError(t, err)
+		return SamplingParameters{
+			ParentContext: trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID:    trace.TraceID{1},
+				SpanID:     trace.SpanID{1},
+				TraceFlags: flags,
+				TraceState: ts,
+			})),
+			TraceID: trace.TraceID{1},
+		}
+	}
+	sampler := CompositeSampler(ParentRatioBased(0.5))
+	require.Equal(t, "ParentRatioBased{0.5}", sampler.Description())
+
+	res := sampler.ShouldSample(params(trace.FlagsSampled, "ot=th:8;rv:f0000000000000"))
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Equal(t, "ot=rv:f0000000000000;th:c", res.Tracestate.String())
+
+	res = sampler.ShouldSample(params(trace.FlagsSampled, "ot=th:8;rv:90000000000000"))
+	require.Equal(t, Drop, res.Decision)
+
+	// Unknown parent threshold.
+	res = sampler.ShouldSample(params(trace.FlagsSampled, "ot=rv:90000000000000"))
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Equal(t, "ot=rv:90000000000000", res.Tracestate.String())
+
+	res = sampler.ShouldSample(params(0, "ot=th:8;rv:00000000000000"))
+	require.Equal(t, Drop, res.Decision)
+
+	// Root spans are not sampled.
+	res = sampler.ShouldSample(SamplingParameters{ParentContext: context.Background(), TraceID: trace.TraceID{15: 0xff}})
+	require.Equal(t, Drop, res.Decision)
+}
+
+func TestRootThreshold(t *testing.T) {
+	// Short TraceIDs make the randomness unreliable.
+	root := SamplingParameters{
+		ParentContext: context.Background(),
+		TraceID:       trace.TraceID{15: 0xff},
+	}
+	sampler := ComposableParentBased(ComposableAlwaysSample())
+	unreliable := WithShortTraceIDPolicy(UnreliableRandomness)
+
+	res := CompositeSampler(sampler, unreliable).ShouldSample(root)
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Equal(t, "", res.Tracestate.String())
+
+	res = CompositeSampler(sampler, unreliable, WithRootThreshold()).ShouldSample(root)
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Equal(t, "ot=th:0", res.Tracestate.String())
+
+	// Not for child spans.
+	child := SamplingParameters{
+		ParentContext: trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{1},
+			SpanID:     trace.SpanID{1},
+			TraceFlags: trace.FlagsSampled,
+		})),
+		TraceID: trace.TraceID{1},
+	}
+	res = CompositeSampler(sampler, WithRootThreshold()).ShouldSample(child)
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Equal(t, "", res.Tracestate.String())
+}
+
+func TestUnknownParentThreshold(t *testing.T) {
+	params := func(tracestate string) SamplingParameters {
+		ts, err := trace.ParseTraceState(tracestate)
+		require.NoError(t, err)
+		return SamplingParameters{
+			ParentContext: trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID:    trace.TraceID{1},
+				SpanID:     trace.SpanID{1},
+				TraceFlags: trace.FlagsSampled,
+				TraceState: ts,
+			})),
+			TraceID: trace.TraceID{1},
+		}
+	}
+	sampler := ComposableParentBased(ComposableNeverSample())
+
+	res := CompositeSampler(sampler).ShouldSample(params("ot=rv:c0000000000000"))
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Equal(t, "ot=rv:c0000000000000", res.Tracestate.String())
+
+	res = CompositeSampler(sampler, WithUnknownParentThreshold(ComposableAlwaysSample())).ShouldSample(params("ot=rv:c0000000000000"))
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Equal(t, "ot=rv:c0000000000000;th:0", res.Tracestate.String())
+
+	half := WithUnknownParentThreshold(TraceIDRatioBased(0.5))
+	res = CompositeSampler(sampler, half).ShouldSample(params("ot=rv:c0000000000000"))
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Equal(t, "ot=rv:c0000000000000;th:8", res.Tracestate.String())
+
+	// Inconsistent with the sampled flag, so not used.
+	res = CompositeSampler(sampler, half).ShouldSample(params("ot=rv:40000000000000"))
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Equal(t, "ot=rv:40000000000000", res.Tracestate.String())
+
+	// A threshold in the tracestate has precedence.
+	res = CompositeSampler(sampler, half).ShouldSample(params("ot=rv:c0000000000000;th:4"))
+	require.Equal(t, "ot=rv:c0000000000000;th:4", res.Tracestate.String())
+}
+
+// halfOfParent samples half the spans its parent sampled, using only
+// exported API.
+type halfOfParent struct {
+	params ComposableSamplingParameters
+}
+
+func (h *halfOfParent) GetSamplingIntent(params ComposableSamplingParameters) SamplingIntent {
+	h.params = params
+	th, reliable := params.ParentThreshold()
+	if !reliable {
+		return SamplingIntent{Threshold: NEVER_SAMPLE_THRESHOLD}
+	}
+	return SamplingIntent{
+		Threshold:         ComposeThresholds(th, ProbabilityToThreshold(0.5)),
+		ThresholdReliable: true,
+	}
+}
+
+func (*halfOfParent) Description() string {
+	return "halfOfParent"
+}
+
+func TestComposableSamplingParametersAccessors(t *testing.T) {
+	ts, err := trace.ParseTraceState("ot=rv:f0000000000000;th:8")
+	require.NoError(t, err)
+	params := SamplingParameters{
+		ParentContext: trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{1},
+			SpanID:     trace.SpanID{1},
+			TraceFlags: trace.FlagsSampled,
+			TraceState: ts,
+		})),
+		TraceID: trace.TraceID{1},
+	}
+	half := &halfOfParent{}
+	res := CompositeSampler(half).ShouldSample(params)
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Equal(t, "ot=rv:f0000000000000;th:c", res.Tracestate.String())
+
+	th, reliable := half.params.ParentThreshold()
+	require.True(t, reliable)
+	require.Equal(t, Threshold(0x80000000000000), th)
+	require.Equal(t, int64(0xf0000000000000), half.params.Randomness())
+
+	// Root spans have unreliable thresholds.
+	CompositeSampler(half).ShouldSample(SamplingParameters{ParentContext: context.Background()})
+	th, reliable = half.params.ParentThreshold()
+	require.False(t, reliable)
+	require.Equal(t, NEVER_SAMPLE_THRESHOLD, th)
+}
@@ -0,0 +1,529 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package samplerconfig
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jmacd/sampler"
+)
+
+// samplerTypes are the field names of Sampler.
+var samplerTypes = []string{
+	"always_on", "always_off", "probability", "parent_threshold", "rule_based", "annotating",
+	"parent_ratio", "cost_based", "error_hint_biased", "replica_decorrelated", "export_only", "annotate_adjusted_count",
+	"inherit_parent_attributes", "rate", "preset",
+}
+
+// set returns the names of the sampler types that are set.
+func (s *Sampler) set() []string {
+	var names []string
+	for i, ok := range []bool{
+		s.AlwaysOn != nil,
+		s.AlwaysOff != nil,
+		s.Probability != nil,
+		s.ParentThreshold != nil,
+		s.RuleBased != nil,
+		s.Annotating != nil,
+		s.ParentRatio != nil,
+		s.CostBased != nil,
+		s.ErrorHintBiased != nil,
+		s.ReplicaDecorrelated != nil,
+		s.ExportOnly != nil,
+		s.AnnotateAdjustedCount != nil,
+		s.InheritParentAttributes != nil,
+		s.Rate != "",
+		s.Preset != "",
+	} {
+		if ok {
+			names = append(names, samplerTypes[i])
+		}
+	}
+	return append(names, sortedKeys(s.Custom)...)
+}
+
+func (s *Sampler) isRegistered(name string) bool {
+	_, ok := lookupSampler(name)
+	return ok
+}
+
+func (r *Rule) isRegistered(name string) bool {
+	if _, ok := builtinPredicates[name]; ok {
+		return true
+	}
+	_, ok := lookupPredicate(name)
+	return ok
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *Sampler) checkOneOf() string {
+	switch names := s.set(); len(names) {
+	case 0:
+		return fmt.Sprintf("expected one of %s", strings.Join(samplerTypes, ", "))
+	case 1:
+		return ""
+	default:
+		return fmt.Sprintf("expected one sampler type, found %s", strings.Join(names, " and "))
+	}
+}
+
+func buildError(path, format string, args ...any) error {
+	return &Error{Path: path, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Build returns the sampler described by the configuration.
+func (c *Config) Build() (sampler.ComposableSampler, error) {
+	if len(c.Profiles) == 0 {
+		return buildSampler(c.Sampler, "sampler")
+	}
+	var errs Errors
+	options := make([]sampler.RuleBasedOption, 0, len(c.Profiles)+1)
+	for i := range c.Profiles {
+		pred, s, err := c.Profiles[i].build(fmt.Sprintf("profiles[%d]", i))
+		errs.add(err)
+		options = append(options, sampler.WithRule(pred, s))
+	}
+	def, err := buildSampler(c.Sampler, "sampler")
+	errs.add(err)
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	return sampler.RuleBased(append(options, sampler.WithDefaultRule(def))...), nil
+}
+
+// build returns the predicate selecting a profile and its sampler.
+func (p *Profile) build(path string) (sampler.Predicate, sampler.ComposableSampler, error) {
+	var errs Errors
+	if len(p.Resource) == 0 {
+		errs.add(buildError(path+".resource", "missing resource"))
+	}
+	preds := make([]sampler.Predicate, 0, len(p.Resource))
+	for _, key := range sortedKeys(p.Resource) {
+		value, err := attributeValue(p.Resource[key])
+		if err != nil {
+			errs.add(buildError(path+".resource."+key, "%v", err))
+			continue
+		}
+		preds = append(preds, sampler.ResourceAttributePredicate(attribute.KeyValue{
+			Key:   attribute.Key(key),
+			Value: value,
+		}))
+	}
+	s, err := buildSampler(p.Sampler, path+".sampler")
+	errs.add(err)
+	if err := errs.err(); err != nil {
+		return sampler.Predicate{}, nil, err
+	}
+	if len(preds) == 1 {
+		return preds[0], s, nil
+	}
+	return sampler.AndPredicate(preds...), s, nil
+}
+
+func buildSampler(s *Sampler, path string) (sampler.ComposableSampler, error) {
+	if s == nil {
+		return nil, buildError(path, "missing sampler")
+	}
+	if msg := s.checkOneOf(); msg != "" {
+		return nil, buildError(path, "%s", msg)
+	}
+	switch {
+	case s.AlwaysOn != nil:
+		return sampler.ComposableAlwaysSample(), nil
+	case s.AlwaysOff != nil:
+		return sampler.ComposableNeverSample(), nil
+	case s.Probability != nil:
+		return s.Probability.build(path + ".probability")
+	case s.ParentThreshold != nil:
+		return s.ParentThreshold.build(path + ".parent_threshold")
+	case s.RuleBased != nil:
+		return s.RuleBased.build(path + ".rule_based")
+	case s.Annotating != nil:
+		return s.Annotating.build(path + ".annotating")
+	case s.ParentRatio != nil:
+		return s.ParentRatio.build(path + ".parent_ratio")
+	case s.CostBased != nil:
+		return s.CostBased.build(path + ".cost_based")
+	case s.ErrorHintBiased != nil:
+		return s.ErrorHintBiased.build(path + ".error_hint_biased")
+	case s.ReplicaDecorrelated != nil:
+		return s.ReplicaDecorrelated.build(path + ".replica_decorrelated")
+	case s.ExportOnly != nil:
+		return wrapSampler(s.ExportOnly.Sampler, path+".export_only", sampler.ExportOnlySampler)
+	case s.AnnotateAdjustedCount != nil:
+		return wrapSampler(s.AnnotateAdjustedCount.Sampler, path+".annotate_adjusted_count", sampler.AnnotateAdjustedCount)
+	case s.InheritParentAttributes != nil:
+		return s.InheritParentAttributes.build(path + ".inherit_parent_attributes")
+	case s.Rate != "":
+		cs, err := parseRate(s.Rate)
+		if err != nil {
+			return nil, buildError(path+".rate", "%v", err)
+		}
+		return cs, nil
+	case s.Preset != "":
+		return buildPreset(s.Preset, path+".preset")
+	}
+	name := s.set()[0]
+	factory, ok := lookupSampler(name)
+	if !ok {
+		return nil, buildError(path, "unknown sampler %q", name)
+	}
+	cs, err := factory(s.Custom[name])
+	if err != nil {
+		return nil, buildError(joinPath(path, name), "%v", err)
+	}
+	return cs, nil
+}
+
+var roundings = map[string]sampler.Rounding{
+	"":        sampler.RoundNearest,
+	"nearest": sampler.RoundNearest,
+	"down":    sampler.RoundProbabilityDown,
+	"up":      sampler.RoundProbabilityUp,
+}
+
+func (p *Probability) build(path string) (sampler.ComposableSampler, error) {
+	if p.Threshold != "" {
+		if p.Ratio != nil {
+			return nil, buildError(path, "expected ratio or threshold, found both")
+		}
+		th, err := sampler.ParseThreshold(p.Threshold)
+		if err != nil {
+			return nil, buildError(path+".threshold", "%v", err)
+		}
+		return sampler.TraceIDRatioFromThreshold(th), nil
+	}
+	var errs Errors
+	errs.add(checkFraction(p.Ratio, path, "ratio"))
+	rounding, ok := roundings[p.Rounding]
+	if !ok {
+		errs.add(buildError(path+".rounding", "unknown rounding %q, expected nearest, down, or up", p.Rounding))
+	}
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	return sampler.TraceIDRatioBased(*p.Ratio, sampler.WithRounding(rounding)), nil
+}
+
+// checkFraction checks that a required field is in the range [0, 1].
+func checkFraction(value *float64, path, name string) error {
+	if value == nil {
+		return buildError(path+"."+name, "missing %s", name)
+	}
+	if !(*value >= 0 && *value <= 1) {
+		return buildError(path+"."+name, "%s %v is not in the range [0, 1]", name, *value)
+	}
+	return nil
+}
+
+func (p *ParentThreshold) build(path string) (sampler.ComposableSampler, error) {
+	if p.Root == nil {
+		return sampler.ParentThreshold(), nil
+	}
+	root, err := buildSampler(p.Root, path+".root")
+	if err != nil {
+		return nil, err
+	}
+	return sampler.ComposableParentBased(root), nil
+}
+
+func (r *RuleBased) build(path string) (sampler.ComposableSampler, error) {
+	var errs Errors
+	var options []sampler.RuleBasedOption
+	for i := range r.Rules {
+		rulePath := fmt.Sprintf("%s.rules[%d]", path, i)
+		pred, err := r.Rules[i].predicate(rulePath)
+		errs.add(err)
+		s, err := buildSampler(r.Rules[i].Sampler, rulePath+".sampler")
+		errs.add(err)
+		if attrs := r.Rules[i].Attributes; len(attrs) != 0 && s != nil {
+			s = sampler.AnnotatingSampler(s, sampler.WithSampledAttributeValues(staticAttributes(attrs)...))
+		}
+		options = append(options, sampler.WithRule(pred, s))
+	}
+	if r.Default != nil {
+		s, err := buildSampler(r.Default, path+".default")
+		errs.add(err)
+		options = append(options, sampler.WithDefaultRule(s))
+	}
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	if r.CombineMatching {
+		options = append(options, sampler.WithCombineMatching())
+	}
+	return sampler.RuleBased(options...), nil
+}
+
+var spanKinds = map[string]trace.SpanKind{
+	"server":   trace.SpanKindServer,
+	"client":   trace.SpanKindClient,
+	"producer": trace.SpanKindProducer,
+	"consumer": trace.SpanKindConsumer,
+	"internal": trace.SpanKindInternal,
+}
+
+var parentKinds = map[string]func() sampler.Predicate{
+	"none":   sampler.IsRootPredicate,
+	"remote": sampler.IsRemoteParentPredicate,
+	"local":  sampler.IsLocalParentPredicate,
+}
+
+// predicate returns the conjunction of the rule's conditions.
+func (r *Rule) predicate(path string) (sampler.Predicate, error) {
+	var errs Errors
+	var preds []sampler.Predicate
+	if r.Expr != "" {
+		pred, err := sampler.ParsePredicate(r.Expr)
+		if err != nil {
+			errs.add(buildError(path+".expr", "%v", err))
+		}
+		preds = append(preds, pred)
+	}
+	if len(r.SpanKinds) != 0 {
+		pred, err := spanKindPredicate(r.SpanKinds, path+".span_kinds")
+		errs.add(err)
+		preds = append(preds, pred)
+	}
+	if len(r.Parent) != 0 {
+		pred, err := parentPredicate(r.Parent, path+".parent")
+		errs.add(err)
+		preds = append(preds, pred)
+	}
+	if len(r.SpanNames) != 0 {
+		preds = append(preds, sampler.SpanNameInSetPredicate(r.SpanNames...))
+	}
+	if av := r.AttributeValues; av != nil {
+		if av.Key == "" {
+			errs.add(buildError(path+".attribute_values.key", "missing key"))
+		}
+		if len(av.Values) == 0 {
+			errs.add(buildError(path+".attribute_values.values", "missing values"))
+		}
+		preds = append(preds, sampler.AttributeValueInSetPredicate(attribute.Key(av.Key), av.Values...))
+	}
+	if ap := r.AttributePatterns; ap != nil {
+		if ap.Key == "" {
+			errs.add(buildError(path+".attribute_patterns.key", "missing key"))
+		}
+		key := attribute.Key(ap.Key)
+		included := ap.Included
+		if len(included) == 0 {
+			included = []string{"*"}
+		}
+		var alts []sampler.Predicate
+		for _, pattern := range included {
+			alts = append(alts, sampler.AttributeGlobPredicate(key, pattern))
+		}
+		preds = append(preds, anyOf(alts))
+		for _, pattern := range ap.Excluded {
+			preds = append(preds, sampler.NotPredicate(sampler.AttributeGlobPredicate(key, pattern)))
+		}
+	}
+	for _, name := range sortedKeys(r.Custom) {
+		pred, err := buildPredicate(name, r.Custom[name], joinPath(path, name))
+		errs.add(err)
+		preds = append(preds, pred)
+	}
+	if err := errs.err(); err != nil {
+		return sampler.Predicate{}, err
+	}
+	if len(preds) == 0 {
+		return sampler.TruePredicate(), nil
+	}
+	if len(preds) == 1 {
+		return preds[0], nil
+	}
+	return sampler.AndPredicate(preds...), nil
+}
+
+// spanKindPredicate matches any of the named span kinds.
+func spanKindPredicate(names []string, path string) (sampler.Predicate, error) {
+	var errs Errors
+	kinds := make([]trace.SpanKind, len(names))
+	for i, name := range names {
+		kind, ok := spanKinds[name]
+		if !ok {
+			errs.add(buildError(fmt.Sprintf("%s[%d]", path, i),
+				"unknown span kind %q, expected server, client, producer, consumer, or internal", name))
+		}
+		kinds[i] = kind
+	}
+	if err := errs.err(); err != nil {
+		return sampler.Predicate{}, err
+	}
+	return sampler.SpanKindPredicate(kinds...), nil
+}
+
+// parentPredicate matches any of the named parent kinds.
+func parentPredicate(names []string, path string) (sampler.Predicate, error) {
+	var errs Errors
+	var alts []sampler.Predicate
+	for i, name := range names {
+		parent, ok := parentKinds[name]
+		if !ok {
+			errs.add(buildError(fmt.Sprintf("%s[%d]", path, i),
+				"unknown parent %q, expected none, remote, or local", name))
+			continue
+		}
+		alts = append(alts, parent())
+	}
+	if err := errs.err(); err != nil {
+		return sampler.Predicate{}, err
+	}
+	return anyOf(alts), nil
+}
+
+// anyOf is OrPredicate, without a wrapper for a single predicate.
+func anyOf(preds []sampler.Predicate) sampler.Predicate {
+	if len(preds) == 1 {
+		return preds[0]
+	}
+	return sampler.OrPredicate(preds...)
+}
+
+func (a *Annotating) build(path string) (sampler.ComposableSampler, error) {
+	s, err := buildSampler(a.Sampler, path+".sampler")
+	if err != nil {
+		return nil, err
+	}
+	var options []sampler.AnnotatingOption
+	if len(a.Attributes) != 0 {
+		options = append(options, sampler.WithSampledAttributeValues(staticAttributes(a.Attributes)...))
+	}
+	if len(a.NonSampledAttributes) != 0 {
+		options = append(options, sampler.WithNonSampledAttributeValues(staticAttributes(a.NonSampledAttributes)...))
+	}
+	if a.IfWouldSample {
+		options = append(options, sampler.WithAttributesIfWouldSample())
+	}
+	return sampler.AnnotatingSampler(s, options...), nil
+}
+
+// staticAttributes returns string attributes, sorted by key.
+func staticAttributes(m map[string]string) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(m))
+	for key, value := range m {
+		attrs = append(attrs, attribute.String(key, value))
+	}
+	sort.Slice(attrs, func(i, j int) bool {
+		return attrs[i].Key < attrs[j].Key
+	})
+	return attrs
+}
+
+func (p *ParentRatio) build(path string) (sampler.ComposableSampler, error) {
+	if err := checkFraction(p.Ratio, path, "ratio"); err != nil {
+		return nil, err
+	}
+	return sampler.ParentRatioBased(*p.Ratio), nil
+}
+
+func (c *CostBased) build(path string) (sampler.ComposableSampler, error) {
+	var errs Errors
+	if c.BytesPerSecond == nil {
+		errs.add(buildError(path+".bytes_per_second", "missing bytes_per_second"))
+	} else if !(*c.BytesPerSecond > 0) {
+		errs.add(buildError(path+".bytes_per_second", "bytes_per_second %v is not positive", *c.BytesPerSecond))
+	}
+	var options []sampler.CostBasedOption
+	for _, name := range sortedKeys(c.SpanNameCosts) {
+		options = append(options, sampler.WithSpanNameCost(name, c.SpanNameCosts[name]))
+	}
+	if c.DefaultSpanCost != nil {
+		options = append(options, sampler.WithDefaultSpanCost(*c.DefaultSpanCost))
+	}
+	if c.AttributeCost != nil {
+		options = append(options, sampler.WithAttributeCost(*c.AttributeCost))
+	}
+	if c.Interval != "" {
+		interval, err := time.ParseDuration(c.Interval)
+		if err != nil || interval <= 0 {
+			errs.add(buildError(path+".interval", "invalid interval %q", c.Interval))
+		}
+		options = append(options, sampler.WithCostInterval(interval))
+	}
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	return sampler.CostBased(*c.BytesPerSecond, options...), nil
+}
+
+func (e *ErrorHintBiased) build(path string) (sampler.ComposableSampler, error) {
+	var errs Errors
+	s, err := buildSampler(e.Sampler, path+".sampler")
+	errs.add(err)
+	errs.add(checkFraction(e.Boosted, path, "boosted"))
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	var options []sampler.ErrorHintOption
+	if len(e.Keys) != 0 {
+		keys := make([]attribute.Key, len(e.Keys))
+		for i, key := range e.Keys {
+			keys[i] = attribute.Key(key)
+		}
+		options = append(options, sampler.WithErrorHintKeys(keys...))
+	}
+	return sampler.ErrorHintBiased(s, *e.Boosted, options...), nil
+}
+
+func (r *ReplicaDecorrelated) build(path string) (sampler.ComposableSampler, error) {
+	var errs Errors
+	s, err := buildSampler(r.Sampler, path+".sampler")
+	errs.add(err)
+	switch {
+	case r.Jitter == nil:
+		errs.add(buildError(path+".jitter", "missing jitter"))
+	case !(*r.Jitter >= 0 && *r.Jitter < 1):
+		errs.add(buildError(path+".jitter", "jitter %v is not in the range [0, 1)", *r.Jitter))
+	}
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	return sampler.ReplicaDecorrelated(s, *r.Jitter), nil
+}
+
+func (i *InheritParentAttributes) build(path string) (sampler.ComposableSampler, error) {
+	var errs Errors
+	s, err := buildSampler(i.Sampler, path+".sampler")
+	errs.add(err)
+	if len(i.Keys) == 0 {
+		errs.add(buildError(path+".keys", "missing keys"))
+	}
+	keys := make([]attribute.Key, len(i.Keys))
+	for j, key := range i.Keys {
+		if key == "" {
+			errs.add(buildError(fmt.Sprintf("%s.keys[%d]", path, j), "empty key"))
+		}
+		keys[j] = attribute.Key(key)
+	}
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	return sampler.InheritParentAttributes(s, keys...), nil
+}
+
+// wrapSampler builds a sampler that wraps another.
+func wrapSampler(s *Sampler, path string, wrap func(sampler.ComposableSampler) sampler.ComposableSampler) (sampler.ComposableSampler, error) {
+	inner, err := buildSampler(s, path+".sampler")
+	if err != nil {
+		return nil, err
+	}
+	return wrap(inner), nil
+}
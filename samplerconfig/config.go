@@ -0,0 +1,471 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package samplerconfig builds composable samplers from declarative
+// YAML or JSON documents, following the shape of the composite
+// sampler in the OpenTelemetry declarative configuration schema.
+//
+// For example:
+//
+//	file_format: "0.4"
+//	sampler:
+//	  parent_threshold:
+//	    root:
+//	      rule_based:
+//	        rules:
+//	          - span_kinds: [server]
+//	            attribute_patterns:
+//	              key: url.path
+//	              included: ["/health*"]
+//	            sampler:
+//	              always_off:
+//	          - expr: 'Span.Attributes["error"] == true'
+//	            sampler:
+//	              always_on:
+//	        default:
+//	          probability:
+//	            ratio: 0.1
+//
+// Since JSON is a subset of YAML, the equivalent JSON document is
+// accepted by the same functions.  Unknown fields are rejected, and
+// errors identify the position of the problem in the document, as in
+//
+//	samplerconfig: line 9: sampler.parent_threshold.root.rule_based.rules[0]: unknown field "samplr"
+//
+// Values of local files, loaded by LoadFile and Watch, may refer to
+// environment variables, as in "${RATIO}" or "${RATIO:-0.1}" with a
+// default, which are substituted before the document is decoded.  An
+// unquoted value is interpreted after substitution, so that the
+// reference may supply a number.  Other documents are substituted only
+// with the WithEnvSubstitution option, so that documents received
+// over the network cannot read the environment.
+//
+// Jaeger sampling strategies files are loaded by LoadJaeger.
+package samplerconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/jmacd/sampler"
+)
+
+// Config is a sampler configuration document.
+type Config struct {
+	// FileFormat is the version of the configuration schema.
+	FileFormat string `yaml:"file_format,omitempty" json:"file_format,omitempty"`
+
+	// Version identifies the configuration, for example a release
+	// or commit of the document.  When it is not set, a Dynamic
+	// sampler identifies the configuration by a hash of its contents.
+	Version string `yaml:"version,omitempty" json:"version,omitempty"`
+
+	// Sampler is the root of the sampler tree.  When there are
+	// Profiles, it is the sampler of tracers that match none of them.
+	Sampler *Sampler `yaml:"sampler" json:"sampler"`
+
+	// Profiles select a sampler by the Resource of the tracer, so
+	// that one document can configure many services.  The first
+	// profile that matches is used.
+	Profiles []Profile `yaml:"profiles,omitempty" json:"profiles,omitempty"`
+}
+
+// Profile is the sampler of tracers whose Resource has the listed
+// attributes, for example:
+//
+//	profiles:
+//	  - resource:
+//	      service.name: checkout
+//	      deployment.environment: production
+//	    sampler:
+//	      probability:
+//	        ratio: 0.5
+//
+// Profiles are selected using sampler.ResourceAttributePredicate, so
+// they apply once the sampler is optimized for a Resource, see
+// sampler.Optimize, as by the WithResource option of Dynamic and
+// Watch, or when the CompositeSampler is configured with
+// sampler.WithResource, as by sampler.NewTracerProvider.
+type Profile struct {
+	// Resource lists the attributes that the Resource must have,
+	// all of which must be equal.
+	Resource map[string]any `yaml:"resource" json:"resource"`
+
+	// Sampler is the sampler of matching tracers.
+	Sampler *Sampler `yaml:"sampler" json:"sampler"`
+}
+
+// Sampler configures exactly one kind of sampler.
+type Sampler struct {
+	AlwaysOn        *AlwaysOn        `yaml:"always_on,omitempty" json:"always_on,omitempty"`
+	AlwaysOff       *AlwaysOff       `yaml:"always_off,omitempty" json:"always_off,omitempty"`
+	Probability     *Probability     `yaml:"probability,omitempty" json:"probability,omitempty"`
+	ParentThreshold *ParentThreshold `yaml:"parent_threshold,omitempty" json:"parent_threshold,omitempty"`
+	RuleBased       *RuleBased       `yaml:"rule_based,omitempty" json:"rule_based,omitempty"`
+	Annotating      *Annotating      `yaml:"annotating,omitempty" json:"annotating,omitempty"`
+
+	ParentRatio           *ParentRatio           `yaml:"parent_ratio,omitempty" json:"parent_ratio,omitempty"`
+	CostBased             *CostBased             `yaml:"cost_based,omitempty" json:"cost_based,omitempty"`
+	ErrorHintBiased       *ErrorHintBiased       `yaml:"error_hint_biased,omitempty" json:"error_hint_biased,omitempty"`
+	ReplicaDecorrelated   *ReplicaDecorrelated   `yaml:"replica_decorrelated,omitempty" json:"replica_decorrelated,omitempty"`
+	ExportOnly            *ExportOnly            `yaml:"export_only,omitempty" json:"export_only,omitempty"`
+	AnnotateAdjustedCount *AnnotateAdjustedCount `yaml:"annotate_adjusted_count,omitempty" json:"annotate_adjusted_count,omitempty"`
+
+	InheritParentAttributes *InheritParentAttributes `yaml:"inherit_parent_attributes,omitempty" json:"inherit_parent_attributes,omitempty"`
+
+	// Rate is a probability or a rate limit in a form that is hard
+	// to get wrong: a fraction such as "1/1000", a percentage such
+	// as "0.1%", a number such as "0.001", or a limit of traces per
+	// second, minute, or hour such as "50/s", "10/m", or "100/h".
+	// Probabilities are sampler.TraceIDRatioBasedRational with an
+	// exact threshold, and limits are sampler.CostBased with a cost
+	// of one per span.
+	Rate string `yaml:"rate,omitempty" json:"rate,omitempty"`
+
+	// Preset names a built-in configuration: "debug" samples every
+	// span and adds the attribute sampler.preset=debug, "off"
+	// samples nothing, and "production-default" samples 10% of
+	// traces consistently, following the parent's decision, except
+	// health checks (url.path or http.route matching /health*,
+	// /ready*, or /live*), which are not sampled.
+	Preset string `yaml:"preset,omitempty" json:"preset,omitempty"`
+
+	// Custom configures samplers registered with RegisterSampler, by
+	// name.
+	Custom map[string]any `yaml:",inline" json:"-"`
+}
+
+// AlwaysOn configures sampler.ComposableAlwaysSample.
+type AlwaysOn struct{}
+
+// AlwaysOff configures sampler.ComposableNeverSample.
+type AlwaysOff struct{}
+
+// Probability configures sampler.TraceIDRatioBased, or, when
+// Threshold is set, sampler.TraceIDRatioFromThreshold.
+type Probability struct {
+	// Ratio is the sampling probability, in the range [0, 1].
+	Ratio *float64 `yaml:"ratio,omitempty" json:"ratio,omitempty"`
+
+	// Threshold is an exact rejection threshold in the tracestate
+	// encoding, e.g., "fff" for probability 1/4096, instead of a
+	// Ratio.
+	Threshold string `yaml:"threshold,omitempty" json:"threshold,omitempty"`
+
+	// Rounding is one of "nearest" (the default), "down", or "up",
+	// see sampler.WithRounding.
+	Rounding string `yaml:"rounding,omitempty" json:"rounding,omitempty"`
+}
+
+// ParentThreshold configures sampler.ParentThreshold, or, when Root
+// is set, sampler.ComposableParentBased with Root for root spans.
+type ParentThreshold struct {
+	Root *Sampler `yaml:"root,omitempty" json:"root,omitempty"`
+}
+
+// RuleBased configures sampler.RuleBased.
+type RuleBased struct {
+	// Rules are evaluated in order.
+	Rules []Rule `yaml:"rules" json:"rules"`
+
+	// Default, if set, is used when no rule matches.
+	Default *Sampler `yaml:"default,omitempty" json:"default,omitempty"`
+
+	// CombineMatching selects sampler.WithCombineMatching.
+	CombineMatching bool `yaml:"combine_matching,omitempty" json:"combine_matching,omitempty"`
+}
+
+// Rule is one rule of a RuleBased sampler.  The rule matches when
+// every condition that is set matches; a rule with no conditions
+// matches every span.
+type Rule struct {
+	// Expr is a predicate expression, see sampler.ParsePredicate.
+	Expr string `yaml:"expr,omitempty" json:"expr,omitempty"`
+
+	// SpanKinds matches any of the listed kinds, which are "server",
+	// "client", "producer", "consumer", and "internal".
+	SpanKinds []string `yaml:"span_kinds,omitempty" json:"span_kinds,omitempty"`
+
+	// Parent matches any of the listed parent kinds, which are
+	// "none", "remote", and "local".
+	Parent []string `yaml:"parent,omitempty" json:"parent,omitempty"`
+
+	// SpanNames matches any of the listed span names.
+	SpanNames []string `yaml:"span_names,omitempty" json:"span_names,omitempty"`
+
+	AttributeValues   *AttributeValues   `yaml:"attribute_values,omitempty" json:"attribute_values,omitempty"`
+	AttributePatterns *AttributePatterns `yaml:"attribute_patterns,omitempty" json:"attribute_patterns,omitempty"`
+
+	// Custom configures other predicates by name: the built-in
+	// predicates written by PredicateConfig, such as
+	// attribute_equals, and those registered with RegisterPredicate.
+	Custom map[string]any `yaml:",inline" json:"-"`
+
+	// Sampler is the sampler used by spans matching the rule.
+	Sampler *Sampler `yaml:"sampler" json:"sampler"`
+
+	// Attributes are added to spans sampled by the rule, for
+	// example to name the policy that sampled them, as if Sampler
+	// were configured by Annotating.
+	Attributes map[string]string `yaml:"attributes,omitempty" json:"attributes,omitempty"`
+}
+
+// AttributeValues matches spans whose attribute Key has any of the
+// listed values, compared with the attribute's string form, so that
+// "500" matches both the string "500" and the integer 500.  See
+// sampler.AttributeValueInSetPredicate.
+type AttributeValues struct {
+	Key    string   `yaml:"key" json:"key"`
+	Values []string `yaml:"values" json:"values"`
+}
+
+// AttributePatterns matches spans having a string attribute Key that
+// matches any Included pattern and no Excluded pattern.  Patterns use
+// the syntax of sampler.SpanNameGlobPredicate.  When Included is
+// empty, every value is included.
+type AttributePatterns struct {
+	Key      string   `yaml:"key" json:"key"`
+	Included []string `yaml:"included,omitempty" json:"included,omitempty"`
+	Excluded []string `yaml:"excluded,omitempty" json:"excluded,omitempty"`
+}
+
+// Annotating configures sampler.AnnotatingSampler, adding static
+// attributes to the spans it samples.
+type Annotating struct {
+	Sampler    *Sampler          `yaml:"sampler" json:"sampler"`
+	Attributes map[string]string `yaml:"attributes,omitempty" json:"attributes,omitempty"`
+
+	// NonSampledAttributes are added to spans that are not sampled,
+	// see sampler.WithNonSampledAttributes.
+	NonSampledAttributes map[string]string `yaml:"non_sampled_attributes,omitempty" json:"non_sampled_attributes,omitempty"`
+
+	// IfWouldSample selects sampler.WithAttributesIfWouldSample.
+	IfWouldSample bool `yaml:"if_would_sample,omitempty" json:"if_would_sample,omitempty"`
+}
+
+// ParentRatio configures sampler.ParentRatioBased.
+type ParentRatio struct {
+	// Ratio is the fraction of the parent's sampled spans to
+	// sample, in the range [0, 1].
+	Ratio *float64 `yaml:"ratio" json:"ratio"`
+}
+
+// CostBased configures sampler.CostBased.
+type CostBased struct {
+	// BytesPerSecond is the budget of estimated span bytes.
+	BytesPerSecond *float64 `yaml:"bytes_per_second" json:"bytes_per_second"`
+
+	SpanNameCosts   map[string]float64 `yaml:"span_name_costs,omitempty" json:"span_name_costs,omitempty"`
+	DefaultSpanCost *float64           `yaml:"default_span_cost,omitempty" json:"default_span_cost,omitempty"`
+	AttributeCost   *float64           `yaml:"attribute_cost,omitempty" json:"attribute_cost,omitempty"`
+
+	// Interval is a duration such as "1s", see sampler.WithCostInterval.
+	Interval string `yaml:"interval,omitempty" json:"interval,omitempty"`
+}
+
+// ErrorHintBiased configures sampler.ErrorHintBiased.
+type ErrorHintBiased struct {
+	Sampler *Sampler `yaml:"sampler" json:"sampler"`
+
+	// Boosted is the probability used for spans with error hints.
+	Boosted *float64 `yaml:"boosted" json:"boosted"`
+
+	// Keys are attribute keys in addition to the defaults, see
+	// sampler.WithErrorHintKeys.
+	Keys []string `yaml:"keys,omitempty" json:"keys,omitempty"`
+}
+
+// ReplicaDecorrelated configures sampler.ReplicaDecorrelated.
+type ReplicaDecorrelated struct {
+	Sampler *Sampler `yaml:"sampler" json:"sampler"`
+	Jitter  *float64 `yaml:"jitter" json:"jitter"`
+}
+
+// ExportOnly configures sampler.ExportOnlySampler.
+type ExportOnly struct {
+	Sampler *Sampler `yaml:"sampler" json:"sampler"`
+}
+
+// AnnotateAdjustedCount configures sampler.AnnotateAdjustedCount.
+type AnnotateAdjustedCount struct {
+	Sampler *Sampler `yaml:"sampler" json:"sampler"`
+}
+
+// InheritParentAttributes configures sampler.InheritParentAttributes.
+type InheritParentAttributes struct {
+	Sampler *Sampler `yaml:"sampler" json:"sampler"`
+
+	// Keys are the attribute keys copied from the local parent span.
+	Keys []string `yaml:"keys" json:"keys"`
+}
+
+// MarshalJSON encodes the sampler with its Custom entries inline.
+func (s Sampler) MarshalJSON() ([]byte, error) {
+	type plain Sampler
+	return marshalInline(plain(s), s.Custom)
+}
+
+// MarshalJSON encodes the rule with its Custom entries inline.
+func (r Rule) MarshalJSON() ([]byte, error) {
+	type plain Rule
+	return marshalInline(plain(r), r.Custom)
+}
+
+// marshalInline encodes v as a JSON object with the entries of custom
+// added to its fields.
+func marshalInline(v any, custom map[string]any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(custom) == 0 {
+		return data, err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for name, config := range custom {
+		fields[name] = config
+	}
+	return json.Marshal(fields)
+}
+
+// Error is a configuration error.  Line is zero for errors detected
+// after parsing.
+type Error struct {
+	Line int    // the line number in the document, starting at 1
+	Path string // the location in the document, e.g., "sampler.probability.ratio"
+	Msg  string // description of the error
+}
+
+func (e *Error) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("samplerconfig: %s: %s", e.Path, e.Msg)
+	}
+	return fmt.Sprintf("samplerconfig: line %d: %s: %s", e.Line, e.Path, e.Msg)
+}
+
+// Pointer returns the path as a JSON pointer (RFC 6901), e.g.,
+// "/sampler/rule_based/rules/0/sampler" for the path
+// "sampler.rule_based.rules[0].sampler".  Since the path separates
+// names with ".", names containing "." are split.
+func (e *Error) Pointer() string {
+	if e.Path == "(document)" {
+		return ""
+	}
+	var b strings.Builder
+	for _, name := range strings.FieldsFunc(e.Path, func(r rune) bool {
+		return r == '.' || r == '[' || r == ']'
+	}) {
+		b.WriteByte('/')
+		b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(name))
+	}
+	return b.String()
+}
+
+// Errors is a list of configuration errors, returned when there is
+// more than one.
+type Errors []*Error
+
+func (errs Errors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap supports errors.As for the individual errors.
+func (errs Errors) Unwrap() []error {
+	list := make([]error, len(errs))
+	for i, err := range errs {
+		list[i] = err
+	}
+	return list
+}
+
+// add appends the errors of err, which is an *Error or Errors.
+func (errs *Errors) add(err error) {
+	switch err := err.(type) {
+	case nil:
+	case Errors:
+		*errs = append(*errs, err...)
+	case *Error:
+		*errs = append(*errs, err)
+	default:
+		*errs = append(*errs, &Error{Path: "(document)", Msg: err.Error()})
+	}
+}
+
+// err returns nil for no errors, an *Error for one error, or the
+// list.
+func (errs Errors) err() error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	return errs
+}
+
+// ParseOption configures Parse, Load, and ValidateDocument.
+type ParseOption func(*parseConfig)
+
+type parseConfig struct {
+	lookupEnv func(string) (string, bool)
+}
+
+// WithEnvSubstitution substitutes references to environment variables
+// in the document's values, see the package documentation.  Use it
+// only for trusted documents.
+func WithEnvSubstitution() ParseOption {
+	return func(c *parseConfig) {
+		c.lookupEnv = os.LookupEnv
+	}
+}
+
+func newParseConfig(options []ParseOption) parseConfig {
+	var cfg parseConfig
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// Parse parses a YAML or JSON document.  The document is checked
+// against the schema, but the samplers are not built.
+func Parse(data []byte, options ...ParseOption) (*Config, error) {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(&doc); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("samplerconfig: %w", err)
+	}
+	var cfg Config
+	if err := decode(&doc, &cfg, newParseConfig(options)).err(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Load parses a YAML or JSON document and builds its sampler.
+func Load(data []byte, options ...ParseOption) (sampler.ComposableSampler, error) {
+	cfg, err := Parse(data, options...)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Build()
+}
+
+// LoadFile is Load for the contents of a file, with
+// WithEnvSubstitution.
+func LoadFile(name string) (sampler.ComposableSampler, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("samplerconfig: %w", err)
+	}
+	return Load(data, WithEnvSubstitution())
+}
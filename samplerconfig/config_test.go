@@ -0,0 +1,429 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package samplerconfig
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jmacd/sampler"
+)
+
+const testYAML = `
+file_format: "0.4"
+sampler:
+  parent_threshold:
+    root:
+      rule_based:
+        rules:
+          - span_kinds: [server]
+            attribute_patterns:
+              key: url.path
+              included: ["/health*"]
+            sampler:
+              always_off:
+          - expr: 'Span.Attributes["error"] == true'
+            sampler:
+              annotating:
+                sampler:
+                  always_on:
+                attributes:
+                  policy: errors
+        default:
+          probability:
+            ratio: 0.25
+`
+
+const testJSON = `{
+  "file_format": "0.4",
+  "sampler": {
+    "parent_threshold": {
+      "root": {
+        "rule_based": {
+          "rules": [
+            {
+              "span_kinds": ["server"],
+              "attribute_patterns": {"key": "url.path", "included": ["/health*"]},
+              "sampler": {"always_off": {}}
+            },
+            {
+              "expr": "Span.Attributes[\"error\"] == true",
+              "sampler": {
+                "annotating": {
+                  "sampler": {"always_on": null},
+                  "attributes": {"policy": "errors"}
+                }
+              }
+            }
+          ],
+          "default": {"probability": {"ratio": 0.25}}
+        }
+      }
+    }
+  }
+}`
+
+func TestLoad(t *testing.T) {
+	errorPred, err := sampler.ParsePredicate(`Span.Attributes["error"] == true`)
+	require.NoError(t, err)
+	expect := sampler.ComposableParentBased(sampler.RuleBased(
+		sampler.WithRule(
+			sampler.AndPredicate(
+				sampler.SpanKindPredicate(trace.SpanKindServer),
+				sampler.AttributeGlobPredicate("url.path", "/health*"),
+			),
+			sampler.ComposableNeverSample(),
+		),
+		sampler.WithRule(errorPred, sampler.AnnotatingSampler(sampler.ComposableAlwaysSample(),
+			sampler.WithSampledAttributes(func() []attribute.KeyValue {
+				return []attribute.KeyValue{attribute.String("policy", "errors")}
+			}),
+		)),
+		sampler.WithDefaultRule(sampler.TraceIDRatioBased(0.25)),
+	))
+
+	for _, doc := range []string{testYAML, testJSON} {
+		s, err := Load([]byte(doc))
+		require.NoError(t, err)
+		require.Equal(t, expect.Description(), s.Description())
+	}
+}
+
+func TestLoadDecides(t *testing.T) {
+	s, err := Load([]byte(testYAML))
+	require.NoError(t, err)
+
+	params := func(kind trace.SpanKind, attrs ...attribute.KeyValue) sampler.ComposableSamplingParameters {
+		return sampler.ComposableSamplingParameters{
+			SamplingParameters: sampler.SamplingParameters{
+				Kind:       kind,
+				Attributes: attrs,
+			},
+		}
+	}
+	health := s.GetSamplingIntent(params(trace.SpanKindServer, attribute.String("url.path", "/healthz")))
+	require.Equal(t, sampler.NEVER_SAMPLE_THRESHOLD, health.Threshold)
+
+	errored := s.GetSamplingIntent(params(trace.SpanKindServer, attribute.Bool("error", true)))
+	require.Equal(t, sampler.ALWAYS_SAMPLE_THRESHOLD, errored.Threshold)
+	require.Equal(t, []attribute.KeyValue{attribute.String("policy", "errors")}, errored.Attributes())
+
+	other := s.GetSamplingIntent(params(trace.SpanKindClient))
+	require.Equal(t, sampler.ProbabilityToThreshold(0.25), other.Threshold)
+}
+
+func TestLoadRules(t *testing.T) {
+	s, err := Load([]byte(`
+sampler:
+  rule_based:
+    combine_matching: true
+    rules:
+      - parent: [none, remote]
+        span_names: [GET, POST]
+        sampler:
+          probability: {ratio: 0.5, rounding: down}
+      - attribute_values: {key: http.response.status_code, values: [500, 503]}
+        attribute_patterns: {key: url.path, excluded: ["/internal/*"]}
+        sampler:
+          parent_threshold:
+        attributes:
+          sampling.policy: errors
+          sampling.team: web
+`))
+	require.NoError(t, err)
+
+	expect := sampler.RuleBased(
+		sampler.WithRule(
+			sampler.AndPredicate(
+				sampler.OrPredicate(sampler.IsRootPredicate(), sampler.IsRemoteParentPredicate()),
+				sampler.SpanNameInSetPredicate("GET", "POST"),
+			),
+			sampler.TraceIDRatioBased(0.5, sampler.WithRounding(sampler.RoundProbabilityDown)),
+		),
+		sampler.WithRule(
+			sampler.AndPredicate(
+				sampler.AttributeValueInSetPredicate("http.response.status_code", "500", "503"),
+				sampler.AttributeGlobPredicate("url.path", "*"),
+				sampler.NotPredicate(sampler.AttributeGlobPredicate("url.path", "/internal/*")),
+			),
+			sampler.AnnotatingSampler(sampler.ParentThreshold(), sampler.WithSampledAttributes(func() []attribute.KeyValue {
+				return []attribute.KeyValue{
+					attribute.String("sampling.policy", "errors"),
+					attribute.String("sampling.team", "web"),
+				}
+			})),
+		),
+		sampler.WithCombineMatching(),
+	)
+	require.Equal(t, expect.Description(), s.Description())
+
+	// Values match attributes of any type by their string form.
+	params := func(status attribute.KeyValue) sampler.ComposableSamplingParameters {
+		return sampler.ComposableSamplingParameters{
+			SamplingParameters: sampler.SamplingParameters{
+				Kind:       trace.SpanKindServer,
+				Attributes: []attribute.KeyValue{status, attribute.String("url.path", "/users")},
+			},
+		}
+	}
+	for _, status := range []attribute.KeyValue{
+		attribute.Int("http.response.status_code", 500),
+		attribute.String("http.response.status_code", "500"),
+	} {
+		intent := s.GetSamplingIntent(params(status))
+		require.Equal(t, []attribute.KeyValue{
+			attribute.String("sampling.policy", "errors"),
+			attribute.String("sampling.team", "web"),
+		}, intent.Attributes(), status.Value.Type())
+	}
+	intent := s.GetSamplingIntent(params(attribute.Int("http.response.status_code", 200)))
+	require.Nil(t, intent.Attributes)
+}
+
+func TestLoadProfiles(t *testing.T) {
+	s, err := Load([]byte(`
+profiles:
+  - resource:
+      service.name: checkout
+      deployment.environment: production
+    sampler:
+      probability: {ratio: 0.5}
+  - resource:
+      service.name: checkout
+    sampler:
+      always_on:
+sampler:
+  probability: {ratio: 0.01}
+`))
+	require.NoError(t, err)
+
+	intent := func(attrs ...attribute.KeyValue) sampler.Threshold {
+		opt := sampler.Optimize(s, resource.NewSchemaless(attrs...), instrumentation.Scope{})
+		return opt.GetSamplingIntent(sampler.ComposableSamplingParameters{}).Threshold
+	}
+	checkout := attribute.String("service.name", "checkout")
+	require.Equal(t, sampler.ProbabilityToThreshold(0.5), intent(checkout, attribute.String("deployment.environment", "production")))
+	require.Equal(t, sampler.ALWAYS_SAMPLE_THRESHOLD, intent(checkout, attribute.String("deployment.environment", "staging")))
+	require.Equal(t, sampler.ProbabilityToThreshold(0.01), intent(attribute.String("service.name", "search")))
+
+	// Without a Resource, the default applies.
+	require.Equal(t, sampler.ProbabilityToThreshold(0.01), s.GetSamplingIntent(sampler.ComposableSamplingParameters{}).Threshold)
+
+	_, err = Load([]byte(`
+profiles:
+  - resource: {}
+    sampler: {always_on: }
+  - resource: {service.name: a}
+sampler: {always_off: }
+`))
+	require.EqualError(t, err, "samplerconfig: profiles[0].resource: missing resource\n"+
+		"samplerconfig: profiles[1].sampler: missing sampler")
+}
+
+func TestLoadErrors(t *testing.T) {
+	for _, test := range []struct {
+		doc    string
+		errstr string
+	}{
+		{"", "samplerconfig: sampler: missing sampler"},
+		{"sampler: [1]", "samplerconfig: line 1: sampler: expected a mapping"},
+		{"sampler:\n  always_on:\n  always_off:\n",
+			"samplerconfig: line 2: sampler: expected one sampler type, found always_on and always_off"},
+		{"sampler:\n  sometimes:\n", `samplerconfig: line 2: sampler: unknown field "sometimes"`},
+		{"sampler: {}", "samplerconfig: line 1: sampler: expected one of always_on, always_off, probability, parent_threshold, rule_based, annotating, parent_ratio, cost_based, error_hint_biased, replica_decorrelated, export_only, annotate_adjusted_count, inherit_parent_attributes, rate, preset"},
+		{"sampler:\n  probability:\n    ratio: lots\n", "samplerconfig: line 3: sampler.probability.ratio: expected a number"},
+		{"sampler:\n  probability:\n", "samplerconfig: sampler.probability.ratio: missing ratio"},
+		{"sampler:\n  probability: {ratio: 2}\n", "samplerconfig: sampler.probability.ratio: ratio 2 is not in the range [0, 1]"},
+		{"sampler:\n  replica_decorrelated: {sampler: {always_on: {}}, jitter: 1}\n",
+			"samplerconfig: sampler.replica_decorrelated.jitter: jitter 1 is not in the range [0, 1)"},
+		{"sampler:\n  probability: {ratio: 1, rounding: sideways}\n",
+			`samplerconfig: sampler.probability.rounding: unknown rounding "sideways", expected nearest, down, or up`},
+		{`
+sampler:
+  rule_based:
+    rules:
+      - span_kinds: [server]
+        samplr:
+          always_on:
+`, `samplerconfig: line 6: sampler.rule_based.rules[0]: unknown field "samplr"`},
+		{`
+sampler:
+  rule_based:
+    rules:
+      - span_kinds: [server]
+`, "samplerconfig: sampler.rule_based.rules[0].sampler: missing sampler"},
+		{`
+sampler:
+  rule_based:
+    rules:
+      - span_kinds: [server, waiter]
+        sampler: {always_on: }
+`, `samplerconfig: sampler.rule_based.rules[0].span_kinds[1]: unknown span kind "waiter", expected server, client, producer, consumer, or internal`},
+		{`
+sampler:
+  rule_based:
+    rules:
+      - parent: [adopted]
+        sampler: {always_on: }
+`, `samplerconfig: sampler.rule_based.rules[0].parent[0]: unknown parent "adopted", expected none, remote, or local`},
+		{`
+sampler:
+  rule_based:
+    rules:
+      - expr: 'Span.Name =='
+        sampler: {always_on: }
+`, `samplerconfig: sampler.rule_based.rules[0].expr: predicate expression at offset 12: unexpected end of expression, expected a value: "Span.Name =="`},
+		{`
+sampler:
+  rule_based:
+    rules:
+      - attribute_values: {values: [x]}
+        sampler: {always_on: }
+`, "samplerconfig: sampler.rule_based.rules[0].attribute_values.key: missing key"},
+		{`
+sampler:
+  annotating:
+    attributes: {a: [b]}
+`, "samplerconfig: line 4: sampler.annotating.attributes.a: expected a string"},
+		{"sampler:\n  probability: {ratio: 0.5, threshold: '8'}\n", "samplerconfig: sampler.probability: expected ratio or threshold, found both"},
+		{"sampler:\n  error_hint_biased: {sampler: {always_off: }}\n", "samplerconfig: sampler.error_hint_biased.boosted: missing boosted"},
+		{`
+sampler:
+  rule_based:
+    rules:
+      - or: [{span_name: a}, {attribute_equals: {value: 1}}]
+        sampler: {always_on: }
+`, "samplerconfig: sampler.rule_based.rules[0].or[1].attribute_equals.key: missing key"},
+		{`
+sampler:
+  rule_based:
+    rules:
+      - not: {span_name: a, span_kinds: [server]}
+        sampler: {always_on: }
+`, "samplerconfig: sampler.rule_based.rules[0].not: expected a mapping with one predicate"},
+		{"sampler: {always_on: {}", "samplerconfig: yaml: line 1: did not find expected ',' or '}'"},
+	} {
+		_, err := Load([]byte(test.doc))
+		require.EqualError(t, err, test.errstr, test.doc)
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	pred := func(p sampler.Predicate, err error) sampler.Predicate {
+		require.NoError(t, err)
+		return p
+	}
+	s := sampler.RuleBased(
+		sampler.WithRule(sampler.AndPredicate(
+			sampler.SpanKindPredicate(trace.SpanKindServer, trace.SpanKindConsumer),
+			sampler.OrPredicate(
+				sampler.IsRemoteParentPredicate(),
+				sampler.IsParentSampledPredicate(),
+				sampler.HasLinksPredicate(),
+			),
+			sampler.NotPredicate(sampler.AttributeGlobPredicate("url.path", "/internal/*")),
+			pred(sampler.AttributeRegexPredicate("user.id", "^test-")),
+			pred(sampler.SpanNameRegexPredicate("^GET ")),
+		), sampler.ErrorHintBiased(sampler.TraceIDRatioBasedN(3), 0.5, sampler.WithErrorHintKeys("retry"))),
+		sampler.WithRule(sampler.OrPredicate(
+			sampler.AttributeEqualsPredicate(attribute.Int("http.response.status_code", 503)),
+			sampler.AttributeEqualsPredicate(attribute.StringSlice("tags", []string{"a", "b"})),
+			sampler.AttributeValueInSetPredicate("http.request.method", "GET", "HEAD"),
+			sampler.AttributeRangePredicate("size", 10, 20.5),
+			sampler.HTTPRoutePredicate("/api/*", "POST"),
+			sampler.ResourceAttributePredicate(attribute.String("deployment.environment", "staging")),
+			sampler.ScopePredicate(instrumentation.Scope{Name: "lib", Version: "1.0"}),
+			sampler.ParentProbabilityAtLeastPredicate(0.01),
+			sampler.TraceFractionPredicate(0.05),
+			sampler.LinkAttributeEqualsPredicate(attribute.Bool("retry", true)),
+			sampler.BaggageEqualsPredicate("tenant", "a"),
+			sampler.OTelTraceStateFieldPredicate("rv"),
+			sampler.TraceStateMemberEqualsPredicate("vnd", "x"),
+			sampler.HasParentAttributePredicate("tenant"),
+			sampler.ParentAttributeEqualsPredicate(attribute.String("tenant", "a")),
+		), sampler.ExportOnlySampler(sampler.CostBased(1000, sampler.WithSpanNameCost("big", 4096), sampler.WithCostInterval(time.Minute)))),
+		sampler.WithRule(sampler.SpanNameInSetPredicate("a", "b"),
+			sampler.AnnotatingSampler(sampler.ParentRatioBased(0.5),
+				sampler.WithNonSampledAttributeValues(attribute.String("dropped", "yes")),
+				sampler.WithAttributesIfWouldSample())),
+		sampler.WithDefaultRule(sampler.InheritParentAttributes(sampler.AnnotateAdjustedCount(sampler.ReplicaDecorrelated(sampler.TraceIDRatioBased(0.1), 0.05)), "tenant", "region")),
+		sampler.WithCombineMatching(),
+	)
+	data, err := MarshalConfig(s)
+	require.NoError(t, err)
+
+	loaded, err := Load(data)
+	require.NoError(t, err)
+	again, err := MarshalConfig(loaded)
+	require.NoError(t, err)
+	require.JSONEq(t, string(data), string(again))
+
+	_, err = Load([]byte(`{"sampler": {"rule_based": {"rules": [{"opaque": {"description": "x"}, "sampler": {"always_on": {}}}]}}}`))
+	require.EqualError(t, err, `samplerconfig: line 1: sampler.rule_based.rules[0]: unknown field "opaque"`)
+}
+
+func TestLoadPresets(t *testing.T) {
+	for _, test := range []struct {
+		preset string
+		desc   string
+	}{
+		{"debug", "Annotate(AlwaysOn, sampler.preset=debug)"},
+		{"off", "AlwaysOff"},
+		{"production-default", "RuleBased{rule(root?)=RuleBased{" +
+			"rule(or(Span.Attributes[url.path] glob /health*,Span.Attributes[url.path] glob /ready*,Span.Attributes[url.path] glob /live*))=AlwaysOff," +
+			"rule(or(Span.Attributes[http.route] glob /health*,Span.Attributes[http.route] glob /ready*,Span.Attributes[http.route] glob /live*))=AlwaysOff," +
+			"rule(true)=TraceIDRatioBased{0.1}},rule(true)=ParentThreshold}"},
+	} {
+		s, err := Load([]byte("sampler:\n  preset: " + test.preset + "\n"))
+		require.NoError(t, err, test.preset)
+		require.Equal(t, test.desc, s.Description(), test.preset)
+
+		env, err := ParseEnv("preset", test.preset)
+		require.NoError(t, err, test.preset)
+		require.Equal(t, test.desc, env.Description(), test.preset)
+	}
+
+	_, err := Load([]byte("sampler:\n  preset: sometimes\n"))
+	require.EqualError(t, err, `samplerconfig: sampler.preset: unknown preset "sometimes", expected debug, off, or production-default`)
+	_, err = ParseEnv("preset", "")
+	require.EqualError(t, err, `samplerconfig: OTEL_TRACES_SAMPLER_ARG: unknown preset "", expected debug, off, or production-default`)
+}
+
+func TestLoadRates(t *testing.T) {
+	for _, test := range []struct {
+		rate string
+		desc string
+	}{
+		{"1/1000", "TraceIDRatioBased{1/1000}"},
+		{"0.1%", "TraceIDRatioBased{1/1000}"},
+		{"0.001", "TraceIDRatioBased{1/1000}"},
+		{"2.5 / 10", "TraceIDRatioBased{1/4}"},
+		{"100%", "AlwaysOn"},
+		{"0", "AlwaysOff"},
+		{"50/s", "CostBased{50}"},
+		{"120/min", "CostBased{2}"},
+	} {
+		s, err := Load([]byte("sampler:\n  rate: " + test.rate + "\n"))
+		require.NoError(t, err, test.rate)
+		require.Equal(t, test.desc, s.Description(), test.rate)
+	}
+
+	for _, test := range []struct {
+		rate   string
+		errstr string
+	}{
+		{"often", `invalid rate "often", expected a probability such as 1/1000, 0.1%, or 0.001, or a limit such as 50/s`},
+		{"1/0", `invalid rate "1/0", expected a probability such as 1/1000, 0.1%, or 0.001, or a limit such as 50/s`},
+		{"150%", `rate "150%" is not a probability in the range [0, 1]`},
+		{"-1/s", `invalid rate "-1/s", expected a non-negative number of traces per s`},
+	} {
+		_, err := Load([]byte("sampler:\n  rate: " + test.rate + "\n"))
+		require.EqualError(t, err, "samplerconfig: sampler.rate: "+test.errstr, test.rate)
+	}
+}
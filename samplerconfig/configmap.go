@@ -0,0 +1,41 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package samplerconfig
+
+import (
+	"path/filepath"
+)
+
+// DefaultConfigMapKey is the ConfigMap key used by WatchConfigMap when
+// none is given.
+const DefaultConfigMapKey = "sampler.yaml"
+
+// WatchConfigMap watches the key of a Kubernetes ConfigMap mounted as
+// a volume at dir, for example "/etc/sampler".  An empty key means
+// DefaultConfigMapKey.
+//
+// The kubelet updates a mounted ConfigMap by writing a new timestamped
+// directory and atomically replacing the "..data" symbolic link that
+// each key's file links through, so the file is never partly written
+// but its own metadata does not change.  The Watcher compares file
+// contents, which observes these updates as well as ordinary writes.
+// Note that volumes mounted using subPath are not updated by the
+// kubelet.
+//
+// To read the ConfigMap through the Kubernetes API instead, use
+// WatchSource with a function that gets it using a client, as in
+//
+//	samplerconfig.WatchSource(func(ctx context.Context) ([]byte, error) {
+//		cm, err := client.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+//		if err != nil {
+//			return nil, err
+//		}
+//		return []byte(cm.Data[samplerconfig.DefaultConfigMapKey]), nil
+//	})
+func WatchConfigMap(dir, key string, options ...WatchOption) (*Watcher, error) {
+	if key == "" {
+		key = DefaultConfigMapKey
+	}
+	return Watch(filepath.Join(dir, key), options...)
+}
@@ -0,0 +1,61 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package configservice
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+)
+
+// Client pushes configurations to a SamplerConfig service.
+type Client struct {
+	client SamplerConfigClient
+}
+
+// NewClient returns a Client using the connection.
+func NewClient(cc grpc.ClientConnInterface) *Client {
+	return &Client{client: NewSamplerConfigClient(cc)}
+}
+
+// Apply sends a configuration, returning an error if it was not
+// applied.
+func (c *Client) Apply(ctx context.Context, version uint64, document []byte) error {
+	ack, err := c.client.Apply(ctx, &ApplyRequest{
+		Configuration: &Configuration{
+			Version:  version,
+			Document: document,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return ackError(ack)
+}
+
+// Rollback restores the previous configuration, returning its
+// version.  If version is nonzero, the rollback happens only if that
+// version is active.
+func (c *Client) Rollback(ctx context.Context, version uint64) (uint64, error) {
+	ack, err := c.client.Rollback(ctx, &RollbackRequest{
+		Version: version,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return ack.GetActiveVersion(), ackError(ack)
+}
+
+// Active returns the active configuration.
+func (c *Client) Active(ctx context.Context) (*Configuration, error) {
+	return c.client.GetActive(ctx, &GetActiveRequest{})
+}
+
+func ackError(ack *Acknowledgement) error {
+	if ack.GetStatus() == Acknowledgement_REJECTED {
+		return fmt.Errorf("configservice: rejected (active version %d): %s", ack.GetActiveVersion(), ack.GetError())
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: configservice.proto
+
+package configservice
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	SamplerConfig_Apply_FullMethodName     = "/jmacd.sampler.config.v1.SamplerConfig/Apply"
+	SamplerConfig_Rollback_FullMethodName  = "/jmacd.sampler.config.v1.SamplerConfig/Rollback"
+	SamplerConfig_GetActive_FullMethodName = "/jmacd.sampler.config.v1.SamplerConfig/GetActive"
+)
+
+// SamplerConfigClient is the client API for SamplerConfig service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// SamplerConfig manages the sampler configuration of a running process.
+type SamplerConfigClient interface {
+	// Apply validates and activates a configuration.  Configurations
+	// with a version not greater than the active version are rejected.
+	Apply(ctx context.Context, in *ApplyRequest, opts ...grpc.CallOption) (*Acknowledgement, error)
+	// Rollback restores the configuration that was active before the
+	// active one.
+	Rollback(ctx context.Context, in *RollbackRequest, opts ...grpc.CallOption) (*Acknowledgement, error)
+	// GetActive returns the active configuration.
+	GetActive(ctx context.Context, in *GetActiveRequest, opts ...grpc.CallOption) (*Configuration, error)
+}
+
+type samplerConfigClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSamplerConfigClient(cc grpc.ClientConnInterface) SamplerConfigClient {
+	return &samplerConfigClient{cc}
+}
+
+func (c *samplerConfigClient) Apply(ctx context.Context, in *ApplyRequest, opts ...grpc.CallOption) (*Acknowledgement, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Acknowledgement)
+	err := c.cc.Invoke(ctx, SamplerConfig_Apply_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samplerConfigClient) Rollback(ctx context.Context, in *RollbackRequest, opts ...grpc.CallOption) (*Acknowledgement, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Acknowledgement)
+	err := c.cc.Invoke(ctx, SamplerConfig_Rollback_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samplerConfigClient) GetActive(ctx context.Context, in *GetActiveRequest, opts ...grpc.CallOption) (*Configuration, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Configuration)
+	err := c.cc.Invoke(ctx, SamplerConfig_GetActive_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SamplerConfigServer is the server API for SamplerConfig service.
+// All implementations must embed UnimplementedSamplerConfigServer
+// for forward compatibility.
+//
+// SamplerConfig manages the sampler configuration of a running process.
+type SamplerConfigServer interface {
+	// Apply validates and activates a configuration.  Configurations
+	// with a version not greater than the active version are rejected.
+	Apply(context.Context, *ApplyRequest) (*Acknowledgement, error)
+	// Rollback restores the configuration that was active before the
+	// active one.
+	Rollback(context.Context, *RollbackRequest) (*Acknowledgement, error)
+	// GetActive returns the active configuration.
+	GetActive(context.Context, *GetActiveRequest) (*Configuration, error)
+	mustEmbedUnimplementedSamplerConfigServer()
+}
+
+// UnimplementedSamplerConfigServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSamplerConfigServer struct{}
+
+func (UnimplementedSamplerConfigServer) Apply(context.Context, *ApplyRequest) (*Acknowledgement, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Apply not implemented")
+}
+func (UnimplementedSamplerConfigServer) Rollback(context.Context, *RollbackRequest) (*Acknowledgement, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Rollback not implemented")
+}
+func (UnimplementedSamplerConfigServer) GetActive(context.Context, *GetActiveRequest) (*Configuration, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetActive not implemented")
+}
+func (UnimplementedSamplerConfigServer) mustEmbedUnimplementedSamplerConfigServer() {}
+func (UnimplementedSamplerConfigServer) testEmbeddedByValue()                       {}
+
+// UnsafeSamplerConfigServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SamplerConfigServer will
+// result in compilation errors.
+type UnsafeSamplerConfigServer interface {
+	mustEmbedUnimplementedSamplerConfigServer()
+}
+
+func RegisterSamplerConfigServer(s grpc.ServiceRegistrar, srv SamplerConfigServer) {
+	// If the following call pancis, it indicates UnimplementedSamplerConfigServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&SamplerConfig_ServiceDesc, srv)
+}
+
+func _SamplerConfig_Apply_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ApplyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamplerConfigServer).Apply(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamplerConfig_Apply_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamplerConfigServer).Apply(ctx, req.(*ApplyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SamplerConfig_Rollback_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RollbackRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamplerConfigServer).Rollback(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamplerConfig_Rollback_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamplerConfigServer).Rollback(ctx, req.(*RollbackRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SamplerConfig_GetActive_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetActiveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamplerConfigServer).GetActive(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamplerConfig_GetActive_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamplerConfigServer).GetActive(ctx, req.(*GetActiveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// SamplerConfig_ServiceDesc is the grpc.ServiceDesc for SamplerConfig service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SamplerConfig_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "jmacd.sampler.config.v1.SamplerConfig",
+	HandlerType: (*SamplerConfigServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Apply",
+			Handler:    _SamplerConfig_Apply_Handler,
+		},
+		{
+			MethodName: "Rollback",
+			Handler:    _SamplerConfig_Rollback_Handler,
+		},
+		{
+			MethodName: "GetActive",
+			Handler:    _SamplerConfig_GetActive_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "configservice.proto",
+}
@@ -0,0 +1,148 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package configservice implements the SamplerConfig gRPC service,
+// through which a control plane pushes versioned sampler
+// configurations to running processes, and a client for it.
+//
+// The service is defined in configservice.proto.
+package configservice
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative configservice.proto
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+
+	"github.com/jmacd/sampler"
+	"github.com/jmacd/sampler/samplerconfig"
+)
+
+// Server is a ComposableSampler whose configuration is managed by
+// the SamplerConfig service.  Register it with a grpc.Server using
+// RegisterSamplerConfigServer.
+type Server struct {
+	UnimplementedSamplerConfigServer
+
+	resource *resource.Resource
+
+	lock     sync.Mutex // serializes updates
+	active   atomic.Pointer[configured]
+	previous *configured
+}
+
+// ServerOption configures a Server.
+type ServerOption func(*Server)
+
+// WithResource optimizes the sampler of each applied configuration
+// for the Resource, see sampler.Optimize, so that its Profiles apply.
+func WithResource(res *resource.Resource) ServerOption {
+	return func(s *Server) {
+		s.resource = res
+	}
+}
+
+// configured is a configuration and its sampler.
+type configured struct {
+	config  *Configuration
+	sampler sampler.ComposableSampler
+}
+
+var _ sampler.ComposableSampler = &Server{}
+
+// NewServer returns a Server using the initial sampler, which has
+// version zero, until a configuration is applied.
+func NewServer(initial sampler.ComposableSampler, options ...ServerOption) *Server {
+	s := &Server{}
+	for _, opt := range options {
+		opt(s)
+	}
+	s.active.Store(&configured{
+		config:  &Configuration{},
+		sampler: initial,
+	})
+	return s
+}
+
+// GetSamplingIntent implements ComposableSampler.
+func (s *Server) GetSamplingIntent(params sampler.ComposableSamplingParameters) sampler.SamplingIntent {
+	return s.active.Load().sampler.GetSamplingIntent(params)
+}
+
+// Description implements ComposableSampler.
+func (s *Server) Description() string {
+	return s.active.Load().sampler.Description()
+}
+
+// Version returns the version of the active configuration.
+func (s *Server) Version() uint64 {
+	return s.active.Load().config.GetVersion()
+}
+
+// Apply implements SamplerConfigServer.
+func (s *Server) Apply(_ context.Context, req *ApplyRequest) (*Acknowledgement, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	active := s.active.Load()
+	config := req.GetConfiguration()
+	if config.GetVersion() <= active.config.GetVersion() {
+		return s.reject(active, fmt.Sprintf("version %d is not greater than active version %d",
+			config.GetVersion(), active.config.GetVersion())), nil
+	}
+	cs, err := samplerconfig.Load(config.GetDocument())
+	if err != nil {
+		return s.reject(active, err.Error()), nil
+	}
+	if s.resource != nil {
+		cs = sampler.Optimize(cs, s.resource, instrumentation.Scope{})
+	}
+	s.previous = active
+	s.active.Store(&configured{
+		config:  config,
+		sampler: cs,
+	})
+	return &Acknowledgement{
+		Status:        Acknowledgement_APPLIED,
+		ActiveVersion: config.GetVersion(),
+	}, nil
+}
+
+// Rollback implements SamplerConfigServer.  One configuration is
+// retained for rollback, so a second Rollback is rejected until
+// another configuration is applied.
+func (s *Server) Rollback(_ context.Context, req *RollbackRequest) (*Acknowledgement, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	active := s.active.Load()
+	if req.GetVersion() != 0 && req.GetVersion() != active.config.GetVersion() {
+		return s.reject(active, fmt.Sprintf("version %d is not active, active version is %d",
+			req.GetVersion(), active.config.GetVersion())), nil
+	}
+	if s.previous == nil {
+		return s.reject(active, "no previous configuration"), nil
+	}
+	s.active.Store(s.previous)
+	s.previous = nil
+	return &Acknowledgement{
+		Status:        Acknowledgement_ROLLED_BACK,
+		ActiveVersion: s.Version(),
+	}, nil
+}
+
+// GetActive implements SamplerConfigServer.
+func (s *Server) GetActive(context.Context, *GetActiveRequest) (*Configuration, error) {
+	return s.active.Load().config, nil
+}
+
+func (s *Server) reject(active *configured, msg string) *Acknowledgement {
+	return &Acknowledgement{
+		Status:        Acknowledgement_REJECTED,
+		ActiveVersion: active.config.GetVersion(),
+		Error:         msg,
+	}
+}
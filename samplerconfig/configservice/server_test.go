@@ -0,0 +1,92 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package configservice
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/jmacd/sampler"
+)
+
+func testClient(t *testing.T, srv *Server) *Client {
+	lis := bufconn.Listen(1 << 16)
+	gs := grpc.NewServer()
+	RegisterSamplerConfigServer(gs, srv)
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return NewClient(conn)
+}
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+	srv := NewServer(sampler.ParentThreshold())
+	client := testClient(t, srv)
+
+	active, err := client.Active(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), active.GetVersion())
+	require.Equal(t, "ParentThreshold", srv.Description())
+
+	require.NoError(t, client.Apply(ctx, 3, []byte("sampler:\n  probability: {ratio: 0.5}\n")))
+	require.Equal(t, uint64(3), srv.Version())
+	require.Equal(t, "TraceIDRatioBased{0.5}", srv.Description())
+
+	// Stale and invalid configurations are rejected.
+	require.EqualError(t, client.Apply(ctx, 3, []byte("sampler:\n  always_on:\n")),
+		"configservice: rejected (active version 3): version 3 is not greater than active version 3")
+	require.EqualError(t, client.Apply(ctx, 4, []byte("sampler:\n  always_sometimes:\n")),
+		`configservice: rejected (active version 3): samplerconfig: line 2: sampler: unknown field "always_sometimes"`)
+	require.Equal(t, "TraceIDRatioBased{0.5}", srv.Description())
+
+	require.NoError(t, client.Apply(ctx, 4, []byte("sampler:\n  always_on:\n")))
+	active, err = client.Active(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), active.GetVersion())
+	require.Equal(t, "sampler:\n  always_on:\n", string(active.GetDocument()))
+
+	// Rollback checks the expected version.
+	_, err = client.Rollback(ctx, 3)
+	require.EqualError(t, err, "configservice: rejected (active version 4): version 3 is not active, active version is 4")
+
+	version, err := client.Rollback(ctx, 4)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), version)
+	require.Equal(t, "TraceIDRatioBased{0.5}", srv.Description())
+
+	_, err = client.Rollback(ctx, 0)
+	require.EqualError(t, err, "configservice: rejected (active version 3): no previous configuration")
+}
+
+func TestServerResource(t *testing.T) {
+	ctx := context.Background()
+	srv := NewServer(sampler.ParentThreshold(),
+		WithResource(resource.NewSchemaless(attribute.String("service.name", "checkout"))))
+	client := testClient(t, srv)
+
+	require.NoError(t, client.Apply(ctx, 1, []byte(`
+profiles:
+  - resource: {service.name: checkout}
+    sampler: {always_on: }
+sampler: {always_off: }
+`)))
+	intent := srv.GetSamplingIntent(sampler.ComposableSamplingParameters{})
+	require.Equal(t, sampler.ALWAYS_SAMPLE_THRESHOLD, intent.Threshold)
+}
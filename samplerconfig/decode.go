@@ -0,0 +1,218 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package samplerconfig
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// decode strictly decodes a YAML node into the configuration types.
+// Compared with yaml.Node.Decode, unknown fields are errors reported
+// with their path, and a field present with a null value, such as
+// "always_on:", is set to an empty struct.  Decoding continues after
+// an error, and every error is returned.  With WithEnvSubstitution,
+// environment variable references in values are substituted first,
+// see substitute.
+func decode(node *yaml.Node, cfg *Config, pc parseConfig) Errors {
+	if node.Kind == yaml.DocumentNode {
+		node = node.Content[0]
+	}
+	var d decoder
+	if pc.lookupEnv != nil {
+		d.substitute(node, "", pc.lookupEnv)
+	}
+	d.value(node, reflect.ValueOf(cfg).Elem(), "")
+	return d.errs
+}
+
+var (
+	samplerType = reflect.TypeOf(Sampler{})
+	ruleType    = reflect.TypeOf(Rule{})
+)
+
+// extensible is implemented by types with an inline map of fields
+// named by registered factories.
+type extensible interface {
+	isRegistered(name string) bool
+}
+
+// oneOf is implemented by types whose fields are mutually exclusive.
+type oneOf interface {
+	checkOneOf() string
+}
+
+func isNull(node *yaml.Node) bool {
+	return node.Kind == 0 || (node.Kind == yaml.ScalarNode && node.Tag == "!!null")
+}
+
+// decoder accumulates the errors found while decoding.
+type decoder struct {
+	errs Errors
+
+	// invalid are the nodes with errors found before decoding,
+	// which are not decoded.
+	invalid map[*yaml.Node]bool
+}
+
+func (d *decoder) fail(node *yaml.Node, path, format string, args ...any) {
+	if path == "" {
+		path = "(document)"
+	}
+	d.errs = append(d.errs, &Error{Line: node.Line, Path: path, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (d *decoder) value(node *yaml.Node, v reflect.Value, path string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if d.invalid[node] {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		if isNull(node) && v.Type().Elem().Kind() != reflect.Struct {
+			return
+		}
+		v.Set(reflect.New(v.Type().Elem()))
+		d.value(node, v.Elem(), path)
+
+	case reflect.Struct:
+		if isNull(node) {
+			return
+		}
+		if node.Kind != yaml.MappingNode {
+			d.fail(node, path, "expected a mapping")
+			return
+		}
+		n := len(d.errs)
+		d.fields(node, v, path)
+		// After an error in the fields, such as an unknown field
+		// that was likely the intended choice, the check is noise.
+		if c, ok := v.Addr().Interface().(oneOf); ok && len(d.errs) == n {
+			if msg := c.checkOneOf(); msg != "" {
+				d.fail(node, path, "%s", msg)
+			}
+		}
+
+	case reflect.Slice:
+		if isNull(node) {
+			return
+		}
+		if node.Kind != yaml.SequenceNode {
+			d.fail(node, path, "expected a sequence")
+			return
+		}
+		v.Set(reflect.MakeSlice(v.Type(), len(node.Content), len(node.Content)))
+		for i, item := range node.Content {
+			d.value(item, v.Index(i), fmt.Sprintf("%s[%d]", path, i))
+		}
+
+	case reflect.Map:
+		if isNull(node) {
+			return
+		}
+		if node.Kind != yaml.MappingNode {
+			d.fail(node, path, "expected a mapping")
+			return
+		}
+		v.Set(reflect.MakeMapWithSize(v.Type(), len(node.Content)/2))
+		for i := 0; i < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			elem := reflect.New(v.Type().Elem()).Elem()
+			d.value(value, elem, path+"."+key.Value)
+			v.SetMapIndex(reflect.ValueOf(key.Value), elem)
+		}
+
+	case reflect.String:
+		if node.Kind != yaml.ScalarNode || isNull(node) {
+			d.fail(node, path, "expected a string")
+			return
+		}
+		v.SetString(node.Value)
+
+	case reflect.Float64:
+		if node.Kind != yaml.ScalarNode || (node.Tag != "!!int" && node.Tag != "!!float") || node.Decode(v.Addr().Interface()) != nil {
+			d.fail(node, path, "expected a number")
+		}
+
+	case reflect.Interface:
+		if err := node.Decode(v.Addr().Interface()); err != nil {
+			d.fail(node, path, "%v", err)
+		}
+
+	case reflect.Bool:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" || node.Decode(v.Addr().Interface()) != nil {
+			d.fail(node, path, "expected true or false")
+		}
+
+	default:
+		d.fail(node, path, "unsupported type %s", v.Type())
+	}
+}
+
+func (d *decoder) fields(node *yaml.Node, v reflect.Value, path string) {
+	fields := structFields(v.Type())
+	ext, _ := v.Addr().Interface().(extensible)
+	seen := map[string]bool{}
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if seen[key.Value] {
+			d.fail(key, path, "duplicate field %q", key.Value)
+			continue
+		}
+		seen[key.Value] = true
+		if index, ok := fields[key.Value]; ok {
+			d.value(value, v.Field(index), joinPath(path, key.Value))
+			continue
+		}
+		if ext == nil || !ext.isRegistered(key.Value) {
+			d.fail(key, path, "unknown field %q", key.Value)
+			continue
+		}
+		var config any
+		if err := value.Decode(&config); err != nil {
+			d.fail(value, joinPath(path, key.Value), "%v", err)
+			continue
+		}
+		inline := v.Field(inlineField(v.Type()))
+		if inline.IsNil() {
+			inline.Set(reflect.MakeMap(inline.Type()))
+		}
+		inline.SetMapIndex(reflect.ValueOf(key.Value), reflect.ValueOf(&config).Elem())
+	}
+}
+
+// structFields maps the YAML field names of a struct type to field
+// indexes.
+func structFields(t reflect.Type) map[string]int {
+	fields := map[string]int{}
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name != "" && name != "-" {
+			fields[name] = i
+		}
+	}
+	return fields
+}
+
+// inlineField returns the index of the inline field of a struct type.
+func inlineField(t reflect.Type) int {
+	for i := 0; i < t.NumField(); i++ {
+		if _, opts, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ","); opts == "inline" {
+			return i
+		}
+	}
+	return -1
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
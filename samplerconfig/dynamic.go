@@ -0,0 +1,267 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package samplerconfig
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"gopkg.in/yaml.v3"
+
+	"github.com/jmacd/sampler"
+)
+
+// defaultHistorySize is the default number of previous configurations
+// kept for Rollback.
+const defaultHistorySize = 10
+
+// ErrNoHistory is returned by Rollback when there is no previous
+// configuration.
+var ErrNoHistory = errors.New("samplerconfig: no previous configuration")
+
+// DynamicOption configures a Dynamic sampler.
+type DynamicOption func(*dynamicConfig)
+
+type dynamicConfig struct {
+	historySize int
+	versionKey  attribute.Key
+	resource    *resource.Resource
+}
+
+// WithHistorySize sets the number of previous configurations kept for
+// Rollback.  The default is 10.
+func WithHistorySize(size int) DynamicOption {
+	return func(c *dynamicConfig) {
+		c.historySize = size
+	}
+}
+
+// WithVersionAttribute adds an attribute with the given key to sampled
+// spans, set to the Version of the active configuration, so that
+// changes in sampling can be correlated with the configuration.
+func WithVersionAttribute(key attribute.Key) DynamicOption {
+	return func(c *dynamicConfig) {
+		c.versionKey = key
+	}
+}
+
+// WithResource optimizes each configuration's sampler for the
+// Resource, see sampler.Optimize, so that its Profiles apply.
+func WithResource(res *resource.Resource) DynamicOption {
+	return func(c *dynamicConfig) {
+		c.resource = res
+	}
+}
+
+// Dynamic is a ComposableSampler whose configuration can be replaced
+// while it is in use.  Each configuration is identified by a version,
+// and the previous configurations are kept so that a change can be
+// undone with Rollback.
+type Dynamic struct {
+	config  dynamicConfig
+	current atomic.Pointer[loaded]
+
+	lock    sync.Mutex // serializes changes to current and history
+	history []*loaded  // previous configurations, oldest first
+}
+
+// loaded is an active configuration and its sampler.
+type loaded struct {
+	Revision
+	sampler    sampler.ComposableSampler
+	attributes sampler.AttributesFunc // the version attribute, if any
+}
+
+// Revision describes a configuration used by a Dynamic sampler.
+type Revision struct {
+	// Version identifies the configuration, see Config.Version.
+	Version string
+
+	// Config is the configuration.  It should not be modified.
+	Config *Config
+
+	// Stored is when the configuration became active.
+	Stored time.Time
+}
+
+var (
+	_ sampler.ComposableSampler = &Dynamic{}
+	_ sampler.RuleStatsProvider = &Dynamic{}
+	_ sampler.CostStatsProvider = &Dynamic{}
+)
+
+// NewDynamic returns a Dynamic sampler with the initial configuration.
+func NewDynamic(cfg *Config, options ...DynamicOption) (*Dynamic, error) {
+	d := &Dynamic{}
+	d.configure(options)
+	if err := d.Store(cfg); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Store builds the configuration and makes it active.  When the
+// configuration cannot be built, the error is returned and the
+// active configuration is unchanged.  When the new sampler is
+// Equal to the active one, the active sampler is kept, along with
+// its state, such as rule statistics, and only the Version and
+// Config change.
+func (d *Dynamic) Store(cfg *Config) error {
+	_, err := d.store(cfg)
+	return err
+}
+
+func (d *Dynamic) configure(options []DynamicOption) {
+	d.config = dynamicConfig{
+		historySize: defaultHistorySize,
+	}
+	for _, opt := range options {
+		opt(&d.config)
+	}
+}
+
+// store is Store, returning whether the active configuration changed.
+func (d *Dynamic) store(cfg *Config) (bool, error) {
+	s, err := cfg.Build()
+	if err != nil {
+		return false, err
+	}
+	if d.config.resource != nil {
+		s = sampler.Optimize(s, d.config.resource, instrumentation.Scope{})
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	cur := d.current.Load()
+	unchanged := cur != nil && Equal(cur.sampler, s)
+	if unchanged {
+		s = cur.sampler
+	}
+	next := &loaded{
+		Revision: Revision{
+			Version: cfg.version(),
+			Config:  cfg,
+			Stored:  time.Now(),
+		},
+		sampler: s,
+	}
+	if unchanged && next.Version == cur.Version {
+		return false, nil
+	}
+	if key := d.config.versionKey; key != "" {
+		kvs := []attribute.KeyValue{key.String(next.Version)}
+		next.attributes = func() []attribute.KeyValue {
+			return kvs
+		}
+	}
+	if unchanged {
+		// The active configuration is replaced without changing the
+		// sampler, so there is nothing to roll back.
+		d.current.Store(next)
+		return false, nil
+	}
+	if cur != nil && d.config.historySize > 0 {
+		d.history = append(d.history, cur)
+		if len(d.history) > d.config.historySize {
+			d.history = d.history[len(d.history)-d.config.historySize:]
+		}
+	}
+	d.current.Store(next)
+	return true, nil
+}
+
+// Rollback makes the previous configuration active again, along with
+// the state of its samplers, and removes it from the history.  When
+// there is no previous configuration, ErrNoHistory is returned.
+func (d *Dynamic) Rollback() error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if len(d.history) == 0 {
+		return ErrNoHistory
+	}
+	prev := d.history[len(d.history)-1]
+	d.history = d.history[:len(d.history)-1]
+	d.current.Store(prev)
+	return nil
+}
+
+// History returns the previous configurations, oldest first, not
+// including the active one.
+func (d *Dynamic) History() []Revision {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	revs := make([]Revision, len(d.history))
+	for i, l := range d.history {
+		revs[i] = l.Revision
+	}
+	return revs
+}
+
+// Config returns the active configuration.  It should not be modified.
+func (d *Dynamic) Config() *Config {
+	return d.current.Load().Config
+}
+
+// Version returns the version of the active configuration.
+func (d *Dynamic) Version() string {
+	return d.current.Load().Version
+}
+
+// Sampler returns the active sampler.
+func (d *Dynamic) Sampler() sampler.ComposableSampler {
+	return d.current.Load().sampler
+}
+
+// GetSamplingIntent implements ComposableSampler.
+func (d *Dynamic) GetSamplingIntent(params sampler.ComposableSamplingParameters) sampler.SamplingIntent {
+	cur := d.current.Load()
+	intent := cur.sampler.GetSamplingIntent(params)
+	if cur.attributes != nil {
+		intent.Attributes = sampler.CombineAttributes(intent.Attributes, cur.attributes)
+	}
+	return intent
+}
+
+// Description implements ComposableSampler.
+func (d *Dynamic) Description() string {
+	return d.Sampler().Description()
+}
+
+// Stats implements RuleStatsProvider, returning the statistics of the
+// active sampler when it is rule-based, otherwise nil.
+func (d *Dynamic) Stats() []sampler.RuleStats {
+	if sp, ok := d.Sampler().(sampler.RuleStatsProvider); ok {
+		return sp.Stats()
+	}
+	return nil
+}
+
+// CostStats implements CostStatsProvider, returning the statistics of
+// the active sampler, if any.
+func (d *Dynamic) CostStats() []sampler.CostStats {
+	if cp, ok := d.Sampler().(sampler.CostStatsProvider); ok {
+		return cp.CostStats()
+	}
+	return nil
+}
+
+// version returns the configuration's Version, or when it is not set,
+// a hash of the configuration.
+func (c *Config) version() string {
+	if c.Version != "" {
+		return c.Version
+	}
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:6])
+}
@@ -0,0 +1,73 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package samplerconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/jmacd/sampler"
+)
+
+func TestDynamicRollback(t *testing.T) {
+	parse := func(doc string) *Config {
+		cfg, err := Parse([]byte(doc))
+		require.NoError(t, err)
+		return cfg
+	}
+	first := parse("sampler:\n  always_on:\n")
+	d, err := NewDynamic(first, WithHistorySize(2), WithVersionAttribute("sampler.config.version"))
+	require.NoError(t, err)
+	firstVersion := d.Version()
+	require.Len(t, firstVersion, 12)
+	require.ErrorIs(t, d.Rollback(), ErrNoHistory)
+
+	// An explicit version is used as is.
+	require.NoError(t, d.Store(parse("version: v2\nsampler:\n  always_off:\n")))
+	require.NoError(t, d.Store(parse("version: v3\nsampler:\n  probability: {ratio: 0.5}\n")))
+	require.NoError(t, d.Store(parse("version: v4\nsampler:\n  probability: {ratio: 0.25}\n")))
+	require.Equal(t, "v4", d.Version())
+
+	var versions []string
+	for _, rev := range d.History() {
+		versions = append(versions, rev.Version)
+	}
+	require.Equal(t, []string{"v2", "v3"}, versions)
+
+	// Sampled spans have the version attribute.
+	intent := d.GetSamplingIntent(sampler.ComposableSamplingParameters{})
+	require.Equal(t, []attribute.KeyValue{attribute.String("sampler.config.version", "v4")}, intent.Attributes())
+
+	require.NoError(t, d.Rollback())
+	require.Equal(t, "v3", d.Version())
+	require.Equal(t, "TraceIDRatioBased{0.5}", d.Description())
+	require.NoError(t, d.Rollback())
+	require.Equal(t, "v2", d.Version())
+	require.ErrorIs(t, d.Rollback(), ErrNoHistory)
+
+	// The hash identifies the contents.
+	require.NoError(t, d.Store(parse("sampler:\n  always_on: {}\n")))
+	require.Equal(t, firstVersion, d.Version())
+}
+
+func TestDynamicEqual(t *testing.T) {
+	cfg, err := Parse([]byte("version: v1\nsampler:\n  probability: {ratio: 0.5}\n"))
+	require.NoError(t, err)
+	d, err := NewDynamic(cfg, WithVersionAttribute("sampler.config.version"))
+	require.NoError(t, err)
+	active := d.Sampler()
+
+	// An equivalent configuration keeps the sampler and its state,
+	// with the new version.
+	cfg, err = Parse([]byte("version: v2\nsampler:\n  rule_based:\n    default: {probability: {ratio: 0.5}}\n"))
+	require.NoError(t, err)
+	require.NoError(t, d.Store(cfg))
+	require.True(t, active == d.Sampler())
+	require.Equal(t, "v2", d.Version())
+	require.Equal(t, cfg, d.Config())
+	require.Empty(t, d.History())
+	intent := d.GetSamplingIntent(sampler.ComposableSamplingParameters{})
+	require.Equal(t, []attribute.KeyValue{attribute.String("sampler.config.version", "v2")}, intent.Attributes())
+}
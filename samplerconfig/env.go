@@ -0,0 +1,138 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package samplerconfig
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jmacd/sampler"
+)
+
+const (
+	// SamplerEnvKey names the environment variable selecting a sampler.
+	SamplerEnvKey = "OTEL_TRACES_SAMPLER"
+
+	// SamplerArgEnvKey names the environment variable holding the
+	// sampler's argument.
+	SamplerArgEnvKey = "OTEL_TRACES_SAMPLER_ARG"
+)
+
+// FromEnv returns the sampler named by OTEL_TRACES_SAMPLER, with the
+// argument in OTEL_TRACES_SAMPLER_ARG, see ParseEnv.  When
+// OTEL_TRACES_SAMPLER is unset or empty, FromEnv returns nil and no
+// error, so that the caller can apply its default.
+func FromEnv(options ...sampler.CompositeOption) (sampler.Sampler, error) {
+	name := strings.TrimSpace(os.Getenv(SamplerEnvKey))
+	if name == "" {
+		return nil, nil
+	}
+	return ParseEnv(name, os.Getenv(SamplerArgEnvKey), options...)
+}
+
+// ParseEnv returns the sampler with the given OTEL_TRACES_SAMPLER name
+// and OTEL_TRACES_SAMPLER_ARG argument.  The names defined by the
+// OpenTelemetry specification are supported:
+//
+//	always_on                 AlwaysSample
+//	always_off                NeverSample
+//	traceidratio              TraceIDRatioBased with ratio arg
+//	parentbased_always_on     ParentBased with an AlwaysSample root
+//	parentbased_always_off    ParentBased with a NeverSample root
+//	parentbased_traceidratio  ParentBased with a TraceIDRatioBased root
+//
+// as well as the composite samplers:
+//
+//	consistent_parentbased_traceidratio  ComposableParentBased with a
+//	                                     TraceIDRatioBased root
+//	rule_based                           the sampler configured by the
+//	                                     file named by arg, see LoadFile
+//	preset                               the preset named by arg, see
+//	                                     Sampler.Preset
+//
+// and samplers registered with RegisterSampler.  The ratio defaults to
+// 1 when arg is empty.  The options apply to the CompositeSampler of
+// each sampler.
+func ParseEnv(name, arg string, options ...sampler.CompositeOption) (sampler.Sampler, error) {
+	name, arg = strings.TrimSpace(name), strings.TrimSpace(arg)
+	composite := func(s sampler.ComposableSampler) sampler.Sampler {
+		return sampler.CompositeSampler(s, options...)
+	}
+	switch name {
+	case "always_on":
+		return composite(sampler.ComposableAlwaysSample()), nil
+	case "always_off":
+		return composite(sampler.ComposableNeverSample()), nil
+	case "parentbased_always_on":
+		return sampler.ParentBased(composite(sampler.ComposableAlwaysSample())), nil
+	case "parentbased_always_off":
+		return sampler.ParentBased(composite(sampler.ComposableNeverSample())), nil
+	case "traceidratio", "parentbased_traceidratio", "consistent_parentbased_traceidratio":
+		ratio, err := parseRatio(arg)
+		if err != nil {
+			return nil, err
+		}
+		switch name {
+		case "traceidratio":
+			return composite(sampler.TraceIDRatioBased(ratio)), nil
+		case "parentbased_traceidratio":
+			return sampler.ParentBased(composite(sampler.TraceIDRatioBased(ratio))), nil
+		default:
+			return composite(sampler.ComposableParentBased(sampler.TraceIDRatioBased(ratio))), nil
+		}
+	case "rule_based":
+		if arg == "" {
+			return nil, fmt.Errorf("samplerconfig: %s: rule_based requires a configuration file", SamplerArgEnvKey)
+		}
+		s, err := LoadFile(arg)
+		if err != nil {
+			return nil, err
+		}
+		return composite(s), nil
+	case "preset":
+		s, err := buildPreset(arg, SamplerArgEnvKey)
+		if err != nil {
+			return nil, err
+		}
+		return composite(s), nil
+	}
+	if factory, ok := lookupSampler(name); ok {
+		var config any
+		if arg != "" {
+			config = arg
+		}
+		s, err := factory(config)
+		if err != nil {
+			return nil, fmt.Errorf("samplerconfig: %s: %s: %w", SamplerEnvKey, name, err)
+		}
+		return composite(s), nil
+	}
+	return nil, fmt.Errorf("samplerconfig: %s: unknown sampler %q", SamplerEnvKey, name)
+}
+
+// envSamplers are the OTEL_TRACES_SAMPLER values supported by ParseEnv.
+var envSamplers = map[string]bool{
+	"always_on":                           true,
+	"always_off":                          true,
+	"traceidratio":                        true,
+	"parentbased_always_on":               true,
+	"parentbased_always_off":              true,
+	"parentbased_traceidratio":            true,
+	"consistent_parentbased_traceidratio": true,
+	"rule_based":                          true,
+	"preset":                              true,
+}
+
+func parseRatio(arg string) (float64, error) {
+	if arg == "" {
+		return 1, nil
+	}
+	ratio, err := strconv.ParseFloat(arg, 64)
+	if err != nil || !(ratio >= 0 && ratio <= 1) {
+		return 0, fmt.Errorf("samplerconfig: %s: invalid ratio %q", SamplerArgEnvKey, arg)
+	}
+	return ratio, nil
+}
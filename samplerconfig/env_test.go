@@ -0,0 +1,61 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package samplerconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEnv(t *testing.T) {
+	for _, test := range []struct {
+		name, arg string
+		desc      string
+	}{
+		{"always_on", "", "AlwaysOn"},
+		{"always_off", "", "AlwaysOff"},
+		{"traceidratio", "0.25", "TraceIDRatioBased{0.25}"},
+		{"traceidratio", "", "AlwaysOn"},
+		{" traceidratio ", " 0.5 ", "TraceIDRatioBased{0.5}"},
+		{"parentbased_always_on", "",
+			"ParentBased{root:AlwaysOn,remoteParentSampled:AlwaysOn,remoteParentNotSampled:AlwaysOff,localParentSampled:AlwaysOn,localParentNotSampled:AlwaysOff}"},
+		{"parentbased_traceidratio", "0.5",
+			"ParentBased{root:TraceIDRatioBased{0.5},remoteParentSampled:AlwaysOn,remoteParentNotSampled:AlwaysOff,localParentSampled:AlwaysOn,localParentNotSampled:AlwaysOff}"},
+		{"consistent_parentbased_traceidratio", "0.5",
+			"RuleBased{rule(root?)=TraceIDRatioBased{0.5},rule(true)=ParentThreshold}"},
+	} {
+		s, err := ParseEnv(test.name, test.arg)
+		require.NoError(t, err, test.name)
+		require.Equal(t, test.desc, s.Description(), test.name)
+	}
+}
+
+func TestParseEnvErrors(t *testing.T) {
+	_, err := ParseEnv("sometimes", "")
+	require.EqualError(t, err, `samplerconfig: OTEL_TRACES_SAMPLER: unknown sampler "sometimes"`)
+
+	_, err = ParseEnv("traceidratio", "1.5")
+	require.EqualError(t, err, `samplerconfig: OTEL_TRACES_SAMPLER_ARG: invalid ratio "1.5"`)
+
+	_, err = ParseEnv("rule_based", "")
+	require.EqualError(t, err, "samplerconfig: OTEL_TRACES_SAMPLER_ARG: rule_based requires a configuration file")
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv(SamplerEnvKey, "")
+	s, err := FromEnv()
+	require.NoError(t, err)
+	require.Nil(t, s)
+
+	name := filepath.Join(t.TempDir(), "sampler.yaml")
+	require.NoError(t, os.WriteFile(name, []byte("sampler:\n  probability: {ratio: 0.125}\n"), 0o600))
+
+	t.Setenv(SamplerEnvKey, "rule_based")
+	t.Setenv(SamplerArgEnvKey, name)
+	s, err = FromEnv()
+	require.NoError(t, err)
+	require.Equal(t, "TraceIDRatioBased{0.125}", s.Description())
+}
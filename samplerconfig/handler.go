@@ -0,0 +1,134 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package samplerconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/jmacd/sampler"
+)
+
+// HandlerOption configures NewHandler.
+type HandlerOption func(*handlerConfig)
+
+type handlerConfig struct {
+	update  func(*Config) error
+	maxBody int64
+}
+
+// WithConfigUpdates allows POST requests carrying a YAML or JSON
+// configuration document, which is parsed and passed to update, for
+// example the Store method of a Dynamic sampler.  Documents are
+// limited to 1 MiB.
+func WithConfigUpdates(update func(*Config) error) HandlerOption {
+	return func(c *handlerConfig) {
+		c.update = update
+	}
+}
+
+// SamplerStatus is the JSON document served by NewHandler.
+type SamplerStatus struct {
+	// Description is the sampler's Description.
+	Description string `json:"description"`
+
+	// Config is the sampler's configuration, when the sampler has a
+	// Config method, as Dynamic does.
+	Config *Config `json:"config,omitempty"`
+
+	// Version identifies the configuration, when the sampler has a
+	// Version method, as Dynamic does.
+	Version string `json:"version,omitempty"`
+
+	// Rules are the statistics of each rule, when the sampler is a
+	// RuleStatsProvider.
+	Rules []RuleStatus `json:"rules,omitempty"`
+}
+
+// RuleStatus is the JSON form of sampler.RuleStats.
+type RuleStatus struct {
+	Description string `json:"description"`
+	Priority    int    `json:"priority"`
+	Matched     uint64 `json:"matched"`
+	Sampled     uint64 `json:"sampled"`
+}
+
+// NewHandler returns an http.Handler that serves the status of a
+// sampler as JSON in response to GET requests, see SamplerStatus.  The
+// handler can be mounted on a debug mux, as in
+//
+//	mux.Handle("/debug/sampler", samplerconfig.NewHandler(dynamic,
+//		samplerconfig.WithConfigUpdates(dynamic.Store)))
+//
+// With WithConfigUpdates, POST requests replace the configuration and
+// are answered with the new status.
+func NewHandler(s sampler.ComposableSampler, options ...HandlerOption) http.Handler {
+	cfg := handlerConfig{
+		maxBody: 1 << 20,
+	}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPost:
+			if cfg.update == nil {
+				w.Header().Set("Allow", "GET, HEAD")
+				http.Error(w, "configuration updates are not enabled", http.StatusMethodNotAllowed)
+				return
+			}
+			data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, cfg.maxBody))
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+				return
+			}
+			var update *Config
+			if err == nil {
+				update, err = Parse(data)
+			}
+			if err == nil {
+				err = cfg.update(update)
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			allow := "GET, HEAD"
+			if cfg.update != nil {
+				allow += ", POST"
+			}
+			w.Header().Set("Allow", allow)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(status(s))
+	})
+}
+
+// status returns the status of a sampler.
+func status(s sampler.ComposableSampler) SamplerStatus {
+	st := SamplerStatus{
+		Description: s.Description(),
+	}
+	if c, ok := s.(interface{ Config() *Config }); ok {
+		st.Config = c.Config()
+	}
+	if v, ok := s.(interface{ Version() string }); ok {
+		st.Version = v.Version()
+	}
+	if sp, ok := s.(sampler.RuleStatsProvider); ok {
+		for _, rule := range sp.Stats() {
+			st.Rules = append(st.Rules, RuleStatus(rule))
+		}
+	}
+	return st
+}
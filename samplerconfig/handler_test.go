@@ -0,0 +1,106 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package samplerconfig
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jmacd/sampler"
+)
+
+func TestHandler(t *testing.T) {
+	cfg, err := Parse([]byte(`
+sampler:
+  rule_based:
+    rules:
+      - span_kinds: [server]
+        sampler:
+          always_on:
+    default:
+      always_off:
+`))
+	require.NoError(t, err)
+	dyn, err := NewDynamic(cfg)
+	require.NoError(t, err)
+	dyn.GetSamplingIntent(sampler.ComposableSamplingParameters{
+		SamplingParameters: sampler.SamplingParameters{Kind: trace.SpanKindServer},
+	})
+
+	h := NewHandler(dyn, WithConfigUpdates(dyn.Store))
+	get := func() SamplerStatus {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		var st SamplerStatus
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &st))
+		return st
+	}
+
+	st := get()
+	require.Equal(t, "RuleBased{rule(Span.Kind==server)=AlwaysOn,rule(true)=AlwaysOff}", st.Description)
+	require.Equal(t, []string{"server"}, st.Config.Sampler.RuleBased.Rules[0].SpanKinds)
+	require.Equal(t, dyn.Version(), st.Version)
+	require.Equal(t, []RuleStatus{
+		{Description: "rule(Span.Kind==server)=AlwaysOn", Matched: 1, Sampled: 1},
+		{Description: "rule(true)=AlwaysOff"},
+	}, st.Rules)
+
+	post := func(doc string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(doc)))
+		return rec
+	}
+	rec := post(`{"sampler": {"probability": {"ratio": 0.5}}}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"description": "TraceIDRatioBased{0.5}"`)
+	require.Equal(t, "TraceIDRatioBased{0.5}", dyn.Description())
+
+	rec = post(`{"sampler": {"probability": {"ratio": 5}}}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Equal(t, "samplerconfig: sampler.probability.ratio: ratio 5 is not in the range [0, 1]\n", rec.Body.String())
+	require.Equal(t, "TraceIDRatioBased{0.5}", dyn.Description())
+	require.Nil(t, get().Rules)
+
+	// Environment variables are not substituted in posted documents.
+	t.Setenv("SAMPLER_SECRET", "hunter2")
+	rec = post(`{"version": "${SAMPLER_SECRET}", "sampler": {"always_on": {}}}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "${SAMPLER_SECRET}", dyn.Version())
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	require.Equal(t, "GET, HEAD, POST", rec.Header().Get("Allow"))
+
+	// Updates are disabled by default.
+	rec = httptest.NewRecorder()
+	NewHandler(dyn).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}")))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestConfigJSON(t *testing.T) {
+	cfg, err := Parse([]byte(`
+sampler:
+  rule_based:
+    rules:
+      - test_span_name: checkout
+        sampler:
+          test_one_in: 10
+`))
+	require.NoError(t, err)
+	data, err := json.Marshal(cfg)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"sampler":{"rule_based":{"rules":[{"test_span_name":"checkout","sampler":{"test_one_in":10}}]}}}`, string(data))
+
+	parsed, err := Parse(data)
+	require.NoError(t, err)
+	require.Equal(t, cfg, parsed)
+}
@@ -0,0 +1,236 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package samplerconfig
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+
+	"github.com/jmacd/sampler"
+	"go.opentelemetry.io/otel/attribute"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultJaegerProbability is the probability of the Jaeger
+// collector's default strategy, used when a strategies file has no
+// default_strategy.
+const defaultJaegerProbability = 0.001
+
+// JaegerStrategies is the sampling strategies file read by the Jaeger
+// collector's file-based strategy provider, for example:
+//
+//	{
+//	  "service_strategies": [
+//	    {
+//	      "service": "checkout",
+//	      "type": "probabilistic",
+//	      "param": 0.5,
+//	      "operation_strategies": [
+//	        {"operation": "GET /health", "type": "probabilistic", "param": 0}
+//	      ]
+//	    },
+//	    {"service": "search", "type": "ratelimiting", "param": 10}
+//	  ],
+//	  "default_strategy": {"type": "probabilistic", "param": 0.01}
+//	}
+type JaegerStrategies struct {
+	DefaultStrategy   *JaegerStrategy  `yaml:"default_strategy"`
+	ServiceStrategies []JaegerStrategy `yaml:"service_strategies"`
+}
+
+// JaegerStrategy is the default strategy or the strategy of one
+// service.  Type is "probabilistic", where Param is the sampling
+// probability, or "ratelimiting", where Param is the number of traces
+// per second.
+type JaegerStrategy struct {
+	Service             string                    `yaml:"service"`
+	Type                string                    `yaml:"type"`
+	Param               *float64                  `yaml:"param"`
+	OperationStrategies []JaegerOperationStrategy `yaml:"operation_strategies"`
+}
+
+// JaegerOperationStrategy is the strategy of spans with one name.
+// Type must be "probabilistic".
+type JaegerOperationStrategy struct {
+	Operation string   `yaml:"operation"`
+	Type      string   `yaml:"type"`
+	Param     *float64 `yaml:"param"`
+}
+
+// ParseJaeger parses a Jaeger sampling strategies file, which is a
+// JSON document, reporting every error as Parse does.
+func ParseJaeger(data []byte) (*JaegerStrategies, error) {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(&doc); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("samplerconfig: %w", err)
+	}
+	var strategies JaegerStrategies
+	if doc.Kind == yaml.DocumentNode {
+		var d decoder
+		d.value(doc.Content[0], reflect.ValueOf(&strategies).Elem(), "")
+		if err := d.errs.err(); err != nil {
+			return nil, err
+		}
+	}
+	return &strategies, nil
+}
+
+// LoadJaeger parses a Jaeger sampling strategies file and builds its
+// sampler.
+func LoadJaeger(data []byte) (sampler.ComposableSampler, error) {
+	strategies, err := ParseJaeger(data)
+	if err != nil {
+		return nil, err
+	}
+	return strategies.Build()
+}
+
+// LoadJaegerFile is LoadJaeger for the contents of a file.
+func LoadJaegerFile(name string) (sampler.ComposableSampler, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("samplerconfig: %w", err)
+	}
+	return LoadJaeger(data)
+}
+
+// Build returns a sampler equivalent to the strategies, in which
+// Jaeger's behavior is expressed as:
+//
+//   - the strategies apply to root spans, and other spans follow
+//     their parent, as ComposableParentBased does;
+//   - a service strategy applies to tracers whose Resource has a
+//     matching service.name, see ResourceAttributePredicate, so the
+//     sampler must be optimized for a Resource (see sampler.Optimize)
+//     for service strategies to apply;
+//   - an operation strategy applies to spans with the operation's
+//     name, and the default strategy's operation strategies apply to
+//     services that do not define the same operation, except for
+//     ratelimiting services without operation strategies;
+//   - a probabilistic strategy is TraceIDRatioBased and a ratelimiting
+//     strategy is CostBased with a cost of one per span, which
+//     approximates a limit of traces per second, as a rate of "N/s"
+//     does in a Sampler.
+//
+// Without a default_strategy, the default is probabilistic with
+// probability 0.001, as in the Jaeger collector.
+func (s *JaegerStrategies) Build() (sampler.ComposableSampler, error) {
+	var errs Errors
+	def := s.DefaultStrategy
+	if def == nil {
+		probability := defaultJaegerProbability
+		def = &JaegerStrategy{Type: "probabilistic", Param: &probability}
+	}
+	if def.Service != "" {
+		errs.add(buildError("default_strategy.service", "the default strategy has no service"))
+	}
+	defSampler, err := def.build(nil, "default_strategy")
+	errs.add(err)
+
+	var options []sampler.RuleBasedOption
+	seen := map[string]int{}
+	for i := range s.ServiceStrategies {
+		strategy := &s.ServiceStrategies[i]
+		path := fmt.Sprintf("service_strategies[%d]", i)
+		if strategy.Service == "" {
+			errs.add(buildError(path+".service", "missing service"))
+			continue
+		}
+		if first, ok := seen[strategy.Service]; ok {
+			errs.add(buildError(path+".service", "duplicate service %q, see service_strategies[%d]", strategy.Service, first))
+			continue
+		}
+		seen[strategy.Service] = i
+		svc, err := strategy.build(def.OperationStrategies, path)
+		errs.add(err)
+		options = append(options, sampler.WithRule(
+			sampler.ResourceAttributePredicate(attribute.String("service.name", strategy.Service)), svc))
+	}
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	if len(options) == 0 {
+		return sampler.ComposableParentBased(defSampler), nil
+	}
+	options = append(options, sampler.WithDefaultRule(defSampler))
+	return sampler.ComposableParentBased(sampler.RuleBased(options...)), nil
+}
+
+// build returns the sampler of a strategy, with the default
+// strategy's operation strategies for operations it does not define.
+func (s *JaegerStrategy) build(defaults []JaegerOperationStrategy, path string) (sampler.ComposableSampler, error) {
+	var errs Errors
+	var base sampler.ComposableSampler
+	switch s.Type {
+	case "probabilistic":
+		if err := checkFraction(s.Param, path, "param"); err != nil {
+			errs.add(err)
+		} else {
+			base = sampler.TraceIDRatioBased(*s.Param)
+		}
+	case "ratelimiting":
+		switch {
+		case s.Param == nil:
+			errs.add(buildError(path+".param", "missing param"))
+		case !(*s.Param >= 0):
+			errs.add(buildError(path+".param", "param %v is negative", *s.Param))
+		default:
+			base = rateLimited(*s.Param)
+		}
+	case "":
+		errs.add(buildError(path+".type", "missing type"))
+	default:
+		errs.add(buildError(path+".type", "unknown type %q, expected probabilistic or ratelimiting", s.Type))
+	}
+
+	var options []sampler.RuleBasedOption
+	defined := map[string]bool{}
+	for i, op := range s.OperationStrategies {
+		rule, err := op.rule(fmt.Sprintf("%s.operation_strategies[%d]", path, i))
+		errs.add(err)
+		defined[op.Operation] = true
+		options = append(options, rule)
+	}
+	if s.Type == "ratelimiting" && len(s.OperationStrategies) == 0 {
+		defaults = nil
+	}
+	for i, op := range defaults {
+		if defined[op.Operation] {
+			continue
+		}
+		rule, err := op.rule(fmt.Sprintf("default_strategy.operation_strategies[%d]", i))
+		if err != nil {
+			// Reported with the default strategy.
+			continue
+		}
+		options = append(options, rule)
+	}
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	if len(options) == 0 {
+		return base, nil
+	}
+	options = append(options, sampler.WithDefaultRule(base))
+	return sampler.RuleBased(options...), nil
+}
+
+// rule returns the rule of an operation strategy.
+func (o *JaegerOperationStrategy) rule(path string) (sampler.RuleBasedOption, error) {
+	var errs Errors
+	if o.Operation == "" {
+		errs.add(buildError(path+".operation", "missing operation"))
+	}
+	if o.Type != "probabilistic" {
+		errs.add(buildError(path+".type", "operation strategy type %q is not probabilistic", o.Type))
+	}
+	errs.add(checkFraction(o.Param, path, "param"))
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	return sampler.WithRule(sampler.SpanNamePredicate(o.Operation), sampler.TraceIDRatioBased(*o.Param)), nil
+}
@@ -0,0 +1,108 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package samplerconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+
+	"github.com/jmacd/sampler"
+)
+
+const testJaeger = `{
+  "service_strategies": [
+    {
+      "service": "checkout",
+      "type": "probabilistic",
+      "param": 0.5,
+      "operation_strategies": [
+        {"operation": "GET /health", "type": "probabilistic", "param": 0}
+      ]
+    },
+    {"service": "search", "type": "ratelimiting", "param": 10}
+  ],
+  "default_strategy": {
+    "type": "probabilistic",
+    "param": 0.01,
+    "operation_strategies": [
+      {"operation": "GET /health", "type": "probabilistic", "param": 0.1},
+      {"operation": "POST /orders", "type": "probabilistic", "param": 1}
+    ]
+  }
+}`
+
+func TestLoadJaeger(t *testing.T) {
+	s, err := LoadJaeger([]byte(testJaeger))
+	require.NoError(t, err)
+
+	expect, err := Load([]byte(`
+sampler:
+  parent_threshold:
+    root:
+      rule_based:
+        rules:
+          - resource_attribute: {key: service.name, value: checkout}
+            sampler:
+              rule_based:
+                rules:
+                  - span_name: GET /health
+                    sampler: {always_off: {}}
+                  - span_name: POST /orders
+                    sampler: {always_on: {}}
+                default: {probability: {ratio: 0.5}}
+          - resource_attribute: {key: service.name, value: search}
+            sampler:
+              cost_based: {bytes_per_second: 10, default_span_cost: 1, attribute_cost: 0}
+        default:
+          rule_based:
+            rules:
+              - span_name: GET /health
+                sampler: {probability: {ratio: 0.1}}
+              - span_name: POST /orders
+                sampler: {always_on: {}}
+            default: {probability: {ratio: 0.01}}
+`))
+	require.NoError(t, err)
+	require.True(t, Equal(expect, s), "%v", Normalize(s))
+
+	intent := func(service, name string) sampler.SamplingIntent {
+		res := resource.NewSchemaless(attribute.String("service.name", service))
+		return sampler.Optimize(s, res, instrumentation.Scope{}).GetSamplingIntent(sampler.ComposableSamplingParameters{
+			SamplingParameters: sampler.SamplingParameters{Name: name},
+		})
+	}
+	require.Equal(t, sampler.NEVER_SAMPLE_THRESHOLD, intent("checkout", "GET /health").Threshold)
+	require.Equal(t, sampler.ProbabilityToThreshold(0.5), intent("checkout", "GET /cart").Threshold)
+	require.Equal(t, sampler.ProbabilityToThreshold(0.1), intent("other", "GET /health").Threshold)
+	require.Equal(t, sampler.ProbabilityToThreshold(0.01), intent("other", "GET /cart").Threshold)
+
+	// Without a default strategy, the Jaeger collector's applies.
+	s, err = LoadJaeger([]byte(`{}`))
+	require.NoError(t, err)
+	require.True(t, Equal(sampler.ComposableParentBased(sampler.TraceIDRatioBased(0.001)), s))
+}
+
+func TestLoadJaegerErrors(t *testing.T) {
+	_, err := LoadJaeger([]byte(`{
+  "service_strategies": [
+    {"service": "a", "type": "probabilistic", "param": 2},
+    {"type": "probabilistic", "param": 0.5},
+    {"service": "a", "type": "ratelimiting", "param": 1},
+    {"service": "b", "type": "adaptive"},
+    {"service": "c", "type": "ratelimiting", "param": 1,
+     "operation_strategies": [{"operation": "x", "type": "ratelimiting", "param": 1}]}
+  ]
+}`))
+	require.EqualError(t, err, "samplerconfig: service_strategies[0].param: param 2 is not in the range [0, 1]\n"+
+		"samplerconfig: service_strategies[1].service: missing service\n"+
+		`samplerconfig: service_strategies[2].service: duplicate service "a", see service_strategies[0]`+"\n"+
+		`samplerconfig: service_strategies[3].type: unknown type "adaptive", expected probabilistic or ratelimiting`+"\n"+
+		`samplerconfig: service_strategies[4].operation_strategies[0].type: operation strategy type "ratelimiting" is not probabilistic`)
+
+	_, err = LoadJaeger([]byte(`{"default_strategy": {"type": "probabilistic", "parm": 0.5}}`))
+	require.EqualError(t, err, `samplerconfig: line 1: default_strategy: unknown field "parm"`)
+}
@@ -0,0 +1,382 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package samplerconfig
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jmacd/sampler"
+)
+
+// specEncoder returns the configuration document of a sampler or
+// predicate from the arguments of its sampler.Spec.
+type specEncoder func(args map[string]any) map[string]any
+
+// samplerEncoders encode the Specs of the built-in samplers.
+var samplerEncoders map[string]specEncoder
+
+// predicateEncoders encode the Specs of the built-in predicates.
+var predicateEncoders map[string]specEncoder
+
+func init() {
+	samplerEncoders = map[string]specEncoder{
+		"ComposableAlwaysSample": encodeFixed("always_on", map[string]any{}),
+		"ComposableNeverSample":  encodeFixed("always_off", map[string]any{}),
+		"ParentThreshold":        encodeFixed("parent_threshold", map[string]any{}),
+		"TraceIDRatioFromThreshold": func(args map[string]any) map[string]any {
+			// The probability is expressed as the shortest ratio
+			// having the same threshold, or as a threshold when
+			// there is none.
+			threshold, _ := args["threshold"].(sampler.Threshold)
+			params := map[string]any{"threshold": threshold.String()}
+			if ratio, ok := shortestRatio(threshold); ok {
+				params = map[string]any{"ratio": ratio}
+			}
+			return map[string]any{"probability": params}
+		},
+		"RuleBased":        encodeRuleBased,
+		"ParentRatioBased": encodeFields("parent_ratio", "ratio", "fraction"),
+		"AnnotatingSampler": func(args map[string]any) map[string]any {
+			params := map[string]any{"sampler": samplerArg(args, "sampler")}
+			if attrs, ok := args["sampledAttributeValues"].([]attribute.KeyValue); ok {
+				params["attributes"] = attributeValues(attrs)
+			}
+			if attrs, ok := args["nonSampledAttributeValues"].([]attribute.KeyValue); ok {
+				params["non_sampled_attributes"] = attributeValues(attrs)
+			}
+			if ifWouldSample, _ := args["attributesIfWouldSample"].(bool); ifWouldSample {
+				params["if_would_sample"] = true
+			}
+			// Functions are not called, so their results are opaque.
+			if _, ok := args["sampledAttributes"]; ok {
+				params["attributes"] = opaque("attributes computed by a function")
+			}
+			if _, ok := args["nonSampledAttributes"]; ok {
+				params["non_sampled_attributes"] = opaque("attributes computed by a function")
+			}
+			if keys, ok := args["traceStateEntry"].([]string); ok {
+				params["tracestate"] = opaque("tracestate entries " + strings.Join(keys, ", "))
+			}
+			return map[string]any{"annotating": params}
+		},
+		"CostBased": func(args map[string]any) map[string]any {
+			interval, _ := args["costInterval"].(time.Duration)
+			params := map[string]any{
+				"bytes_per_second":  args["bytesPerSecond"],
+				"default_span_cost": args["defaultSpanCost"],
+				"attribute_cost":    args["attributeCost"],
+				"interval":          interval.String(),
+			}
+			if costs, ok := args["spanNameCost"]; ok {
+				params["span_name_costs"] = costs
+			}
+			return map[string]any{"cost_based": params}
+		},
+		"ErrorHintBiased": func(args map[string]any) map[string]any {
+			params := map[string]any{
+				"sampler": samplerArg(args, "base"),
+				"boosted": args["boosted"],
+			}
+			if keys, ok := args["errorHintKeys"].([]attribute.Key); ok {
+				params["keys"] = keyStrings(keys)
+			}
+			return map[string]any{"error_hint_biased": params}
+		},
+		"ReplicaDecorrelated": func(args map[string]any) map[string]any {
+			return map[string]any{"replica_decorrelated": map[string]any{
+				"sampler": samplerArg(args, "sampler"),
+				"jitter":  args["jitter"],
+			}}
+		},
+		"ExportOnlySampler":     encodeWrapper("export_only"),
+		"AnnotateAdjustedCount": encodeWrapper("annotate_adjusted_count"),
+		"InheritParentAttributes": func(args map[string]any) map[string]any {
+			keys, _ := args["keys"].([]attribute.Key)
+			return map[string]any{"inherit_parent_attributes": map[string]any{
+				"sampler": samplerArg(args, "sampler"),
+				"keys":    keyStrings(keys),
+			}}
+		},
+	}
+
+	predicateEncoders = map[string]specEncoder{
+		"TruePredicate":  encodeFixed("constant", true),
+		"FalsePredicate": encodeFixed("constant", false),
+		"NotPredicate": func(args map[string]any) map[string]any {
+			original, _ := args["original"].(sampler.Predicate)
+			return map[string]any{"not": PredicateConfig(original)}
+		},
+		"AndPredicate": encodeCombination("and"),
+		"OrPredicate":  encodeCombination("or"),
+
+		"SpanNamePredicate":      encodeArg("span_name", "name"),
+		"SpanNameInSetPredicate": encodeArg("span_names", "names"),
+		"SpanNameRegexPredicate": encodeArg("span_name_regex", "expr"),
+		"SpanNameGlobPredicate":  encodeArg("span_name_glob", "pattern"),
+		"SpanKindPredicate": func(args map[string]any) map[string]any {
+			kinds, _ := args["kinds"].([]trace.SpanKind)
+			names := make([]string, len(kinds))
+			for i, kind := range kinds {
+				names[i] = kind.String()
+			}
+			return map[string]any{"span_kinds": names}
+		},
+		"IsRootPredicate":         encodeFixed("parent", []string{"none"}),
+		"IsRemoteParentPredicate": encodeFixed("parent", []string{"remote"}),
+		"IsLocalParentPredicate":  encodeFixed("parent", []string{"local"}),
+
+		"AttributeEqualsPredicate":     encodeKeyValue("attribute_equals"),
+		"AttributeValueInSetPredicate": encodeFields("attribute_values", "key", "key", "values", "values"),
+		"AttributeRegexPredicate":      encodeFields("attribute_regex", "key", "key", "pattern", "expr"),
+		"AttributeGlobPredicate":       encodeFields("attribute_glob", "key", "key", "pattern", "pattern"),
+		"AttributeGreaterPredicate":    encodeFields("attribute_greater", "key", "key", "limit", "limit"),
+		"AttributeLessPredicate":       encodeFields("attribute_less", "key", "key", "limit", "limit"),
+		"AttributeRangePredicate":      encodeFields("attribute_range", "key", "key", "low", "low", "high", "high"),
+		"HTTPRoutePredicate": func(args map[string]any) map[string]any {
+			params := map[string]any{"pattern": args["pattern"]}
+			if methods, _ := args["methods"].([]string); len(methods) != 0 {
+				params["methods"] = methods
+			}
+			return map[string]any{"http_route": params}
+		},
+		"ResourceAttributePredicate": encodeKeyValue("resource_attribute"),
+		"ScopePredicate": func(args map[string]any) map[string]any {
+			match, _ := args["match"].(instrumentation.Scope)
+			params := map[string]any{}
+			if match.Name != "" {
+				params["name"] = match.Name
+			}
+			if match.Version != "" {
+				params["version"] = match.Version
+			}
+			if match.SchemaURL != "" {
+				params["schema_url"] = match.SchemaURL
+			}
+			return map[string]any{"scope": params}
+		},
+
+		"IsParentSampledPredicate":          encodeFixed("parent_sampled", true),
+		"IsParentNotSampledPredicate":       encodeFixed("parent_sampled", false),
+		"HasParentThresholdPredicate":       encodeFixed("has_parent_threshold", true),
+		"ParentProbabilityAtLeastPredicate": encodeArg("parent_probability_at_least", "fraction"),
+		"TraceFractionPredicate":            encodeArg("trace_fraction", "fraction"),
+
+		"HasLinksPredicate":            encodeFixed("has_links", true),
+		"AnyLinkSampledPredicate":      encodeFixed("any_link_sampled", true),
+		"HasLinkAttributePredicate":    encodeArg("has_link_attribute", "key"),
+		"LinkAttributeEqualsPredicate": encodeKeyValue("link_attribute_equals"),
+
+		"HasParentAttributePredicate":    encodeArg("has_parent_attribute", "key"),
+		"ParentAttributeEqualsPredicate": encodeKeyValue("parent_attribute_equals"),
+
+		"HasBaggagePredicate":                encodeArg("has_baggage", "key"),
+		"BaggageEqualsPredicate":             encodeFields("baggage_equals", "key", "key", "value", "value"),
+		"TraceStateMemberPredicate":          encodeArg("tracestate_member", "key"),
+		"TraceStateMemberEqualsPredicate":    encodeFields("tracestate_member_equals", "key", "key", "value", "value"),
+		"OTelTraceStateFieldPredicate":       encodeArg("otel_tracestate_field", "field"),
+		"OTelTraceStateFieldEqualsPredicate": encodeFields("otel_tracestate_field_equals", "field", "field", "value", "value"),
+	}
+}
+
+// ConfigOf returns the configuration document of a sampler, with one
+// entry keyed by the sampler type, which Load loads as an equivalent
+// sampler, for example
+//
+//	{"probability": {"ratio": 0.1}}
+//
+// Samplers are encoded by their sampler.Spec.  The Specs of samplers
+// other than the built-in ones are encoded as {Name: Args}, the form
+// of samplers registered by RegisterSampler.  Samplers without a Spec
+// are represented by an "opaque" entry holding their description.
+func ConfigOf(s sampler.ComposableSampler) map[string]any {
+	if s == nil {
+		return opaque("")
+	}
+	spec, ok := sampler.SpecOf(s)
+	if !ok {
+		return opaque(s.Description())
+	}
+	if encode, ok := samplerEncoders[spec.Name]; ok {
+		return encode(spec.Args)
+	}
+	return map[string]any{spec.Name: spec.Args}
+}
+
+// MarshalConfig returns a JSON configuration document for a sampler
+// tree, which Load loads as an equivalent sampler, as in
+//
+//	{"sampler":{"probability":{"ratio":0.1}}}
+//
+// This is meant for auditing and comparing configurations, so the
+// document is a complete description of the sampler's behavior where
+// possible, unlike its Description.
+func MarshalConfig(s sampler.ComposableSampler) ([]byte, error) {
+	return json.Marshal(map[string]any{"sampler": ConfigOf(s)})
+}
+
+// PredicateConfig returns a document describing the predicate, with
+// one entry keyed by the predicate type, in the form used by rules,
+// for example
+//
+//	{"span_kinds": ["server"]}
+//
+// Predicates are encoded by their sampler.Spec, as for ConfigOf, so
+// that custom predicates configured by sampler.Predicate.WithSpec are
+// encoded as registered predicates.  Predicates without a Spec are
+// represented by an "opaque" entry holding their description.
+func PredicateConfig(pred sampler.Predicate) map[string]any {
+	spec, ok := pred.Spec()
+	if !ok {
+		return opaque(pred.Description())
+	}
+	if encode, ok := predicateEncoders[spec.Name]; ok {
+		return encode(spec.Args)
+	}
+	return map[string]any{spec.Name: spec.Args}
+}
+
+// opaque returns the document of a sampler or predicate that cannot be
+// encoded.
+func opaque(description string) map[string]any {
+	return map[string]any{"opaque": map[string]any{"description": description}}
+}
+
+// encodeRuleBased encodes the Spec of sampler.RuleBased.  Rules are
+// listed in evaluation order, and a final rule whose predicate is
+// always true is listed as the default.
+func encodeRuleBased(args map[string]any) map[string]any {
+	preds, _ := args["predicates"].([]sampler.Predicate)
+	samplers, _ := args["samplers"].([]sampler.ComposableSampler)
+	params := map[string]any{}
+	if n := len(preds); n != 0 && n == len(samplers) {
+		if spec, _ := preds[n-1].Spec(); spec.Name == "TruePredicate" {
+			params["default"] = ConfigOf(samplers[n-1])
+			preds, samplers = preds[:n-1], samplers[:n-1]
+		}
+	}
+	configs := make([]any, len(preds))
+	for i := range preds {
+		config := map[string]any{}
+		for name, value := range PredicateConfig(preds[i]) {
+			config[name] = value
+		}
+		if i < len(samplers) {
+			config["sampler"] = ConfigOf(samplers[i])
+		}
+		configs[i] = config
+	}
+	params["rules"] = configs
+	if combine, _ := args["combineMatching"].(bool); combine {
+		params["combine_matching"] = true
+	}
+	return map[string]any{"rule_based": params}
+}
+
+// encodeCombination encodes the Spec of sampler.AndPredicate or
+// sampler.OrPredicate.
+func encodeCombination(name string) specEncoder {
+	return func(args map[string]any) map[string]any {
+		preds, _ := args["preds"].([]sampler.Predicate)
+		configs := make([]any, len(preds))
+		for i, pred := range preds {
+			configs[i] = PredicateConfig(pred)
+		}
+		return map[string]any{name: configs}
+	}
+}
+
+// encodeFixed encodes a Spec as a fixed document.
+func encodeFixed(name string, value any) specEncoder {
+	return func(map[string]any) map[string]any {
+		return map[string]any{name: value}
+	}
+}
+
+// encodeArg encodes a Spec by the value of one argument.
+func encodeArg(name, arg string) specEncoder {
+	return func(args map[string]any) map[string]any {
+		return map[string]any{name: argValue(args[arg])}
+	}
+}
+
+// encodeFields encodes a Spec as a mapping, given pairs of field and
+// argument names.
+func encodeFields(name string, pairs ...string) specEncoder {
+	return func(args map[string]any) map[string]any {
+		params := map[string]any{}
+		for i := 0; i+1 < len(pairs); i += 2 {
+			params[pairs[i]] = argValue(args[pairs[i+1]])
+		}
+		return map[string]any{name: params}
+	}
+}
+
+// encodeKeyValue encodes a Spec whose argument is an attribute.
+func encodeKeyValue(name string) specEncoder {
+	return func(args map[string]any) map[string]any {
+		kv, _ := args["kv"].(attribute.KeyValue)
+		return map[string]any{name: map[string]any{"key": string(kv.Key), "value": kv.Value.AsInterface()}}
+	}
+}
+
+// encodeWrapper encodes the Spec of a sampler that modifies another.
+func encodeWrapper(name string) specEncoder {
+	return func(args map[string]any) map[string]any {
+		return map[string]any{name: map[string]any{"sampler": samplerArg(args, "sampler")}}
+	}
+}
+
+// samplerArg returns the configuration document of a sampler argument.
+func samplerArg(args map[string]any, arg string) map[string]any {
+	s, _ := args[arg].(sampler.ComposableSampler)
+	return ConfigOf(s)
+}
+
+// argValue converts attribute keys to strings.
+func argValue(value any) any {
+	if key, ok := value.(attribute.Key); ok {
+		return string(key)
+	}
+	return value
+}
+
+// keyStrings converts attribute keys to strings.
+func keyStrings(keys []attribute.Key) []any {
+	strs := make([]any, len(keys))
+	for i, key := range keys {
+		strs[i] = string(key)
+	}
+	return strs
+}
+
+// attributeValues encodes attributes as strings, by key.
+func attributeValues(attrs []attribute.KeyValue) map[string]any {
+	values := map[string]any{}
+	for _, kv := range attrs {
+		values[string(kv.Key)] = kv.Value.Emit()
+	}
+	return values
+}
+
+// shortestRatio returns the probability with the fewest significant
+// digits that ProbabilityToThreshold maps to the threshold, so that
+// TraceIDRatioBased(0.1) is written as 0.1, not as the probability
+// of its threshold.
+func shortestRatio(threshold sampler.Threshold) (float64, bool) {
+	prob := threshold.Probability()
+	for digits := 1; digits <= 17; digits++ {
+		ratio, _ := strconv.ParseFloat(strconv.FormatFloat(prob, 'g', digits, 64), 64)
+		if sampler.ProbabilityToThreshold(ratio) == threshold {
+			return ratio, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,90 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package samplerconfig
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jmacd/sampler"
+)
+
+func TestMarshalConfig(t *testing.T) {
+	s := sampler.ComposableParentBased(sampler.RuleBased(
+		sampler.WithRule(sampler.AndPredicate(
+			sampler.SpanKindPredicate(trace.SpanKindServer),
+			sampler.NotPredicate(sampler.HTTPRoutePredicate("/health*", "GET")),
+		), sampler.AnnotatingSampler(sampler.TraceIDRatioBasedN(3),
+			sampler.WithSampledAttributeValues(attribute.String("policy", "third")))),
+		sampler.WithRule(sampler.AttributeEqualsPredicate(attribute.Int("http.response.status_code", 503)), sampler.ComposableAlwaysSample()),
+		sampler.WithDefaultRule(sampler.TraceIDRatioBased(0.25)),
+	))
+	data, err := MarshalConfig(s)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"sampler": {"rule_based": {
+  "rules": [{"parent": ["none"], "sampler": {"rule_based": {
+    "rules": [
+      {
+        "and": [{"span_kinds": ["server"]}, {"not": {"http_route": {"pattern": "/health*", "methods": ["GET"]}}}],
+        "sampler": {"annotating": {"sampler": {"probability": {"ratio": 0.33333}}, "attributes": {"policy": "third"}}}
+      },
+      {
+        "attribute_equals": {"key": "http.response.status_code", "value": 503},
+        "sampler": {"always_on": {}}
+      }
+    ],
+    "default": {"probability": {"ratio": 0.25}}
+  }}}],
+  "default": {"parent_threshold": {}}
+}}}`, string(data))
+}
+
+func TestMarshalConfigFunctions(t *testing.T) {
+	// Attributes computed by functions are not evaluated.
+	called := false
+	s := sampler.AnnotatingSampler(sampler.ComposableAlwaysSample(),
+		sampler.WithSampledAttributes(func() []attribute.KeyValue {
+			called = true
+			return []attribute.KeyValue{attribute.String("policy", "all")}
+		}),
+		sampler.WithTraceStateEntry("vendor", func() string {
+			called = true
+			return "x"
+		}))
+	data, err := MarshalConfig(s)
+	require.NoError(t, err)
+	require.False(t, called)
+	require.JSONEq(t, `{"sampler": {"annotating": {
+  "sampler": {"always_on": {}},
+  "attributes": {"opaque": {"description": "attributes computed by a function"}},
+  "tracestate": {"opaque": {"description": "tracestate entries vendor"}}
+}}}`, string(data))
+	require.False(t, Equal(s, s))
+}
+
+func TestPredicateConfig(t *testing.T) {
+	for _, test := range []struct {
+		pred   sampler.Predicate
+		config string
+	}{
+		{sampler.TruePredicate(), `{"constant": true}`},
+		{sampler.OrPredicate(sampler.FalsePredicate(), sampler.SpanNamePredicate("a")), `{"or": [{"span_name": "a"}]}`},
+		{sampler.AttributeRangePredicate("size", 1, 2.5), `{"attribute_range": {"key": "size", "low": 1, "high": 2.5}}`},
+		{sampler.ScopePredicate(instrumentation.Scope{Name: "lib"}), `{"scope": {"name": "lib"}}`},
+		{sampler.IsParentNotSampledPredicate(), `{"parent_sampled": false}`},
+		{sampler.MemoizePredicate(sampler.SpanNameGlobPredicate("/api/*")), `{"span_name_glob": "/api/*"}`},
+		{sampler.OTelTraceStateFieldEqualsPredicate("rv", "x"), `{"otel_tracestate_field_equals": {"field": "rv", "value": "x"}}`},
+		{sampler.NewPredicate(nil, "custom"), `{"opaque": {"description": "custom"}}`},
+		{sampler.NewPredicate(nil, "custom").WithSpec(sampler.Spec{Name: "mine", Args: map[string]any{"n": 1}}), `{"mine": {"n": 1}}`},
+	} {
+		data, err := json.Marshal(PredicateConfig(test.pred))
+		require.NoError(t, err)
+		require.JSONEq(t, test.config, string(data), test.pred.Description())
+	}
+}
@@ -0,0 +1,305 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package samplerconfig
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/jmacd/sampler"
+)
+
+// Normalize returns the configuration document of a sampler (see
+// ConfigOf) in a canonical form, so that samplers constructed
+// differently but with the same behavior have equal documents.  The
+// normalized document is loadable as the original one is.
+//
+// Normalization applies rules that do not change sampling decisions:
+// nested and and or predicates are flattened, their operands are
+// sorted and duplicates removed, double negations, constant operands,
+// and operands combined with their negation are eliminated, sets of
+// span kinds, names, and parent kinds are sorted, rules that cannot
+// match are removed, a rule that always matches becomes the default,
+// and a rule-based sampler with only a default is replaced by it.
+func Normalize(s sampler.ComposableSampler) map[string]any {
+	return normalizeSampler(canonical(ConfigOf(s)))
+}
+
+// Equal returns true when two samplers have equal normalized
+// configurations.  Samplers that contain a sampler or predicate
+// without a configuration document are not equal to any sampler,
+// since their behavior cannot be compared.
+func Equal(a, b sampler.ComposableSampler) bool {
+	na, nb := Normalize(a), Normalize(b)
+	if hasOpaque(na) || hasOpaque(nb) {
+		return false
+	}
+	return encodeCanonical(na) == encodeCanonical(nb)
+}
+
+// canonical converts a document to the types produced by decoding
+// JSON, i.e., map[string]any, []any, float64, string, and bool.
+func canonical(doc map[string]any) map[string]any {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return opaque(err.Error())
+	}
+	var out map[string]any
+	_ = json.Unmarshal(data, &out)
+	return out
+}
+
+// encodeCanonical returns a JSON encoding of a document, in which map
+// keys are sorted.
+func encodeCanonical(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// hasOpaque returns true when a document contains an "opaque" entry.
+func hasOpaque(v any) bool {
+	switch v := v.(type) {
+	case map[string]any:
+		if _, ok := v["opaque"]; ok {
+			return true
+		}
+		for _, elem := range v {
+			if hasOpaque(elem) {
+				return true
+			}
+		}
+	case []any:
+		for _, elem := range v {
+			if hasOpaque(elem) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// single returns the name and value of a one-entry document.
+func single(doc map[string]any) (string, any, bool) {
+	if len(doc) != 1 {
+		return "", nil, false
+	}
+	for name, value := range doc {
+		return name, value, true
+	}
+	return "", nil, false
+}
+
+// samplerFields are the parameters of built-in samplers that hold
+// samplers.
+var samplerFields = []string{"sampler", "default", "root"}
+
+func normalizeSampler(doc map[string]any) map[string]any {
+	name, value, ok := single(doc)
+	if !ok {
+		return doc
+	}
+	params, ok := value.(map[string]any)
+	if !ok {
+		return doc
+	}
+	for _, field := range samplerFields {
+		if nested, ok := params[field].(map[string]any); ok {
+			params[field] = normalizeSampler(nested)
+		}
+	}
+	switch name {
+	case "probability":
+		switch params["ratio"] {
+		case 1.0:
+			return map[string]any{"always_on": map[string]any{}}
+		case 0.0:
+			return map[string]any{"always_off": map[string]any{}}
+		}
+	case "rule_based":
+		return normalizeRuleBased(doc, params)
+	}
+	return doc
+}
+
+func normalizeRuleBased(doc, params map[string]any) map[string]any {
+	rules, _ := params["rules"].([]any)
+	combine, _ := params["combine_matching"].(bool)
+	var kept []any
+	for _, r := range rules {
+		rule, ok := r.(map[string]any)
+		if !ok {
+			kept = append(kept, r)
+			continue
+		}
+		// A rule's conditions are combined by and.
+		var conds []any
+		for name, value := range rule {
+			if name != "sampler" {
+				conds = append(conds, map[string]any{name: value})
+			}
+		}
+		pred := normalizePredicate(map[string]any{"and": conds})
+		s, _ := rule["sampler"].(map[string]any)
+		s = normalizeSampler(s)
+		if value, ok := constantValue(pred); ok {
+			if !value {
+				continue
+			}
+			if !combine {
+				// Later rules cannot match.
+				params["default"] = s
+				break
+			}
+		}
+		rule = map[string]any{"sampler": s}
+		for name, value := range pred {
+			rule[name] = value
+		}
+		kept = append(kept, rule)
+	}
+	if len(kept) == 0 {
+		if def, ok := params["default"].(map[string]any); ok {
+			return def
+		}
+	}
+	params["rules"] = append([]any{}, kept...)
+	return doc
+}
+
+// constantValue returns the value of a constant predicate document.
+func constantValue(doc map[string]any) (value, ok bool) {
+	if name, v, ok := single(doc); ok && name == "constant" {
+		value, ok := v.(bool)
+		return value, ok
+	}
+	return false, false
+}
+
+func constantDoc(value bool) map[string]any {
+	return map[string]any{"constant": value}
+}
+
+func normalizePredicate(doc map[string]any) map[string]any {
+	name, value, ok := single(doc)
+	if !ok {
+		return doc
+	}
+	switch name {
+	case "and", "or":
+		return normalizeCombination(name, value)
+	case "not":
+		inner, ok := value.(map[string]any)
+		if !ok {
+			return doc
+		}
+		inner = normalizePredicate(inner)
+		if v, ok := constantValue(inner); ok {
+			return constantDoc(!v)
+		}
+		if n, v, ok := single(inner); ok && n == "not" {
+			if original, ok := v.(map[string]any); ok {
+				return original
+			}
+		}
+		return map[string]any{"not": inner}
+	case "span_kinds", "span_names", "parent":
+		return map[string]any{name: sortedSet(value)}
+	case "http_route":
+		if params, ok := value.(map[string]any); ok {
+			if methods, ok := params["methods"]; ok {
+				params["methods"] = sortedSet(methods)
+			}
+		}
+	}
+	return doc
+}
+
+// normalizeCombination normalizes an and (shortCircuit false) or an
+// or (shortCircuit true) of predicates.
+func normalizeCombination(name string, value any) map[string]any {
+	shortCircuit := name == "or"
+	operands, ok := value.([]any)
+	if !ok {
+		return map[string]any{name: value}
+	}
+	seen := map[string]bool{}
+	var flat []any
+	var add func(operands []any) bool
+	add = func(operands []any) bool {
+		for _, op := range operands {
+			doc, ok := op.(map[string]any)
+			if !ok {
+				flat = append(flat, op)
+				continue
+			}
+			doc = normalizePredicate(doc)
+			if v, ok := constantValue(doc); ok {
+				if v == shortCircuit {
+					return false
+				}
+				continue
+			}
+			if n, nested, ok := single(doc); ok && n == name {
+				if nested, ok := nested.([]any); ok {
+					if !add(nested) {
+						return false
+					}
+					continue
+				}
+			}
+			key := encodeCanonical(doc)
+			if !seen[key] {
+				seen[key] = true
+				flat = append(flat, doc)
+			}
+		}
+		return true
+	}
+	if !add(operands) {
+		return constantDoc(shortCircuit)
+	}
+	// An operand and its negation determine the result.
+	for _, op := range flat {
+		doc, _ := op.(map[string]any)
+		if n, inner, ok := single(doc); ok && n == "not" && seen[encodeCanonical(inner)] {
+			return constantDoc(shortCircuit)
+		}
+	}
+	switch len(flat) {
+	case 0:
+		return constantDoc(!shortCircuit)
+	case 1:
+		if doc, ok := flat[0].(map[string]any); ok {
+			return doc
+		}
+	}
+	sort.Slice(flat, func(i, j int) bool {
+		return encodeCanonical(flat[i]) < encodeCanonical(flat[j])
+	})
+	return map[string]any{name: flat}
+}
+
+// sortedSet sorts and removes duplicates from a list of strings.
+func sortedSet(value any) any {
+	list, ok := value.([]any)
+	if !ok {
+		return value
+	}
+	var strs []string
+	for _, elem := range list {
+		s, ok := elem.(string)
+		if !ok {
+			return value
+		}
+		strs = append(strs, s)
+	}
+	sort.Strings(strs)
+	out := []any{}
+	for i, s := range strs {
+		if i == 0 || s != strs[i-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
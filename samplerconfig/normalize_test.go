@@ -0,0 +1,101 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package samplerconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jmacd/sampler"
+)
+
+func TestEqual(t *testing.T) {
+	health := sampler.SpanNameGlobPredicate("/health*")
+	errors := sampler.AttributeEqualsPredicate(attribute.Bool("error", true))
+	ratio := sampler.TraceIDRatioBased(0.1)
+
+	for _, test := range []struct {
+		name string
+		a, b sampler.ComposableSampler
+	}{
+		{"ratio", sampler.TraceIDRatioBasedRational(1, 10), ratio},
+		{"parent-based", sampler.ComposableParentBased(ratio), sampler.RuleBased(
+			sampler.WithRule(sampler.IsRootPredicate(), sampler.TraceIDRatioBased(0.1)),
+			sampler.WithDefaultRule(sampler.ParentThreshold()),
+		)},
+		{"default only", sampler.RuleBased(sampler.WithDefaultRule(ratio)), ratio},
+		{"true rule", sampler.RuleBased(
+			sampler.WithRule(sampler.OrPredicate(health, sampler.NotPredicate(health)), ratio),
+			sampler.WithRule(health, sampler.ComposableNeverSample()),
+		), ratio},
+		{"false rule", sampler.RuleBased(
+			sampler.WithRule(sampler.AndPredicate(errors, sampler.NotPredicate(errors)), sampler.ComposableAlwaysSample()),
+			sampler.WithDefaultRule(ratio),
+		), ratio},
+		{"flattened", sampler.RuleBased(
+			sampler.WithRule(sampler.AndPredicate(health, sampler.AndPredicate(errors, health)), sampler.ComposableNeverSample()),
+			sampler.WithDefaultRule(ratio),
+		), sampler.RuleBased(
+			sampler.WithRule(sampler.AndPredicate(errors, health), sampler.ComposableNeverSample()),
+			sampler.WithDefaultRule(ratio),
+		)},
+		{"double negation", sampler.RuleBased(
+			sampler.WithRule(sampler.NotPredicate(sampler.NotPredicate(health)), sampler.ComposableNeverSample()),
+			sampler.WithDefaultRule(ratio),
+		), sampler.RuleBased(
+			sampler.WithRule(sampler.MemoizePredicate(health), sampler.ComposableNeverSample()),
+			sampler.WithDefaultRule(ratio),
+		)},
+		{"span kinds", sampler.RuleBased(
+			sampler.WithRule(sampler.SpanKindPredicate(trace.SpanKindServer, trace.SpanKindClient), ratio),
+		), sampler.RuleBased(
+			sampler.WithRule(sampler.SpanKindPredicate(trace.SpanKindClient, trace.SpanKindServer, trace.SpanKindClient), ratio),
+		)},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			require.True(t, Equal(test.a, test.b), "%v != %v", Normalize(test.a), Normalize(test.b))
+		})
+	}
+
+	require.False(t, Equal(ratio, sampler.TraceIDRatioBased(0.2)))
+	require.False(t, Equal(
+		sampler.RuleBased(sampler.WithRule(health, ratio), sampler.WithRule(errors, sampler.ComposableAlwaysSample())),
+		sampler.RuleBased(sampler.WithRule(errors, sampler.ComposableAlwaysSample()), sampler.WithRule(health, ratio)),
+	))
+
+	// Opaque samplers and predicates cannot be compared.
+	custom := sampler.RuleBased(sampler.WithRule(sampler.NewPredicate(func(sampler.ComposableSamplingParameters) bool {
+		return true
+	}, "custom"), ratio))
+	require.False(t, Equal(custom, custom))
+}
+
+func TestNormalize(t *testing.T) {
+	s := sampler.RuleBased(
+		sampler.WithRule(sampler.OrPredicate(
+			sampler.SpanNameInSetPredicate("b", "a", "b"),
+			sampler.OrPredicate(sampler.HTTPRoutePredicate("/api/*", "POST", "GET"), sampler.TruePredicate()),
+		), sampler.ComposableAlwaysSample()),
+	)
+	require.Equal(t, map[string]any{"always_on": map[string]any{}}, Normalize(s))
+
+	s = sampler.RuleBased(
+		sampler.WithRule(sampler.OrPredicate(
+			sampler.SpanNameInSetPredicate("b", "a", "b"),
+			sampler.OrPredicate(sampler.HTTPRoutePredicate("/api/*", "POST", "GET"), sampler.FalsePredicate()),
+		), sampler.ComposableAlwaysSample()),
+	)
+	require.Equal(t, map[string]any{"rule_based": map[string]any{"rules": []any{
+		map[string]any{
+			"or": []any{
+				map[string]any{"http_route": map[string]any{"pattern": "/api/*", "methods": []any{"GET", "POST"}}},
+				map[string]any{"span_names": []any{"a", "b"}},
+			},
+			"sampler": map[string]any{"always_on": map[string]any{}},
+		},
+	}}}, Normalize(s))
+}
@@ -0,0 +1,401 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package samplerconfig
+
+import (
+	"fmt"
+	"reflect"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"gopkg.in/yaml.v3"
+
+	"github.com/jmacd/sampler"
+)
+
+// builtinPredicate builds a built-in predicate from its configuration,
+// reporting errors at path.
+type builtinPredicate func(config any, path string) (sampler.Predicate, error)
+
+// builtinPredicates are the predicates that rules can use in addition
+// to the fields of Rule, in the form written by PredicateConfig, as
+// in
+//
+//	rules:
+//	  - attribute_equals: {key: http.response.status_code, value: 503}
+//	    sampler:
+//	      always_on:
+//
+// The combinations and, or, and not contain predicates in the same
+// form, including those named by the fields of Rule.
+var builtinPredicates map[string]builtinPredicate
+
+func init() {
+	builtinPredicates = map[string]builtinPredicate{
+		"constant": func(config any, path string) (sampler.Predicate, error) {
+			var value bool
+			if err := decodeConfig(config, &value, path); err != nil {
+				return sampler.Predicate{}, err
+			}
+			if value {
+				return sampler.TruePredicate(), nil
+			}
+			return sampler.FalsePredicate(), nil
+		},
+		"not": func(config any, path string) (sampler.Predicate, error) {
+			pred, err := predicateFromConfig(config, path)
+			if err != nil {
+				return sampler.Predicate{}, err
+			}
+			return sampler.NotPredicate(pred), nil
+		},
+		"and": combination(sampler.AndPredicate),
+		"or":  combination(sampler.OrPredicate),
+
+		"span_name": stringPredicate(sampler.SpanNamePredicate),
+		"span_names": func(config any, path string) (sampler.Predicate, error) {
+			var names []string
+			if err := decodeConfig(config, &names, path); err != nil {
+				return sampler.Predicate{}, err
+			}
+			return sampler.SpanNameInSetPredicate(names...), nil
+		},
+		"span_name_regex": func(config any, path string) (sampler.Predicate, error) {
+			var expr string
+			if err := decodeConfig(config, &expr, path); err != nil {
+				return sampler.Predicate{}, err
+			}
+			pred, err := sampler.SpanNameRegexPredicate(expr)
+			if err != nil {
+				return sampler.Predicate{}, buildError(path, "%v", err)
+			}
+			return pred, nil
+		},
+		"span_name_glob": stringPredicate(sampler.SpanNameGlobPredicate),
+		"span_kinds": func(config any, path string) (sampler.Predicate, error) {
+			var names []string
+			if err := decodeConfig(config, &names, path); err != nil {
+				return sampler.Predicate{}, err
+			}
+			return spanKindPredicate(names, path)
+		},
+		"parent": func(config any, path string) (sampler.Predicate, error) {
+			var names []string
+			if err := decodeConfig(config, &names, path); err != nil {
+				return sampler.Predicate{}, err
+			}
+			return parentPredicate(names, path)
+		},
+
+		"attribute_equals": keyValuePredicate(sampler.AttributeEqualsPredicate),
+		"attribute_values": func(config any, path string) (sampler.Predicate, error) {
+			var av AttributeValues
+			if err := decodeConfig(config, &av, path); err != nil {
+				return sampler.Predicate{}, err
+			}
+			if av.Key == "" {
+				return sampler.Predicate{}, buildError(path+".key", "missing key")
+			}
+			return sampler.AttributeValueInSetPredicate(attribute.Key(av.Key), av.Values...), nil
+		},
+		"attribute_regex": func(config any, path string) (sampler.Predicate, error) {
+			var p keyPattern
+			if err := decodeConfig(config, &p, path); err != nil {
+				return sampler.Predicate{}, err
+			}
+			if p.Key == "" {
+				return sampler.Predicate{}, buildError(path+".key", "missing key")
+			}
+			pred, err := sampler.AttributeRegexPredicate(attribute.Key(p.Key), p.Pattern)
+			if err != nil {
+				return sampler.Predicate{}, buildError(path+".pattern", "%v", err)
+			}
+			return pred, nil
+		},
+		"attribute_glob": func(config any, path string) (sampler.Predicate, error) {
+			var p keyPattern
+			if err := decodeConfig(config, &p, path); err != nil {
+				return sampler.Predicate{}, err
+			}
+			if p.Key == "" {
+				return sampler.Predicate{}, buildError(path+".key", "missing key")
+			}
+			return sampler.AttributeGlobPredicate(attribute.Key(p.Key), p.Pattern), nil
+		},
+		"attribute_greater": limitPredicate(sampler.AttributeGreaterPredicate),
+		"attribute_less":    limitPredicate(sampler.AttributeLessPredicate),
+		"attribute_range": func(config any, path string) (sampler.Predicate, error) {
+			var r struct {
+				Key  string  `yaml:"key"`
+				Low  float64 `yaml:"low"`
+				High float64 `yaml:"high"`
+			}
+			if err := decodeConfig(config, &r, path); err != nil {
+				return sampler.Predicate{}, err
+			}
+			if r.Key == "" {
+				return sampler.Predicate{}, buildError(path+".key", "missing key")
+			}
+			return sampler.AttributeRangePredicate(attribute.Key(r.Key), r.Low, r.High), nil
+		},
+		"http_route": func(config any, path string) (sampler.Predicate, error) {
+			var r struct {
+				Pattern string   `yaml:"pattern"`
+				Methods []string `yaml:"methods"`
+			}
+			if err := decodeConfig(config, &r, path); err != nil {
+				return sampler.Predicate{}, err
+			}
+			return sampler.HTTPRoutePredicate(r.Pattern, r.Methods...), nil
+		},
+		"resource_attribute": keyValuePredicate(sampler.ResourceAttributePredicate),
+		"scope": func(config any, path string) (sampler.Predicate, error) {
+			var s struct {
+				Name      string `yaml:"name"`
+				Version   string `yaml:"version"`
+				SchemaURL string `yaml:"schema_url"`
+			}
+			if err := decodeConfig(config, &s, path); err != nil {
+				return sampler.Predicate{}, err
+			}
+			return sampler.ScopePredicate(instrumentation.Scope{
+				Name:      s.Name,
+				Version:   s.Version,
+				SchemaURL: s.SchemaURL,
+			}), nil
+		},
+
+		"parent_sampled": func(config any, path string) (sampler.Predicate, error) {
+			var sampled bool
+			if err := decodeConfig(config, &sampled, path); err != nil {
+				return sampler.Predicate{}, err
+			}
+			if sampled {
+				return sampler.IsParentSampledPredicate(), nil
+			}
+			return sampler.IsParentNotSampledPredicate(), nil
+		},
+		"has_parent_threshold":        flagPredicate(sampler.HasParentThresholdPredicate),
+		"parent_probability_at_least": fractionPredicate(sampler.ParentProbabilityAtLeastPredicate),
+		"trace_fraction":              fractionPredicate(sampler.TraceFractionPredicate),
+
+		"has_links":        flagPredicate(sampler.HasLinksPredicate),
+		"any_link_sampled": flagPredicate(sampler.AnyLinkSampledPredicate),
+		"has_link_attribute": stringPredicate(func(key string) sampler.Predicate {
+			return sampler.HasLinkAttributePredicate(attribute.Key(key))
+		}),
+		"link_attribute_equals": keyValuePredicate(sampler.LinkAttributeEqualsPredicate),
+
+		"has_parent_attribute": stringPredicate(func(key string) sampler.Predicate {
+			return sampler.HasParentAttributePredicate(attribute.Key(key))
+		}),
+		"parent_attribute_equals": keyValuePredicate(sampler.ParentAttributeEqualsPredicate),
+
+		"has_baggage":                  stringPredicate(sampler.HasBaggagePredicate),
+		"baggage_equals":               stringPairPredicate("key", sampler.BaggageEqualsPredicate),
+		"tracestate_member":            stringPredicate(sampler.TraceStateMemberPredicate),
+		"tracestate_member_equals":     stringPairPredicate("key", sampler.TraceStateMemberEqualsPredicate),
+		"otel_tracestate_field":        stringPredicate(sampler.OTelTraceStateFieldPredicate),
+		"otel_tracestate_field_equals": stringPairPredicate("field", sampler.OTelTraceStateFieldEqualsPredicate),
+	}
+}
+
+// keyPattern is the configuration of a pattern-matching attribute
+// predicate.
+type keyPattern struct {
+	Key     string `yaml:"key"`
+	Pattern string `yaml:"pattern"`
+}
+
+// predicateFromConfig builds a predicate from a document with one
+// entry naming a built-in or registered predicate.
+func predicateFromConfig(config any, path string) (sampler.Predicate, error) {
+	doc, ok := config.(map[string]any)
+	if !ok || len(doc) != 1 {
+		return sampler.Predicate{}, buildError(path, "expected a mapping with one predicate")
+	}
+	var name string
+	for name = range doc {
+	}
+	return buildPredicate(name, doc[name], joinPath(path, name))
+}
+
+// buildPredicate builds a built-in or registered predicate.
+func buildPredicate(name string, config any, path string) (sampler.Predicate, error) {
+	if builtin, ok := builtinPredicates[name]; ok {
+		return builtin(config, path)
+	}
+	factory, ok := lookupPredicate(name)
+	if !ok {
+		return sampler.Predicate{}, buildError(path, "unknown predicate %q", name)
+	}
+	pred, err := factory(config)
+	if err != nil {
+		return sampler.Predicate{}, buildError(path, "%v", err)
+	}
+	return pred, nil
+}
+
+// decodeConfig strictly decodes a predicate's configuration into v,
+// as for configuration documents.
+func decodeConfig(config, v any, path string) error {
+	var node yaml.Node
+	if err := node.Encode(config); err != nil {
+		return buildError(path, "%v", err)
+	}
+	var d decoder
+	d.value(&node, reflect.ValueOf(v).Elem(), path)
+	return d.errs.err()
+}
+
+func combination(combine func(...sampler.Predicate) sampler.Predicate) builtinPredicate {
+	return func(config any, path string) (sampler.Predicate, error) {
+		configs, ok := config.([]any)
+		if !ok {
+			return sampler.Predicate{}, buildError(path, "expected a sequence")
+		}
+		var errs Errors
+		preds := make([]sampler.Predicate, len(configs))
+		for i, config := range configs {
+			pred, err := predicateFromConfig(config, fmt.Sprintf("%s[%d]", path, i))
+			errs.add(err)
+			preds[i] = pred
+		}
+		if err := errs.err(); err != nil {
+			return sampler.Predicate{}, err
+		}
+		return combine(preds...), nil
+	}
+}
+
+func stringPredicate(build func(string) sampler.Predicate) builtinPredicate {
+	return func(config any, path string) (sampler.Predicate, error) {
+		var value string
+		if err := decodeConfig(config, &value, path); err != nil {
+			return sampler.Predicate{}, err
+		}
+		return build(value), nil
+	}
+}
+
+func stringPairPredicate(key string, build func(key, value string) sampler.Predicate) builtinPredicate {
+	return func(config any, path string) (sampler.Predicate, error) {
+		var pair map[string]string
+		if err := decodeConfig(config, &pair, path); err != nil {
+			return sampler.Predicate{}, err
+		}
+		for name := range pair {
+			if name != key && name != "value" {
+				return sampler.Predicate{}, buildError(path, "unknown field %q", name)
+			}
+		}
+		return build(pair[key], pair["value"]), nil
+	}
+}
+
+// flagPredicate builds a predicate configured as true, or its negation
+// configured as false.
+func flagPredicate(build func() sampler.Predicate) builtinPredicate {
+	return func(config any, path string) (sampler.Predicate, error) {
+		var value bool
+		if err := decodeConfig(config, &value, path); err != nil {
+			return sampler.Predicate{}, err
+		}
+		if value {
+			return build(), nil
+		}
+		return sampler.NotPredicate(build()), nil
+	}
+}
+
+func fractionPredicate(build func(float64) sampler.Predicate) builtinPredicate {
+	return func(config any, path string) (sampler.Predicate, error) {
+		var fraction float64
+		if err := decodeConfig(config, &fraction, path); err != nil {
+			return sampler.Predicate{}, err
+		}
+		if !(fraction >= 0 && fraction <= 1) {
+			return sampler.Predicate{}, buildError(path, "fraction %v is not in the range [0, 1]", fraction)
+		}
+		return build(fraction), nil
+	}
+}
+
+func limitPredicate(build func(attribute.Key, float64) sampler.Predicate) builtinPredicate {
+	return func(config any, path string) (sampler.Predicate, error) {
+		var l struct {
+			Key   string  `yaml:"key"`
+			Limit float64 `yaml:"limit"`
+		}
+		if err := decodeConfig(config, &l, path); err != nil {
+			return sampler.Predicate{}, err
+		}
+		if l.Key == "" {
+			return sampler.Predicate{}, buildError(path+".key", "missing key")
+		}
+		return build(attribute.Key(l.Key), l.Limit), nil
+	}
+}
+
+func keyValuePredicate(build func(attribute.KeyValue) sampler.Predicate) builtinPredicate {
+	return func(config any, path string) (sampler.Predicate, error) {
+		var kv struct {
+			Key   string `yaml:"key"`
+			Value any    `yaml:"value"`
+		}
+		if err := decodeConfig(config, &kv, path); err != nil {
+			return sampler.Predicate{}, err
+		}
+		if kv.Key == "" {
+			return sampler.Predicate{}, buildError(path+".key", "missing key")
+		}
+		value, err := attributeValue(kv.Value)
+		if err != nil {
+			return sampler.Predicate{}, buildError(path+".value", "%v", err)
+		}
+		return build(attribute.KeyValue{Key: attribute.Key(kv.Key), Value: value}), nil
+	}
+}
+
+// attributeValue converts a decoded YAML value to an attribute value.
+// Sequences must contain values of one type.
+func attributeValue(v any) (attribute.Value, error) {
+	switch v := v.(type) {
+	case bool:
+		return attribute.BoolValue(v), nil
+	case int:
+		return attribute.Int64Value(int64(v)), nil
+	case float64:
+		return attribute.Float64Value(v), nil
+	case string:
+		return attribute.StringValue(v), nil
+	case []any:
+		if len(v) == 0 {
+			return attribute.StringSliceValue(nil), nil
+		}
+		switch v[0].(type) {
+		case bool:
+			return sliceValue(v, attribute.BoolSliceValue)
+		case int:
+			return sliceValue(v, attribute.IntSliceValue)
+		case float64:
+			return sliceValue(v, attribute.Float64SliceValue)
+		case string:
+			return sliceValue(v, attribute.StringSliceValue)
+		}
+	}
+	return attribute.Value{}, fmt.Errorf("unsupported attribute value %v", v)
+}
+
+func sliceValue[T any](values []any, build func([]T) attribute.Value) (attribute.Value, error) {
+	slice := make([]T, len(values))
+	for i, value := range values {
+		elem, ok := value.(T)
+		if !ok {
+			return attribute.Value{}, fmt.Errorf("mixed types in attribute value %v", values)
+		}
+		slice[i] = elem
+	}
+	return build(slice), nil
+}
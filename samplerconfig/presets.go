@@ -0,0 +1,83 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package samplerconfig
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jmacd/sampler"
+)
+
+// presetDocuments are the configurations of the presets named by
+// Sampler.Preset.
+var presetDocuments = map[string]string{
+	// debug samples every span and marks it, so that spans
+	// sampled while debugging are recognized.
+	"debug": `
+sampler:
+  annotating:
+    sampler:
+      always_on:
+    attributes:
+      sampler.preset: debug
+`,
+	// production-default samples 10% of traces consistently,
+	// following the parent's decision, and drops health checks.
+	"production-default": `
+sampler:
+  parent_threshold:
+    root:
+      rule_based:
+        rules:
+          - attribute_patterns:
+              key: url.path
+              included: ["/health*", "/ready*", "/live*"]
+            sampler:
+              always_off:
+          - attribute_patterns:
+              key: http.route
+              included: ["/health*", "/ready*", "/live*"]
+            sampler:
+              always_off:
+        default:
+          probability:
+            ratio: 0.1
+`,
+	// off samples nothing.
+	"off": `
+sampler:
+  always_off:
+`,
+}
+
+// presets returns the parsed presetDocuments.  They are parsed on
+// first use, after the package is initialized.
+var presets = sync.OnceValue(func() map[string]*Sampler {
+	m := map[string]*Sampler{}
+	for name, doc := range presetDocuments {
+		cfg, err := Parse([]byte(doc))
+		if err != nil {
+			panic(fmt.Sprintf("preset %s: %v", name, err))
+		}
+		m[name] = cfg.Sampler
+	}
+	return m
+})
+
+// presetNames lists the presets for error messages.
+func presetNames() string {
+	names := sortedKeys(presets())
+	return strings.Join(names[:len(names)-1], ", ") + ", or " + names[len(names)-1]
+}
+
+// buildPreset returns the sampler of a named preset.
+func buildPreset(name, path string) (sampler.ComposableSampler, error) {
+	s, ok := presets()[name]
+	if !ok {
+		return nil, buildError(path, "unknown preset %q, expected %s", name, presetNames())
+	}
+	return buildSampler(s, "preset("+name+")")
+}
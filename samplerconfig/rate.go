@@ -0,0 +1,103 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package samplerconfig
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/jmacd/sampler"
+)
+
+// rateUnits are the units of rate limits, in seconds.
+var rateUnits = map[string]float64{
+	"s":   1,
+	"sec": 1,
+	"m":   60,
+	"min": 60,
+	"h":   3600,
+}
+
+// parseRate returns the sampler of a rate expression, which is one
+// of:
+//
+//	1/1000   a probability, as a fraction
+//	0.1%     a probability, as a percentage
+//	0.001    a probability
+//	50/s     a limit of 50 traces per second, also /m or /min and /h
+//
+// Probabilities are converted to thresholds exactly, see
+// sampler.TraceIDRatioBasedRational.
+func parseRate(expr string) (sampler.ComposableSampler, error) {
+	s := strings.TrimSpace(expr)
+	if num, unit, ok := strings.Cut(s, "/"); ok {
+		if seconds, ok := rateUnits[strings.TrimSpace(unit)]; ok {
+			limit, err := parseDecimal(num)
+			if err != nil || limit.Sign() < 0 {
+				return nil, fmt.Errorf("invalid rate %q, expected a non-negative number of traces per %s", expr, strings.TrimSpace(unit))
+			}
+			perSecond, _ := limit.Float64()
+			return rateLimited(perSecond / seconds), nil
+		}
+	}
+
+	var ratio *big.Rat
+	var err error
+	switch {
+	case strings.HasSuffix(s, "%"):
+		ratio, err = parseDecimal(strings.TrimSuffix(s, "%"))
+		if err == nil {
+			ratio.Quo(ratio, big.NewRat(100, 1))
+		}
+	case strings.Contains(s, "/"):
+		num, den, _ := strings.Cut(s, "/")
+		var n, d *big.Rat
+		n, err = parseDecimal(num)
+		if err == nil {
+			d, err = parseDecimal(den)
+		}
+		if err == nil && d.Sign() == 0 {
+			err = fmt.Errorf("zero denominator")
+		}
+		if err == nil {
+			ratio = n.Quo(n, d)
+		}
+	default:
+		ratio, err = parseDecimal(s)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid rate %q, expected a probability such as 1/1000, 0.1%%, or 0.001, or a limit such as 50/s", expr)
+	}
+	if ratio.Sign() < 0 || ratio.Cmp(big.NewRat(1, 1)) > 0 {
+		return nil, fmt.Errorf("rate %q is not a probability in the range [0, 1]", expr)
+	}
+	if ratio.Num().IsUint64() && ratio.Denom().IsUint64() {
+		return sampler.TraceIDRatioBasedRational(ratio.Num().Uint64(), ratio.Denom().Uint64()), nil
+	}
+	f, _ := ratio.Float64()
+	return sampler.TraceIDRatioBased(f), nil
+}
+
+// parseDecimal parses a finite decimal number exactly.
+func parseDecimal(s string) (*big.Rat, error) {
+	s = strings.TrimSpace(s)
+	// Rat.SetString also accepts fractions, which are handled by
+	// the caller.
+	if s == "" || strings.ContainsAny(s, "/") {
+		return nil, fmt.Errorf("invalid number %q", s)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("invalid number %q", s)
+	}
+	return r, nil
+}
+
+// rateLimited returns a sampler that limits spans to approximately
+// the given number per second, using CostBased with a cost of one per
+// span.  Used for root spans, this limits traces per second.
+func rateLimited(perSecond float64) sampler.ComposableSampler {
+	return sampler.CostBased(perSecond, sampler.WithDefaultSpanCost(1), sampler.WithAttributeCost(0))
+}
@@ -0,0 +1,107 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package samplerconfig
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/jmacd/sampler"
+)
+
+// SamplerFactory builds a registered sampler from its configuration,
+// which is the decoded YAML or JSON value following the sampler's
+// name: nil, a string, float64, bool, []any, or map[string]any.  When
+// named by OTEL_TRACES_SAMPLER, the configuration is the string
+// OTEL_TRACES_SAMPLER_ARG, or nil when it is empty.
+type SamplerFactory func(config any) (sampler.ComposableSampler, error)
+
+// PredicateFactory builds a registered predicate from its
+// configuration, as for SamplerFactory.
+type PredicateFactory func(config any) (sampler.Predicate, error)
+
+// registry holds the registered factories.
+var registry struct {
+	lock       sync.RWMutex
+	samplers   map[string]SamplerFactory
+	predicates map[string]PredicateFactory
+}
+
+// RegisterSampler registers a sampler factory, so that configuration
+// documents can use the name as a sampler type, as in
+//
+//	sampler:
+//	  my_sampler:
+//	    setting: value
+//
+// and OTEL_TRACES_SAMPLER can name it.  Names cannot be those of the
+// built-in samplers or OTEL_TRACES_SAMPLER values, and each name can
+// be registered once.
+func RegisterSampler(name string, factory SamplerFactory) error {
+	if name == "" || isBuiltinSampler(name) {
+		return fmt.Errorf("samplerconfig: sampler name is reserved: %q", name)
+	}
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	if _, ok := registry.samplers[name]; ok {
+		return fmt.Errorf("samplerconfig: sampler is already registered: %q", name)
+	}
+	if registry.samplers == nil {
+		registry.samplers = map[string]SamplerFactory{}
+	}
+	registry.samplers[name] = factory
+	return nil
+}
+
+// RegisterPredicate registers a predicate factory, so that rules can
+// use the name as a condition, as in
+//
+//	rules:
+//	  - my_predicate: value
+//	    sampler:
+//	      always_on:
+//
+// Names cannot be those of the built-in rule fields and predicates, and
+// each name can be registered once.
+func RegisterPredicate(name string, factory PredicateFactory) error {
+	_, field := structFields(ruleType)[name]
+	_, builtin := builtinPredicates[name]
+	if name == "" || field || builtin {
+		return fmt.Errorf("samplerconfig: predicate name is reserved: %q", name)
+	}
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	if _, ok := registry.predicates[name]; ok {
+		return fmt.Errorf("samplerconfig: predicate is already registered: %q", name)
+	}
+	if registry.predicates == nil {
+		registry.predicates = map[string]PredicateFactory{}
+	}
+	registry.predicates[name] = factory
+	return nil
+}
+
+func lookupSampler(name string) (SamplerFactory, bool) {
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+	factory, ok := registry.samplers[name]
+	return factory, ok
+}
+
+func lookupPredicate(name string) (PredicateFactory, bool) {
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+	factory, ok := registry.predicates[name]
+	return factory, ok
+}
+
+// isBuiltinSampler returns true for the built-in sampler types and
+// OTEL_TRACES_SAMPLER values.
+func isBuiltinSampler(name string) bool {
+	if _, ok := structFields(samplerType)[name]; ok {
+		return true
+	}
+	_, ok := envSamplers[name]
+	return ok
+}
@@ -0,0 +1,76 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package samplerconfig
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/jmacd/sampler"
+)
+
+// The test factories are registered once, since the registry is global.
+func init() {
+	if err := RegisterSampler("test_one_in", func(config any) (sampler.ComposableSampler, error) {
+		switch n := config.(type) {
+		case int:
+			return sampler.TraceIDRatioBasedN(uint64(n)), nil
+		case string:
+			var parsed uint64
+			if _, err := fmt.Sscan(n, &parsed); err != nil {
+				return nil, err
+			}
+			return sampler.TraceIDRatioBasedN(parsed), nil
+		}
+		return nil, fmt.Errorf("expected an integer, not %v", config)
+	}); err != nil {
+		panic(err)
+	}
+	if err := RegisterPredicate("test_span_name", func(config any) (sampler.Predicate, error) {
+		name, ok := config.(string)
+		if !ok {
+			return sampler.Predicate{}, fmt.Errorf("expected a string, not %v", config)
+		}
+		return sampler.SpanNamePredicate(name), nil
+	}); err != nil {
+		panic(err)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	s, err := Load([]byte(`
+sampler:
+  rule_based:
+    rules:
+      - test_span_name: checkout
+        span_kinds: [server]
+        sampler:
+          test_one_in: 10
+`))
+	require.NoError(t, err)
+	require.Equal(t, "RuleBased{rule(and(Span.Kind==server,Span.Name==checkout))=TraceIDRatioBasedN{10}}", s.Description())
+
+	env, err := ParseEnv("test_one_in", "4")
+	require.NoError(t, err)
+	require.Equal(t, "TraceIDRatioBasedN{4}", env.Description())
+
+	_, err = ParseEnv("test_one_in", "")
+	require.EqualError(t, err, "samplerconfig: OTEL_TRACES_SAMPLER: test_one_in: expected an integer, not <nil>")
+
+	_, err = Load([]byte("sampler:\n  test_one_in: [1]\n"))
+	require.EqualError(t, err, "samplerconfig: sampler.test_one_in: expected an integer, not [1]")
+
+	_, err = Load([]byte("sampler:\n  test_one_in: 1\n  always_on:\n"))
+	require.EqualError(t, err, "samplerconfig: line 2: sampler: expected one sampler type, found always_on and test_one_in")
+
+	_, err = Load([]byte("sampler:\n  rule_based:\n    rules:\n      - test_span_name: 1\n        sampler: {always_on: }\n"))
+	require.EqualError(t, err, "samplerconfig: sampler.rule_based.rules[0].test_span_name: expected a string, not 1")
+
+	require.EqualError(t, RegisterSampler("test_one_in", nil), `samplerconfig: sampler is already registered: "test_one_in"`)
+	require.EqualError(t, RegisterSampler("probability", nil), `samplerconfig: sampler name is reserved: "probability"`)
+	require.EqualError(t, RegisterSampler("traceidratio", nil), `samplerconfig: sampler name is reserved: "traceidratio"`)
+	require.EqualError(t, RegisterPredicate("test_span_name", nil), `samplerconfig: predicate is already registered: "test_span_name"`)
+	require.EqualError(t, RegisterPredicate("span_kinds", nil), `samplerconfig: predicate name is reserved: "span_kinds"`)
+}
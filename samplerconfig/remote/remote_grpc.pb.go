@@ -0,0 +1,176 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: remote.proto
+
+package remote
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	SamplingPolicy_GetPolicy_FullMethodName   = "/jmacd.sampler.remote.v1.SamplingPolicy/GetPolicy"
+	SamplingPolicy_ReportRates_FullMethodName = "/jmacd.sampler.remote.v1.SamplingPolicy/ReportRates"
+)
+
+// SamplingPolicyClient is the client API for SamplingPolicy service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// SamplingPolicy is a central control plane for sampling, from which
+// agents fetch their sampler configuration and to which they report
+// the rates they observe.
+type SamplingPolicyClient interface {
+	// GetPolicy returns the policy for an agent.  The policy is omitted
+	// when the agent already has its version.
+	GetPolicy(ctx context.Context, in *GetPolicyRequest, opts ...grpc.CallOption) (*GetPolicyResponse, error)
+	// ReportRates reports the span rates observed by an agent.
+	ReportRates(ctx context.Context, in *ReportRatesRequest, opts ...grpc.CallOption) (*ReportRatesResponse, error)
+}
+
+type samplingPolicyClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSamplingPolicyClient(cc grpc.ClientConnInterface) SamplingPolicyClient {
+	return &samplingPolicyClient{cc}
+}
+
+func (c *samplingPolicyClient) GetPolicy(ctx context.Context, in *GetPolicyRequest, opts ...grpc.CallOption) (*GetPolicyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetPolicyResponse)
+	err := c.cc.Invoke(ctx, SamplingPolicy_GetPolicy_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samplingPolicyClient) ReportRates(ctx context.Context, in *ReportRatesRequest, opts ...grpc.CallOption) (*ReportRatesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportRatesResponse)
+	err := c.cc.Invoke(ctx, SamplingPolicy_ReportRates_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SamplingPolicyServer is the server API for SamplingPolicy service.
+// All implementations must embed UnimplementedSamplingPolicyServer
+// for forward compatibility.
+//
+// SamplingPolicy is a central control plane for sampling, from which
+// agents fetch their sampler configuration and to which they report
+// the rates they observe.
+type SamplingPolicyServer interface {
+	// GetPolicy returns the policy for an agent.  The policy is omitted
+	// when the agent already has its version.
+	GetPolicy(context.Context, *GetPolicyRequest) (*GetPolicyResponse, error)
+	// ReportRates reports the span rates observed by an agent.
+	ReportRates(context.Context, *ReportRatesRequest) (*ReportRatesResponse, error)
+	mustEmbedUnimplementedSamplingPolicyServer()
+}
+
+// UnimplementedSamplingPolicyServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSamplingPolicyServer struct{}
+
+func (UnimplementedSamplingPolicyServer) GetPolicy(context.Context, *GetPolicyRequest) (*GetPolicyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPolicy not implemented")
+}
+func (UnimplementedSamplingPolicyServer) ReportRates(context.Context, *ReportRatesRequest) (*ReportRatesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportRates not implemented")
+}
+func (UnimplementedSamplingPolicyServer) mustEmbedUnimplementedSamplingPolicyServer() {}
+func (UnimplementedSamplingPolicyServer) testEmbeddedByValue()                        {}
+
+// UnsafeSamplingPolicyServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SamplingPolicyServer will
+// result in compilation errors.
+type UnsafeSamplingPolicyServer interface {
+	mustEmbedUnimplementedSamplingPolicyServer()
+}
+
+func RegisterSamplingPolicyServer(s grpc.ServiceRegistrar, srv SamplingPolicyServer) {
+	// If the following call pancis, it indicates UnimplementedSamplingPolicyServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&SamplingPolicy_ServiceDesc, srv)
+}
+
+func _SamplingPolicy_GetPolicy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPolicyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamplingPolicyServer).GetPolicy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamplingPolicy_GetPolicy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamplingPolicyServer).GetPolicy(ctx, req.(*GetPolicyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SamplingPolicy_ReportRates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportRatesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamplingPolicyServer).ReportRates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamplingPolicy_ReportRates_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamplingPolicyServer).ReportRates(ctx, req.(*ReportRatesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// SamplingPolicy_ServiceDesc is the grpc.ServiceDesc for SamplingPolicy service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SamplingPolicy_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "jmacd.sampler.remote.v1.SamplingPolicy",
+	HandlerType: (*SamplingPolicyServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetPolicy",
+			Handler:    _SamplingPolicy_GetPolicy_Handler,
+		},
+		{
+			MethodName: "ReportRates",
+			Handler:    _SamplingPolicy_ReportRates_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "remote.proto",
+}
@@ -0,0 +1,179 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package remote
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/jmacd/sampler"
+)
+
+const rulesDoc = `
+sampler:
+  rule_based:
+    rules:
+      - span_kinds: [server]
+        sampler:
+          always_on:
+    default:
+      always_off:
+`
+
+func testConn(t *testing.T, srv *Server) *grpc.ClientConn {
+	lis := bufconn.Listen(1 << 16)
+	gs := grpc.NewServer()
+	RegisterSamplingPolicyServer(gs, srv)
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func testSampler(t *testing.T, srv *Server, service string, options ...Option) *Sampler {
+	res := resource.NewSchemaless(attribute.String("service.name", service))
+	options = append([]Option{
+		WithPollInterval(time.Hour),
+		WithReportInterval(0),
+		WithErrorHandler(func(error) {}),
+	}, options...)
+	s := NewSampler(testConn(t, srv), res, sampler.ComposableAlwaysSample(), options...)
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func TestPolicy(t *testing.T) {
+	ctx := context.Background()
+	srv := NewServer()
+	s := testSampler(t, srv, "checkout")
+
+	// Without a policy, the initial sampler remains.
+	require.NoError(t, s.Poll(ctx))
+	require.Equal(t, uint64(0), s.Version())
+	require.Equal(t, "AlwaysOn", s.Description())
+
+	require.Error(t, srv.SetPolicy("", 0, []byte("sampler:\n  always_off:\n")))
+	require.Error(t, srv.SetPolicy("", 1, []byte("sampler:\n  unknown:\n")))
+
+	require.NoError(t, srv.SetPolicy("", 1, []byte("sampler:\n  always_off:\n")))
+	require.NoError(t, s.Poll(ctx))
+	require.Equal(t, uint64(1), s.Version())
+	require.Equal(t, "AlwaysOff", s.Description())
+
+	// The service's policy overrides the default.
+	require.NoError(t, srv.SetPolicy("checkout", 2, []byte("sampler:\n  probability: {ratio: 0.5}\n")))
+	require.NoError(t, s.Poll(ctx))
+	require.Equal(t, uint64(2), s.Version())
+	require.Equal(t, "TraceIDRatioBased{0.5}", s.Description())
+
+	// The policy is not sent when unchanged.
+	resp, err := srv.GetPolicy(ctx, &GetPolicyRequest{Agent: s.agent, Version: 2})
+	require.NoError(t, err)
+	require.Nil(t, resp.GetPolicy())
+
+	// Other services receive the default.
+	other := testSampler(t, srv, "search")
+	require.NoError(t, other.Poll(ctx))
+	require.Equal(t, uint64(1), other.Version())
+
+	// After removal, the agent keeps its policy until another applies.
+	srv.RemovePolicy("checkout")
+	require.NoError(t, s.Poll(ctx))
+	require.Equal(t, uint64(1), s.Version())
+	srv.RemovePolicy("")
+	require.NoError(t, s.Poll(ctx))
+	require.Equal(t, uint64(1), s.Version())
+}
+
+func TestReportRates(t *testing.T) {
+	ctx := context.Background()
+	srv := NewServer()
+	require.NoError(t, srv.SetPolicy("", 7, []byte(rulesDoc)))
+	s := testSampler(t, srv, "checkout")
+	require.NoError(t, s.Poll(ctx))
+
+	cs := sampler.CompositeSampler(s)
+	start := func(kind trace.SpanKind, n int) {
+		for i := 0; i < n; i++ {
+			cs.ShouldSample(sampler.SamplingParameters{ParentContext: ctx, TraceID: trace.TraceID{1}, Kind: kind})
+		}
+	}
+	start(trace.SpanKindServer, 3)
+	start(trace.SpanKindClient, 2)
+	require.NoError(t, s.Report(ctx))
+
+	reports := srv.Reports()
+	require.Len(t, reports, 1)
+	report := reports[0]
+	require.Equal(t, "checkout", report.GetAgent().GetResource()["service.name"])
+	require.Equal(t, uint64(7), report.GetVersion())
+	require.Len(t, report.GetRules(), 2)
+	interval := report.GetIntervalSeconds()
+	require.InDelta(t, 3, report.GetRules()[0].GetMatchedPerSecond()*interval, 1e-6)
+	require.InDelta(t, 3, report.GetRules()[0].GetSampledPerSecond()*interval, 1e-6)
+	require.InDelta(t, 2, report.GetRules()[1].GetMatchedPerSecond()*interval, 1e-6)
+	require.InDelta(t, 0, report.GetRules()[1].GetSampledPerSecond()*interval, 1e-6)
+
+	// The next report covers spans since the previous one.
+	start(trace.SpanKindServer, 1)
+	require.NoError(t, s.Report(ctx))
+	report = srv.Reports()[0]
+	interval = report.GetIntervalSeconds()
+	require.InDelta(t, 1, report.GetRules()[0].GetMatchedPerSecond()*interval, 1e-6)
+	require.InDelta(t, 0, report.GetRules()[1].GetMatchedPerSecond()*interval, 1e-6)
+}
+
+func TestAgentPollInterval(t *testing.T) {
+	srv := NewServer(WithAgentPollInterval(time.Second))
+	require.NoError(t, srv.SetPolicy("", 1, []byte("sampler:\n  always_off:\n")))
+	s := testSampler(t, srv, "search")
+
+	// The background poll fetches the default policy, then the
+	// server's interval applies to later polls.
+	require.Eventually(t, func() bool { return s.Version() == 1 }, 5*time.Second, time.Millisecond)
+	require.NoError(t, srv.SetPolicy("", 3, []byte("sampler:\n  always_on:\n")))
+	require.Eventually(t, func() bool { return s.Version() == 3 }, 5*time.Second, time.Millisecond)
+}
+
+func TestPolicyProfiles(t *testing.T) {
+	srv := NewServer()
+	require.NoError(t, srv.SetPolicy("", 1, []byte(`
+profiles:
+  - resource: {service.name: checkout}
+    sampler: {always_on: }
+sampler: {always_off: }
+`)))
+	threshold := func(s *Sampler) sampler.Threshold {
+		require.NoError(t, s.Poll(context.Background()))
+		return s.GetSamplingIntent(sampler.ComposableSamplingParameters{}).Threshold
+	}
+	require.Equal(t, sampler.ALWAYS_SAMPLE_THRESHOLD, threshold(testSampler(t, srv, "checkout")))
+	require.Equal(t, sampler.NEVER_SAMPLE_THRESHOLD, threshold(testSampler(t, srv, "search")))
+}
+
+func TestPollIntervalOption(t *testing.T) {
+	cfg := config{pollInterval: time.Minute}
+	WithPollInterval(0)(&cfg)
+	WithPollInterval(-time.Second)(&cfg)
+	require.Equal(t, time.Minute, cfg.pollInterval)
+	WithPollInterval(time.Second)(&cfg)
+	require.Equal(t, time.Second, cfg.pollInterval)
+}
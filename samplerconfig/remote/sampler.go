@@ -0,0 +1,272 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package remote implements the SamplingPolicy gRPC service, a
+// vendor-neutral control plane from which processes fetch their
+// sampler configuration and to which they report the rates they
+// observe.  Sampler is the process side and Server is a reference
+// implementation of the control plane.
+//
+// Unlike the configservice package, in which the control plane pushes
+// configurations to each process, processes poll for their policy, so
+// the control plane does not need to know their addresses.
+//
+// The service is defined in remote.proto.
+package remote
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative remote.proto
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmacd/sampler"
+	"github.com/jmacd/sampler/samplerconfig"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"google.golang.org/grpc"
+)
+
+// Option configures a Sampler.
+type Option func(*config)
+
+type config struct {
+	pollInterval   time.Duration
+	reportInterval time.Duration
+	handler        func(error)
+}
+
+// WithPollInterval sets how often the policy is fetched, by default
+// one minute.  The server may override it.  An interval that is not
+// positive is ignored.
+func WithPollInterval(interval time.Duration) Option {
+	return func(c *config) {
+		if interval > 0 {
+			c.pollInterval = interval
+		}
+	}
+}
+
+// WithReportInterval sets how often rates are reported, by default
+// one minute.  Zero disables reporting.
+func WithReportInterval(interval time.Duration) Option {
+	return func(c *config) {
+		c.reportInterval = interval
+	}
+}
+
+// WithErrorHandler sets the function called with errors fetching or
+// loading the policy and reporting rates, by default otel.Handle.
+func WithErrorHandler(handler func(error)) Option {
+	return func(c *config) {
+		c.handler = handler
+	}
+}
+
+// Sampler is a ComposableSampler configured by a SamplingPolicy
+// service.  It fetches its policy, a samplerconfig document, in the
+// background until closed, and reports the rates at which the rules of
+// the policy's sampler matched and sampled spans, when its sampler
+// implements sampler.RuleStatsProvider.  A policy that fails to load
+// is reported and the previous sampler remains in use.  The policy's
+// sampler is optimized for the process's Resource, see
+// sampler.Optimize, so that its Profiles apply.
+type Sampler struct {
+	client   SamplingPolicyClient
+	agent    *Agent
+	resource *resource.Resource
+	handler  func(error)
+
+	active       atomic.Pointer[policy]
+	pollInterval atomic.Int64
+
+	pollLock sync.Mutex // serializes Poll
+
+	reportLock sync.Mutex // serializes Report
+	reported   *policy    // the policy of the last report
+	stats      []sampler.RuleStats
+	reportedAt time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// policy is a policy version and its sampler.
+type policy struct {
+	version uint64
+	sampler sampler.ComposableSampler
+	loaded  time.Time
+}
+
+var _ sampler.ComposableSampler = &Sampler{}
+
+// NewSampler returns a Sampler for a process with the Resource, using
+// the connection.  The initial sampler, which has version zero, is
+// used until a policy is fetched.  A nil Resource is
+// resource.Default(), as in the SDK.
+func NewSampler(cc grpc.ClientConnInterface, res *resource.Resource, initial sampler.ComposableSampler, options ...Option) *Sampler {
+	cfg := config{
+		pollInterval:   time.Minute,
+		reportInterval: time.Minute,
+		handler:        otel.Handle,
+	}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	if res == nil {
+		res = resource.Default()
+	}
+	agent := &Agent{Resource: map[string]string{}}
+	for iter := res.Iter(); iter.Next(); {
+		kv := iter.Attribute()
+		agent.Resource[string(kv.Key)] = kv.Value.Emit()
+	}
+	s := &Sampler{
+		client:   NewSamplingPolicyClient(cc),
+		agent:    agent,
+		resource: res,
+		handler:  cfg.handler,
+		done:     make(chan struct{}),
+	}
+	now := time.Now()
+	s.active.Store(&policy{sampler: initial, loaded: now})
+	s.reportedAt = now
+	s.pollInterval.Store(int64(cfg.pollInterval))
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	go s.run(cfg.reportInterval)
+	return s
+}
+
+func (s *Sampler) run(reportInterval time.Duration) {
+	defer close(s.done)
+	poll := time.NewTimer(0)
+	defer poll.Stop()
+	var report <-chan time.Time
+	if reportInterval > 0 {
+		ticker := time.NewTicker(reportInterval)
+		defer ticker.Stop()
+		report = ticker.C
+	}
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-poll.C:
+			s.handle(s.Poll(s.ctx))
+			poll.Reset(time.Duration(s.pollInterval.Load()))
+		case <-report:
+			s.handle(s.Report(s.ctx))
+		}
+	}
+}
+
+// handle reports errors, other than those caused by Close.
+func (s *Sampler) handle(err error) {
+	if err != nil && s.ctx.Err() == nil && s.handler != nil {
+		s.handler(err)
+	}
+}
+
+// GetSamplingIntent implements ComposableSampler.
+func (s *Sampler) GetSamplingIntent(params sampler.ComposableSamplingParameters) sampler.SamplingIntent {
+	return s.active.Load().sampler.GetSamplingIntent(params)
+}
+
+// Description implements ComposableSampler.
+func (s *Sampler) Description() string {
+	return s.active.Load().sampler.Description()
+}
+
+// Version returns the version of the active policy, which is zero
+// until a policy is fetched.
+func (s *Sampler) Version() uint64 {
+	return s.active.Load().version
+}
+
+// Poll fetches the policy immediately, returning an error if it could
+// not be fetched or a changed policy could not be loaded.
+func (s *Sampler) Poll(ctx context.Context) error {
+	s.pollLock.Lock()
+	defer s.pollLock.Unlock()
+	active := s.active.Load()
+	resp, err := s.client.GetPolicy(ctx, &GetPolicyRequest{
+		Agent:   s.agent,
+		Version: active.version,
+	})
+	if err != nil {
+		return fmt.Errorf("remote: %w", err)
+	}
+	if secs := resp.GetPollIntervalSeconds(); secs != 0 {
+		s.pollInterval.Store(int64(time.Duration(secs) * time.Second))
+	}
+	p := resp.GetPolicy()
+	if p == nil || p.GetVersion() == active.version {
+		return nil
+	}
+	cs, err := samplerconfig.Load(p.GetDocument())
+	if err != nil {
+		return fmt.Errorf("remote: policy version %d: %w", p.GetVersion(), err)
+	}
+	s.active.Store(&policy{
+		version: p.GetVersion(),
+		sampler: sampler.Optimize(cs, s.resource, instrumentation.Scope{}),
+		loaded:  time.Now(),
+	})
+	return nil
+}
+
+// Report reports the rates of the active policy's rules immediately,
+// measured since the previous report or since the policy was loaded.
+func (s *Sampler) Report(ctx context.Context) error {
+	s.reportLock.Lock()
+	defer s.reportLock.Unlock()
+	active := s.active.Load()
+	now := time.Now()
+	var stats []sampler.RuleStats
+	if sp, ok := active.sampler.(sampler.RuleStatsProvider); ok {
+		stats = sp.Stats()
+	}
+	since, previous := s.reportedAt, s.stats
+	if s.reported != active {
+		since, previous = active.loaded, nil
+	}
+	interval := now.Sub(since).Seconds()
+	if interval <= 0 {
+		return nil
+	}
+	req := &ReportRatesRequest{
+		Agent:           s.agent,
+		Version:         active.version,
+		IntervalSeconds: interval,
+	}
+	for i, rs := range stats {
+		matched, sampled := rs.Matched, rs.Sampled
+		if i < len(previous) {
+			matched -= previous[i].Matched
+			sampled -= previous[i].Sampled
+		}
+		req.Rules = append(req.Rules, &RuleRate{
+			Description:      rs.Description,
+			MatchedPerSecond: float64(matched) / interval,
+			SampledPerSecond: float64(sampled) / interval,
+		})
+	}
+	if _, err := s.client.ReportRates(ctx, req); err != nil {
+		return fmt.Errorf("remote: %w", err)
+	}
+	s.reported, s.stats, s.reportedAt = active, stats, now
+	return nil
+}
+
+// Close stops fetching the policy and reporting rates.  The active
+// sampler remains in use.
+func (s *Sampler) Close() error {
+	s.cancel()
+	<-s.done
+	return nil
+}
@@ -0,0 +1,144 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package remote
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmacd/sampler/samplerconfig"
+)
+
+// ServerOption configures a Server.
+type ServerOption func(*serverConfig)
+
+type serverConfig struct {
+	pollInterval time.Duration
+}
+
+// WithAgentPollInterval sets the poll interval returned to agents,
+// overriding their own.  It is rounded down to whole seconds.
+func WithAgentPollInterval(interval time.Duration) ServerOption {
+	return func(c *serverConfig) {
+		c.pollInterval = interval
+	}
+}
+
+// Server is a reference implementation of the SamplingPolicy service,
+// holding policies in memory.  Each agent receives the policy of its
+// service.name resource attribute, or else the default policy.
+// Register it with a grpc.Server using RegisterSamplingPolicyServer.
+type Server struct {
+	UnimplementedSamplingPolicyServer
+
+	pollInterval uint32
+
+	lock     sync.Mutex
+	policies map[string]*Policy             // by service name, "" is the default
+	reports  map[string]*ReportRatesRequest // by agent
+}
+
+// NewServer returns a Server without policies.
+func NewServer(options ...ServerOption) *Server {
+	var cfg serverConfig
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	return &Server{
+		pollInterval: uint32(cfg.pollInterval / time.Second),
+		policies:     map[string]*Policy{},
+		reports:      map[string]*ReportRatesRequest{},
+	}
+}
+
+// SetPolicy sets the policy of a service, or the default policy when
+// service is empty.  The version must be nonzero, and it must change
+// when the document does, since agents fetch only policies with a
+// version different from their own.  A document that fails to load is
+// rejected.
+func (s *Server) SetPolicy(service string, version uint64, document []byte) error {
+	if version == 0 {
+		return fmt.Errorf("remote: policy version must be nonzero")
+	}
+	if _, err := samplerconfig.Load(document); err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.policies[service] = &Policy{
+		Version:  version,
+		Document: document,
+	}
+	return nil
+}
+
+// RemovePolicy removes the policy of a service, or the default policy
+// when service is empty.  Agents keep the policy they last fetched
+// until another applies to them.
+func (s *Server) RemovePolicy(service string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.policies, service)
+}
+
+// Reports returns the latest report of each agent, ordered by their
+// resource attributes.
+func (s *Server) Reports() []*ReportRatesRequest {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	keys := make([]string, 0, len(s.reports))
+	for key := range s.reports {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	reports := make([]*ReportRatesRequest, len(keys))
+	for i, key := range keys {
+		reports[i] = s.reports[key]
+	}
+	return reports
+}
+
+// GetPolicy implements SamplingPolicyServer.
+func (s *Server) GetPolicy(_ context.Context, req *GetPolicyRequest) (*GetPolicyResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	resp := &GetPolicyResponse{
+		PollIntervalSeconds: s.pollInterval,
+	}
+	p, ok := s.policies[req.GetAgent().GetResource()["service.name"]]
+	if !ok {
+		p = s.policies[""]
+	}
+	if p != nil && p.GetVersion() != req.GetVersion() {
+		resp.Policy = p
+	}
+	return resp, nil
+}
+
+// ReportRates implements SamplingPolicyServer.
+func (s *Server) ReportRates(_ context.Context, req *ReportRatesRequest) (*ReportRatesResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.reports[agentKey(req.GetAgent())] = req
+	return &ReportRatesResponse{}, nil
+}
+
+// agentKey identifies an agent by its resource attributes.
+func agentKey(agent *Agent) string {
+	res := agent.GetResource()
+	keys := make([]string, 0, len(res))
+	for key := range res {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%q=%q,", key, res[key])
+	}
+	return b.String()
+}
@@ -0,0 +1,115 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package samplerconfig
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// substitute replaces environment variable references in the scalar
+// values of a document, found by lookup, following the OpenTelemetry
+// declarative configuration rules:
+//
+//	${NAME}            the value of NAME, or empty when it is not set
+//	${env:NAME}        the same
+//	${NAME:-default}   the value of NAME, or default when it is not
+//	                   set or empty
+//	$$                 a literal $
+//
+// Mapping keys are not substituted.  The result of substituting an
+// unquoted value is interpreted as YAML, so that "ratio: ${RATIO}"
+// is a number, while a quoted value remains a string.
+func (d *decoder) substitute(node *yaml.Node, path string, lookup func(string) (string, bool)) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			d.substitute(child, path, lookup)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			d.substitute(node.Content[i+1], joinPath(path, node.Content[i].Value), lookup)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			d.substitute(child, fmt.Sprintf("%s[%d]", path, i), lookup)
+		}
+	case yaml.ScalarNode:
+		if !strings.Contains(node.Value, "$") {
+			return
+		}
+		value, err := expand(node.Value, lookup)
+		if err != nil {
+			d.fail(node, path, "%v", err)
+			if d.invalid == nil {
+				d.invalid = map[*yaml.Node]bool{}
+			}
+			d.invalid[node] = true
+			return
+		}
+		node.Value = value
+		if node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+			// Resolve the tag of the new plain value.
+			node.Tag = ""
+			node.Tag = node.ShortTag()
+		}
+	}
+}
+
+// expand replaces the environment variable references in s.
+func expand(s string, lookup func(string) (string, bool)) (string, error) {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(s, '$')
+		if i < 0 || i+1 == len(s) {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		b.WriteString(s[:i])
+		s = s[i+1:]
+		switch s[0] {
+		case '$':
+			b.WriteByte('$')
+			s = s[1:]
+			continue
+		case '{':
+		default:
+			b.WriteByte('$')
+			continue
+		}
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated reference %q", "$"+s)
+		}
+		ref := s[1:end]
+		s = s[end+1:]
+		name, def, hasDefault := strings.Cut(strings.TrimPrefix(ref, "env:"), ":-")
+		if !validEnvName(name) {
+			return "", fmt.Errorf("invalid environment variable reference %q", "${"+ref+"}")
+		}
+		value, ok := lookup(name)
+		if hasDefault && (!ok || value == "") {
+			value = def
+		}
+		b.WriteString(value)
+	}
+}
+
+// validEnvName returns true for names matching [a-zA-Z_][a-zA-Z0-9_]*.
+func validEnvName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
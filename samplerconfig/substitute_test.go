@@ -0,0 +1,77 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package samplerconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubstitute(t *testing.T) {
+	t.Setenv("SAMPLER_RATIO", "0.25")
+	t.Setenv("SAMPLER_ROUTE", "/api")
+	t.Setenv("SAMPLER_EMPTY", "")
+
+	doc := `
+version: "${SAMPLER_RATIO}"
+sampler:
+  rule_based:
+    rules:
+      - span_names:
+          - ${env:SAMPLER_ROUTE}/$${id}
+        sampler:
+          probability:
+            ratio: %s
+      - span_name_regex: ^${SAMPLER_ROUTE}/.*$
+        sampler:
+          probability:
+            ratio: ${SAMPLER_EMPTY:-1}
+    default:
+      probability:
+        ratio: ${SAMPLER_RATIO}
+`
+	// Quoted values remain strings.
+	_, err := Parse([]byte(fmt.Sprintf(doc, `"${SAMPLER_MISSING:-0.5}"`)), WithEnvSubstitution())
+	require.EqualError(t, err, `samplerconfig: line 10: sampler.rule_based.rules[0].sampler.probability.ratio: expected a number`)
+
+	cfg, err := Parse([]byte(fmt.Sprintf(doc, `${SAMPLER_MISSING:-0.5}`)), WithEnvSubstitution())
+	require.NoError(t, err)
+	require.Equal(t, "0.25", cfg.Version)
+	rules := cfg.Sampler.RuleBased.Rules
+	require.Equal(t, []string{"/api/${id}"}, rules[0].SpanNames)
+	require.Equal(t, 0.5, *rules[0].Sampler.Probability.Ratio)
+	require.Equal(t, "^/api/.*$", rules[1].Custom["span_name_regex"])
+	require.Equal(t, 1.0, *rules[1].Sampler.Probability.Ratio)
+	require.Equal(t, 0.25, *cfg.Sampler.RuleBased.Default.Probability.Ratio)
+
+	// Unset variables are empty.
+	_, err = Load([]byte("sampler:\n  probability:\n    ratio: ${SAMPLER_MISSING}\n"), WithEnvSubstitution())
+	require.EqualError(t, err, "samplerconfig: sampler.probability.ratio: missing ratio")
+
+	errs := ValidateDocument([]byte("sampler:\n  probability:\n    ratio: ${SAMPLER_RATIO\n    threshold: ${1X}\n"), WithEnvSubstitution())
+	require.EqualError(t, errs, `samplerconfig: line 3: sampler.probability.ratio: unterminated reference "${SAMPLER_RATIO"`+"\n"+
+		`samplerconfig: line 4: sampler.probability.threshold: invalid environment variable reference "${1X}"`)
+}
+
+func TestSubstituteOptIn(t *testing.T) {
+	t.Setenv("SAMPLER_SECRET", "hunter2")
+	doc := []byte("version: ${SAMPLER_SECRET}\nsampler:\n  always_on:\n")
+
+	// Without the option, references are not substituted.
+	cfg, err := Parse(doc)
+	require.NoError(t, err)
+	require.Equal(t, "${SAMPLER_SECRET}", cfg.Version)
+
+	// Files are substituted.
+	name := filepath.Join(t.TempDir(), "sampler.yaml")
+	require.NoError(t, os.WriteFile(name, doc, 0o600))
+	w, err := Watch(name, WithPollInterval(time.Hour))
+	require.NoError(t, err)
+	defer w.Close()
+	require.Equal(t, "hunter2", w.Version())
+}
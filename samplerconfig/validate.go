@@ -0,0 +1,128 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package samplerconfig
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/jmacd/sampler"
+)
+
+// Validate checks the configuration, returning every problem found
+// as Errors.  In addition to the errors reported by Build, Validate
+// reports rules that never match and rules that cannot be reached
+// because an earlier rule always matches, which Build accepts.  The
+// result is nil when there are no problems.
+//
+// Each Error identifies its location with Path, or with Pointer in
+// the form of a JSON pointer.
+func (c *Config) Validate() Errors {
+	var errs Errors
+	_, err := c.Build()
+	errs.add(err)
+	lint(c.Sampler, "sampler", &errs)
+	for i := range c.Profiles {
+		lint(c.Profiles[i].Sampler, fmt.Sprintf("profiles[%d].sampler", i), &errs)
+	}
+	return errs
+}
+
+// ValidateDocument is Validate for a YAML or JSON document, also
+// reporting every problem found while parsing it.
+func ValidateDocument(data []byte, options ...ParseOption) Errors {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(&doc); err != nil && err != io.EOF {
+		return Errors{{Path: "(document)", Msg: err.Error()}}
+	}
+	var cfg Config
+	if errs := decode(&doc, &cfg, newParseConfig(options)); errs != nil {
+		return errs
+	}
+	return cfg.Validate()
+}
+
+// nested returns the samplers contained in a sampler, with their
+// paths.
+func (s *Sampler) nested(path string) (samplers []*Sampler, paths []string) {
+	add := func(s *Sampler, p string) {
+		if s != nil {
+			samplers = append(samplers, s)
+			paths = append(paths, p)
+		}
+	}
+	switch {
+	case s.ParentThreshold != nil:
+		add(s.ParentThreshold.Root, path+".parent_threshold.root")
+	case s.RuleBased != nil:
+		for i, rule := range s.RuleBased.Rules {
+			add(rule.Sampler, fmt.Sprintf("%s.rule_based.rules[%d].sampler", path, i))
+		}
+		add(s.RuleBased.Default, path+".rule_based.default")
+	case s.Annotating != nil:
+		add(s.Annotating.Sampler, path+".annotating.sampler")
+	case s.ErrorHintBiased != nil:
+		add(s.ErrorHintBiased.Sampler, path+".error_hint_biased.sampler")
+	case s.ReplicaDecorrelated != nil:
+		add(s.ReplicaDecorrelated.Sampler, path+".replica_decorrelated.sampler")
+	case s.ExportOnly != nil:
+		add(s.ExportOnly.Sampler, path+".export_only.sampler")
+	case s.AnnotateAdjustedCount != nil:
+		add(s.AnnotateAdjustedCount.Sampler, path+".annotate_adjusted_count.sampler")
+	case s.InheritParentAttributes != nil:
+		add(s.InheritParentAttributes.Sampler, path+".inherit_parent_attributes.sampler")
+	}
+	return samplers, paths
+}
+
+// lint reports rules that never match or cannot be reached.
+func lint(s *Sampler, path string, errs *Errors) {
+	if s == nil {
+		return
+	}
+	if rb := s.RuleBased; rb != nil {
+		rbPath := path + ".rule_based"
+		always := -1
+		for i := range rb.Rules {
+			rulePath := fmt.Sprintf("%s.rules[%d]", rbPath, i)
+			if always >= 0 {
+				errs.add(buildError(rulePath, "rule is unreachable, rules[%d] always matches", always))
+				continue
+			}
+			pred, err := rb.Rules[i].predicate(rulePath)
+			if err != nil {
+				continue
+			}
+			switch value, ok := constant(pred); {
+			case ok && !value:
+				errs.add(buildError(rulePath, "rule never matches"))
+			case ok && value && !rb.CombineMatching:
+				always = i
+			}
+		}
+		if always >= 0 && rb.Default != nil {
+			errs.add(buildError(rbPath+".default", "default is unreachable, rules[%d] always matches", always))
+		}
+	}
+	samplers, paths := s.nested(path)
+	for i := range samplers {
+		lint(samplers[i], paths[i], errs)
+	}
+}
+
+// constant returns the value of a predicate that does not depend on
+// the span.
+func constant(pred sampler.Predicate) (value, ok bool) {
+	spec, _ := pred.Spec()
+	switch spec.Name {
+	case "TruePredicate":
+		return true, true
+	case "FalsePredicate":
+		return false, true
+	}
+	return false, false
+}
@@ -0,0 +1,78 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package samplerconfig
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	type problem struct {
+		pointer, msg string
+	}
+	problems := func(errs Errors) (list []problem) {
+		for _, err := range errs {
+			list = append(list, problem{err.Pointer(), err.Msg})
+		}
+		return list
+	}
+
+	errs := ValidateDocument([]byte(`
+sampler:
+  rule_based:
+    rules:
+      - span_name_regex: "(unclosed"
+        sampler:
+          probability: {ratio: 1.5}
+      - span_kinds: [server]
+        constant: false
+        sampler:
+          always_on:
+      - sampler:
+          parent_ratio: {}
+      - span_kinds: [client]
+        sampler:
+          always_on:
+    default:
+      always_off:
+`))
+	require.Equal(t, []problem{
+		{"/sampler/rule_based/rules/0/span_name_regex", "span name: error parsing regexp: missing closing ): `(unclosed`"},
+		{"/sampler/rule_based/rules/0/sampler/probability/ratio", "ratio 1.5 is not in the range [0, 1]"},
+		{"/sampler/rule_based/rules/2/sampler/parent_ratio/ratio", "missing ratio"},
+		{"/sampler/rule_based/rules/1", "rule never matches"},
+		{"/sampler/rule_based/rules/3", "rule is unreachable, rules[2] always matches"},
+		{"/sampler/rule_based/default", "default is unreachable, rules[2] always matches"},
+	}, problems(errs))
+
+	// Build reports the same errors, except the unreachable rules.
+	cfg, err := Parse([]byte("sampler:\n  error_hint_biased: {boosted: 2}\n"))
+	require.NoError(t, err)
+	_, err = cfg.Build()
+	require.EqualError(t, err, "samplerconfig: sampler.error_hint_biased.sampler: missing sampler\n"+
+		"samplerconfig: sampler.error_hint_biased.boosted: boosted 2 is not in the range [0, 1]")
+	var target *Error
+	require.True(t, errors.As(err, &target))
+	require.Equal(t, Errors(nil), (&Config{Sampler: &Sampler{AlwaysOn: &AlwaysOn{}}}).Validate())
+
+	// Parse errors are reported together, before building.
+	errs = ValidateDocument([]byte(`
+sampler:
+  rule_based:
+    rules:
+      - span_kinds: server
+        sampler:
+          my_sampler: {}
+    default:
+      probability: {ratio: high}
+`))
+	require.Equal(t, []problem{
+		{"/sampler/rule_based/rules/0/span_kinds", "expected a sequence"},
+		{"/sampler/rule_based/rules/0/sampler", `unknown field "my_sampler"`},
+		{"/sampler/rule_based/default/probability/ratio", "expected a number"},
+	}, problems(errs))
+	require.Equal(t, 5, errs[0].Line)
+}
@@ -0,0 +1,230 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package samplerconfig
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/jmacd/sampler"
+)
+
+// WatchOption configures Watch.
+type WatchOption func(*watchConfig)
+
+type watchConfig struct {
+	interval      time.Duration
+	handler       func(error)
+	meterProvider metric.MeterProvider
+	dynamic       []DynamicOption
+	parse         []ParseOption
+}
+
+// WithPollInterval sets how often the file is checked for changes.
+// The default is 10 seconds.
+func WithPollInterval(interval time.Duration) WatchOption {
+	return func(c *watchConfig) {
+		c.interval = interval
+	}
+}
+
+// WithReloadHandler sets a function called after each attempt to load
+// a changed file, with nil on success or the error that prevented the
+// new configuration from being used.
+func WithReloadHandler(handler func(error)) WatchOption {
+	return func(c *watchConfig) {
+		c.handler = handler
+	}
+}
+
+// WithMeterProvider sets the MeterProvider used for the reload
+// counter, which is the global MeterProvider by default.
+func WithMeterProvider(mp metric.MeterProvider) WatchOption {
+	return func(c *watchConfig) {
+		c.meterProvider = mp
+	}
+}
+
+// WithDynamicOptions configures the Watcher's Dynamic sampler, for
+// example with WithVersionAttribute or WithResource.
+func WithDynamicOptions(options ...DynamicOption) WatchOption {
+	return func(c *watchConfig) {
+		c.dynamic = append(c.dynamic, options...)
+	}
+}
+
+// WithParseOptions configures how the Watcher parses documents, for
+// example with WithEnvSubstitution, which Watch uses for files.
+func WithParseOptions(options ...ParseOption) WatchOption {
+	return func(c *watchConfig) {
+		c.parse = append(c.parse, options...)
+	}
+}
+
+// Watcher is a Dynamic sampler configured by a file, reloading the
+// file when its contents change.  A changed file that fails to load is
+// reported and the previous sampler remains in use.
+//
+// Reloads are counted by the "sampler.config.reloads" metric, with
+// attribute "result" of "success", "failure", or "unchanged" when the
+// new sampler is Equal to the active one, which stays in use with
+// the new Version.
+// After Rollback, the previous configuration stays in use until the
+// file changes again.
+type Watcher struct {
+	Dynamic
+
+	read    func(context.Context) ([]byte, error)
+	parse   []ParseOption
+	handler func(error)
+	reloads metric.Int64Counter
+
+	lock     sync.Mutex // serializes Reload
+	contents []byte     // the last contents read
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+var _ sampler.ComposableSampler = &Watcher{}
+
+var (
+	reloadSuccess   = metric.WithAttributeSet(attribute.NewSet(attribute.String("result", "success")))
+	reloadFailure   = metric.WithAttributeSet(attribute.NewSet(attribute.String("result", "failure")))
+	reloadUnchanged = metric.WithAttributeSet(attribute.NewSet(attribute.String("result", "unchanged")))
+)
+
+// Watch loads the configuration file and returns a Watcher that polls
+// it for changes until closed.  An error loading the file initially
+// is returned.  Environment variable references in the file are
+// substituted, see WithEnvSubstitution.
+func Watch(name string, options ...WatchOption) (*Watcher, error) {
+	options = append([]WatchOption{WithParseOptions(WithEnvSubstitution())}, options...)
+	return WatchSource(func(context.Context) ([]byte, error) {
+		return os.ReadFile(name)
+	}, options...)
+}
+
+// WatchSource is Watch for configuration documents returned by read,
+// which is called with a context that is canceled by Close.
+// Environment variable references are not substituted unless
+// configured by WithParseOptions.
+func WatchSource(read func(context.Context) ([]byte, error), options ...WatchOption) (*Watcher, error) {
+	cfg := watchConfig{
+		interval:      10 * time.Second,
+		meterProvider: otel.GetMeterProvider(),
+	}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	reloads, err := cfg.meterProvider.Meter("github.com/jmacd/sampler/samplerconfig").Int64Counter(
+		"sampler.config.reloads",
+		metric.WithDescription("Sampler configuration reloads, by result"),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+	w := &Watcher{
+		read:    read,
+		parse:   cfg.parse,
+		handler: cfg.handler,
+		reloads: reloads,
+		done:    make(chan struct{}),
+	}
+	w.configure(cfg.dynamic)
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	if err := w.load(); err != nil {
+		w.cancel()
+		return nil, err
+	}
+	go w.run(cfg.interval)
+	return w, nil
+}
+
+func (w *Watcher) run(interval time.Duration) {
+	defer close(w.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			_ = w.Reload()
+		}
+	}
+}
+
+// Reload checks the file for changes immediately, returning an error
+// if a changed file could not be loaded.
+func (w *Watcher) Reload() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	data, err := w.read(w.ctx)
+	if err == nil && bytes.Equal(data, w.contents) {
+		return nil
+	}
+	changed := false
+	if err == nil {
+		w.contents = data
+		changed, err = w.swap(data)
+	} else {
+		err = fmt.Errorf("samplerconfig: %w", err)
+	}
+	w.report(changed, err)
+	return err
+}
+
+// load reads the file the first time.
+func (w *Watcher) load() error {
+	data, err := w.read(w.ctx)
+	if err != nil {
+		return fmt.Errorf("samplerconfig: %w", err)
+	}
+	w.contents = data
+	_, err = w.swap(data)
+	return err
+}
+
+// swap parses the configuration and makes it active, unless it is
+// equivalent to the active configuration.
+func (w *Watcher) swap(data []byte) (bool, error) {
+	cfg, err := Parse(data, w.parse...)
+	if err != nil {
+		return false, err
+	}
+	return w.store(cfg)
+}
+
+func (w *Watcher) report(changed bool, err error) {
+	if w.reloads != nil {
+		result := reloadSuccess
+		switch {
+		case err != nil:
+			result = reloadFailure
+		case !changed:
+			result = reloadUnchanged
+		}
+		w.reloads.Add(context.Background(), 1, result)
+	}
+	if w.handler != nil {
+		w.handler(err)
+	}
+}
+
+// Close stops polling the file.  The active sampler remains in use.
+func (w *Watcher) Close() error {
+	w.cancel()
+	<-w.done
+	return nil
+}
@@ -0,0 +1,167 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package samplerconfig
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+
+	"github.com/jmacd/sampler"
+)
+
+func TestWatch(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "sampler.yaml")
+	write := func(doc string) {
+		require.NoError(t, os.WriteFile(name, []byte(doc), 0o600))
+	}
+
+	_, err := Watch(name)
+	require.ErrorContains(t, err, "no such file or directory")
+
+	write("sampler:\n  probability: {ratio: 0.5}\n")
+	var reported []error
+	w, err := Watch(name, WithPollInterval(time.Hour), WithReloadHandler(func(err error) {
+		reported = append(reported, err)
+	}))
+	require.NoError(t, err)
+	defer w.Close()
+	require.Equal(t, "TraceIDRatioBased{0.5}", w.Description())
+
+	// Unchanged contents are not reloaded.
+	require.NoError(t, w.Reload())
+	require.Empty(t, reported)
+
+	write("sampler:\n  always_off:\n")
+	require.NoError(t, w.Reload())
+	require.Equal(t, "AlwaysOff", w.Description())
+	require.Equal(t, []error{nil}, reported)
+
+	// An invalid file is reported once, and the previous sampler
+	// remains in use.
+	write("sampler:\n  probability: {ratio: 2}\n")
+	require.EqualError(t, w.Reload(), "samplerconfig: sampler.probability.ratio: ratio 2 is not in the range [0, 1]")
+	require.NoError(t, w.Reload())
+	require.Equal(t, "AlwaysOff", w.Description())
+	require.Len(t, reported, 2)
+	require.Error(t, reported[1])
+
+	write("sampler:\n  always_on:\n")
+	require.NoError(t, w.Reload())
+	require.Equal(t, "AlwaysOn", w.Description())
+	require.Len(t, reported, 3)
+	require.NoError(t, reported[2])
+
+	// An equivalent configuration does not replace the sampler.
+	write("sampler:\n  probability: {ratio: 0.5}\n")
+	require.NoError(t, w.Reload())
+	active := w.Sampler()
+	write("sampler:\n  rule_based:\n    rules: [{span_kinds: [server], constant: false, sampler: {always_on: }}]\n    default: {probability: {ratio: 0.5}}\n")
+	require.NoError(t, w.Reload())
+	require.True(t, active == w.Sampler())
+	require.Equal(t, "TraceIDRatioBased{0.5}", w.Description())
+	require.Len(t, reported, 5)
+	require.NoError(t, reported[4])
+}
+
+func TestWatchPolls(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "sampler.yaml")
+	require.NoError(t, os.WriteFile(name, []byte("sampler:\n  always_off:\n"), 0o600))
+
+	reloaded := make(chan error, 1)
+	w, err := Watch(name, WithPollInterval(time.Millisecond), WithReloadHandler(func(err error) {
+		select {
+		case reloaded <- err:
+		default:
+		}
+	}))
+	require.NoError(t, err)
+	defer w.Close()
+
+	// Replace the file atomically, so it is not read partially written.
+	tmp := name + ".tmp"
+	require.NoError(t, os.WriteFile(tmp, []byte("sampler:\n  always_on:\n"), 0o600))
+	require.NoError(t, os.Rename(tmp, name))
+	require.NoError(t, <-reloaded)
+	require.Equal(t, "AlwaysOn", w.Description())
+}
+
+func TestWatchConfigMap(t *testing.T) {
+	// Create the layout used by the kubelet for mounted ConfigMaps.
+	dir := t.TempDir()
+	version := func(name, doc string) {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, name), 0o700))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name, DefaultConfigMapKey), []byte(doc), 0o600))
+		require.NoError(t, os.Symlink(name, filepath.Join(dir, "..data_tmp")))
+		require.NoError(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+	}
+	version("..2024_01_01", "sampler:\n  always_off:\n")
+	require.NoError(t, os.Symlink(filepath.Join("..data", DefaultConfigMapKey), filepath.Join(dir, DefaultConfigMapKey)))
+
+	w, err := WatchConfigMap(dir, "", WithPollInterval(time.Hour))
+	require.NoError(t, err)
+	defer w.Close()
+	require.Equal(t, "AlwaysOff", w.Description())
+
+	version("..2024_01_02", "sampler:\n  always_on:\n")
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, "..2024_01_01")))
+	require.NoError(t, w.Reload())
+	require.Equal(t, "AlwaysOn", w.Description())
+}
+
+func TestWatchSource(t *testing.T) {
+	doc := "sampler:\n  always_off:\n"
+	var readCtx context.Context
+	w, err := WatchSource(func(ctx context.Context) ([]byte, error) {
+		readCtx = ctx
+		return []byte(doc), nil
+	}, WithPollInterval(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, "AlwaysOff", w.Description())
+
+	doc = "sampler:\n  always_on:\n"
+	require.NoError(t, w.Reload())
+	require.Equal(t, "AlwaysOn", w.Description())
+
+	require.NoError(t, w.Close())
+	require.Error(t, readCtx.Err())
+	require.NoError(t, w.Close())
+}
+
+func TestWatchProfiles(t *testing.T) {
+	doc := `
+profiles:
+  - resource: {service.name: checkout}
+    sampler: {probability: {ratio: 0.5}}
+sampler: {always_off: }
+`
+	w, err := WatchSource(func(context.Context) ([]byte, error) {
+		return []byte(doc), nil
+	}, WithPollInterval(time.Hour), WithDynamicOptions(
+		WithResource(resource.NewSchemaless(attribute.String("service.name", "checkout"))),
+	))
+	require.NoError(t, err)
+	defer w.Close()
+	threshold := func() sampler.Threshold {
+		return w.GetSamplingIntent(sampler.ComposableSamplingParameters{}).Threshold
+	}
+	require.Equal(t, sampler.ProbabilityToThreshold(0.5), threshold())
+
+	// Reloaded configurations are optimized for the Resource.
+	doc = `
+profiles:
+  - resource: {service.name: checkout}
+    sampler: {always_on: }
+  - resource: {service.name: search}
+    sampler: {always_off: }
+sampler: {always_off: }
+`
+	require.NoError(t, w.Reload())
+	require.Equal(t, sampler.ALWAYS_SAMPLE_THRESHOLD, threshold())
+}
@@ -0,0 +1,305 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package samplermetrics exposes the internal counters of a sampler,
+// its decisions by type, the matches of its rules, tracestate errors,
+// and the state of its rate limiters, as OpenTelemetry metrics and as
+// a Prometheus collector, for agents whose telemetry is scraped
+// rather than pushed:
+//
+//	m := samplermetrics.New(s)
+//	prometheus.MustRegister(m)
+//	tp := sampler.NewTracerProvider(m, res,
+//		sampler.WithCompositeOptions(m.CompositeOption()))
+//
+// Rule statistics come from samplers that implement
+// sampler.RuleStatsProvider, such as RuleBased, and rate limiter
+// statistics from samplers that implement sampler.CostStatsProvider,
+// such as CostBased, including the rate limits of samplerconfig.
+package samplermetrics
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+
+	"github.com/jmacd/sampler"
+)
+
+// decisionNames are the values of the decision label, by
+// sampler.SamplingDecision.
+var decisionNames = [...]string{
+	sampler.Drop:            "drop",
+	sampler.RecordOnly:      "record_only",
+	sampler.ExportOnly:      "export_only",
+	sampler.RecordAndSample: "record_and_sample",
+}
+
+// Option configures New.
+type Option func(*config)
+
+type config struct {
+	meterProvider metric.MeterProvider
+	handler       func(error)
+}
+
+// WithMeterProvider sets the MeterProvider of the OpenTelemetry
+// metrics, which is the global MeterProvider by default.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = mp
+	}
+}
+
+// WithErrorHandler sets the function to which tracestate errors are
+// passed after they are counted, see CompositeOption, by default
+// otel.Handle.
+func WithErrorHandler(handler func(error)) Option {
+	return func(c *config) {
+		c.handler = handler
+	}
+}
+
+// Metrics is a ComposableSampler that makes the decisions of another
+// sampler, counting them, and that reports its counters as
+// OpenTelemetry metrics:
+//
+//	sampler.decisions          spans by "decision"
+//	sampler.tracestate.errors  parent tracestate errors
+//	sampler.rule.matched       spans matched by each rule, by "rule.index" and "rule"
+//	sampler.rule.sampled       matched spans sampled by each rule
+//	sampler.cost.budget        the budget of each rate limiter, by "limiter.index" and "limiter"
+//	sampler.cost.span_rate     the span arrival rate measured by each rate limiter
+//
+// Metrics is also a prometheus.Collector of the same metrics, named
+// sampler_decisions_total, sampler_tracestate_errors_total,
+// sampler_rule_matched_total, sampler_rule_sampled_total,
+// sampler_cost_budget, and sampler_cost_span_rate, with the labels
+// decision, index, and rule or limiter.
+//
+// The decision is derived from the sampler's intent as
+// CompositeSampler derives it, so it is the decision made for the
+// span when Metrics is the root of the CompositeSampler's sampler
+// tree.  It is counted before the SDK converts it, see NewSDKSampler.
+type Metrics struct {
+	sampler sampler.ComposableSampler
+	counts  *counts
+}
+
+// counts are shared by optimized copies of Metrics.
+type counts struct {
+	decisions        [len(decisionNames)]atomic.Uint64
+	tracestateErrors atomic.Uint64
+	handler          func(error)
+	registration     metric.Registration
+}
+
+var (
+	_ sampler.ComposableSampler = &Metrics{}
+	_ sampler.SamplerOptimizer  = &Metrics{}
+	_ sampler.RuleStatsProvider = &Metrics{}
+	_ sampler.CostStatsProvider = &Metrics{}
+	_ prometheus.Collector      = &Metrics{}
+)
+
+// New returns Metrics for the sampler s, registering its
+// OpenTelemetry metrics.  Use the returned sampler in place of s.
+func New(s sampler.ComposableSampler, options ...Option) *Metrics {
+	cfg := config{
+		meterProvider: otel.GetMeterProvider(),
+		handler:       otel.Handle,
+	}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	m := &Metrics{
+		sampler: s,
+		counts: &counts{
+			handler: cfg.handler,
+		},
+	}
+	if err := m.register(cfg.meterProvider); err != nil {
+		otel.Handle(err)
+	}
+	return m
+}
+
+// GetSamplingIntent implements ComposableSampler.
+func (m *Metrics) GetSamplingIntent(params sampler.ComposableSamplingParameters) sampler.SamplingIntent {
+	intent := m.sampler.GetSamplingIntent(params)
+	// As in CompositeSampler.
+	decision := sampler.Drop
+	switch {
+	case intent.WouldSample(params) && intent.ExportOnly:
+		decision = sampler.ExportOnly
+	case intent.WouldSample(params):
+		decision = sampler.RecordAndSample
+	case intent.Record:
+		decision = sampler.RecordOnly
+	}
+	m.counts.decisions[decision].Add(1)
+	return intent
+}
+
+// Description implements ComposableSampler.
+func (m *Metrics) Description() string {
+	return m.sampler.Description()
+}
+
+// Optimize implements SamplerOptimizer.  The optimized sampler shares
+// the counters of this one.
+func (m *Metrics) Optimize(res *resource.Resource, scope instrumentation.Scope) sampler.ComposableSampler {
+	return &Metrics{
+		sampler: sampler.Optimize(m.sampler, res, scope),
+		counts:  m.counts,
+	}
+}
+
+// Stats implements RuleStatsProvider, returning the statistics of the
+// sampler, if any.
+func (m *Metrics) Stats() []sampler.RuleStats {
+	if sp, ok := m.sampler.(sampler.RuleStatsProvider); ok {
+		return sp.Stats()
+	}
+	return nil
+}
+
+// CostStats implements CostStatsProvider, returning the statistics of
+// the sampler, if any.
+func (m *Metrics) CostStats() []sampler.CostStats {
+	if cp, ok := m.sampler.(sampler.CostStatsProvider); ok {
+		return cp.CostStats()
+	}
+	return nil
+}
+
+// CompositeOption returns the option of the CompositeSampler using
+// these Metrics that counts its tracestate errors, replacing
+// sampler.WithTraceStateErrorHandler.
+func (m *Metrics) CompositeOption() sampler.CompositeOption {
+	return sampler.WithTraceStateErrorHandler(func(err error) {
+		m.counts.tracestateErrors.Add(1)
+		if m.counts.handler != nil {
+			m.counts.handler(err)
+		}
+	})
+}
+
+// Unregister unregisters the OpenTelemetry metrics.
+func (m *Metrics) Unregister() error {
+	if m.counts.registration == nil {
+		return nil
+	}
+	return m.counts.registration.Unregister()
+}
+
+// register registers the OpenTelemetry metrics.
+func (m *Metrics) register(mp metric.MeterProvider) error {
+	meter := mp.Meter("github.com/jmacd/sampler/samplermetrics")
+	decisions, err := meter.Int64ObservableCounter("sampler.decisions",
+		metric.WithDescription("Sampling decisions, by decision"))
+	if err != nil {
+		return err
+	}
+	tracestateErrors, err := meter.Int64ObservableCounter("sampler.tracestate.errors",
+		metric.WithDescription("Errors in the OpenTelemetry tracestate of parent contexts"))
+	if err != nil {
+		return err
+	}
+	matched, err := meter.Int64ObservableCounter("sampler.rule.matched",
+		metric.WithDescription("Spans matched by each rule"))
+	if err != nil {
+		return err
+	}
+	sampled, err := meter.Int64ObservableCounter("sampler.rule.sampled",
+		metric.WithDescription("Matched spans sampled by each rule"))
+	if err != nil {
+		return err
+	}
+	budget, err := meter.Float64ObservableGauge("sampler.cost.budget",
+		metric.WithDescription("Budget of each rate limiter, in estimated bytes or spans per second"))
+	if err != nil {
+		return err
+	}
+	spanRate, err := meter.Float64ObservableGauge("sampler.cost.span_rate",
+		metric.WithDescription("Span arrival rate measured by each rate limiter"),
+		metric.WithUnit("{span}/s"))
+	if err != nil {
+		return err
+	}
+	m.counts.registration, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		for decision, name := range decisionNames {
+			o.ObserveInt64(decisions, int64(m.counts.decisions[decision].Load()),
+				metric.WithAttributes(attribute.String("decision", name)))
+		}
+		o.ObserveInt64(tracestateErrors, int64(m.counts.tracestateErrors.Load()))
+		for i, rs := range m.Stats() {
+			attrs := metric.WithAttributes(attribute.Int("rule.index", i), attribute.String("rule", rs.Description))
+			o.ObserveInt64(matched, int64(rs.Matched), attrs)
+			o.ObserveInt64(sampled, int64(rs.Sampled), attrs)
+		}
+		for i, cs := range m.CostStats() {
+			attrs := metric.WithAttributes(attribute.Int("limiter.index", i), attribute.String("limiter", cs.Description))
+			o.ObserveFloat64(budget, cs.Budget, attrs)
+			o.ObserveFloat64(spanRate, cs.SpanRate, attrs)
+		}
+		return nil
+	}, decisions, tracestateErrors, matched, sampled, budget, spanRate)
+	if err != nil {
+		return fmt.Errorf("samplermetrics: %w", err)
+	}
+	return nil
+}
+
+var (
+	decisionsDesc = prometheus.NewDesc("sampler_decisions_total",
+		"Sampling decisions, by decision.", []string{"decision"}, nil)
+	tracestateErrorsDesc = prometheus.NewDesc("sampler_tracestate_errors_total",
+		"Errors in the OpenTelemetry tracestate of parent contexts.", nil, nil)
+	matchedDesc = prometheus.NewDesc("sampler_rule_matched_total",
+		"Spans matched by each rule.", []string{"index", "rule"}, nil)
+	sampledDesc = prometheus.NewDesc("sampler_rule_sampled_total",
+		"Matched spans sampled by each rule.", []string{"index", "rule"}, nil)
+	budgetDesc = prometheus.NewDesc("sampler_cost_budget",
+		"Budget of each rate limiter, in estimated bytes or spans per second.", []string{"index", "limiter"}, nil)
+	spanRateDesc = prometheus.NewDesc("sampler_cost_span_rate",
+		"Span arrival rate measured by each rate limiter, in spans per second.", []string{"index", "limiter"}, nil)
+)
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	ch <- decisionsDesc
+	ch <- tracestateErrorsDesc
+	ch <- matchedDesc
+	ch <- sampledDesc
+	ch <- budgetDesc
+	ch <- spanRateDesc
+}
+
+// Collect implements prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	for decision, name := range decisionNames {
+		ch <- prometheus.MustNewConstMetric(decisionsDesc, prometheus.CounterValue,
+			float64(m.counts.decisions[decision].Load()), name)
+	}
+	ch <- prometheus.MustNewConstMetric(tracestateErrorsDesc, prometheus.CounterValue,
+		float64(m.counts.tracestateErrors.Load()))
+	for i, rs := range m.Stats() {
+		index := strconv.Itoa(i)
+		ch <- prometheus.MustNewConstMetric(matchedDesc, prometheus.CounterValue, float64(rs.Matched), index, rs.Description)
+		ch <- prometheus.MustNewConstMetric(sampledDesc, prometheus.CounterValue, float64(rs.Sampled), index, rs.Description)
+	}
+	for i, cs := range m.CostStats() {
+		index := strconv.Itoa(i)
+		ch <- prometheus.MustNewConstMetric(budgetDesc, prometheus.GaugeValue, cs.Budget, index, cs.Description)
+		ch <- prometheus.MustNewConstMetric(spanRateDesc, prometheus.GaugeValue, cs.SpanRate, index, cs.Description)
+	}
+}
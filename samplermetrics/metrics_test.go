@@ -0,0 +1,136 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package samplermetrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jmacd/sampler"
+	"github.com/jmacd/sampler/samplerconfig"
+)
+
+func TestMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	var errs []error
+	m := New(sampler.RuleBased(
+		sampler.WithRule(sampler.SpanKindPredicate(trace.SpanKindServer), sampler.ComposableAlwaysSample()),
+		sampler.WithRule(sampler.SpanKindPredicate(trace.SpanKindClient), sampler.ComposableNeverSample()),
+		sampler.WithRule(sampler.SpanNamePredicate("export"), sampler.ExportOnlySampler(sampler.ComposableAlwaysSample())),
+		sampler.WithDefaultRule(sampler.CostBased(50, sampler.WithDefaultSpanCost(1), sampler.WithAttributeCost(0))),
+	),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+		WithErrorHandler(func(err error) { errs = append(errs, err) }),
+	)
+	defer func() { require.NoError(t, m.Unregister()) }()
+
+	// Counters are shared by optimized copies.
+	s := sampler.CompositeSampler(sampler.Optimize(m, resource.Empty(), instrumentation.Scope{}), m.CompositeOption())
+	ts, err := trace.ParseTraceState("ot=th:zz")
+	require.NoError(t, err)
+	bad := trace.ContextWithRemoteSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceState: ts,
+		Remote:     true,
+	}))
+	for _, span := range []struct {
+		ctx  context.Context
+		name string
+		kind trace.SpanKind
+	}{
+		{context.Background(), "a", trace.SpanKindServer},
+		{bad, "b", trace.SpanKindServer},
+		{context.Background(), "c", trace.SpanKindClient},
+		{context.Background(), "export", trace.SpanKindInternal},
+		{context.Background(), "d", trace.SpanKindInternal},
+	} {
+		s.ShouldSample(sampler.SamplingParameters{
+			ParentContext: span.ctx,
+			TraceID:       trace.TraceID{9: 0xff},
+			Name:          span.name,
+			Kind:          span.kind,
+		})
+	}
+	require.Len(t, errs, 1)
+
+	require.NoError(t, testutil.CollectAndCompare(m, strings.NewReader(`
+# HELP sampler_cost_budget Budget of each rate limiter, in estimated bytes or spans per second.
+# TYPE sampler_cost_budget gauge
+sampler_cost_budget{index="0",limiter="CostBased{50}"} 50
+# HELP sampler_cost_span_rate Span arrival rate measured by each rate limiter, in spans per second.
+# TYPE sampler_cost_span_rate gauge
+sampler_cost_span_rate{index="0",limiter="CostBased{50}"} 0
+# HELP sampler_decisions_total Sampling decisions, by decision.
+# TYPE sampler_decisions_total counter
+sampler_decisions_total{decision="drop"} 1
+sampler_decisions_total{decision="export_only"} 1
+sampler_decisions_total{decision="record_and_sample"} 3
+sampler_decisions_total{decision="record_only"} 0
+# HELP sampler_rule_matched_total Spans matched by each rule.
+# TYPE sampler_rule_matched_total counter
+sampler_rule_matched_total{index="0",rule="rule(Span.Kind==server)=AlwaysOn"} 2
+sampler_rule_matched_total{index="1",rule="rule(Span.Kind==client)=AlwaysOff"} 1
+sampler_rule_matched_total{index="2",rule="rule(Span.Name==export)=ExportOnly(AlwaysOn)"} 1
+sampler_rule_matched_total{index="3",rule="rule(true)=CostBased{50}"} 1
+# HELP sampler_rule_sampled_total Matched spans sampled by each rule.
+# TYPE sampler_rule_sampled_total counter
+sampler_rule_sampled_total{index="0",rule="rule(Span.Kind==server)=AlwaysOn"} 2
+sampler_rule_sampled_total{index="1",rule="rule(Span.Kind==client)=AlwaysOff"} 0
+sampler_rule_sampled_total{index="2",rule="rule(Span.Name==export)=ExportOnly(AlwaysOn)"} 1
+sampler_rule_sampled_total{index="3",rule="rule(true)=CostBased{50}"} 1
+# HELP sampler_tracestate_errors_total Errors in the OpenTelemetry tracestate of parent contexts.
+# TYPE sampler_tracestate_errors_total counter
+sampler_tracestate_errors_total 1
+`)))
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	got := map[string]metricdata.Aggregation{}
+	for _, metric := range rm.ScopeMetrics[0].Metrics {
+		got[metric.Name] = metric.Data
+	}
+	decisions := map[string]int64{}
+	for _, dp := range got["sampler.decisions"].(metricdata.Sum[int64]).DataPoints {
+		name, _ := dp.Attributes.Value("decision")
+		decisions[name.AsString()] = dp.Value
+	}
+	require.Equal(t, map[string]int64{"drop": 1, "record_only": 0, "export_only": 1, "record_and_sample": 3}, decisions)
+	require.Equal(t, int64(1), got["sampler.tracestate.errors"].(metricdata.Sum[int64]).DataPoints[0].Value)
+	require.Len(t, got["sampler.rule.matched"].(metricdata.Sum[int64]).DataPoints, 4)
+	require.Len(t, got["sampler.rule.sampled"].(metricdata.Sum[int64]).DataPoints, 4)
+	budget := got["sampler.cost.budget"].(metricdata.Gauge[float64]).DataPoints
+	require.Len(t, budget, 1)
+	require.Equal(t, 50.0, budget[0].Value)
+	limiter, _ := budget[0].Attributes.Value("limiter")
+	require.Equal(t, attribute.StringValue("CostBased{50}"), limiter)
+	require.Len(t, got["sampler.cost.span_rate"].(metricdata.Gauge[float64]).DataPoints, 1)
+}
+
+func TestMetricsConfigRateLimits(t *testing.T) {
+	// The rate limits of samplerconfig rules with attributes are
+	// wrapped by AnnotatingSampler.
+	s, err := samplerconfig.Load([]byte(`
+sampler:
+  rule_based:
+    rules:
+      - span_kinds: [server]
+        attributes: {policy: limited}
+        sampler: {rate: 50/s}
+    default: {always_off: }
+`))
+	require.NoError(t, err)
+	m := New(s, WithMeterProvider(sdkmetric.NewMeterProvider()))
+	defer func() { require.NoError(t, m.Unregister()) }()
+	require.Equal(t, []sampler.CostStats{{Description: "CostBased{50}", Budget: 50}}, m.CostStats())
+}
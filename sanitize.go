@@ -0,0 +1,129 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithTraceStateSanitizer removes malformed sub-keys and sub-keys other
+// than "th", "rv", registered sub-keys (see RegisterOTelSubKey), and
+// the allowed ones from the OpenTelemetry tracestate of parent
+// contexts, and removes vendor members longer than 128 characters,
+// before the tracestate is sampled and propagated.  This prevents one
+// misbehaving upstream service from affecting the tracestate of the
+// whole downstream call tree.
+// Removals are reported to the tracestate error handler.
+func WithTraceStateSanitizer(allowed ...string) CompositeOption {
+	return func(cfg *compositeConfig) {
+		keys := map[string]bool{"th": true, "rv": true}
+		for _, key := range allowed {
+			keys[key] = true
+		}
+		cfg.sanitizer = &traceStateSanitizer{allowed: keys}
+	}
+}
+
+type traceStateSanitizer struct {
+	allowed map[string]bool
+}
+
+// sanitize returns the sanitized tracestate and an error describing
+// removals, if any.
+func (s *traceStateSanitizer) sanitize(ts trace.TraceState) (trace.TraceState, error) {
+	var removed []string
+	var otts string
+	var hasOT bool
+	ts.Walk(func(key, value string) bool {
+		if key == "ot" {
+			otts, hasOT = value, true
+			return true
+		}
+		if len(key)+1+len(value) > largeTraceStateMember {
+			removed = append(removed, key)
+		}
+		return true
+	})
+	for _, key := range removed {
+		ts = ts.Delete(key)
+	}
+	if !hasOT {
+		return ts, sanitizeError(removed)
+	}
+
+	var kept []string
+	for _, field := range strings.Split(otts, ";") {
+		key, value, _ := strings.Cut(field, ":")
+		if s.validOTelField(key, value) {
+			kept = append(kept, field)
+			continue
+		}
+		removed = append(removed, "ot."+key)
+	}
+	if len(kept) == 0 {
+		return ts.Delete("ot"), sanitizeError(removed)
+	}
+	if sanitized := strings.Join(kept, ";"); sanitized != otts {
+		var err error
+		if ts, err = ts.Insert("ot", sanitized); err != nil {
+			otel.Handle(fmt.Errorf("tracestate: %w", err))
+		}
+	}
+	return ts, sanitizeError(removed)
+}
+
+// validOTelField returns true for well-formed, allowed sub-keys.
+func (s *traceStateSanitizer) validOTelField(key, value string) bool {
+	if !(s.allowed[key] || isRegisteredSubKey(key)) || !validOTelSubKey(key) || !validOTelSubValue(value) {
+		return false
+	}
+	switch key {
+	case "th":
+		_, err := ParseThreshold(value)
+		return err == nil
+	case "rv":
+		_, _, err := tracestateHasRandomness("rv:" + value)
+		return err == nil
+	}
+	return true
+}
+
+// validOTelSubKey checks the syntax lcalpha *(lcalpha / DIGIT).
+func validOTelSubKey(key string) bool {
+	if key == "" || key[0] < 'a' || key[0] > 'z' {
+		return false
+	}
+	for i := 1; i < len(key); i++ {
+		c := key[i]
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// validOTelSubValue checks the syntax *(ALPHA / DIGIT / "." / "_" / "-").
+func validOTelSubValue(value string) bool {
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '.', c == '_', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func sanitizeError(removed []string) error {
+	if len(removed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("tracestate: removed %s", strings.Join(removed, ","))
+}
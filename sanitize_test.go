@@ -0,0 +1,73 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTraceStateSanitizer(t *testing.T) {
+	long := strings.Repeat("x", 130)
+	for _, test := range []struct {
+		allowed []string
+		in      string
+		out     string
+		removed string
+	}{
+		{nil, "ot=th:8;rv:0123456789abcd,a=b", "ot=th:8;rv:0123456789abcd,a=b", ""},
+		{nil, "ot=th:8;xx:1;yy:2", "ot=th:8", "ot.xx,ot.yy"},
+		{[]string{"xx"}, "ot=th:8;xx:1;yy:2", "ot=th:8;xx:1", "ot.yy"},
+		{nil, "ot=th:xyz;rv:0123456789abcd", "ot=rv:0123456789abcd", "ot.th"},
+		{nil, "a=b,ot=rv:123", "a=b", "ot.rv"},
+		{[]string{"Xx", "yy"}, "ot=th:8;Xx:1;yy:a%b", "ot=th:8", "ot.Xx,ot.yy"},
+		{nil, "a=" + long + ",ot=th:8,b=c", "ot=th:8,b=c", "a"},
+		{nil, "a=" + long + ",ot=zz:8,b=c", "b=c", "a,ot.zz"},
+	} {
+		t.Run(test.in, func(t *testing.T) {
+			in, err := trace.ParseTraceState(test.in)
+			require.NoError(t, err)
+
+			var cfg compositeConfig
+			WithTraceStateSanitizer(test.allowed...)(&cfg)
+			out, err := cfg.sanitizer.sanitize(in)
+			require.Equal(t, test.out, out.String())
+			if test.removed == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, "tracestate: removed "+test.removed)
+			}
+		})
+	}
+}
+
+func TestCompositeTraceStateSanitizer(t *testing.T) {
+	ts, err := trace.ParseTraceState("ot=th:8;bad:%;rv:c0000000000000,a=b")
+	require.NoError(t, err)
+	params := SamplingParameters{
+		ParentContext: trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{1},
+			SpanID:     trace.SpanID{1},
+			TraceFlags: trace.FlagsSampled,
+			TraceState: ts,
+		})),
+		TraceID: trace.TraceID{1},
+	}
+
+	var errs []error
+	res := CompositeSampler(ComposableParentBased(ComposableNeverSample()),
+		WithTraceStateSanitizer(),
+		WithTraceStateErrorHandler(func(err error) {
+			errs = append(errs, err)
+		}),
+	).ShouldSample(params)
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Equal(t, "ot=th:8;rv:c0000000000000,a=b", res.Tracestate.String())
+	require.Len(t, errs, 1)
+	require.EqualError(t, errs[0], "tracestate: removed ot.bad")
+}
@@ -0,0 +1,130 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// NewSDKSampler returns a sampler for the OpenTelemetry-Go SDK, for
+// use with sdktrace.WithSampler, that makes the decisions of
+// CompositeSampler(s, options...).
+//
+// The SDK does not distinguish ExportOnly decisions, which become
+// RecordAndSample decisions with the propagated tracestate, so that
+// the span is exported and its children are not given its threshold.
+// See WithExportOnlyRecording for spans that are exported without
+// appearing sampled.  The SDK does not pass its Resource to samplers;
+// predicates that depend on it are resolved by optimizing the sampler
+// first, as in
+//
+//	sdktrace.WithSampler(sampler.NewSDKSampler(sampler.Optimize(s, res, instrumentation.Scope{})))
+//
+// or by using NewTracerProvider, which also optimizes for each Scope.
+// Samplers that read ComposableSamplingParameters.Resource instead
+// need the WithResource option.
+func NewSDKSampler(s ComposableSampler, options ...CompositeOption) sdktrace.Sampler {
+	return newSDKSampler(CompositeSampler(s, options...))
+}
+
+// WithExportOnlyRecording makes ExportOnly decisions of a sampler for
+// the SDK (see NewSDKSampler) into RecordOnly decisions, so that the
+// span is not sampled in its SpanContext, its children, or span
+// processors that count sampled spans.  The span's tracestate is
+// recorded in the SamplingExportOnlyKey attribute, from which
+// ExportOnlyProcessor restores it, so that the span is exported with
+// its threshold.  Without an ExportOnlyProcessor, these spans are not
+// exported.
+func WithExportOnlyRecording() CompositeOption {
+	return func(cfg *compositeConfig) {
+		cfg.exportOnlyRecording = true
+	}
+}
+
+func newSDKSampler(s Sampler) sdkSampler {
+	cs, ok := s.(*compositeSampler)
+	return sdkSampler{
+		sampler:    s,
+		exportOnly: ok && cs.exportOnlyRecording,
+	}
+}
+
+type sdkSampler struct {
+	sampler    Sampler
+	exportOnly bool
+}
+
+var _ sdktrace.Sampler = sdkSampler{}
+
+// ShouldSample implements sdktrace.Sampler.
+func (s sdkSampler) ShouldSample(params sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	result := s.sampler.ShouldSample(SamplingParameters(params))
+	if result.Decision == ExportOnly && s.exportOnly {
+		// Copy, since the attributes may be shared.
+		attrs := result.Attributes[:len(result.Attributes):len(result.Attributes)]
+		return sdktrace.SamplingResult{
+			Decision:   sdktrace.RecordOnly,
+			Attributes: append(attrs, SamplingExportOnlyKey.String(result.SpanTracestate.String())),
+			Tracestate: result.Tracestate,
+		}
+	}
+	return sdktrace.SamplingResult{
+		Decision:   sdkDecision(result.Decision),
+		Attributes: result.Attributes,
+		Tracestate: result.Tracestate,
+	}
+}
+
+// Description implements sdktrace.Sampler.
+func (s sdkSampler) Description() string {
+	return s.sampler.Description()
+}
+
+func sdkDecision(decision SamplingDecision) sdktrace.SamplingDecision {
+	switch decision {
+	case RecordOnly:
+		return sdktrace.RecordOnly
+	case ExportOnly, RecordAndSample:
+		return sdktrace.RecordAndSample
+	default:
+		return sdktrace.Drop
+	}
+}
+
+// FromSDKSampler returns a Sampler that makes the decisions of a
+// sampler for the OpenTelemetry-Go SDK, so that it can be composed
+// using LegacySampler.
+func FromSDKSampler(s sdktrace.Sampler) Sampler {
+	return fromSDKSampler{
+		sampler: s,
+	}
+}
+
+type fromSDKSampler struct {
+	sampler sdktrace.Sampler
+}
+
+var _ Sampler = fromSDKSampler{}
+
+// ShouldSample implements Sampler.
+func (s fromSDKSampler) ShouldSample(params SamplingParameters) SamplingResult {
+	result := s.sampler.ShouldSample(sdktrace.SamplingParameters(params))
+	decision := Drop
+	switch result.Decision {
+	case sdktrace.RecordOnly:
+		decision = RecordOnly
+	case sdktrace.RecordAndSample:
+		decision = RecordAndSample
+	}
+	return SamplingResult{
+		Decision:   decision,
+		Attributes: result.Attributes,
+		Tracestate: result.Tracestate,
+	}
+}
+
+// Description implements Sampler.
+func (s fromSDKSampler) Description() string {
+	return s.sampler.Description()
+}
@@ -0,0 +1,54 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSDKSampler(t *testing.T) {
+	s := NewSDKSampler(RuleBased(
+		WithRule(SpanNamePredicate("drop"), ComposableNeverSample()),
+		WithRule(IsRootPredicate(), AnnotatingSampler(ComposableAlwaysSample(), WithSampledAttributes(func() []attribute.KeyValue {
+			return []attribute.KeyValue{attribute.String("policy", "root")}
+		}))),
+		WithDefaultRule(ParentThreshold()),
+	))
+	require.Equal(t, "RuleBased{rule(Span.Name==drop)=AlwaysOff,rule(root?)=Annotate(AlwaysOn, policy=root),rule(true)=ParentThreshold}", s.Description())
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSampler(s), sdktrace.WithSpanProcessor(recorder))
+	tracer := tp.Tracer("test")
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := tracer.Start(ctx, "child")
+	_, dropped := tracer.Start(ctx, "drop")
+	child.End()
+	dropped.End()
+	root.End()
+
+	require.True(t, root.SpanContext().IsSampled())
+	require.Equal(t, "th:0", root.SpanContext().TraceState().Get("ot"))
+	require.True(t, child.SpanContext().IsSampled())
+	require.False(t, dropped.SpanContext().IsSampled())
+	require.False(t, dropped.IsRecording())
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	require.Equal(t, "child", spans[0].Name())
+	require.Equal(t, "root", spans[1].Name())
+	require.Equal(t, []attribute.KeyValue{attribute.String("policy", "root")}, spans[1].Attributes())
+}
+
+func TestSDKDecision(t *testing.T) {
+	require.Equal(t, sdktrace.Drop, sdkDecision(Drop))
+	require.Equal(t, sdktrace.RecordOnly, sdkDecision(RecordOnly))
+	require.Equal(t, sdktrace.RecordAndSample, sdkDecision(ExportOnly))
+	require.Equal(t, sdktrace.RecordAndSample, sdkDecision(RecordAndSample))
+}
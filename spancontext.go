@@ -0,0 +1,152 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"encoding/binary"
+	"math/bits"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traceIDRandomness interprets the least-significant 8 bytes of the
+// TraceID as an unsigned number, then zeros the top 8 bits using
+// randomnessMask, yielding the least-significant 56 bits of
+// randomness, as specified in W3C Trace Context Level 2.
+func traceIDRandomness(id trace.TraceID) int64 {
+	return int64(binary.BigEndian.Uint64(id[8:16]) & randomnessMask)
+}
+
+// isShortTraceID returns true for TraceIDs with only the
+// least-significant 64 bits populated.
+func isShortTraceID(id trace.TraceID) bool {
+	return binary.BigEndian.Uint64(id[0:8]) == 0
+}
+
+// traceIDBits returns width bits of the TraceID starting at offset
+// bits from its most-significant bit, scaled to 56 bits.
+func traceIDBits(id trace.TraceID, offset, width int) int64 {
+	hi := binary.BigEndian.Uint64(id[0:8])
+	lo := binary.BigEndian.Uint64(id[8:16])
+
+	// Shift the selected bits to the most-significant position.
+	var top uint64
+	switch {
+	case offset == 0:
+		top = hi
+	case offset < 64:
+		top = hi<<offset | lo>>(64-offset)
+	default:
+		top = lo << (offset - 64)
+	}
+	return int64(top >> (64 - width) << (56 - width))
+}
+
+// RandomnessFromSpanContext returns the 56-bit randomness value of a
+// span context, which is the "rv" sub-key of its OpenTelemetry
+// tracestate when present, otherwise the least-significant 56 bits of
+// its TraceID.  The result is false for invalid span contexts.
+func RandomnessFromSpanContext(sc trace.SpanContext) (int64, bool) {
+	if !sc.IsValid() {
+		return 0, false
+	}
+	return randomnessFromOTelTraceState(sc.TraceID(), parseSpanContextOTelTraceState(sc)), true
+}
+
+// randomnessFromOTelTraceState returns the randomness for a TraceID
+// and its parsed "ot" tracestate.
+func randomnessFromOTelTraceState(id trace.TraceID, ots otelTraceState) int64 {
+	if ots.hasRandomness {
+		return ots.randomness
+	}
+	return traceIDRandomness(id)
+}
+
+// ThresholdFromSpanContext returns the sampling threshold of a span
+// context, from the "th" sub-key of its OpenTelemetry tracestate, for
+// exporters and span processors to compute the adjusted count of a
+// span.  The result is false when the threshold is missing, or when it
+// is inconsistent with the randomness of a sampled span context.
+func ThresholdFromSpanContext(sc trace.SpanContext) (Threshold, bool) {
+	if !sc.IsValid() {
+		return INVALID_THRESHOLD, false
+	}
+	ots := parseSpanContextOTelTraceState(sc)
+	if !ots.hasThreshold {
+		return INVALID_THRESHOLD, false
+	}
+	if sc.IsSampled() && !ots.threshold.ShouldSample(randomnessFromOTelTraceState(sc.TraceID(), ots)) {
+		return INVALID_THRESHOLD, false
+	}
+	return ots.threshold, true
+}
+
+// parseSpanContextOTelTraceState parses the "ot" tracestate of a span
+// context, reporting errors via otel.Handle.
+func parseSpanContextOTelTraceState(sc trace.SpanContext) otelTraceState {
+	ots := parseOTelTraceState(sc.TraceState().Get("ot"))
+	if ots.err != nil {
+		otel.Handle(ots.err)
+	}
+	return ots
+}
+
+// sipHash24 computes SipHash-2-4 of msg with a 128-bit key.
+func sipHash24(k0, k1 uint64, msg []byte) uint64 {
+	v0 := k0 ^ 0x736f6d6570736575
+	v1 := k1 ^ 0x646f72616e646f6d
+	v2 := k0 ^ 0x6c7967656e657261
+	v3 := k1 ^ 0x7465646279746573
+
+	round := func() {
+		v0 += v1
+		v1 = bits.RotateLeft64(v1, 13)
+		v1 ^= v0
+		v0 = bits.RotateLeft64(v0, 32)
+		v2 += v3
+		v3 = bits.RotateLeft64(v3, 16)
+		v3 ^= v2
+		v0 += v3
+		v3 = bits.RotateLeft64(v3, 21)
+		v3 ^= v0
+		v2 += v1
+		v1 = bits.RotateLeft64(v1, 17)
+		v1 ^= v2
+		v2 = bits.RotateLeft64(v2, 32)
+	}
+	compress := func(m uint64) {
+		v3 ^= m
+		round()
+		round()
+		v0 ^= m
+	}
+
+	n := len(msg)
+	for ; len(msg) >= 8; msg = msg[8:] {
+		compress(binary.LittleEndian.Uint64(msg))
+	}
+	last := uint64(n) << 56
+	for i, b := range msg {
+		last |= uint64(b) << (8 * i)
+	}
+	compress(last)
+
+	v2 ^= 0xff
+	round()
+	round()
+	round()
+	round()
+	return v0 ^ v1 ^ v2 ^ v3
+}
+
+// hashedTraceIDRandomness returns 56 bits of a SipHash-2-4 of the
+// TraceID with a 128-bit key.
+func hashedTraceIDRandomness(key [16]byte) func(trace.TraceID) int64 {
+	k0 := binary.LittleEndian.Uint64(key[0:8])
+	k1 := binary.LittleEndian.Uint64(key[8:16])
+	return func(id trace.TraceID) int64 {
+		return int64(sipHash24(k0, k1, id[:]) & randomnessMask)
+	}
+}
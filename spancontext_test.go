@@ -0,0 +1,166 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSpanContextAccessors(t *testing.T) {
+	traceID := trace.TraceID{0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xc0, 0, 0, 0, 0, 0, 1}
+
+	for _, test := range []struct {
+		tracestate   string
+		sampled      bool
+		randomness   int64
+		threshold    Threshold
+		hasThreshold bool
+	}{
+		{"", true, 0xc0000000000001, INVALID_THRESHOLD, false},
+		{"ot=th:8", true, 0xc0000000000001, 0x80000000000000, true},
+		{"ot=th:8;rv:90000000000000", true, 0x90000000000000, 0x80000000000000, true},
+		// The threshold is inconsistent with the randomness.
+		{"ot=th:8;rv:10000000000000", true, 0x10000000000000, INVALID_THRESHOLD, false},
+		{"ot=th:8;rv:10000000000000", false, 0x10000000000000, 0x80000000000000, true},
+		{"ot=rv:abcdefabcdefab", false, 0xabcdefabcdefab, INVALID_THRESHOLD, false},
+		{"vendor=x,ot=th:c", true, 0xc0000000000001, 0xc0000000000000, true},
+	} {
+		ts, err := trace.ParseTraceState(test.tracestate)
+		require.NoError(t, err)
+		var flags trace.TraceFlags
+		if test.sampled {
+			flags = trace.FlagsSampled
+		}
+		sc := trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    traceID,
+			SpanID:     trace.SpanID{1},
+			TraceFlags: flags,
+			TraceState: ts,
+		})
+
+		rnd, ok := RandomnessFromSpanContext(sc)
+		require.True(t, ok)
+		require.Equal(t, test.randomness, rnd, test.tracestate)
+
+		th, ok := ThresholdFromSpanContext(sc)
+		require.Equal(t, test.hasThreshold, ok, test.tracestate)
+		require.Equal(t, test.threshold, th, test.tracestate)
+	}
+
+	_, ok := RandomnessFromSpanContext(trace.SpanContext{})
+	require.False(t, ok)
+	_, ok = ThresholdFromSpanContext(trace.SpanContext{})
+	require.False(t, ok)
+}
+
+func TestTraceIDBits(t *testing.T) {
+	id := trace.TraceID{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, 0xfe, 0xdc, 0xba, 0x98, 0x76, 0x54, 0x32, 0x10}
+	for _, test := range []struct {
+		offset, width int
+		expect        int64
+	}{
+		{72, 56, 0xdcba9876543210},
+		{0, 56, 0x0123456789abcd},
+		{8, 56, 0x23456789abcdef},
+		{32, 56, 0x89abcdeffedcba},
+		{60, 8, 0xff000000000000},
+		{120, 8, 0x10000000000000},
+		{4, 4, 0x10000000000000},
+	} {
+		require.Equal(t, test.expect, traceIDBits(id, test.offset, test.width), "%d %d", test.offset, test.width)
+	}
+	require.Equal(t, traceIDRandomness(id), traceIDBits(id, 72, 56))
+}
+
+func TestTraceIDRandomnessBits(t *testing.T) {
+	// Randomness is in the most-significant bytes, as with some
+	// legacy ID generators; the least-significant bytes are zero.
+	params := SamplingParameters{
+		ParentContext: context.Background(),
+		TraceID:       trace.TraceID{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		Name:          "root",
+	}
+	half := TraceIDRatioBased(0.5)
+	require.Equal(t, Drop, CompositeSampler(half).ShouldSample(params).Decision)
+	require.Equal(t, RecordAndSample, CompositeSampler(half, WithTraceIDRandomnessBits(0, 56)).ShouldSample(params).Decision)
+
+	// Invalid arguments are ignored.
+	require.Equal(t, Drop, CompositeSampler(half, WithTraceIDRandomnessBits(0, 64)).ShouldSample(params).Decision)
+	require.Equal(t, Drop, CompositeSampler(half, WithTraceIDRandomnessBits(100, 56)).ShouldSample(params).Decision)
+}
+
+func TestSipHash24(t *testing.T) {
+	// Test vectors from the SipHash paper.
+	msg := make([]byte, 15)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	k0, k1 := uint64(0x0706050403020100), uint64(0x0f0e0d0c0b0a0908)
+	require.Equal(t, uint64(0x726fdb47dd0e0e31), sipHash24(k0, k1, nil))
+	require.Equal(t, uint64(0xa129ca6149be45e5), sipHash24(k0, k1, msg))
+}
+
+func TestHashedTraceIDRandomness(t *testing.T) {
+	// Sequential TraceIDs, as with some legacy ID generators.
+	half := TraceIDRatioBased(0.5)
+	plain := CompositeSampler(half)
+	hashed := CompositeSampler(half, WithHashedTraceIDRandomness([16]byte{1, 2, 3}))
+	var plainCount, hashedCount int
+	for i := range 1000 {
+		params := SamplingParameters{
+			ParentContext: context.Background(),
+			TraceID:       trace.TraceID{14: byte(i >> 8), 15: byte(i)},
+		}
+		if plain.ShouldSample(params).Decision == RecordAndSample {
+			plainCount++
+		}
+		if hashed.ShouldSample(params).Decision == RecordAndSample {
+			hashedCount++
+		}
+	}
+	require.Equal(t, 0, plainCount)
+	require.InDelta(t, 500, hashedCount, 100)
+
+	// The derivation is deterministic for a key.
+	id := trace.TraceID{1}
+	require.Equal(t, hashedTraceIDRandomness([16]byte{1})(id), hashedTraceIDRandomness([16]byte{1})(id))
+	require.NotEqual(t, hashedTraceIDRandomness([16]byte{1})(id), hashedTraceIDRandomness([16]byte{2})(id))
+}
+
+func TestShortTraceIDPolicy(t *testing.T) {
+	short := SamplingParameters{
+		ParentContext: context.Background(),
+		TraceID:       trace.TraceID{8: 0xff, 9: 0xff, 10: 0xff, 11: 0xff, 12: 0xff, 13: 0xff, 14: 0xff, 15: 0xff},
+	}
+	long := short
+	long.TraceID[0] = 0x01
+	half := TraceIDRatioBased(0.5)
+
+	// Randomness in the most-significant bits is zero for short
+	// TraceIDs.
+	high := WithTraceIDRandomnessBits(0, 56)
+	require.Equal(t, Drop, CompositeSampler(half, high).ShouldSample(short).Decision)
+
+	res := CompositeSampler(half, high, WithShortTraceIDPolicy(UseTraceIDRandomness)).ShouldSample(short)
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Equal(t, "ot=th:8", res.Tracestate.String())
+	require.True(t, isShortTraceID(short.TraceID))
+	require.False(t, isShortTraceID(long.TraceID))
+
+	// Long TraceIDs are not affected.
+	require.Equal(t, Drop, CompositeSampler(half, high, WithShortTraceIDPolicy(UseTraceIDRandomness)).ShouldSample(long).Decision)
+
+	res = CompositeSampler(half, WithShortTraceIDPolicy(UnreliableRandomness)).ShouldSample(short)
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Equal(t, "", res.Tracestate.String())
+
+	res = CompositeSampler(ComposableAlwaysSample(), WithShortTraceIDPolicy(GenerateRandomness)).ShouldSample(short)
+	require.Equal(t, RecordAndSample, res.Decision)
+	_, has, err := tracestateHasRandomness(res.Tracestate.Get("ot"))
+	require.NoError(t, err)
+	require.True(t, has)
+}
@@ -0,0 +1,96 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"context"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type spanIDKey struct{}
+
+// spanIDSlot holds the SpanID of the next span started in a context,
+// i.e., the span whose parent is the context's span.  The SpanID is
+// set by ContextWithSpanID or by a spanIDRecorder, after the context
+// is created.
+type spanIDSlot struct {
+	parent trace.SpanContext
+	id     trace.SpanID
+}
+
+// WithSpanIDParameter sets the SpanID of ComposableSamplingParameters,
+// for span-ID-keyed sampling experiments, when the SpanID is
+// generated before the sampling decision.  The specification creates
+// the SpanID after ShouldSample, so it is available only:
+//
+//   - in a TracerProvider configured with this option (see
+//     WithCompositeOptions), whose ID generator records the SpanID
+//     that the SDK generates before calling its sampler, or
+//   - when the span is started in a context returned by
+//     ContextWithSpanID, for SDKs and bridges that pre-generate
+//     span IDs.
+//
+// Otherwise, including for spans started by the SDK's own tracers,
+// the SpanID is invalid, and samplers using it should treat the span
+// as if the option were not set, e.g., by not matching.
+func WithSpanIDParameter() CompositeOption {
+	return func(cfg *compositeConfig) {
+		cfg.spanID = true
+	}
+}
+
+// ContextWithSpanID returns a context for starting a span whose SpanID
+// was generated before the sampling decision, which CompositeSamplers
+// configured WithSpanIDParameter pass to their samplers.  The SpanID
+// applies only to spans whose parent is the span of ctx.
+func ContextWithSpanID(ctx context.Context, id trace.SpanID) context.Context {
+	return context.WithValue(ctx, spanIDKey{}, &spanIDSlot{
+		parent: trace.SpanContextFromContext(ctx),
+		id:     id,
+	})
+}
+
+// spanIDFromContext returns the SpanID set for the next span started
+// in ctx, see ContextWithSpanID.
+func spanIDFromContext(ctx context.Context) trace.SpanID {
+	if ctx == nil {
+		return trace.SpanID{}
+	}
+	slot, ok := ctx.Value(spanIDKey{}).(*spanIDSlot)
+	if !ok || !slot.parent.Equal(trace.SpanContextFromContext(ctx)) {
+		return trace.SpanID{}
+	}
+	return slot.id
+}
+
+// spanIDRecorder is an ID generator that records the SpanIDs that it
+// generates in the context's spanIDSlot, which the SDK then passes to
+// the sampler.
+type spanIDRecorder struct {
+	sdktrace.IDGenerator
+}
+
+var _ sdktrace.IDGenerator = spanIDRecorder{}
+
+// NewIDs implements sdktrace.IDGenerator.
+func (r spanIDRecorder) NewIDs(ctx context.Context) (trace.TraceID, trace.SpanID) {
+	tid, sid := r.IDGenerator.NewIDs(ctx)
+	r.record(ctx, sid)
+	return tid, sid
+}
+
+// NewSpanID implements sdktrace.IDGenerator.
+func (r spanIDRecorder) NewSpanID(ctx context.Context, tid trace.TraceID) trace.SpanID {
+	sid := r.IDGenerator.NewSpanID(ctx, tid)
+	r.record(ctx, sid)
+	return sid
+}
+
+func (spanIDRecorder) record(ctx context.Context, sid trace.SpanID) {
+	if slot, ok := ctx.Value(spanIDKey{}).(*spanIDSlot); ok {
+		slot.id = sid
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSpanIDParameter(t *testing.T) {
+	var got []trace.SpanID
+	// Samples spans with odd SpanIDs, and spans without a SpanID.
+	odd := NewPredicate(func(params ComposableSamplingParameters) bool {
+		got = append(got, params.SpanID)
+		return !params.SpanID.IsValid() || params.SpanID[7]&1 == 1
+	}, "odd")
+	cs := RuleBased(
+		WithRule(odd, ComposableAlwaysSample()),
+		WithDefaultRule(ComposableNeverSample()),
+	)
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := NewTracerProvider(cs, nil,
+		WithCompositeOptions(WithSpanIDParameter()),
+		WithSDKOptions(sdktrace.WithSpanProcessor(recorder)),
+	)
+	defer func() { require.NoError(t, tp.Shutdown(context.Background())) }()
+	tracer := tp.Tracer("test")
+
+	// The sampler sees the SpanID of roots and children.
+	ctx, root := tracer.Start(context.Background(), "root")
+	for i := 0; i < 20; i++ {
+		_, child := tracer.Start(ctx, "child")
+		child.End()
+	}
+	root.End()
+	require.Len(t, got, 21)
+	var ended []trace.SpanID
+	for _, span := range recorder.Ended() {
+		ended = append(ended, span.SpanContext().SpanID())
+	}
+	var sampled []trace.SpanID
+	for _, id := range got {
+		require.True(t, id.IsValid())
+		if id[7]&1 == 1 {
+			sampled = append(sampled, id)
+		}
+	}
+	require.ElementsMatch(t, sampled, ended)
+
+	// Spans of the SDK's own tracers do not have it.
+	got = nil
+	_, span := tp.TracerProvider.Tracer("sdk").Start(ctx, "other")
+	span.End()
+	require.Equal(t, []trace.SpanID{{}}, got)
+
+	// Nor do spans of samplers without the option.
+	got = nil
+	params := SamplingParameters{
+		ParentContext: ContextWithSpanID(context.Background(), trace.SpanID{7: 1}),
+	}
+	CompositeSampler(cs).ShouldSample(params)
+	require.Equal(t, []trace.SpanID{{}}, got)
+
+	// Pre-generated SpanIDs apply to children of the context's span.
+	got = nil
+	res := CompositeSampler(cs, WithSpanIDParameter()).ShouldSample(params)
+	require.Equal(t, RecordAndSample, res.Decision)
+	params.ParentContext = ContextWithSpanID(context.Background(), trace.SpanID{7: 2})
+	res = CompositeSampler(cs, WithSpanIDParameter()).ShouldSample(params)
+	require.Equal(t, Drop, res.Decision)
+	params.ParentContext = trace.ContextWithSpanContext(params.ParentContext, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1},
+		SpanID:  trace.SpanID{1},
+	}))
+	CompositeSampler(cs, WithSpanIDParameter()).ShouldSample(params)
+	require.Equal(t, []trace.SpanID{{7: 1}, {7: 2}, {}}, got)
+}
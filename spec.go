@@ -0,0 +1,219 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Spec describes how a sampler or predicate was constructed, as the
+// name of the function that constructed it and that function's
+// arguments, so that other packages, such as samplerconfig, can encode
+// sampler trees in their own schema.
+//
+// The Spec of a built-in sampler or predicate is named after its
+// constructor, for example "TraceIDRatioFromThreshold" or
+// "SpanKindPredicate".  Its Args are keyed by the constructor's
+// parameter names, and by the names of options without their "With"
+// prefix, for example "combineMatching".  Nested samplers are
+// ComposableSampler values and nested predicates are Predicate
+// values, which have their own Specs.
+type Spec struct {
+	// Name identifies the constructor.
+	Name string
+
+	// Args holds the constructor's arguments, by name.
+	Args map[string]any
+}
+
+// SpecProvider is an optional interface for ComposableSamplers that
+// can describe how they were constructed.  The built-in samplers
+// implement it.
+type SpecProvider interface {
+	// Spec returns the sampler's Spec, or false when the sampler
+	// cannot be described.  Functions the sampler was configured
+	// with are not called to describe it.
+	Spec() (Spec, bool)
+}
+
+// SpecOf returns the Spec of a sampler, or false when it does not
+// implement SpecProvider or cannot be described.
+func SpecOf(sampler ComposableSampler) (Spec, bool) {
+	if sp, ok := sampler.(SpecProvider); ok {
+		return sp.Spec()
+	}
+	return Spec{}, false
+}
+
+// Spec returns the Spec of a built-in predicate or of one configured
+// by WithSpec.  Constant predicates are described as TruePredicate or
+// FalsePredicate.  Predicates built by NewPredicate have no Spec.
+func (p Predicate) Spec() (Spec, bool) {
+	if value, ok := p.isConstant(); ok {
+		if value {
+			return Spec{Name: "TruePredicate"}, true
+		}
+		return Spec{Name: "FalsePredicate"}, true
+	}
+	return p.spec, p.spec.Name != ""
+}
+
+// WithSpec returns a copy of the predicate with a Spec, for use with
+// custom predicates, for example naming a predicate registered with
+// the samplerconfig package.
+func (p Predicate) WithSpec(spec Spec) Predicate {
+	p.spec = spec
+	return p
+}
+
+// withSpec returns a copy of the predicate described by its
+// constructor's name and arguments.
+func (p Predicate) withSpec(name string, args map[string]any) Predicate {
+	return p.WithSpec(Spec{Name: name, Args: args})
+}
+
+// keyValueArgs returns the arguments of a predicate of one attribute.
+func keyValueArgs(kv attribute.KeyValue) map[string]any {
+	return map[string]any{"kv": kv}
+}
+
+// Spec implements SpecProvider.
+func (ts *traceIDRatio) Spec() (Spec, bool) {
+	return Spec{Name: "TraceIDRatioFromThreshold", Args: map[string]any{"threshold": ts.threshold}}, true
+}
+
+// Spec implements SpecProvider.
+func (cAlwaysOn) Spec() (Spec, bool) {
+	return Spec{Name: "ComposableAlwaysSample"}, true
+}
+
+// Spec implements SpecProvider.
+func (alwaysOff) Spec() (Spec, bool) {
+	return Spec{Name: "ComposableNeverSample"}, true
+}
+
+// Spec implements SpecProvider.  Rules are listed in evaluation order,
+// including the default rule, whose predicate is TruePredicate.
+func (rb ruleBased) Spec() (Spec, bool) {
+	preds := make([]Predicate, len(rb.rules))
+	samplers := make([]ComposableSampler, len(rb.rules))
+	for i, rule := range rb.rules {
+		preds[i], samplers[i] = rule.Predicate, rule.ComposableSampler
+	}
+	return Spec{Name: "RuleBased", Args: map[string]any{
+		"predicates":      preds,
+		"samplers":        samplers,
+		"combineMatching": rb.combine,
+	}}, true
+}
+
+// Spec implements SpecProvider.
+func (pr *parentRatio) Spec() (Spec, bool) {
+	return Spec{Name: "ParentRatioBased", Args: map[string]any{"fraction": pr.fraction}}, true
+}
+
+// Spec implements SpecProvider.
+func (parentThreshold) Spec() (Spec, bool) {
+	return Spec{Name: "ParentThreshold"}, true
+}
+
+// Spec implements SpecProvider.  Attributes and tracestate entries
+// computed by functions are represented by the functions, which are
+// not called, and the tracestate keys, since their values are not
+// known; see WithSampledAttributeValues.
+func (as annotatingSampler) Spec() (Spec, bool) {
+	args := map[string]any{"sampler": as.sampler}
+	if len(as.sampledValues) != 0 {
+		args["sampledAttributeValues"] = as.sampledValues
+	}
+	if len(as.nonSampledValues) != 0 {
+		args["nonSampledAttributeValues"] = as.nonSampledValues
+	}
+	if as.sampledFunctions {
+		args["sampledAttributes"] = as.attributes
+	}
+	if as.nonSampledFunctions {
+		args["nonSampledAttributes"] = as.nonSampled
+	}
+	if len(as.traceKeys) != 0 {
+		args["traceStateEntry"] = append([]string(nil), as.traceKeys...)
+	}
+	if as.ifWouldSample {
+		args["attributesIfWouldSample"] = true
+	}
+	return Spec{Name: "AnnotatingSampler", Args: args}, true
+}
+
+// Spec implements SpecProvider.
+func (cb *costBased) Spec() (Spec, bool) {
+	args := map[string]any{
+		"bytesPerSecond":  cb.budget,
+		"defaultSpanCost": cb.config.defaultCost,
+		"attributeCost":   cb.config.attributeCost,
+		"costInterval":    cb.config.interval,
+	}
+	if len(cb.config.nameCosts) != 0 {
+		costs := make(map[string]float64, len(cb.config.nameCosts))
+		for name, cost := range cb.config.nameCosts {
+			costs[name] = cost
+		}
+		args["spanNameCost"] = costs
+	}
+	return Spec{Name: "CostBased", Args: args}, true
+}
+
+// Spec implements SpecProvider.  The error hint keys do not include
+// the default keys.
+func (eh *errorHintBiased) Spec() (Spec, bool) {
+	args := map[string]any{
+		"base":    eh.base,
+		"boosted": eh.boosted,
+	}
+	if extra := eh.keys[len(defaultErrorHintKeys):]; len(extra) != 0 {
+		args["errorHintKeys"] = append([]attribute.Key(nil), extra...)
+	}
+	return Spec{Name: "ErrorHintBiased", Args: args}, true
+}
+
+// Spec implements SpecProvider.
+func (rd *replicaDecorrelated) Spec() (Spec, bool) {
+	return Spec{Name: "ReplicaDecorrelated", Args: map[string]any{
+		"sampler": rd.sampler,
+		"jitter":  rd.jitter,
+	}}, true
+}
+
+// Spec implements SpecProvider.
+func (eo *exportOnlySampler) Spec() (Spec, bool) {
+	return Spec{Name: "ExportOnlySampler", Args: map[string]any{"sampler": eo.sampler}}, true
+}
+
+// Spec implements SpecProvider.
+func (ac *adjustedCountSampler) Spec() (Spec, bool) {
+	return Spec{Name: "AnnotateAdjustedCount", Args: map[string]any{"sampler": ac.sampler}}, true
+}
+
+// Spec implements SpecProvider.
+func (ip *inheritParentAttributes) Spec() (Spec, bool) {
+	return Spec{Name: "InheritParentAttributes", Args: map[string]any{
+		"sampler": ip.sampler,
+		"keys":    append([]attribute.Key(nil), ip.keys...),
+	}}, true
+}
+
+var (
+	_ SpecProvider = &traceIDRatio{}
+	_ SpecProvider = cAlwaysOn{}
+	_ SpecProvider = alwaysOff{}
+	_ SpecProvider = ruleBased{}
+	_ SpecProvider = &parentRatio{}
+	_ SpecProvider = parentThreshold{}
+	_ SpecProvider = annotatingSampler{}
+	_ SpecProvider = &costBased{}
+	_ SpecProvider = &errorHintBiased{}
+	_ SpecProvider = &replicaDecorrelated{}
+	_ SpecProvider = &exportOnlySampler{}
+	_ SpecProvider = &adjustedCountSampler{}
+	_ SpecProvider = &inheritParentAttributes{}
+)
@@ -0,0 +1,110 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSamplerSpec(t *testing.T) {
+	health := SpanNameGlobPredicate("/health*")
+	ratio := TraceIDRatioBasedN(3)
+	s := RuleBased(
+		WithRule(health, ComposableNeverSample()),
+		WithDefaultRule(ratio),
+		WithCombineMatching(),
+	)
+	spec, ok := SpecOf(s)
+	require.True(t, ok)
+	require.Equal(t, "RuleBased", spec.Name)
+	require.Equal(t, true, spec.Args["combineMatching"])
+	preds := spec.Args["predicates"].([]Predicate)
+	require.Len(t, preds, 2)
+	require.Equal(t, health.Description(), preds[0].Description())
+	require.Equal(t, []ComposableSampler{ComposableNeverSample(), ratio}, spec.Args["samplers"])
+
+	spec, ok = SpecOf(ratio)
+	require.True(t, ok)
+	require.Equal(t, Spec{Name: "TraceIDRatioFromThreshold", Args: map[string]any{
+		"threshold": ratio.(*traceIDRatio).threshold,
+	}}, spec)
+
+	// Samplers without a Spec.
+	_, ok = SpecOf(CallbackSampler(func(SamplingContext) float64 {
+		return 1
+	}, "callback"))
+	require.False(t, ok)
+}
+
+func TestAnnotatingSpec(t *testing.T) {
+	// Attribute values are part of the Spec.
+	spec, ok := SpecOf(AnnotatingSampler(ComposableAlwaysSample(),
+		WithSampledAttributeValues(attribute.String("policy", "all")),
+		WithNonSampledAttributeValues(attribute.Int("weight", 2)),
+		WithAttributesIfWouldSample(),
+	))
+	require.True(t, ok)
+	require.Equal(t, Spec{Name: "AnnotatingSampler", Args: map[string]any{
+		"sampler":                   ComposableAlwaysSample(),
+		"sampledAttributeValues":    []attribute.KeyValue{attribute.String("policy", "all")},
+		"nonSampledAttributeValues": []attribute.KeyValue{attribute.Int("weight", 2)},
+		"attributesIfWouldSample":   true,
+	}}, spec)
+
+	// Functions are not called to describe a sampler.
+	called := false
+	af := func() []attribute.KeyValue {
+		called = true
+		return nil
+	}
+	spec, ok = SpecOf(AnnotatingSampler(ComposableAlwaysSample(),
+		WithSampledAttributes(af),
+		WithNonSampledAttributes(af),
+		WithTraceStateEntry("vendor", func() string {
+			called = true
+			return "x"
+		}),
+	))
+	require.True(t, ok)
+	require.False(t, called)
+	require.Contains(t, spec.Args, "sampledAttributes")
+	require.Contains(t, spec.Args, "nonSampledAttributes")
+	require.Equal(t, []string{"vendor"}, spec.Args["traceStateEntry"])
+}
+
+func TestPredicateSpec(t *testing.T) {
+	for _, test := range []struct {
+		pred Predicate
+		spec Spec
+		ok   bool
+	}{
+		{TruePredicate(), Spec{Name: "TruePredicate"}, true},
+		{AndPredicate(FalsePredicate(), SpanNamePredicate("a")), Spec{Name: "FalsePredicate"}, true},
+		{AttributeRangePredicate("size", 1, 2.5), Spec{Name: "AttributeRangePredicate", Args: map[string]any{
+			"key": attribute.Key("size"), "low": 1.0, "high": 2.5,
+		}}, true},
+		{SpanKindPredicate(trace.SpanKindServer), Spec{Name: "SpanKindPredicate", Args: map[string]any{
+			"kinds": []trace.SpanKind{trace.SpanKindServer},
+		}}, true},
+		{IsParentNotSampledPredicate(), Spec{Name: "IsParentNotSampledPredicate"}, true},
+		{MemoizePredicate(SpanNameGlobPredicate("/api/*")), Spec{Name: "SpanNameGlobPredicate", Args: map[string]any{
+			"pattern": "/api/*",
+		}}, true},
+		{NewPredicate(nil, "custom"), Spec{}, false},
+		{NewPredicate(nil, "custom").WithSpec(Spec{Name: "mine", Args: map[string]any{"n": 1}}),
+			Spec{Name: "mine", Args: map[string]any{"n": 1}}, true},
+	} {
+		spec, ok := test.pred.Spec()
+		require.Equal(t, test.ok, ok, test.pred.Description())
+		require.Equal(t, test.spec, spec, test.pred.Description())
+	}
+
+	spec, ok := OrPredicate(FalsePredicate(), SpanNamePredicate("a"), IsRootPredicate()).Spec()
+	require.True(t, ok)
+	require.Equal(t, "OrPredicate", spec.Name)
+	require.Len(t, spec.Args["preds"], 2)
+}
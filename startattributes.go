@@ -0,0 +1,82 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type startAttributesKey struct{}
+
+// startAttributes are attributes for the next span started in a
+// context, i.e., the span whose parent is the context's span.
+type startAttributes struct {
+	parent trace.SpanContext
+	attrs  []attribute.KeyValue
+}
+
+// ContextWithStartAttributes returns a context with attributes for the
+// next span started in it, for middleware that runs before the
+// instrumentation that starts a span, such as an HTTP handler wrapping
+// an instrumented one.  A CompositeSampler adds the attributes to the
+// span's starting attributes, so that predicates see them, and
+// records them with the span when it is sampled or recorded.
+// Attributes given when the span is started take precedence.
+//
+// The attributes apply only to spans whose parent is the span of ctx,
+// so that they do not apply to the span's descendants.  When ctx has
+// no span, they apply to root spans and to spans with a remote parent,
+// since instrumentation typically extracts the remote parent after
+// middleware has run.
+func ContextWithStartAttributes(ctx context.Context, attrs ...attribute.KeyValue) context.Context {
+	parent := trace.SpanContextFromContext(ctx)
+	if sa, ok := ctx.Value(startAttributesKey{}).(*startAttributes); ok && sa.parent.Equal(parent) {
+		attrs = append(sa.attrs[:len(sa.attrs):len(sa.attrs)], attrs...)
+	}
+	return context.WithValue(ctx, startAttributesKey{}, &startAttributes{
+		parent: parent,
+		attrs:  attrs,
+	})
+}
+
+// StartAttributesFromContext returns the attributes set by
+// ContextWithStartAttributes for the next span started in ctx.
+func StartAttributesFromContext(ctx context.Context) []attribute.KeyValue {
+	sa, ok := ctx.Value(startAttributesKey{}).(*startAttributes)
+	if !ok {
+		return nil
+	}
+	parent := trace.SpanContextFromContext(ctx)
+	if sa.parent.Equal(parent) || (!sa.parent.IsValid() && parent.IsRemote()) {
+		return sa.attrs
+	}
+	return nil
+}
+
+// extraStartAttributes returns the attributes of the context for the
+// next span whose keys are not among the span's attributes.
+func extraStartAttributes(ctx context.Context, attrs []attribute.KeyValue) []attribute.KeyValue {
+	if ctx == nil {
+		return nil
+	}
+	candidates := StartAttributesFromContext(ctx)
+	if len(candidates) == 0 {
+		return nil
+	}
+	present := make(map[attribute.Key]bool, len(attrs))
+	for _, kv := range attrs {
+		present[kv.Key] = true
+	}
+	var extra []attribute.KeyValue
+	for _, kv := range candidates {
+		if !present[kv.Key] {
+			present[kv.Key] = true
+			extra = append(extra, kv)
+		}
+	}
+	return extra
+}
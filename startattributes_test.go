@@ -0,0 +1,60 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestStartAttributes(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(NewSDKSampler(RuleBased(
+			WithRule(AttributeEqualsPredicate(attribute.String("tenant", "gold")), ComposableAlwaysSample()),
+			WithDefaultRule(ParentThreshold()),
+		))),
+		sdktrace.WithSpanProcessor(recorder),
+	)
+	tracer := tp.Tracer("test")
+
+	ctx := ContextWithStartAttributes(context.Background(), attribute.String("tenant", "gold"))
+	ctx = ContextWithStartAttributes(ctx, attribute.String("region", "west"), attribute.String("tenant", "ignored"))
+	require.Equal(t, []attribute.KeyValue{
+		attribute.String("tenant", "gold"),
+		attribute.String("region", "west"),
+		attribute.String("tenant", "ignored"),
+	}, StartAttributesFromContext(ctx))
+
+	// The span's own attributes take precedence.
+	ctx, root := tracer.Start(ctx, "root", trace.WithAttributes(attribute.String("region", "east")))
+	require.True(t, root.SpanContext().IsSampled())
+	require.Nil(t, StartAttributesFromContext(ctx))
+
+	// Descendants do not have the attributes.
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+	root.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	require.Empty(t, spans[0].Attributes())
+	require.Equal(t, []attribute.KeyValue{
+		attribute.String("tenant", "gold"),
+		attribute.String("region", "east"),
+	}, spans[1].Attributes())
+
+	// Spans with a remote parent have the attributes of a context
+	// without a span.
+	remote := trace.ContextWithRemoteSpanContext(
+		ContextWithStartAttributes(context.Background(), attribute.String("tenant", "gold")),
+		trace.NewSpanContext(trace.SpanContextConfig{TraceID: trace.TraceID{1}, SpanID: trace.SpanID{1}}))
+	_, server := tracer.Start(remote, "server")
+	require.True(t, server.SpanContext().IsSampled())
+}
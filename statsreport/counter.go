@@ -0,0 +1,169 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package statsreport
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jmacd/sampler"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// OverflowOperation is the operation of spans whose names exceed the
+// limit set by WithMaxOperations.
+const OverflowOperation = ""
+
+// OperationStats are the runtime statistics of one operation, i.e.,
+// span name.
+type OperationStats struct {
+	// Operation is the span name, or OverflowOperation.
+	Operation string
+	// Matched counts spans of the operation.
+	Matched uint64
+	// Sampled counts spans of the operation that were sampled.
+	Sampled uint64
+}
+
+// OperationStatsProvider is implemented by OperationCounter.
+type OperationStatsProvider interface {
+	// OperationStats returns statistics for each operation, ordered
+	// by operation.
+	OperationStats() []OperationStats
+}
+
+// CounterOption configures CountOperations.
+type CounterOption func(*counterConfig)
+
+type counterConfig struct {
+	maxOperations int
+}
+
+// WithMaxOperations limits the number of operations counted, by
+// default 1000, to bound memory when span names have high
+// cardinality.  Spans of further operations are counted as
+// OverflowOperation.
+func WithMaxOperations(n int) CounterOption {
+	return func(c *counterConfig) {
+		c.maxOperations = n
+	}
+}
+
+// OperationCounter is a ComposableSampler that counts the spans of
+// each operation that another sampler sees and would sample.  It is a
+// sampler.RuleStatsProvider when the other sampler is.
+type OperationCounter struct {
+	sampler sampler.ComposableSampler
+	counts  *operationCounts
+}
+
+// operationCounts are shared by optimized copies of a counter.
+type operationCounts struct {
+	maxOperations int
+	size          atomic.Int64
+	operations    sync.Map // string -> *operationCount
+}
+
+type operationCount struct {
+	matched atomic.Uint64
+	sampled atomic.Uint64
+}
+
+var (
+	_ sampler.ComposableSampler = &OperationCounter{}
+	_ sampler.SamplerOptimizer  = &OperationCounter{}
+	_ sampler.RuleStatsProvider = &OperationCounter{}
+	_ OperationStatsProvider    = &OperationCounter{}
+)
+
+// CountOperations returns a sampler that makes the decisions of s,
+// counting spans by operation.
+func CountOperations(s sampler.ComposableSampler, options ...CounterOption) *OperationCounter {
+	cfg := counterConfig{
+		maxOperations: 1000,
+	}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	return &OperationCounter{
+		sampler: s,
+		counts: &operationCounts{
+			maxOperations: cfg.maxOperations,
+		},
+	}
+}
+
+// GetSamplingIntent implements ComposableSampler.
+func (oc *OperationCounter) GetSamplingIntent(params sampler.ComposableSamplingParameters) sampler.SamplingIntent {
+	intent := oc.sampler.GetSamplingIntent(params)
+	count := oc.counts.lookup(params.Name)
+	count.matched.Add(1)
+	if intent.WouldSample(params) {
+		count.sampled.Add(1)
+	}
+	return intent
+}
+
+// lookup returns the counts of an operation, adding it unless the
+// limit is reached.
+func (c *operationCounts) lookup(operation string) *operationCount {
+	if count, ok := c.operations.Load(operation); ok {
+		return count.(*operationCount)
+	}
+	added := true
+	if c.size.Add(1) > int64(c.maxOperations) {
+		c.size.Add(-1)
+		operation, added = OverflowOperation, false
+	}
+	count, loaded := c.operations.LoadOrStore(operation, &operationCount{})
+	if loaded && added {
+		// Another goroutine added it first.
+		c.size.Add(-1)
+	}
+	return count.(*operationCount)
+}
+
+// Description implements ComposableSampler.
+func (oc *OperationCounter) Description() string {
+	return fmt.Sprintf("CountOperations(%s)", oc.sampler.Description())
+}
+
+// Optimize implements SamplerOptimizer.  The optimized sampler shares
+// the counts of this one.
+func (oc *OperationCounter) Optimize(res *resource.Resource, scope instrumentation.Scope) sampler.ComposableSampler {
+	return &OperationCounter{
+		sampler: sampler.Optimize(oc.sampler, res, scope),
+		counts:  oc.counts,
+	}
+}
+
+// Stats implements RuleStatsProvider, returning the statistics of the
+// counted sampler, if any.
+func (oc *OperationCounter) Stats() []sampler.RuleStats {
+	if sp, ok := oc.sampler.(sampler.RuleStatsProvider); ok {
+		return sp.Stats()
+	}
+	return nil
+}
+
+// OperationStats implements OperationStatsProvider.
+func (oc *OperationCounter) OperationStats() []OperationStats {
+	var stats []OperationStats
+	oc.counts.operations.Range(func(key, value any) bool {
+		count := value.(*operationCount)
+		stats = append(stats, OperationStats{
+			Operation: key.(string),
+			Matched:   count.matched.Load(),
+			Sampled:   count.sampled.Load(),
+		})
+		return true
+	})
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Operation < stats[j].Operation
+	})
+	return stats
+}
@@ -0,0 +1,89 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package statsreport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/jmacd/sampler/samplerconfig/remote"
+	"google.golang.org/grpc"
+)
+
+// HTTPExporter returns an Exporter that POSTs each Report as JSON to
+// the URL, using the client, or http.DefaultClient when nil.
+// Responses other than 2xx are errors.
+func HTTPExporter(url string, client *http.Client) Exporter {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return ExporterFunc(func(ctx context.Context, report *Report) error {
+		body, err := json.Marshal(report)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, resp.Body)
+		if resp.StatusCode/100 != 2 {
+			return fmt.Errorf("%s: %s", url, resp.Status)
+		}
+		return nil
+	})
+}
+
+// GRPCExporter returns an Exporter that sends each Report to the
+// ReportRates method of a remote.SamplingPolicy service, converting
+// its counts to rates over its interval.
+func GRPCExporter(cc grpc.ClientConnInterface) Exporter {
+	client := remote.NewSamplingPolicyClient(cc)
+	return ExporterFunc(func(ctx context.Context, report *Report) error {
+		_, err := client.ReportRates(ctx, ReportRatesRequest(report))
+		return err
+	})
+}
+
+// ReportRatesRequest converts a Report to the request of the
+// ReportRates method of a remote.SamplingPolicy service.
+func ReportRatesRequest(report *Report) *remote.ReportRatesRequest {
+	interval := report.End.Sub(report.Start).Seconds()
+	perSecond := func(n uint64) float64 {
+		if interval <= 0 {
+			return 0
+		}
+		return float64(n) / interval
+	}
+	req := &remote.ReportRatesRequest{
+		Agent:           &remote.Agent{Resource: report.Resource},
+		Version:         report.Version,
+		IntervalSeconds: interval,
+	}
+	for _, count := range report.Rules {
+		req.Rules = append(req.Rules, &remote.RuleRate{
+			Description:      count.Name,
+			MatchedPerSecond: perSecond(count.Matched),
+			SampledPerSecond: perSecond(count.Sampled),
+		})
+	}
+	for _, count := range report.Operations {
+		req.Operations = append(req.Operations, &remote.OperationRate{
+			Operation:        count.Name,
+			MatchedPerSecond: perSecond(count.Matched),
+			SampledPerSecond: perSecond(count.Sampled),
+		})
+	}
+	return req
+}
@@ -0,0 +1,287 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package statsreport periodically reports the throughput that a
+// process's sampler observes, per rule and per operation, to a
+// central service that computes adaptive sampling rates, which the
+// process then fetches, e.g., from the remote package's
+// SamplingPolicy service.  This is the feedback half of adaptive
+// sampling:
+//
+//	counter := statsreport.CountOperations(s)
+//	reporter := statsreport.NewReporter(counter,
+//		statsreport.GRPCExporter(cc), statsreport.WithResource(res))
+//	defer reporter.Close()
+//	tp := sampler.NewTracerProvider(counter, res)
+//
+// Rule statistics come from samplers that implement
+// sampler.RuleStatsProvider, such as RuleBased, and operation
+// statistics from CountOperations.  When s is a remote.Sampler,
+// disable its own reports with remote.WithReportInterval(0), since
+// the service keeps the latest report of each process.
+package statsreport
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jmacd/sampler"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// Report holds the numbers of spans that matched and were sampled by
+// each rule and operation in an interval.
+type Report struct {
+	// Resource holds the attributes of the process's Resource, such
+	// as service.name.
+	Resource map[string]string `json:"resource"`
+
+	// Version is the version of the process's sampling policy, see
+	// WithPolicyVersion.
+	Version uint64 `json:"version,omitempty"`
+
+	// Start and End are the bounds of the interval.
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	// Rules holds the counts of each rule, in evaluation order.
+	Rules []Count `json:"rules,omitempty"`
+
+	// Operations holds the counts of each operation with spans in
+	// the interval, ordered by operation.
+	Operations []Count `json:"operations,omitempty"`
+}
+
+// Count is the number of spans that matched a rule or operation, and
+// the number of those that were sampled, in a Report's interval.
+type Count struct {
+	// Name is the description of a rule, or the operation.
+	Name    string `json:"name"`
+	Matched uint64 `json:"matched"`
+	Sampled uint64 `json:"sampled"`
+}
+
+// Exporter sends Reports to a central service.
+type Exporter interface {
+	// Export sends a report.  Reports that fail are retried by
+	// the next report, which covers their interval.
+	Export(context.Context, *Report) error
+}
+
+// ExporterFunc is an Exporter function.
+type ExporterFunc func(context.Context, *Report) error
+
+var _ Exporter = ExporterFunc(nil)
+
+// Export implements Exporter.
+func (f ExporterFunc) Export(ctx context.Context, report *Report) error {
+	return f(ctx, report)
+}
+
+// Option configures a Reporter.
+type Option func(*config)
+
+type config struct {
+	interval time.Duration
+	resource *resource.Resource
+	version  func() uint64
+	handler  func(error)
+}
+
+// WithInterval sets how often reports are sent, by default one
+// minute.  Zero disables periodic reports, which are then sent by
+// calling Report.
+func WithInterval(interval time.Duration) Option {
+	return func(c *config) {
+		c.interval = interval
+	}
+}
+
+// WithResource sets the Resource identifying the process in reports,
+// by default resource.Default().
+func WithResource(res *resource.Resource) Option {
+	return func(c *config) {
+		c.resource = res
+	}
+}
+
+// WithPolicyVersion sets the function returning the version of the
+// process's sampling policy, such as the Version method of a
+// remote.Sampler, so that the service can tell which policy the
+// counts were observed with.
+func WithPolicyVersion(version func() uint64) Option {
+	return func(c *config) {
+		c.version = version
+	}
+}
+
+// WithErrorHandler sets the function called with errors sending
+// periodic reports, by default otel.Handle.
+func WithErrorHandler(handler func(error)) Option {
+	return func(c *config) {
+		c.handler = handler
+	}
+}
+
+// Reporter periodically reports the statistics of a sampler until
+// closed.
+type Reporter struct {
+	sampler  sampler.ComposableSampler
+	exporter Exporter
+	resource map[string]string
+	version  func() uint64
+	handler  func(error)
+
+	lock       sync.Mutex // serializes Report
+	reportedAt time.Time
+	rules      []sampler.RuleStats
+	operations map[string]OperationStats
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewReporter returns a Reporter sending the statistics of s, when it
+// is a sampler.RuleStatsProvider or an OperationStatsProvider, to the
+// exporter.  The first report covers the interval since the Reporter
+// was created.
+func NewReporter(s sampler.ComposableSampler, exporter Exporter, options ...Option) *Reporter {
+	cfg := config{
+		interval: time.Minute,
+		handler:  otel.Handle,
+	}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	if cfg.resource == nil {
+		cfg.resource = resource.Default()
+	}
+	r := &Reporter{
+		sampler:  s,
+		exporter: exporter,
+		resource: map[string]string{},
+		version:  cfg.version,
+		handler:  cfg.handler,
+		done:     make(chan struct{}),
+	}
+	for iter := cfg.resource.Iter(); iter.Next(); {
+		kv := iter.Attribute()
+		r.resource[string(kv.Key)] = kv.Value.Emit()
+	}
+	rules, operations := r.stats()
+	r.setStats(time.Now(), rules, operations)
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	go r.run(cfg.interval)
+	return r
+}
+
+func (r *Reporter) run(interval time.Duration) {
+	defer close(r.done)
+	if interval <= 0 {
+		<-r.ctx.Done()
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Report(r.ctx); err != nil && r.ctx.Err() == nil && r.handler != nil {
+				r.handler(err)
+			}
+		}
+	}
+}
+
+// stats returns the cumulative statistics of the sampler.
+func (r *Reporter) stats() ([]sampler.RuleStats, []OperationStats) {
+	var rules []sampler.RuleStats
+	if sp, ok := r.sampler.(sampler.RuleStatsProvider); ok {
+		rules = sp.Stats()
+	}
+	var operations []OperationStats
+	if op, ok := r.sampler.(OperationStatsProvider); ok {
+		operations = op.OperationStats()
+	}
+	return rules, operations
+}
+
+// setStats records the statistics of the last report.
+func (r *Reporter) setStats(now time.Time, rules []sampler.RuleStats, operations []OperationStats) {
+	r.reportedAt, r.rules = now, rules
+	r.operations = make(map[string]OperationStats, len(operations))
+	for _, stats := range operations {
+		r.operations[stats.Operation] = stats
+	}
+}
+
+// Report sends a report immediately, covering the interval since the
+// previous report that was sent.
+func (r *Reporter) Report(ctx context.Context) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	now := time.Now()
+	rules, operations := r.stats()
+	report := &Report{
+		Resource: r.resource,
+		Start:    r.reportedAt,
+		End:      now,
+	}
+	if r.version != nil {
+		report.Version = r.version()
+	}
+	for i, rs := range rules {
+		count := Count{
+			Name:    rs.Description,
+			Matched: rs.Matched,
+			Sampled: rs.Sampled,
+		}
+		// Rules whose description changed were replaced, e.g., by
+		// a new policy, and count from zero.
+		if i < len(r.rules) && r.rules[i].Description == rs.Description {
+			count.Matched, count.Sampled = delta(r.rules[i].Matched, r.rules[i].Sampled, rs.Matched, rs.Sampled)
+		}
+		report.Rules = append(report.Rules, count)
+	}
+	for _, stats := range operations {
+		count := Count{
+			Name:    stats.Operation,
+			Matched: stats.Matched,
+			Sampled: stats.Sampled,
+		}
+		if prev, ok := r.operations[stats.Operation]; ok {
+			count.Matched, count.Sampled = delta(prev.Matched, prev.Sampled, stats.Matched, stats.Sampled)
+		}
+		if count.Matched == 0 {
+			continue
+		}
+		report.Operations = append(report.Operations, count)
+	}
+	if err := r.exporter.Export(ctx, report); err != nil {
+		return fmt.Errorf("statsreport: %w", err)
+	}
+	r.setStats(now, rules, operations)
+	return nil
+}
+
+// delta returns the counts since the previous ones, which are reset
+// when they decrease.
+func delta(prevMatched, prevSampled, matched, sampled uint64) (uint64, uint64) {
+	if matched < prevMatched || sampled < prevSampled {
+		return matched, sampled
+	}
+	return matched - prevMatched, sampled - prevSampled
+}
+
+// Close stops sending periodic reports.
+func (r *Reporter) Close() error {
+	r.cancel()
+	<-r.done
+	return nil
+}
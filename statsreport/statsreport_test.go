@@ -0,0 +1,208 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package statsreport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/jmacd/sampler"
+	"github.com/jmacd/sampler/samplerconfig/remote"
+)
+
+func testSampler() *OperationCounter {
+	return CountOperations(sampler.RuleBased(
+		sampler.WithRule(sampler.SpanKindPredicate(trace.SpanKindServer), sampler.ComposableAlwaysSample()),
+		sampler.WithDefaultRule(sampler.ComposableNeverSample()),
+	), WithMaxOperations(2))
+}
+
+func start(s sampler.ComposableSampler, name string, kind trace.SpanKind) {
+	sampler.CompositeSampler(s).ShouldSample(sampler.SamplingParameters{
+		ParentContext: context.Background(),
+		Name:          name,
+		Kind:          kind,
+	})
+}
+
+func TestCountOperations(t *testing.T) {
+	s := testSampler()
+	require.Equal(t, "CountOperations(RuleBased{rule(Span.Kind==server)=AlwaysOn,rule(true)=AlwaysOff})", s.Description())
+
+	opt := sampler.Optimize(s, resource.Empty(), instrumentation.Scope{})
+	start(s, "GET", trace.SpanKindServer)
+	start(opt, "GET", trace.SpanKindServer)
+	start(s, "query", trace.SpanKindClient)
+	// Beyond the limit.
+	start(s, "PUT", trace.SpanKindServer)
+	start(opt, "POST", trace.SpanKindClient)
+
+	require.Equal(t, []OperationStats{
+		{Operation: OverflowOperation, Matched: 2, Sampled: 1},
+		{Operation: "GET", Matched: 2, Sampled: 2},
+		{Operation: "query", Matched: 1, Sampled: 0},
+	}, s.OperationStats())
+	require.Equal(t, []sampler.RuleStats{
+		{Description: "rule(Span.Kind==server)=AlwaysOn", Matched: 3, Sampled: 3},
+		{Description: "rule(true)=AlwaysOff", Matched: 2, Sampled: 0},
+	}, s.Stats())
+}
+
+func TestReporter(t *testing.T) {
+	ctx := context.Background()
+	s := testSampler()
+	start(s, "GET", trace.SpanKindServer)
+
+	var reports []*Report
+	fail := errors.New("unavailable")
+	var err error
+	r := NewReporter(s, ExporterFunc(func(_ context.Context, report *Report) error {
+		reports = append(reports, report)
+		return err
+	}),
+		WithInterval(0),
+		WithResource(resource.NewSchemaless(attribute.String("service.name", "checkout"))),
+		WithPolicyVersion(func() uint64 { return 7 }),
+	)
+	defer func() { require.NoError(t, r.Close()) }()
+
+	// Spans before the Reporter are not reported.
+	start(s, "GET", trace.SpanKindServer)
+	start(s, "query", trace.SpanKindClient)
+	require.NoError(t, r.Report(ctx))
+	require.Len(t, reports, 1)
+	report := reports[0]
+	require.Equal(t, map[string]string{"service.name": "checkout"}, report.Resource)
+	require.Equal(t, uint64(7), report.Version)
+	require.True(t, report.End.After(report.Start))
+	require.Equal(t, []Count{
+		{Name: "rule(Span.Kind==server)=AlwaysOn", Matched: 1, Sampled: 1},
+		{Name: "rule(true)=AlwaysOff", Matched: 1, Sampled: 0},
+	}, report.Rules)
+	require.Equal(t, []Count{
+		{Name: "GET", Matched: 1, Sampled: 1},
+		{Name: "query", Matched: 1, Sampled: 0},
+	}, report.Operations)
+
+	// Failed reports are covered by the next one, and operations
+	// without spans are omitted.
+	start(s, "query", trace.SpanKindClient)
+	err = fail
+	require.ErrorIs(t, r.Report(ctx), fail)
+	start(s, "query", trace.SpanKindClient)
+	err = nil
+	require.NoError(t, r.Report(ctx))
+	require.Len(t, reports, 3)
+	require.Equal(t, report.End, reports[2].Start)
+	require.Equal(t, []Count{
+		{Name: "rule(Span.Kind==server)=AlwaysOn", Matched: 0, Sampled: 0},
+		{Name: "rule(true)=AlwaysOff", Matched: 2, Sampled: 0},
+	}, reports[2].Rules)
+	require.Equal(t, []Count{{Name: "query", Matched: 2, Sampled: 0}}, reports[2].Operations)
+}
+
+func TestReporterInterval(t *testing.T) {
+	s := testSampler()
+	reported := make(chan *Report, 1)
+	r := NewReporter(s, ExporterFunc(func(_ context.Context, report *Report) error {
+		select {
+		case reported <- report:
+		default:
+		}
+		return nil
+	}), WithInterval(10*time.Millisecond))
+	start(s, "GET", trace.SpanKindServer)
+	select {
+	case report := <-reported:
+		require.NotNil(t, report.Resource)
+	case <-time.After(10 * time.Second):
+		t.Fatal("no report")
+	}
+	require.NoError(t, r.Close())
+}
+
+func TestHTTPExporter(t *testing.T) {
+	var got Report
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		got = Report{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		if got.Version == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	now := time.Now().UTC()
+	report := &Report{
+		Resource:   map[string]string{"service.name": "checkout"},
+		Version:    3,
+		Start:      now.Add(-time.Minute),
+		End:        now,
+		Rules:      []Count{{Name: "rule(true)=AlwaysOn", Matched: 10, Sampled: 10}},
+		Operations: []Count{{Name: "GET", Matched: 10, Sampled: 10}},
+	}
+	exp := HTTPExporter(srv.URL, nil)
+	require.NoError(t, exp.Export(context.Background(), report))
+	require.True(t, report.Start.Equal(got.Start))
+	got.Start, got.End = report.Start, report.End
+	require.Equal(t, *report, got)
+
+	report.Version = 0
+	require.ErrorContains(t, exp.Export(context.Background(), report), "400 Bad Request")
+}
+
+func TestGRPCExporter(t *testing.T) {
+	srv := remote.NewServer()
+	lis := bufconn.Listen(1 << 16)
+	gs := grpc.NewServer()
+	remote.RegisterSamplingPolicyServer(gs, srv)
+	go func() { _ = gs.Serve(lis) }()
+	defer gs.Stop()
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	now := time.Now()
+	require.NoError(t, GRPCExporter(conn).Export(context.Background(), &Report{
+		Resource:   map[string]string{"service.name": "checkout"},
+		Version:    3,
+		Start:      now.Add(-10 * time.Second),
+		End:        now,
+		Rules:      []Count{{Name: "rule(true)=AlwaysOn", Matched: 20, Sampled: 10}},
+		Operations: []Count{{Name: "GET", Matched: 20, Sampled: 10}},
+	}))
+	reports := srv.Reports()
+	require.Len(t, reports, 1)
+	req := reports[0]
+	require.Equal(t, "checkout", req.GetAgent().GetResource()["service.name"])
+	require.Equal(t, uint64(3), req.GetVersion())
+	require.Equal(t, 10.0, req.GetIntervalSeconds())
+	require.Len(t, req.GetRules(), 1)
+	require.Equal(t, 2.0, req.GetRules()[0].GetMatchedPerSecond())
+	require.Equal(t, 1.0, req.GetRules()[0].GetSampledPerSecond())
+	require.Len(t, req.GetOperations(), 1)
+	require.Equal(t, "GET", req.GetOperations()[0].GetOperation())
+	require.Equal(t, 2.0, req.GetOperations()[0].GetMatchedPerSecond())
+}
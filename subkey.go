@@ -0,0 +1,152 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// subKeyRegistry holds the registered custom sub-keys.
+var subKeyRegistry struct {
+	lock sync.RWMutex
+	keys map[string]bool
+}
+
+// isRegisteredSubKey returns true for registered custom sub-keys.
+func isRegisteredSubKey(key string) bool {
+	subKeyRegistry.lock.RLock()
+	defer subKeyRegistry.lock.RUnlock()
+	return subKeyRegistry.keys[key]
+}
+
+// OTelSubKey is a registered custom sub-key of the OpenTelemetry
+// tracestate, holding values of type T.  See RegisterOTelSubKey.
+type OTelSubKey[T any] struct {
+	key    string
+	search fieldSearchKey
+	encode func(T) string
+	decode func(string) (T, error)
+}
+
+// RegisterOTelSubKey registers a custom two-character sub-key of the
+// OpenTelemetry tracestate, for example to propagate a policy
+// identifier, with functions to encode and decode its values.  Keys
+// consist of a lowercase letter followed by a lowercase letter or
+// digit.  The "rv" and "th" sub-keys are reserved, and each key can be
+// registered once, until unregistered.  Registered sub-keys are
+// allowed by every WithTraceStateSanitizer in the process.
+func RegisterOTelSubKey[T any](key string, encode func(T) string, decode func(string) (T, error)) (*OTelSubKey[T], error) {
+	if len(key) != 2 || !validOTelSubKey(key) {
+		return nil, fmt.Errorf("invalid tracestate sub-key: %q", key)
+	}
+	if key == "rv" || key == "th" {
+		return nil, fmt.Errorf("tracestate sub-key is reserved: %q", key)
+	}
+	subKeyRegistry.lock.Lock()
+	defer subKeyRegistry.lock.Unlock()
+	if subKeyRegistry.keys[key] {
+		return nil, fmt.Errorf("tracestate sub-key is already registered: %q", key)
+	}
+	if subKeyRegistry.keys == nil {
+		subKeyRegistry.keys = map[string]bool{}
+	}
+	subKeyRegistry.keys[key] = true
+	return &OTelSubKey[T]{
+		key:    key,
+		search: fieldSearchKey(";" + key + ":"),
+		encode: encode,
+		decode: decode,
+	}, nil
+}
+
+// RegisterStringSubKey registers a custom sub-key holding strings.
+// See RegisterOTelSubKey.
+func RegisterStringSubKey(key string) (*OTelSubKey[string], error) {
+	return RegisterOTelSubKey(key, func(v string) string { return v }, func(v string) (string, error) { return v, nil })
+}
+
+// RegisterUint64SubKey registers a custom sub-key holding unsigned
+// integers, encoded in decimal.  See RegisterOTelSubKey.
+func RegisterUint64SubKey(key string) (*OTelSubKey[uint64], error) {
+	return RegisterOTelSubKey(key,
+		func(v uint64) string { return strconv.FormatUint(v, 10) },
+		func(v string) (uint64, error) { return strconv.ParseUint(v, 10, 64) },
+	)
+}
+
+// Unregister releases the sub-key, which is then no longer allowed
+// by WithTraceStateSanitizer and can be registered again, e.g., in
+// t.Cleanup of tests.
+func (k *OTelSubKey[T]) Unregister() {
+	subKeyRegistry.lock.Lock()
+	defer subKeyRegistry.lock.Unlock()
+	delete(subKeyRegistry.keys, k.key)
+}
+
+// Key returns the sub-key.
+func (k *OTelSubKey[T]) Key() string {
+	return k.key
+}
+
+// Get returns the value of the sub-key in the parent's tracestate.
+// Values that cannot be decoded are reported via otel.Handle.
+func (k *OTelSubKey[T]) Get(params ComposableSamplingParameters) (T, bool) {
+	var zero T
+	val, has := params.parentOTelTraceState().field(k.search)
+	if !has {
+		return zero, false
+	}
+	v, err := k.decode(val)
+	if err != nil {
+		otel.Handle(fmt.Errorf("could not parse tracestate sub-key %s: %q: %w", k.key, val, err))
+		return zero, false
+	}
+	return v, true
+}
+
+// Set returns a TraceStateFunc that writes the sub-key, replacing
+// any existing value, for use in a SamplingIntent.  Encoded values
+// that are not valid sub-key values are reported via otel.Handle and
+// not written.
+func (k *OTelSubKey[T]) Set(value T) TraceStateFunc {
+	return func(ts trace.TraceState) trace.TraceState {
+		encoded := k.encode(value)
+		if !validOTelSubValue(encoded) {
+			otel.Handle(fmt.Errorf("invalid tracestate sub-key %s value: %q", k.key, encoded))
+			return ts
+		}
+		otts := ts.Get("ot")
+		_, pos, _ := tracestateHasOTelField(otts, k.search)
+		var stack [otelValueBufferLen]byte
+		buf := appendOTelFields(stack[:0], otts, pos, fieldPos{})
+		if len(buf) != 0 {
+			buf = append(buf, ';')
+		}
+		buf = append(buf, k.search[1:]...)
+		buf = append(buf, encoded...)
+		rts, err := updateOT(ts, string(buf))
+		if err != nil {
+			otel.Handle(fmt.Errorf("tracestate: %w", err))
+			return ts
+		}
+		return rts
+	}
+}
+
+// WithOTelSubKeyEntry sets a registered sub-key of the OpenTelemetry
+// tracestate on sampled spans, as WithTraceStateEntry does for vendor
+// entries.
+func WithOTelSubKeyEntry[T any](key *OTelSubKey[T], value func() T) AnnotatingOption {
+	return func(cfg *annotatingConfig) {
+		cfg.traceKeys = append(cfg.traceKeys, "ot."+key.key)
+		cfg.traceState = CombineTraceState(cfg.traceState, func(ts trace.TraceState) trace.TraceState {
+			return key.Set(value())(ts)
+		})
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRegisterOTelSubKey(t *testing.T) {
+	for _, key := range []string{"", "a", "abc", "Ab", "1a", "a-"} {
+		_, err := RegisterStringSubKey(key)
+		require.ErrorContains(t, err, "invalid tracestate sub-key")
+	}
+	for _, key := range []string{"rv", "th"} {
+		_, err := RegisterStringSubKey(key)
+		require.ErrorContains(t, err, "reserved")
+	}
+	key, err := RegisterUint64SubKey("t1")
+	require.NoError(t, err)
+	t.Cleanup(key.Unregister)
+	require.Equal(t, "t1", key.Key())
+	_, err = RegisterStringSubKey("t1")
+	require.ErrorContains(t, err, "already registered")
+
+	// Unregistered keys can be registered again.
+	require.True(t, isRegisteredSubKey("t1"))
+	key.Unregister()
+	require.False(t, isRegisteredSubKey("t1"))
+	key, err = RegisterUint64SubKey("t1")
+	require.NoError(t, err)
+}
+
+func TestOTelSubKeyAccessors(t *testing.T) {
+	policy, err := RegisterUint64SubKey("t2")
+	require.NoError(t, err)
+	t.Cleanup(policy.Unregister)
+	name, err := RegisterStringSubKey("t3")
+	require.NoError(t, err)
+	t.Cleanup(name.Unregister)
+
+	params := func(tracestate string) ComposableSamplingParameters {
+		ts, err := trace.ParseTraceState(tracestate)
+		require.NoError(t, err)
+		return ComposableSamplingParameters{
+			ParentSpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID:    trace.TraceID{1},
+				SpanID:     trace.SpanID{1},
+				TraceState: ts,
+			}),
+		}
+	}
+
+	v, has := policy.Get(params("ot=th:8;t2:17"))
+	require.True(t, has)
+	require.Equal(t, uint64(17), v)
+	_, has = policy.Get(params("ot=th:8;t2:x"))
+	require.False(t, has)
+	_, has = policy.Get(params("ot=th:8"))
+	require.False(t, has)
+
+	for _, test := range []struct {
+		in, out string
+		set     TraceStateFunc
+	}{
+		{"", "ot=t2:5", policy.Set(5)},
+		{"ot=rv:abcdefabcdefab;th:8,a=b", "ot=rv:abcdefabcdefab;th:8;t2:5,a=b", policy.Set(5)},
+		{"ot=t2:4;th:8", "ot=th:8;t2:5", policy.Set(5)},
+		{"ot=th:8;t3:x;xx:1", "ot=th:8;xx:1;t3:abc", name.Set("abc")},
+		{"ot=th:8", "ot=th:8", name.Set("a;th:0")},
+	} {
+		ts, err := trace.ParseTraceState(test.in)
+		require.NoError(t, err)
+		require.Equal(t, test.out, test.set(ts).String())
+	}
+
+	// Set allocates only the new "ot" value beyond what updating
+	// the tracestate requires.
+	ts, err := trace.ParseTraceState("ot=rv:abcdefabcdefab;th:8,a=b")
+	require.NoError(t, err)
+	set := name.Set("abc")
+	base := testing.AllocsPerRun(100, func() {
+		_, _ = updateOT(ts, "rv:abcdefabcdefab;th:8;t3:abc")
+	})
+	require.Equal(t, base+1, testing.AllocsPerRun(100, func() {
+		_ = set(ts)
+	}))
+
+	// Registered sub-keys are allowed by the sanitizer.
+	var cfg compositeConfig
+	WithTraceStateSanitizer()(&cfg)
+	ts, err = trace.ParseTraceState("ot=th:8;t2:5;zz:1")
+	require.NoError(t, err)
+	ts, err = cfg.sanitizer.sanitize(ts)
+	require.EqualError(t, err, "tracestate: removed ot.zz")
+	require.Equal(t, "ot=th:8;t2:5", ts.String())
+
+	// Written by a sampler.
+	sampler := CompositeSampler(AnnotatingSampler(ComposableAlwaysSample(), WithOTelSubKeyEntry(name, func() string { return "p1" })))
+	require.Equal(t, "Annotate(AlwaysOn, , tracestate(ot.t3))", sampler.Description())
+	res := sampler.ShouldSample(SamplingParameters{
+		ParentContext: context.Background(),
+		TraceID:       trace.TraceID{1},
+	})
+	require.Equal(t, "ot=th:0;t3:p1", res.Tracestate.String())
+}
@@ -0,0 +1,319 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampler
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+)
+
+// Threshold is a 56-bit rejection threshold, as used in the
+// OpenTelemetry tracestate "th" sub-key.  A span is sampled when its
+// 56-bit randomness value is greater than or equal to the threshold,
+// so the sampling probability is (2^56 - threshold) / 2^56.
+type Threshold int64
+
+const (
+	// NEVER_SAMPLE_THRESHOLD indicates a span that should not be sampled.
+	// This is equivalent to sampling with 0% probability.
+	NEVER_SAMPLE_THRESHOLD Threshold = 1 << 56
+
+	// ALWAYS_SAMPLE_THRESHOLD indicates to sample with 100% probability.
+	ALWAYS_SAMPLE_THRESHOLD Threshold = 0
+
+	// INVALID_THRESHOLD indicates a span that should be sampled with
+	// unknown probability.
+	INVALID_THRESHOLD Threshold = -1
+)
+
+// Rounding determines how a probability is rounded to a threshold
+// with a limited number of hex digits.
+type Rounding int
+
+const (
+	// RoundNearest rounds to the nearest representable
+	// probability.
+	RoundNearest Rounding = iota
+
+	// RoundProbabilityDown rounds to a probability that does not
+	// exceed the requested probability, for users constrained by
+	// a budget.
+	RoundProbabilityDown
+
+	// RoundProbabilityUp rounds to a probability that is not less
+	// than the requested probability, for users constrained by
+	// coverage.
+	RoundProbabilityUp
+)
+
+func (r Rounding) String() string {
+	switch r {
+	case RoundProbabilityDown:
+		return "down"
+	case RoundProbabilityUp:
+		return "up"
+	}
+	return "nearest"
+}
+
+// ProbabilityToThreshold computes the rejection threshold for a
+// sampling probability, rounded to a reasonable number of hex digits.
+// Fractions outside the supported range map to the always- and
+// never-sample thresholds.
+func ProbabilityToThreshold(fraction float64) Threshold {
+	return ProbabilityToThresholdRounded(fraction, RoundNearest)
+}
+
+// ProbabilityToThresholdRounded is ProbabilityToThreshold with a
+// rounding mode.
+func ProbabilityToThresholdRounded(fraction float64, rounding Rounding) Threshold {
+	const (
+		maxp  = 14                       // maximum precision is 56 bits
+		defp  = defaultSamplingPrecision // default precision
+		hbits = 4                        // bits per hex digit
+	)
+
+	if fraction > maxSupportedProbability {
+		return ALWAYS_SAMPLE_THRESHOLD
+	}
+
+	if fraction < minSupportedProbability {
+		return NEVER_SAMPLE_THRESHOLD
+	}
+
+	// Calculate the amount of precision needed to encode the
+	// threshold with reasonable precision.
+	//
+	// 13 hex digits is the maximum reasonable precision, since
+	// that equals 52 bits, the number of bits in the float64
+	// significand.
+	//
+	// Frexp() normalizes both the fraction and one-minus the
+	// fraction, because more digits of precision are needed in
+	// both cases -- in these cases the threshold has all leading
+	// '0' or 'f' characters.
+	//
+	// We know that `exp <= 0`.  If `exp <= -4`, there will be a
+	// leading hex `0` or `f`.  For every multiple of -4, another
+	// leading `0` or `f` appears, so this raises precision
+	// accordingly.
+	_, expF := math.Frexp(fraction)
+	precision := min(maxp, defp+expF/-hbits)
+
+	// Compute the threshold
+	scaled := uint64(math.Round(fraction * float64(maxAdjustedCount)))
+	threshold := maxAdjustedCount - scaled
+
+	// Round to the specified precision, if less than the maximum.
+	// Note that a higher threshold is a lower probability.
+	if shift := hbits * (maxp - precision); shift != 0 {
+		switch rounding {
+		case RoundProbabilityDown:
+			threshold += uint64(1)<<shift - 1
+		case RoundProbabilityUp:
+		default:
+			threshold += uint64(1) << (shift - 1)
+		}
+		threshold >>= shift
+		threshold <<= shift
+	}
+
+	return Threshold(threshold)
+}
+
+// oneInNThreshold computes the rejection threshold for sampling one in
+// n spans using integer arithmetic, rounded to the same number of hex
+// digits as ProbabilityToThresholdRounded.  n must be in the range
+// [1, 2^56].
+func oneInNThreshold(n uint64, rounding Rounding) Threshold {
+	return rationalThreshold(1, n, rounding)
+}
+
+// rationalThreshold computes the rejection threshold for the
+// probability num/den using integer arithmetic, rounded to the same
+// number of hex digits as ProbabilityToThresholdRounded.  The
+// probability must be in the range [2^-56, 1].
+func rationalThreshold(num, den uint64, rounding Rounding) Threshold {
+	const (
+		maxp  = 14
+		defp  = defaultSamplingPrecision
+		hbits = 4
+	)
+	_, expF := math.Frexp(float64(num) / float64(den))
+	precision := min(maxp, defp+expF/-hbits)
+	shift := hbits * (maxp - precision)
+
+	// The threshold in units of 2^shift is units*(den-num)/den,
+	// where units*(den-num) may exceed 64 bits.
+	units := maxAdjustedCount >> shift
+	hi, lo := bits.Mul64(units, den-num)
+	quo, rem := bits.Div64(hi, lo, den)
+	switch rounding {
+	case RoundProbabilityDown:
+		if rem != 0 {
+			quo++
+		}
+	case RoundProbabilityUp:
+	default:
+		if rem >= den-rem {
+			quo++
+		}
+	}
+	return Threshold(quo << shift)
+}
+
+// ComposeThresholds returns the threshold whose probability is the
+// product of the probabilities of two thresholds, rounded to the
+// nearest threshold, for computing the adjusted count of spans that
+// were sampled in more than one stage.  The result is never less than
+// either threshold.  Composing with an invalid threshold yields
+// INVALID_THRESHOLD.
+func ComposeThresholds(a, b Threshold) Threshold {
+	return ComposeThresholdsRounded(a, b, RoundNearest)
+}
+
+// ComposeThresholdsRounded is ComposeThresholds with a rounding mode.
+func ComposeThresholdsRounded(a, b Threshold, rounding Rounding) Threshold {
+	switch {
+	case !a.IsValid() || !b.IsValid():
+		return INVALID_THRESHOLD
+	case a == NEVER_SAMPLE_THRESHOLD || b == NEVER_SAMPLE_THRESHOLD:
+		return NEVER_SAMPLE_THRESHOLD
+	}
+	// The product of the 56-bit scaled probabilities fits in 112
+	// bits; divide by 2^56 with rounding.
+	hi, lo := bits.Mul64(maxAdjustedCount-uint64(a), maxAdjustedCount-uint64(b))
+	scaled := hi<<8 | lo>>56
+	rem := lo & (maxAdjustedCount - 1)
+	switch rounding {
+	case RoundProbabilityDown:
+	case RoundProbabilityUp:
+		if rem != 0 {
+			scaled++
+		}
+	default:
+		if rem >= maxAdjustedCount/2 {
+			scaled++
+		}
+	}
+	return Threshold(maxAdjustedCount - scaled)
+}
+
+// ThresholdToProbability returns the sampling probability of a
+// threshold.  Invalid thresholds have probability zero.
+func ThresholdToProbability(threshold Threshold) float64 {
+	if !threshold.IsValid() {
+		return 0
+	}
+	return float64(maxAdjustedCount-uint64(threshold)) / float64(maxAdjustedCount)
+}
+
+// IsValid returns true for thresholds in the range
+// [ALWAYS_SAMPLE_THRESHOLD, NEVER_SAMPLE_THRESHOLD].
+func (t Threshold) IsValid() bool {
+	return t >= ALWAYS_SAMPLE_THRESHOLD && t <= NEVER_SAMPLE_THRESHOLD
+}
+
+// Probability returns the sampling probability of the threshold.
+func (t Threshold) Probability() float64 {
+	return ThresholdToProbability(t)
+}
+
+// AdjustedCount returns the number of spans represented by each span
+// sampled with this threshold, the inverse of its probability.  The
+// never-sample and invalid thresholds have adjusted count zero,
+// meaning unknown.
+func (t Threshold) AdjustedCount() float64 {
+	if !t.IsValid() || t == NEVER_SAMPLE_THRESHOLD {
+		return 0
+	}
+	return float64(maxAdjustedCount) / float64(maxAdjustedCount-uint64(t))
+}
+
+// ShouldSample returns true when randomness, a 56-bit value, is
+// sampled by this threshold.
+func (t Threshold) ShouldSample(randomness int64) bool {
+	return t.IsValid() && t != NEVER_SAMPLE_THRESHOLD && int64(t) <= randomness
+}
+
+// truncate rounds the threshold down to the given number of hex
+// digits, which raises its probability.  A truncated threshold samples
+// every randomness value that the original threshold samples.
+func (t Threshold) truncate(digits int) Threshold {
+	if !t.IsValid() || t == NEVER_SAMPLE_THRESHOLD || digits >= 14 {
+		return t
+	}
+	shift := 4 * (14 - digits)
+	return t >> shift << shift
+}
+
+// ParseThreshold decodes the value of a tracestate "th" sub-key, which
+// is 1 to 14 hexadecimal digits with trailing zeros removed.
+func ParseThreshold(s string) (Threshold, error) {
+	if len(s) == 0 || len(s) > 14 {
+		return INVALID_THRESHOLD, fmt.Errorf("threshold %q: %w", s, strconv.ErrSyntax)
+	}
+	th, err := strconv.ParseUint(s, 16, 64)
+	if err != nil {
+		return INVALID_THRESHOLD, fmt.Errorf("threshold %q: %w", s, strconv.ErrSyntax)
+	}
+	// Add trailing zeros
+	th <<= (14 - len(s)) * 4
+	return Threshold(th), nil
+}
+
+// String returns the tracestate encoding of a threshold that can be
+// sampled, i.e., 14 hexadecimal digits with trailing zeros removed.
+// Other thresholds are formatted as "never" and "invalid".
+func (t Threshold) String() string {
+	switch {
+	case t == NEVER_SAMPLE_THRESHOLD:
+		return "never"
+	case !t.IsValid():
+		return "invalid"
+	case t == ALWAYS_SAMPLE_THRESHOLD:
+		return "0"
+	}
+	return string(t.appendText(make([]byte, 0, 14)))
+}
+
+// appendText appends the tracestate encoding of a threshold that can
+// be sampled to buf, without allocating when buf has capacity.
+func (t Threshold) appendText(buf []byte) []byte {
+	const hex = "0123456789abcdef"
+	v := uint64(t)
+	digits := 14
+	for digits > 1 && v&0xf == 0 {
+		// Remove trailing zeros.
+		v >>= 4
+		digits--
+	}
+	for i := digits - 1; i >= 0; i-- {
+		buf = append(buf, hex[(v>>(4*i))&0xf])
+	}
+	return buf
+}
+
+// MarshalText implements encoding.TextMarshaler using the tracestate
+// encoding.  The never-sample and invalid thresholds cannot be
+// encoded.
+func (t Threshold) MarshalText() ([]byte, error) {
+	if !t.IsValid() || t == NEVER_SAMPLE_THRESHOLD {
+		return nil, fmt.Errorf("threshold %s cannot be encoded", t)
+	}
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler using the
+// tracestate encoding.
+func (t *Threshold) UnmarshalText(text []byte) error {
+	th, err := ParseThreshold(string(text))
+	if err != nil {
+		return err
+	}
+	*t = th
+	return nil
+}
@@ -0,0 +1,239 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"encoding/json"
+	"math/bits"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+)
+
+func TestThresholdProbability(t *testing.T) {
+	for _, test := range []struct {
+		fraction  float64
+		threshold Threshold
+		encoded   string
+		adjusted  float64
+	}{
+		{1, ALWAYS_SAMPLE_THRESHOLD, "0", 1},
+		{0.5, 0x80000000000000, "8", 2},
+		{0.25, 0xc0000000000000, "c", 4},
+		{0.01, 0xfd70a000000000, "fd70a", 0},
+		{0.99, 0x028f0000000000, "028f", 0},
+		{0x1p-56, 0xffffffffffffff, "ffffffffffffff", 0x1p56},
+	} {
+		th := ProbabilityToThreshold(test.fraction)
+		require.Equal(t, test.threshold, th, "%g", test.fraction)
+		require.Equal(t, test.encoded, th.String())
+		require.InEpsilon(t, test.fraction, ThresholdToProbability(th), 1e-3)
+		require.InEpsilon(t, test.fraction, th.Probability(), 1e-3)
+		if test.adjusted != 0 {
+			require.Equal(t, test.adjusted, th.AdjustedCount())
+		}
+		require.InEpsilon(t, 1/test.fraction, th.AdjustedCount(), 1e-3)
+
+		parsed, err := ParseThreshold(test.encoded)
+		require.NoError(t, err)
+		require.Equal(t, th, parsed)
+	}
+
+	require.Equal(t, NEVER_SAMPLE_THRESHOLD, ProbabilityToThreshold(0))
+	require.Equal(t, 0.0, ThresholdToProbability(NEVER_SAMPLE_THRESHOLD))
+	require.Equal(t, 0.0, NEVER_SAMPLE_THRESHOLD.AdjustedCount())
+	require.Equal(t, 0.0, ThresholdToProbability(INVALID_THRESHOLD))
+	require.Equal(t, 0.0, INVALID_THRESHOLD.AdjustedCount())
+	require.Equal(t, "never", NEVER_SAMPLE_THRESHOLD.String())
+	require.Equal(t, "invalid", INVALID_THRESHOLD.String())
+}
+
+func TestThresholdShouldSample(t *testing.T) {
+	half := ProbabilityToThreshold(0.5)
+	require.True(t, half.ShouldSample(0x80000000000000))
+	require.True(t, half.ShouldSample(0xffffffffffffff))
+	require.False(t, half.ShouldSample(0x7fffffffffffff))
+	require.True(t, ALWAYS_SAMPLE_THRESHOLD.ShouldSample(0))
+	require.False(t, NEVER_SAMPLE_THRESHOLD.ShouldSample(0xffffffffffffff))
+	require.False(t, INVALID_THRESHOLD.ShouldSample(0xffffffffffffff))
+}
+
+func TestThresholdText(t *testing.T) {
+	for _, bad := range []string{"", "g", "-1", "123456789abcdef", "+8"} {
+		_, err := ParseThreshold(bad)
+		require.ErrorIs(t, err, strconv.ErrSyntax, bad)
+	}
+
+	type config struct {
+		Threshold Threshold `json:"threshold"`
+	}
+	data, err := json.Marshal(config{ProbabilityToThreshold(0.25)})
+	require.NoError(t, err)
+	require.Equal(t, `{"threshold":"c"}`, string(data))
+
+	var cfg config
+	require.NoError(t, json.Unmarshal([]byte(`{"threshold":"fd70a"}`), &cfg))
+	require.Equal(t, ProbabilityToThreshold(0.01), cfg.Threshold)
+
+	require.Error(t, json.Unmarshal([]byte(`{"threshold":"xyz"}`), &cfg))
+	_, err = json.Marshal(config{NEVER_SAMPLE_THRESHOLD})
+	require.Error(t, err)
+}
+
+func TestThresholdRounding(t *testing.T) {
+	rnd := rand.New(rand.NewSource(101333))
+	for range 10000 {
+		fraction := rnd.Float64()
+		if fraction < minSupportedProbability {
+			continue
+		}
+		nearest := ProbabilityToThresholdRounded(fraction, RoundNearest)
+		down := ProbabilityToThresholdRounded(fraction, RoundProbabilityDown)
+		up := ProbabilityToThresholdRounded(fraction, RoundProbabilityUp)
+		require.Equal(t, ProbabilityToThreshold(fraction), nearest)
+
+		require.LessOrEqual(t, ThresholdToProbability(down), fraction)
+		require.GreaterOrEqual(t, ThresholdToProbability(up), fraction)
+		require.LessOrEqual(t, up, nearest)
+		require.LessOrEqual(t, nearest, down)
+	}
+
+	// 1/3 is not exactly representable.
+	require.Equal(t, Threshold(0xaaab0000000000), ProbabilityToThresholdRounded(1.0/3, RoundNearest))
+	require.Equal(t, Threshold(0xaaab0000000000), ProbabilityToThresholdRounded(1.0/3, RoundProbabilityDown))
+	require.Equal(t, Threshold(0xaaaa0000000000), ProbabilityToThresholdRounded(1.0/3, RoundProbabilityUp))
+
+	// 1/2 is exactly representable.
+	for _, rounding := range []Rounding{RoundNearest, RoundProbabilityDown, RoundProbabilityUp} {
+		require.Equal(t, Threshold(0x80000000000000), ProbabilityToThresholdRounded(0.5, rounding))
+	}
+
+	require.Equal(t, "TraceIDRatioBased{0.1}", TraceIDRatioBased(0.1, WithRounding(RoundNearest)).Description())
+	require.Equal(t, "TraceIDRatioBased{0.1,down}", TraceIDRatioBased(0.1, WithRounding(RoundProbabilityDown)).Description())
+	require.Equal(t, "TraceIDRatioBased{0.1,up}", TraceIDRatioBased(0.1, WithRounding(RoundProbabilityUp)).Description())
+}
+
+func TestComposeThresholds(t *testing.T) {
+	half := ProbabilityToThreshold(0.5)
+	quarter := ProbabilityToThreshold(0.25)
+	third := ProbabilityToThreshold(1.0 / 3)
+	require.Equal(t, quarter, ComposeThresholds(half, half))
+	require.Equal(t, ProbabilityToThreshold(0.125), ComposeThresholds(half, quarter))
+	require.Equal(t, half, ComposeThresholds(half, ALWAYS_SAMPLE_THRESHOLD))
+	require.Equal(t, NEVER_SAMPLE_THRESHOLD, ComposeThresholds(half, NEVER_SAMPLE_THRESHOLD))
+	require.Equal(t, INVALID_THRESHOLD, ComposeThresholds(half, INVALID_THRESHOLD))
+	require.Equal(t, ComposeThresholds(third, half), ComposeThresholds(half, third))
+
+	// The adjusted counts multiply.
+	require.InEpsilon(t, third.AdjustedCount()*half.AdjustedCount(), ComposeThresholds(third, half).AdjustedCount(), 1e-12)
+
+	// 2^-56 * 2^-56 rounds to zero probability, or up to 2^-56.
+	smallest := Threshold(maxAdjustedCount - 1)
+	require.Equal(t, NEVER_SAMPLE_THRESHOLD, ComposeThresholds(smallest, smallest))
+	require.Equal(t, NEVER_SAMPLE_THRESHOLD, ComposeThresholdsRounded(smallest, smallest, RoundProbabilityDown))
+	require.Equal(t, smallest, ComposeThresholdsRounded(smallest, smallest, RoundProbabilityUp))
+
+	// Rounding modes bracket the exact product.
+	a, b := Threshold(0x12345678912345), Threshold(0x54321987654321)
+	down := ComposeThresholdsRounded(a, b, RoundProbabilityDown)
+	up := ComposeThresholdsRounded(a, b, RoundProbabilityUp)
+	require.Equal(t, down-1, up)
+	require.GreaterOrEqual(t, up, max(a, b))
+}
+
+func TestOneInNThreshold(t *testing.T) {
+	// probabilityTimesN compares (2^56 - threshold) * n with 2^56.
+	probabilityTimesN := func(threshold Threshold, n uint64) int {
+		hi, lo := bits.Mul64(maxAdjustedCount-uint64(threshold), n)
+		switch {
+		case hi != 0 || lo > maxAdjustedCount:
+			return 1
+		case lo < maxAdjustedCount:
+			return -1
+		}
+		return 0
+	}
+	rnd := rand.New(rand.NewSource(101333))
+	for range 10000 {
+		n := uint64(rnd.Int63n(1<<rnd.Intn(57))) + 2
+		if n > maxAdjustedCount {
+			continue
+		}
+		nearest := oneInNThreshold(n, RoundNearest)
+		down := oneInNThreshold(n, RoundProbabilityDown)
+		up := oneInNThreshold(n, RoundProbabilityUp)
+
+		require.LessOrEqual(t, probabilityTimesN(down, n), 0)
+		require.GreaterOrEqual(t, probabilityTimesN(up, n), 0)
+		require.LessOrEqual(t, up, nearest)
+		require.LessOrEqual(t, nearest, down)
+	}
+
+	require.Equal(t, Threshold(0xaaab0000000000), oneInNThreshold(3, RoundNearest))
+	require.Equal(t, Threshold(0xaaaa0000000000), oneInNThreshold(3, RoundProbabilityUp))
+	require.Equal(t, Threshold(0x80000000000000), oneInNThreshold(2, RoundProbabilityUp))
+	require.Equal(t, Threshold(0x80000000000000), oneInNThreshold(2, RoundProbabilityDown))
+	require.Equal(t, Threshold(maxAdjustedCount-1), oneInNThreshold(maxAdjustedCount, RoundNearest))
+	require.Equal(t, ProbabilityToThreshold(1e-6), oneInNThreshold(1000000, RoundNearest))
+
+	require.Equal(t, "TraceIDRatioBasedN{3}", TraceIDRatioBasedN(3).Description())
+	require.Equal(t, "TraceIDRatioBasedN{3,up}", TraceIDRatioBasedN(3, WithRounding(RoundProbabilityUp)).Description())
+	require.Equal(t, ComposableAlwaysSample(), TraceIDRatioBasedN(1))
+	require.Equal(t, ComposableNeverSample(), TraceIDRatioBasedN(0))
+	require.Equal(t, ComposableNeverSample(), TraceIDRatioBasedN(maxAdjustedCount+1))
+}
+
+func TestRationalThreshold(t *testing.T) {
+	require.Equal(t, oneInNThreshold(3, RoundNearest), rationalThreshold(1, 3, RoundNearest))
+	require.Equal(t, Threshold(0x55550000000000), rationalThreshold(2, 3, RoundNearest))
+	require.Equal(t, Threshold(0x55560000000000), rationalThreshold(2, 3, RoundProbabilityDown))
+	require.Equal(t, Threshold(0x55550000000000), rationalThreshold(2, 3, RoundProbabilityUp))
+	require.Equal(t, Threshold(0x40000000000000), rationalThreshold(3, 4, RoundProbabilityDown))
+
+	// 1/1000 in floating point is slightly more than 1/1000.
+	down := rationalThreshold(1, 1000, RoundProbabilityDown)
+	require.Equal(t, down, rationalThreshold(1000, 1000000, RoundProbabilityDown))
+	hi, lo := bits.Mul64(maxAdjustedCount-uint64(down), 1000)
+	require.Zero(t, hi)
+	require.LessOrEqual(t, lo, maxAdjustedCount)
+
+	require.Equal(t, "TraceIDRatioBased{1/1000}", TraceIDRatioBasedRational(1, 1000).Description())
+	require.Equal(t, "TraceIDRatioBased{1/1000,down}", TraceIDRatioBasedRational(1, 1000, WithRounding(RoundProbabilityDown)).Description())
+	require.Equal(t, ComposableAlwaysSample(), TraceIDRatioBasedRational(3, 3))
+	require.Equal(t, ComposableAlwaysSample(), TraceIDRatioBasedRational(4, 3))
+	require.Equal(t, ComposableNeverSample(), TraceIDRatioBasedRational(0, 3))
+	require.Equal(t, ComposableNeverSample(), TraceIDRatioBasedRational(1, 0))
+	require.Equal(t, ComposableNeverSample(), TraceIDRatioBasedRational(1, maxAdjustedCount+1))
+	require.NotEqual(t, ComposableNeverSample(), TraceIDRatioBasedRational(1, maxAdjustedCount))
+}
+
+func TestTraceIDRatioFromAdjustedCount(t *testing.T) {
+	var errs []error
+	previous := otel.GetErrorHandler()
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		errs = append(errs, err)
+	}))
+	t.Cleanup(func() { otel.SetErrorHandler(previous) })
+
+	intent := func(s ComposableSampler) SamplingIntent {
+		return s.GetSamplingIntent(ComposableSamplingParameters{})
+	}
+	for k := range 57 {
+		n := uint64(1) << k
+		sampler := TraceIDRatioFromAdjustedCount(n)
+		require.Equal(t, float64(n), intent(sampler).Threshold.AdjustedCount())
+	}
+	require.Empty(t, errs)
+	require.Equal(t, "TraceIDRatioFromAdjustedCount{1024}", TraceIDRatioFromAdjustedCount(1024).Description())
+	require.Equal(t, ComposableAlwaysSample(), TraceIDRatioFromAdjustedCount(1))
+	require.Equal(t, ComposableNeverSample(), TraceIDRatioFromAdjustedCount(0))
+
+	// Rounded down to a power of two.
+	require.Equal(t, "TraceIDRatioFromAdjustedCount{512}", TraceIDRatioFromAdjustedCount(1000).Description())
+	require.Len(t, errs, 1)
+	require.EqualError(t, errs[0], "adjusted count is not a power of two: 1000")
+	require.Equal(t, float64(maxAdjustedCount), intent(TraceIDRatioFromAdjustedCount(1<<63)).Threshold.AdjustedCount())
+}
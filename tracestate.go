@@ -4,7 +4,9 @@
 package sampler
 
 import (
+	"errors"
 	"fmt"
+	"math/rand/v2"
 	"strconv"
 	"strings"
 
@@ -12,13 +14,17 @@ import (
 	"go.opentelemetry.io/otel/trace"
 )
 
-// fieldSearchKey is a two-character OpenTelemetry tracestate field name
-// (e.g., "rv", "th"), preceded by ';', followed by ':'.
+// fieldSearchKey is an OpenTelemetry tracestate field name (e.g.,
+// "rv", "th"), preceded by ';', followed by ':'.
 type fieldSearchKey string
 
 const randomnessSearchKey fieldSearchKey = ";rv:"
 const thresholdSearchKey fieldSearchKey = ";th:"
 
+// legacyProbabilitySearchKey is the deprecated power-of-two
+// probability field.
+const legacyProbabilitySearchKey fieldSearchKey = ";p:"
+
 // fieldPos indicates the position of the start of the key through the end of the value, ignoring the sub-key separator.
 type fieldPos struct {
 	start int
@@ -28,9 +34,9 @@ type fieldPos struct {
 func tracestateHasOTelField(otts string, search fieldSearchKey) (value string, savePos fieldPos, has bool) {
 	var low int
 	if has := strings.HasPrefix(otts, string(search[1:])); has {
-		low = 3
+		low = len(search) - 1
 	} else if pos := strings.Index(otts, string(search)); pos > 0 {
-		low = pos + 4
+		low = pos + len(search)
 	} else {
 		return "", fieldPos{}, false
 	}
@@ -44,46 +50,124 @@ func tracestateHasOTelField(otts string, search fieldSearchKey) (value string, s
 		// add the offset used above in `otts[low:]`
 		high += low
 	}
-	start := low - 3
+	start := low - (len(search) - 1)
 	return otts[low:high], fieldPos{start: start, end: high}, true
 }
 
 // tracestateHasRandomness determines whether there is a "rv" sub-key
-func tracestateHasRandomness(otts string) (int64, bool) {
+func tracestateHasRandomness(otts string) (int64, bool, error) {
 	val, _, has := tracestateHasOTelField(otts, randomnessSearchKey)
 	if !has {
-		return 0, false
+		return 0, false, nil
 	}
 	if len(val) != 14 {
-		otel.Handle(fmt.Errorf("could not parse tracestate randomness: %q: %w", otts, strconv.ErrSyntax))
-		return 0, false
+		return 0, false, fmt.Errorf("could not parse tracestate randomness: %q: %w", otts, strconv.ErrSyntax)
 	}
 	rv, err := strconv.ParseUint(val, 16, 64)
 	if err != nil {
-		otel.Handle(fmt.Errorf("could not parse tracestate randomness: %q: %w", val, err))
-		return 0, false
+		return 0, false, fmt.Errorf("could not parse tracestate randomness: %q: %w", val, err)
+	}
+	return int64(rv), true, nil
+}
+
+// tracestateHasLegacyProbability determines whether there is a
+// deprecated "p" sub-key, which encodes a power-of-two sampling
+// probability 2^-p, and converts it to a threshold.  Probabilities
+// smaller than 2^-56 cannot be converted, except for p=63, which
+// indicates zero probability.
+func tracestateHasLegacyProbability(otts string) (Threshold, bool, error) {
+	val, _, has := tracestateHasOTelField(otts, legacyProbabilitySearchKey)
+	if !has {
+		return 0, false, nil
+	}
+	p, err := strconv.ParseUint(val, 10, 8)
+	if err != nil || p > 63 {
+		return 0, false, fmt.Errorf("could not parse tracestate probability: %q: %w", otts, strconv.ErrSyntax)
+	}
+	switch {
+	case p == 63:
+		return NEVER_SAMPLE_THRESHOLD, true, nil
+	case p > 56:
+		return 0, false, nil
+	}
+	return Threshold(maxAdjustedCount - maxAdjustedCount>>p), true, nil
+}
+
+// insertRandomness writes a new random "rv" sub-key to the
+// tracestate, preceding the existing "ot" value otts, and returns the
+// new tracestate and randomness.
+func insertRandomness(ts trace.TraceState, otts string) (trace.TraceState, int64) {
+	rnd := int64(rand.Uint64() & randomnessMask)
+	value := fmt.Sprintf("rv:%014x", rnd)
+	if otts != "" {
+		value += ";" + otts
+	}
+	rts, err := ts.Insert("ot", value)
+	if err != nil {
+		otel.Handle(fmt.Errorf("tracestate: %w", err))
+		return ts, rnd
 	}
-	return int64(rv), true
+	return rts, rnd
 }
 
 // tracestateHasThreshold determines whether there is a "th" sub-key
-func tracestateHasThreshold(otts string) (int64, fieldPos, bool) {
+func tracestateHasThreshold(otts string) (Threshold, fieldPos, bool, error) {
 	val, savePos, has := tracestateHasOTelField(otts, thresholdSearchKey)
 	if !has {
-		return 0, fieldPos{}, false
-	}
-	if len(val) == 0 || len(val) > 14 {
-		otel.Handle(fmt.Errorf("could not parse tracestate threshold: %q: %w", otts, strconv.ErrSyntax))
-		return -1, fieldPos{}, false
+		return 0, fieldPos{}, false, nil
 	}
-	th, err := strconv.ParseUint(val, 16, 64)
+	th, err := ParseThreshold(val)
 	if err != nil {
-		otel.Handle(fmt.Errorf("could not parse tracestate threshold: %q: %w", val, err))
-		return -1, fieldPos{}, false
+		return INVALID_THRESHOLD, fieldPos{}, false, fmt.Errorf("could not parse tracestate threshold: %q: %w", otts, err)
 	}
-	// Add trailing zeros
-	th <<= (14 - len(val)) * 4
-	return int64(th), savePos, true
+	return th, savePos, true, nil
+}
+
+// otelTraceState is the parsed value of the OpenTelemetry "ot"
+// tracestate member, parsed once per sampling decision.
+type otelTraceState struct {
+	// value is the unmodified "ot" value.
+	value string
+
+	// parsed distinguishes the parsed empty value from the zero
+	// value of this struct.
+	parsed bool
+
+	threshold    Threshold
+	thresholdPos fieldPos
+	hasThreshold bool
+
+	randomness    int64
+	hasRandomness bool
+
+	// err is the first error parsing the sub-keys above.
+	err error
+}
+
+// parseOTelTraceState parses the "rv" and "th" sub-keys of an "ot"
+// tracestate value.
+func parseOTelTraceState(otts string) otelTraceState {
+	ots := otelTraceState{
+		value:  otts,
+		parsed: true,
+	}
+	if otts == "" {
+		return ots
+	}
+	var thErr, rvErr error
+	ots.threshold, ots.thresholdPos, ots.hasThreshold, thErr = tracestateHasThreshold(otts)
+	ots.randomness, ots.hasRandomness, rvErr = tracestateHasRandomness(otts)
+	ots.err = errors.Join(thErr, rvErr)
+	return ots
+}
+
+// field returns the value of an arbitrary sub-key.
+func (ots otelTraceState) field(search fieldSearchKey) (string, bool) {
+	if ots.value == "" {
+		return "", false
+	}
+	val, _, has := tracestateHasOTelField(ots.value, search)
+	return val, has
 }
 
 var simpleAlwaysSampleTracestate = func() trace.TraceState {
@@ -98,9 +182,74 @@ func updateOT(original trace.TraceState, out string) (trace.TraceState, error) {
 	return original.Insert("ot", out)
 }
 
+// canonicalOTelTraceState removes optional whitespace around the
+// sub-keys of an "ot" tracestate value and orders them canonically,
+// with "rv" first, "th" second, and others in their original order.
+// Canonical values are returned without allocating.
+func canonicalOTelTraceState(otts string) string {
+	if isCanonicalOTelTraceState(otts) {
+		return otts
+	}
+	var rv, th string
+	var others []string
+	for _, field := range strings.Split(otts, ";") {
+		field = strings.Trim(field, " \t")
+		switch {
+		case field == "":
+		case rv == "" && strings.HasPrefix(field, "rv:"):
+			rv = field
+		case th == "" && strings.HasPrefix(field, "th:"):
+			th = field
+		default:
+			others = append(others, field)
+		}
+	}
+	fields := make([]string, 0, len(others)+2)
+	if rv != "" {
+		fields = append(fields, rv)
+	}
+	if th != "" {
+		fields = append(fields, th)
+	}
+	return strings.Join(append(fields, others...), ";")
+}
+
+// isCanonicalOTelTraceState returns true when the value has no
+// whitespace, no empty sub-keys, and canonical ordering.
+func isCanonicalOTelTraceState(otts string) bool {
+	if otts == "" {
+		return true
+	}
+	if !isTrimmedOTelTraceState(otts) {
+		return false
+	}
+	leadingRandomness := strings.HasPrefix(otts, "rv:")
+	for i, pos := 0, 0; pos <= len(otts); i++ {
+		end := strings.IndexByte(otts[pos:], ';')
+		if end < 0 {
+			end = len(otts)
+		} else {
+			end += pos
+		}
+		field := otts[pos:end]
+		switch {
+		case i != 0 && strings.HasPrefix(field, "rv:"):
+			return false
+		case strings.HasPrefix(field, "th:") && i != 0 && (i != 1 || !leadingRandomness):
+			return false
+		}
+		pos = end + 1
+	}
+	return true
+}
+
 // combineTracestate combines an existing OTel tracestate fragment,
-// which is the value of a top-level "ot" tracestate vendor tag.
-func combineTracestate(original trace.TraceState, updateThreshold int64, thresholdReliable bool, parsedThreshold int64, thPos fieldPos, hasThreshold bool) (trace.TraceState, error) {
+// which is the value of a top-level "ot" tracestate vendor tag, parsed
+// as ots.  When canonical is set, the output is ordered canonically;
+// see canonicalOTelTraceState.
+func combineTracestate(original trace.TraceState, updateThreshold Threshold, thresholdReliable bool, ots otelTraceState, canonical bool) (trace.TraceState, error) {
+	parsedThreshold, thPos, hasThreshold := ots.threshold, ots.thresholdPos, ots.hasThreshold
+
 	// Try to optimize several fast paths. Remember this is a prototype :-)
 	switch {
 	case !thresholdReliable && !hasThreshold && parsedThreshold == 0:
@@ -114,62 +263,213 @@ func combineTracestate(original trace.TraceState, updateThreshold int64, thresho
 		return original, nil
 	}
 
-	// By design the OT tracestate value is unmodified.
-	// Note: Maybe trim whitespace from the value below?
-	unmodified := original.Get("ot")
+	// By design the OT tracestate value is unmodified, except
+	// when canonicalized by the caller.  The new value is
+	// appended to a buffer on the stack and converted to a string
+	// once, because this is the principal allocation site when
+	// thresholds change.
+	unmodified := ots.value
+	var stack [otelValueBufferLen]byte
+	buf := stack[:0]
 
-	var out strings.Builder
+	if !thresholdReliable {
+		buf = appendOTelFields(buf, unmodified, thPos, fieldPos{})
+		return updateOT(original, string(buf))
+	}
+	var rvPos fieldPos
+	if canonical {
+		// Write "rv" first and "th" second.
+		if _, pos, has := tracestateHasOTelField(unmodified, randomnessSearchKey); has {
+			rvPos = pos
+			buf = append(buf, unmodified[pos.start:pos.end]...)
+		}
+		buf = appendThresholdField(buf, updateThreshold)
+		buf = appendOTelFields(buf, unmodified, thPos, rvPos)
+		return updateOT(original, string(buf))
+	}
+	buf = appendOTelFields(buf, unmodified, thPos, rvPos)
+	buf = appendThresholdField(buf, updateThreshold)
+	return updateOT(original, string(buf))
+}
+
+// otelValueBufferLen is the size of the stack buffer used to build
+// "ot" tracestate values, which is the W3C limit on the length of a
+// tracestate list member value.  Longer values are built on the heap.
+const otelValueBufferLen = 256
+
+// appendOTelFields appends the sub-keys of otts to buf, separated by
+// ';', except those starting at the positions of skip1 and skip2.
+// Empty positions are not skipped.
+func appendOTelFields(buf []byte, otts string, skip1, skip2 fieldPos) []byte {
+	for pos := 0; pos < len(otts); {
+		end := strings.IndexByte(otts[pos:], ';')
+		if end < 0 {
+			end = len(otts)
+		} else {
+			end += pos
+		}
+		skip := (skip1.start != skip1.end && pos == skip1.start) ||
+			(skip2.start != skip2.end && pos == skip2.start)
+		if !skip {
+			if len(buf) != 0 {
+				buf = append(buf, ';')
+			}
+			buf = append(buf, otts[pos:end]...)
+		}
+		pos = end + 1
+	}
+	return buf
+}
+
+// appendThresholdField appends a "th" sub-key to buf.
+func appendThresholdField(buf []byte, threshold Threshold) []byte {
+	if len(buf) != 0 {
+		buf = append(buf, ';')
+	}
+	buf = append(buf, "th:"...)
+	return threshold.appendText(buf)
+}
+
+const (
+	// maxTraceStateLen is the W3C limit on the length of a
+	// tracestate header.
+	maxTraceStateLen = 512
+
+	// maxTraceStateMembers is the W3C limit on the number of
+	// tracestate list members.
+	maxTraceStateMembers = 32
+
+	// largeTraceStateMember is the length of list members that
+	// W3C Trace Context recommends removing first.
+	largeTraceStateMember = 128
+)
+
+// TraceStateEvictionPolicy determines which tracestate members a
+// CompositeSampler removes to keep the tracestate within the W3C
+// limits of 512 characters and 32 members.  The OpenTelemetry "ot"
+// member is never removed.
+type TraceStateEvictionPolicy int
+
+const (
+	// EvictLargeThenOldest removes members longer than 128
+	// characters, then the oldest (right-most) members, as
+	// recommended by W3C Trace Context.
+	EvictLargeThenOldest TraceStateEvictionPolicy = iota
+
+	// EvictOldest removes the oldest (right-most) members.
+	EvictOldest
+)
 
-	copyExceptThreshold := func() int {
-		sections := 0
+// traceStateMember is a tracestate list member.
+type traceStateMember struct {
+	key, value string
+}
+
+func (m traceStateMember) len() int {
+	return len(m.key) + 1 + len(m.value)
+}
 
-		// Case where we erase a threshold.
-		//
-		// hadThreshold is true, otherwise the branch
-		// above. Write all except the former threshold.
-		start := thPos.start
-		end := thPos.end
+// traceStateMembers returns the members of a tracestate in order and
+// the length of its encoding.
+func traceStateMembers(ts trace.TraceState) ([]traceStateMember, int) {
+	members := make([]traceStateMember, 0, ts.Len())
+	size := -1
+	ts.Walk(func(key, value string) bool {
+		m := traceStateMember{key: key, value: value}
+		members = append(members, m)
+		size += m.len() + 1
+		return true
+	})
+	return members, max(size, 0)
+}
+
+// traceStateSize returns the length of the encoding of a tracestate
+// without allocating.
+func traceStateSize(ts trace.TraceState) int {
+	size := -1
+	ts.Walk(func(key, value string) bool {
+		size += len(key) + 1 + len(value) + 1
+		return true
+	})
+	return max(size, 0)
+}
 
-		// If the th sub-key is not first, subtract 1 to consume a
-		// separator.
-		if start != 0 {
-			start--
-			_, _ = out.WriteString(unmodified[:start])
-			sections++
+// evictionVictim returns the index of the member to remove, or -1 if
+// only the "ot" member remains.
+func (policy TraceStateEvictionPolicy) evictionVictim(members []traceStateMember) int {
+	if policy == EvictLargeThenOldest {
+		for i := len(members) - 1; i >= 0; i-- {
+			if members[i].key != "ot" && members[i].len() > largeTraceStateMember {
+				return i
+			}
 		}
-		// If the th sub-key is not last, [end:] includes a separator.
-		if end != len(unmodified) {
-			_, _ = out.WriteString(unmodified[end:])
-			sections++
+	}
+	for i := len(members) - 1; i >= 0; i-- {
+		if members[i].key != "ot" {
+			return i
 		}
-		return sections
 	}
+	return -1
+}
 
-	if !thresholdReliable {
-		copyExceptThreshold()
-		return updateOT(original, out.String())
+// limitTraceState removes members from the tracestate until it has at
+// most maxMembers members and at most maxTraceStateLen characters.
+// Evictions are reported via otel.Handle.
+func (policy TraceStateEvictionPolicy) limitTraceState(ts trace.TraceState, maxMembers int) trace.TraceState {
+	if ts.Len() <= maxMembers && traceStateSize(ts) <= maxTraceStateLen {
+		return ts
 	}
-	// Sections is zero or non-zero, absolute value does not matter.
-	var sections int
-	if thPos.start != thPos.end {
-		sections = copyExceptThreshold()
-	} else {
-		_, _ = out.WriteString(unmodified)
-		sections = len(unmodified)
+	members, size := traceStateMembers(ts)
+	var evicted []string
+	for len(members) > maxMembers || size > maxTraceStateLen {
+		victim := policy.evictionVictim(members)
+		if victim < 0 {
+			break
+		}
+		size -= members[victim].len() + 1
+		evicted = append(evicted, members[victim].key)
+		ts = ts.Delete(members[victim].key)
+		members = append(members[:victim], members[victim+1:]...)
 	}
-	nf := ";th:"
-	if sections == 0 {
-		nf = nf[1:]
+	if len(evicted) != 0 {
+		otel.Handle(fmt.Errorf("tracestate: evicted %s to enforce W3C limits", strings.Join(evicted, ",")))
 	}
-	_, _ = out.WriteString(nf)
+	return ts
+}
 
-	if updateThreshold == 0 {
-		// Special case is required, otherwise the TrimRight() below
-		// would leave an empty string.
-		out.WriteString("0")
-	} else {
-		// Format as an unsigned integer and remove trailing zeros.
-		out.WriteString(strings.TrimRight(strconv.FormatUint(uint64(updateThreshold), 16), "0"))
+// reserveOTelTraceState removes a member from a full tracestate that
+// has no "ot" member, so that inserting one does not silently remove
+// the right-most member.
+func (policy TraceStateEvictionPolicy) reserveOTelTraceState(ts trace.TraceState) trace.TraceState {
+	if ts.Len() < maxTraceStateMembers || ts.Get("ot") != "" {
+		return ts
+	}
+	return policy.limitTraceState(ts, maxTraceStateMembers-1)
+}
+
+// isTrimmedOTelTraceState returns true when the value has no
+// whitespace and no empty sub-keys.
+func isTrimmedOTelTraceState(otts string) bool {
+	return !strings.ContainsAny(otts, " \t") &&
+		!strings.HasPrefix(otts, ";") &&
+		!strings.HasSuffix(otts, ";") &&
+		!strings.Contains(otts, ";;")
+}
+
+// canonicalizeTraceState replaces an "ot" member having whitespace or
+// empty sub-keys with its canonical value.  Otherwise, the sub-keys
+// are reordered only when the threshold is written, so that parent
+// tracestate can be propagated unmodified.  See
+// canonicalOTelTraceState.
+func canonicalizeTraceState(ts trace.TraceState) trace.TraceState {
+	otts := ts.Get("ot")
+	if isTrimmedOTelTraceState(otts) {
+		return ts
 	}
-	return updateOT(original, out.String())
+	rts, err := updateOT(ts, canonicalOTelTraceState(otts))
+	if err != nil {
+		otel.Handle(fmt.Errorf("tracestate: %w", err))
+		return ts
+	}
+	return rts
 }
@@ -3,18 +3,22 @@
 package sampler
 
 import (
+	"context"
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/trace"
 )
 
 func TestTracestateThresholdUpdate(t *testing.T) {
 	type testCase struct {
 		tstate       string
-		threshold    int64
+		threshold    Threshold
 		randomness   int64
-		newThreshold int64
+		newThreshold Threshold
 		output       string
 	}
 
@@ -37,21 +41,21 @@ func TestTracestateThresholdUpdate(t *testing.T) {
 			tstate:       "co=whateverr,ed=nowaysir,ot=xx:abc;yy:def;th:0;rv:abcdefabcdefab",
 			threshold:    0,
 			randomness:   0xabcdefabcdefab,
-			newThreshold: 0x8000000000,
+			newThreshold: 0x80000000000000,
 			output:       "ot=xx:abc;yy:def;rv:abcdefabcdefab;th:8,co=whateverr,ed=nowaysir",
 		},
 		{
 			tstate:       "ot=xx:abc;yy:def;th:0;rv:abcdefabcdefab,co=whateverr,ed=nowaysir",
 			threshold:    0,
 			randomness:   0xabcdefabcdefab,
-			newThreshold: 0x7c00000000,
+			newThreshold: 0x7c000000000000,
 			output:       "ot=xx:abc;yy:def;rv:abcdefabcdefab;th:7c,co=whateverr,ed=nowaysir",
 		},
 		{
 			tstate:       "co=whateverr,ot=xx:abc;yy:def;th:0,ed=nowaysir",
 			threshold:    0,
 			randomness:   -1,
-			newThreshold: 0x7c00000000,
+			newThreshold: 0x7c000000000000,
 			output:       "ot=xx:abc;yy:def;th:7c,co=whateverr,ed=nowaysir",
 		},
 		{
@@ -61,25 +65,318 @@ func TestTracestateThresholdUpdate(t *testing.T) {
 			newThreshold: -1,
 			output:       "ot=xx:abc;yy:def,co=whateverr,ed=nowaysir",
 		},
+		{
+			tstate:       "ot=th:8;rv:abcdefabcdefab",
+			threshold:    0x80000000000000,
+			randomness:   0xabcdefabcdefab,
+			newThreshold: -1,
+			output:       "ot=rv:abcdefabcdefab",
+		},
+		{
+			tstate:       "ot=th:8;xx:abc",
+			threshold:    0x80000000000000,
+			randomness:   -1,
+			newThreshold: 0x12340000000000,
+			output:       "ot=xx:abc;th:1234",
+		},
+		{
+			// Leading zero digits are significant.
+			tstate:       "ot=th:028f",
+			threshold:    0x028f0000000000,
+			randomness:   -1,
+			newThreshold: 0x00400000000000,
+			output:       "ot=th:004",
+		},
 	} {
 		ts, err := trace.ParseTraceState(test.tstate)
 		require.NoError(t, err)
 
 		otts := ts.Get("ot")
-		threshold, savePos, hasThreshold := tracestateHasThreshold(otts)
+		ots := parseOTelTraceState(otts)
+		threshold, hasThreshold := ots.threshold, ots.hasThreshold
 		require.Equal(t, test.threshold >= 0, hasThreshold)
 		if test.threshold >= 0 {
 			require.Equal(t, test.threshold, threshold)
 		}
 
-		rnd, hasRandom := tracestateHasRandomness(otts)
+		rnd, hasRandom := ots.randomness, ots.hasRandomness
 		require.Equal(t, test.randomness >= 0, hasRandom)
 		if test.randomness >= 0 {
 			require.Equal(t, test.randomness, rnd)
 		}
 
-		rts, err := combineTracestate(ts, test.newThreshold, test.newThreshold >= 0, threshold, savePos, hasThreshold)
+		rts, err := combineTracestate(ts, test.newThreshold, test.newThreshold >= 0, ots, false)
 		require.NoError(t, err)
 		require.Equal(t, test.output, rts.String())
 	}
 }
+
+// TestCombineTracestateAllocs tests that combining a tracestate
+// allocates only the new "ot" value beyond what updating the
+// tracestate requires.
+func TestCombineTracestateAllocs(t *testing.T) {
+	ts, err := trace.ParseTraceState("co=whateverr,ot=xx:abc;rv:abcdefabcdefab;th:8,ed=nowaysir")
+	require.NoError(t, err)
+	ots := parseOTelTraceState(ts.Get("ot"))
+	update := Threshold(0x12340000000000)
+
+	for _, test := range []struct {
+		name      string
+		reliable  bool
+		canonical bool
+		output    string
+	}{
+		{"unreliable", false, false, "xx:abc;rv:abcdefabcdefab"},
+		{"reliable", true, false, "xx:abc;rv:abcdefabcdefab;th:1234"},
+		{"canonical", true, true, "rv:abcdefabcdefab;th:1234;xx:abc"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			rts, err := combineTracestate(ts, update, test.reliable, ots, test.canonical)
+			require.NoError(t, err)
+			require.Equal(t, test.output, rts.Get("ot"))
+
+			base := testing.AllocsPerRun(100, func() {
+				_, _ = updateOT(ts, test.output)
+			})
+			allocs := testing.AllocsPerRun(100, func() {
+				_, _ = combineTracestate(ts, update, test.reliable, ots, test.canonical)
+			})
+			require.Equal(t, base+1, allocs)
+		})
+	}
+}
+
+func TestParseOTelTraceState(t *testing.T) {
+	ots := parseOTelTraceState("xx:abc;th:c;rv:abcdefabcdefab")
+	require.True(t, ots.parsed)
+	require.True(t, ots.hasThreshold)
+	require.Equal(t, Threshold(0xc0000000000000), ots.threshold)
+	require.Equal(t, fieldPos{start: 7, end: 11}, ots.thresholdPos)
+	require.True(t, ots.hasRandomness)
+	require.Equal(t, int64(0xabcdefabcdefab), ots.randomness)
+
+	val, has := ots.field(";xx:")
+	require.True(t, has)
+	require.Equal(t, "abc", val)
+	_, has = ots.field(";yy:")
+	require.False(t, has)
+
+	empty := parseOTelTraceState("")
+	require.True(t, empty.parsed)
+	require.False(t, empty.hasThreshold)
+	require.False(t, empty.hasRandomness)
+
+	// The parameters constructed by CompositeSampler carry the
+	// parsed value, otherwise it is parsed on demand.
+	ts, err := trace.ParseTraceState("ot=th:8")
+	require.NoError(t, err)
+	params := ComposableSamplingParameters{
+		ParentSpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{1},
+			SpanID:     trace.SpanID{1},
+			TraceState: ts,
+		}),
+	}
+	require.Equal(t, Threshold(0x80000000000000), params.parentOTelTraceState().threshold)
+	params.otelTraceState = ots
+	require.Equal(t, ots, params.parentOTelTraceState())
+}
+
+func TestTraceStateLimits(t *testing.T) {
+	var errs []error
+	previous := otel.GetErrorHandler()
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		errs = append(errs, err)
+	}))
+	t.Cleanup(func() { otel.SetErrorHandler(previous) })
+
+	build := func(members ...string) trace.TraceState {
+		ts, err := trace.ParseTraceState(strings.Join(members, ","))
+		require.NoError(t, err)
+		return ts
+	}
+	keys := func(ts trace.TraceState) (r []string) {
+		ts.Walk(func(key, _ string) bool {
+			r = append(r, key)
+			return true
+		})
+		return r
+	}
+	sample := func(ts trace.TraceState, policy TraceStateEvictionPolicy) trace.TraceState {
+		params := SamplingParameters{
+			ParentContext: trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID:    trace.TraceID{1},
+				SpanID:     trace.SpanID{1},
+				TraceFlags: trace.FlagsSampled,
+				TraceState: ts,
+			})),
+			TraceID: trace.TraceID{1},
+		}
+		return CompositeSampler(ComposableAlwaysSample(), WithTraceStateEviction(policy)).ShouldSample(params).Tracestate
+	}
+
+	// 32 members without "ot": inserting it evicts one member.
+	var full []string
+	for i := range maxTraceStateMembers {
+		full = append(full, fmt.Sprintf("k%d=v", i))
+	}
+	full[3] = "k3=" + strings.Repeat("x", 130)
+	result := sample(build(full...), EvictOldest)
+	require.Equal(t, maxTraceStateMembers, result.Len())
+	require.Equal(t, "th:0", result.Get("ot"))
+	require.Equal(t, "", result.Get("k31"))
+	require.Len(t, errs, 1)
+	require.ErrorContains(t, errs[0], "evicted k31 to enforce W3C limits")
+
+	errs = nil
+	result = sample(build(full...), EvictLargeThenOldest)
+	require.Equal(t, maxTraceStateMembers, result.Len())
+	require.Equal(t, "", result.Get("k3"))
+	require.Equal(t, "v", result.Get("k31"))
+	require.Len(t, errs, 1)
+
+	// Exceeding 512 characters evicts until the limit is met,
+	// never evicting "ot".
+	errs = nil
+	long := build(
+		"a="+strings.Repeat("a", 200),
+		"ot=th:0",
+		"b="+strings.Repeat("b", 100),
+		"c="+strings.Repeat("c", 190),
+		"d="+strings.Repeat("d", 100),
+	)
+	result = sample(long, EvictOldest)
+	require.Equal(t, []string{"a", "ot", "b", "c"}, keys(result))
+	require.LessOrEqual(t, len(result.String()), maxTraceStateLen)
+	require.ErrorContains(t, errs[0], "evicted d")
+
+	result = sample(long, EvictLargeThenOldest)
+	require.Equal(t, []string{"a", "ot", "b", "d"}, keys(result))
+	require.LessOrEqual(t, len(result.String()), maxTraceStateLen)
+
+	// Within the limits, nothing changes.
+	errs = nil
+	small := build("a=1", "ot=th:0")
+	require.Equal(t, small, sample(small, EvictOldest))
+	require.Empty(t, errs)
+}
+
+func TestLegacyProbability(t *testing.T) {
+	for _, test := range []struct {
+		otts      string
+		threshold Threshold
+		has       bool
+		err       bool
+	}{
+		{"", 0, false, false},
+		{"p:0", ALWAYS_SAMPLE_THRESHOLD, true, false},
+		{"p:1;r:4", 0x80000000000000, true, false},
+		{"r:4;p:2", 0xc0000000000000, true, false},
+		{"p:56", 0xffffffffffffff, true, false},
+		{"p:57", 0, false, false},
+		{"p:63", NEVER_SAMPLE_THRESHOLD, true, false},
+		{"p:64", 0, false, true},
+		{"p:x", 0, false, true},
+	} {
+		th, has, err := tracestateHasLegacyProbability(test.otts)
+		require.Equal(t, test.threshold, th, test.otts)
+		require.Equal(t, test.has, has, test.otts)
+		require.Equal(t, test.err, err != nil, test.otts)
+	}
+
+	ts, err := trace.ParseTraceState("ot=p:2;r:5")
+	require.NoError(t, err)
+	traceID := trace.TraceID{0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	params := SamplingParameters{
+		ParentContext: trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    traceID,
+			SpanID:     trace.SpanID{1},
+			TraceFlags: trace.FlagsSampled,
+			TraceState: ts,
+		})),
+		TraceID: traceID,
+	}
+	withThreshold := RuleBased(
+		WithRule(HasParentThresholdPredicate(), ParentThreshold()),
+		WithDefaultRule(ComposableNeverSample()),
+	)
+
+	res := CompositeSampler(withThreshold).ShouldSample(params)
+	require.Equal(t, Drop, res.Decision)
+	require.Equal(t, "p:2;r:5", res.Tracestate.Get("ot"))
+
+	res = CompositeSampler(withThreshold, WithLegacyProbability()).ShouldSample(params)
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Equal(t, "th:c;p:2;r:5", res.Tracestate.Get("ot"))
+
+	// The "p" sub-key was decided against the "r" sub-key, which is
+	// consistent with it even when the TraceID is not.
+	var inconsistencies []Inconsistency
+	traceID = trace.TraceID{1}
+	params.TraceID = traceID
+	params.ParentContext = trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+		TraceState: ts,
+	}))
+	res = CompositeSampler(withThreshold, WithLegacyProbability(), WithInconsistencyHandler(func(inc Inconsistency) {
+		inconsistencies = append(inconsistencies, inc)
+	})).ShouldSample(params)
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Equal(t, "th:c;p:2;r:5", res.Tracestate.Get("ot"))
+	require.Empty(t, inconsistencies)
+}
+
+func TestCanonicalOTelTraceState(t *testing.T) {
+	for _, test := range []struct {
+		in, out string
+	}{
+		{"", ""},
+		{"th:8", "th:8"},
+		{"rv:abcdefabcdefab;th:8;xx:1", "rv:abcdefabcdefab;th:8;xx:1"},
+		{"xx:1;th:8;rv:abcdefabcdefab", "rv:abcdefabcdefab;th:8;xx:1"},
+		{"xx:1;th:8", "th:8;xx:1"},
+		{"xx:1 ; yy:2;\tth:8 ", "th:8;xx:1;yy:2"},
+		{"th:8;;xx:1;", "th:8;xx:1"},
+		{"th:8;th:4", "th:8;th:4"},
+		{"th:4;rv:abcdefabcdefab;rv:00000000000000", "rv:abcdefabcdefab;th:4;rv:00000000000000"},
+	} {
+		t.Run(test.in, func(t *testing.T) {
+			require.Equal(t, test.out, canonicalOTelTraceState(test.in))
+			if test.in != test.out {
+				require.False(t, isCanonicalOTelTraceState(test.in))
+			}
+		})
+	}
+}
+
+func TestStrictTraceState(t *testing.T) {
+	ts, err := trace.ParseTraceState("ot=xx:1; th:8 ;rv:c0000000000000,a=b")
+	require.NoError(t, err)
+	params := SamplingParameters{
+		ParentContext: trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{1},
+			SpanID:     trace.SpanID{1},
+			TraceFlags: trace.FlagsSampled,
+			TraceState: ts,
+		})),
+		TraceID: trace.TraceID{1},
+	}
+	sampler := ComposableParentBased(TraceIDRatioBased(0.5))
+
+	var errs []error
+	handler := WithTraceStateErrorHandler(func(err error) {
+		errs = append(errs, err)
+	})
+	res := CompositeSampler(sampler, handler).ShouldSample(params)
+	require.Empty(t, errs)
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Equal(t, "ot=rv:c0000000000000;th:8;xx:1,a=b", res.Tracestate.String())
+
+	// The threshold is not found, so the sampled flag is trusted.
+	res = CompositeSampler(sampler, handler, WithStrictTraceState()).ShouldSample(params)
+	require.Empty(t, errs)
+	require.Equal(t, RecordAndSample, res.Decision)
+	require.Equal(t, "ot=xx:1; th:8 ;rv:c0000000000000,a=b", res.Tracestate.String())
+}